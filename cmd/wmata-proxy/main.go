@@ -0,0 +1,53 @@
+// Command wmata-proxy serves the WMATA API paths with the server's API key, so client apps never embed the key.
+//
+// Usage:
+//
+//	wmata-proxy [-addr :8080] [-upstream https://api.wmata.com] [-rps 10] [-cors-origin *]
+//
+// The API key is read from the WMATA_API_KEY environment variable. Responses are cached per path, identical concurrent
+// requests share a single upstream request and upstream requests are rate limited.
+package main
+
+import (
+	"flag"
+	"github.com/awiede/wmata-go-sdk/wmata"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+)
+
+// apiKeyEnv is the environment variable holding the WMATA API key
+const apiKeyEnv = "WMATA_API_KEY"
+
+func main() {
+	addr := flag.String("addr", ":8080", "address to listen on")
+	upstream := flag.String("upstream", "https://api.wmata.com", "WMATA API base URL")
+	rps := flag.Int("rps", wmata.DefaultRequestsPerSecond, "upstream requests per second")
+	corsOrigin := flag.String("cors-origin", "*", "Access-Control-Allow-Origin value, empty to disable CORS")
+	flag.Parse()
+
+	apiKey := os.Getenv(apiKeyEnv)
+
+	if apiKey == "" {
+		log.Fatalf("%s environment variable is required", apiKeyEnv)
+	}
+
+	upstreamURL, parseErr := url.Parse(*upstream)
+
+	if parseErr != nil {
+		log.Fatalf("invalid upstream URL: %s", parseErr)
+	}
+
+	handler := newProxy(config{
+		upstream:    upstreamURL,
+		apiKey:      apiKey,
+		httpClient:  &http.Client{Timeout: 30 * time.Second},
+		rateLimiter: wmata.NewRateLimiter(*rps),
+		corsOrigin:  *corsOrigin,
+	})
+
+	log.Printf("proxying %s on %s", upstreamURL, *addr)
+	log.Fatal(http.ListenAndServe(*addr, handler))
+}
@@ -0,0 +1,329 @@
+package main
+
+import (
+	"github.com/awiede/wmata-go-sdk/internal/singleflight"
+	"github.com/awiede/wmata-go-sdk/wmata"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"sync"
+	"time"
+)
+
+// allowedPrefixes are the API paths forwarded upstream, every other path is rejected
+var allowedPrefixes = []string{
+	"/Bus.svc/",
+	"/Incidents.svc/",
+	"/NextBusService.svc/",
+	"/Rail.svc/",
+	"/StationPrediction.svc/",
+	"/TrainPositions/",
+}
+
+// cacheRule caches successful responses for paths starting with prefix
+type cacheRule struct {
+	prefix string
+	ttl    time.Duration
+}
+
+// defaultCacheRules are checked in order, so realtime paths come before the reference data services they belong to
+var defaultCacheRules = []cacheRule{
+	{prefix: "/Bus.svc/json/jBusPositions", ttl: 10 * time.Second},
+	{prefix: "/Bus.svc/BusPositions", ttl: 10 * time.Second},
+	{prefix: "/StationPrediction.svc/", ttl: 10 * time.Second},
+	{prefix: "/NextBusService.svc/", ttl: 10 * time.Second},
+	{prefix: "/TrainPositions/TrainPositions", ttl: 5 * time.Second},
+	{prefix: "/Incidents.svc/", ttl: time.Minute},
+	{prefix: "/TrainPositions/", ttl: 24 * time.Hour},
+	{prefix: "/Rail.svc/", ttl: time.Hour},
+	{prefix: "/Bus.svc/", ttl: time.Hour},
+}
+
+// endpointParams are the query parameters each endpoint accepts, by JSON and XML path. Other parameters are dropped so
+// they neither reach upstream nor split the cache, endpoints not listed take no parameters
+var endpointParams = map[string][]string{
+	"/Bus.svc/json/jBusPositions":                {"RouteID", "Lat", "Lon", "Radius"},
+	"/Bus.svc/BusPositions":                      {"RouteID", "Lat", "Lon", "Radius"},
+	"/Bus.svc/json/jRouteDetails":                {"RouteID", "Date"},
+	"/Bus.svc/RouteDetails":                      {"RouteID", "Date"},
+	"/Bus.svc/json/jRouteSchedule":               {"RouteID", "Date", "IncludingVariations"},
+	"/Bus.svc/RouteSchedule":                     {"RouteID", "Date", "IncludingVariations"},
+	"/Bus.svc/json/jStopSchedule":                {"StopID", "Date"},
+	"/Bus.svc/StopSchedule":                      {"StopID", "Date"},
+	"/Bus.svc/json/jStops":                       {"Lat", "Lon", "Radius"},
+	"/Bus.svc/Stops":                             {"Lat", "Lon", "Radius"},
+	"/Incidents.svc/json/BusIncidents":           {"Route"},
+	"/Incidents.svc/BusIncidents":                {"Route"},
+	"/Incidents.svc/json/ElevatorIncidents":      {"StationCode"},
+	"/Incidents.svc/ElevatorIncidents":           {"StationCode"},
+	"/NextBusService.svc/json/jPredictions":      {"StopID"},
+	"/NextBusService.svc/Predictions":            {"StopID"},
+	"/Rail.svc/json/jPath":                       {"FromStationCode", "ToStationCode"},
+	"/Rail.svc/Path":                             {"FromStationCode", "ToStationCode"},
+	"/Rail.svc/json/jSrcStationToDstStationInfo": {"FromStationCode", "ToStationCode"},
+	"/Rail.svc/SrcStationToDstStationInfo":       {"FromStationCode", "ToStationCode"},
+	"/Rail.svc/json/jStationEntrances":           {"Lat", "Lon", "Radius"},
+	"/Rail.svc/StationEntrances":                 {"Lat", "Lon", "Radius"},
+	"/Rail.svc/json/jStationInfo":                {"StationCode"},
+	"/Rail.svc/StationInfo":                      {"StationCode"},
+	"/Rail.svc/json/jStationParking":             {"StationCode"},
+	"/Rail.svc/StationParking":                   {"StationCode"},
+	"/Rail.svc/json/jStations":                   {"LineCode"},
+	"/Rail.svc/Stations":                         {"LineCode"},
+	"/Rail.svc/json/jStationTimes":               {"StationCode"},
+	"/Rail.svc/StationTimes":                     {"StationCode"},
+	"/TrainPositions/StandardRoutes":             {"contentType"},
+	"/TrainPositions/TrackCircuits":              {"contentType"},
+	"/TrainPositions/TrainPositions":             {"contentType"},
+}
+
+// defaultMaxCacheEntries is the number of responses cached when config.maxCacheEntries is zero
+const defaultMaxCacheEntries = 1000
+
+// config holds the proxy settings
+type config struct {
+	upstream    *url.URL
+	apiKey      string
+	httpClient  wmata.HTTPClient
+	rateLimiter wmata.RateLimiter
+	// corsOrigin is returned in Access-Control-Allow-Origin, CORS headers are omitted when empty
+	corsOrigin string
+	cacheRules []cacheRule
+	// maxCacheEntries caps the cache, expired entries are swept first and then the oldest entries are evicted
+	maxCacheEntries int
+	now             func() time.Time
+}
+
+// cachedResponse is an upstream response kept until expires
+type cachedResponse struct {
+	statusCode  int
+	contentType string
+	body        []byte
+	stored      time.Time
+	expires     time.Time
+}
+
+// proxy forwards WMATA API requests upstream with the server's API key, caching and coalescing identical requests
+type proxy struct {
+	config config
+	group  singleflight.Group
+	mutex  sync.Mutex
+	cache  map[string]*cachedResponse
+}
+
+func newProxy(cfg config) *proxy {
+	if cfg.cacheRules == nil {
+		cfg.cacheRules = defaultCacheRules
+	}
+
+	if cfg.maxCacheEntries <= 0 {
+		cfg.maxCacheEntries = defaultMaxCacheEntries
+	}
+
+	if cfg.now == nil {
+		cfg.now = time.Now
+	}
+
+	return &proxy{
+		config: cfg,
+		cache:  make(map[string]*cachedResponse),
+	}
+}
+
+func (p *proxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	p.writeCORS(w)
+
+	switch r.Method {
+	case http.MethodOptions:
+		w.WriteHeader(http.StatusNoContent)
+		return
+	case http.MethodGet, http.MethodHead:
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// paths must already be clean, otherwise /Rail.svc/../ would pass the allowlist and be resolved upstream
+	if path.Clean(r.URL.Path) != r.URL.Path || strings.Contains(r.URL.Path, "..") || !allowed(r.URL.Path) {
+		http.NotFound(w, r)
+		return
+	}
+
+	// clients must never choose the key, no endpoint accepts api_key so the server's key is always used upstream
+	query := acceptedQuery(r.URL.Path, r.URL.Query())
+	key := r.URL.Path + "?" + query.Encode()
+
+	cacheStatus := "HIT"
+	response, cached := p.cached(key)
+
+	if !cached {
+		value, fetchErr, shared := p.group.Do(key, func() (interface{}, error) {
+			return p.fetch(r.URL.Path, query, key)
+		})
+
+		if fetchErr != nil {
+			http.Error(w, "upstream request failed", http.StatusBadGateway)
+			return
+		}
+
+		response = value.(*cachedResponse)
+		cacheStatus = "MISS"
+
+		if shared {
+			cacheStatus = "SHARED"
+		}
+	}
+
+	if response.contentType != "" {
+		w.Header().Set("Content-Type", response.contentType)
+	}
+
+	w.Header().Set("X-Cache", cacheStatus)
+	w.WriteHeader(response.statusCode)
+
+	if r.Method == http.MethodGet {
+		w.Write(response.body)
+	}
+}
+
+// fetch sends a request upstream with the server's API key and caches successful responses
+func (p *proxy) fetch(path string, query url.Values, key string) (*cachedResponse, error) {
+	upstream := *p.config.upstream
+	upstream.Path = strings.TrimRight(upstream.Path, "/") + path
+	upstream.RawQuery = query.Encode()
+
+	request, requestErr := http.NewRequest(http.MethodGet, upstream.String(), nil)
+
+	if requestErr != nil {
+		return nil, requestErr
+	}
+
+	request.Header.Set(wmata.APIKeyHeader, p.config.apiKey)
+
+	if p.config.rateLimiter != nil {
+		p.config.rateLimiter.Wait()
+	}
+
+	upstreamResponse, responseErr := p.config.httpClient.Do(request)
+
+	if responseErr != nil {
+		return nil, responseErr
+	}
+
+	defer wmata.CloseResponseBody(upstreamResponse)
+
+	body, readErr := ioutil.ReadAll(upstreamResponse.Body)
+
+	if readErr != nil {
+		return nil, readErr
+	}
+
+	response := cachedResponse{
+		statusCode:  upstreamResponse.StatusCode,
+		contentType: upstreamResponse.Header.Get("Content-Type"),
+		body:        body,
+	}
+
+	if ttl := p.ttl(path); ttl > 0 && response.statusCode == http.StatusOK {
+		response.stored = p.config.now()
+		response.expires = response.stored.Add(ttl)
+		p.store(key, &response)
+	}
+
+	return &response, nil
+}
+
+// cached returns an unexpired cached response
+func (p *proxy) cached(key string) (*cachedResponse, bool) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	response, exist := p.cache[key]
+
+	if !exist || !p.config.now().Before(response.expires) {
+		return nil, false
+	}
+
+	return response, true
+}
+
+// store caches a response, sweeping expired entries when the cache is full and evicting the oldest entries if it is
+// still full
+func (p *proxy) store(key string, response *cachedResponse) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	delete(p.cache, key)
+
+	if len(p.cache) >= p.config.maxCacheEntries {
+		now := p.config.now()
+
+		for cachedKey, cachedResponse := range p.cache {
+			if !now.Before(cachedResponse.expires) {
+				delete(p.cache, cachedKey)
+			}
+		}
+	}
+
+	for len(p.cache) >= p.config.maxCacheEntries {
+		var oldestKey string
+		var oldest *cachedResponse
+
+		for cachedKey, cachedResponse := range p.cache {
+			if oldest == nil || cachedResponse.stored.Before(oldest.stored) {
+				oldestKey, oldest = cachedKey, cachedResponse
+			}
+		}
+
+		delete(p.cache, oldestKey)
+	}
+
+	p.cache[key] = response
+}
+
+// ttl returns how long responses for a path are cached, zero when they are not cached
+func (p *proxy) ttl(path string) time.Duration {
+	for _, rule := range p.config.cacheRules {
+		if strings.HasPrefix(path, rule.prefix) {
+			return rule.ttl
+		}
+	}
+
+	return 0
+}
+
+func (p *proxy) writeCORS(w http.ResponseWriter) {
+	if p.config.corsOrigin == "" {
+		return
+	}
+
+	w.Header().Set("Access-Control-Allow-Origin", p.config.corsOrigin)
+	w.Header().Set("Access-Control-Allow-Methods", "GET, HEAD, OPTIONS")
+	w.Header().Set("Access-Control-Allow-Headers", "Accept, Content-Type")
+	w.Header().Set("Access-Control-Max-Age", "86400")
+}
+
+// acceptedQuery returns the parameters of a query the endpoint at path accepts, keeping the first value of each
+func acceptedQuery(path string, query url.Values) url.Values {
+	accepted := make(url.Values)
+
+	for _, param := range endpointParams[path] {
+		if value := query.Get(param); value != "" {
+			accepted.Set(param, value)
+		}
+	}
+
+	return accepted
+}
+
+func allowed(path string) bool {
+	for _, prefix := range allowedPrefixes {
+		if strings.HasPrefix(path, prefix) {
+			return true
+		}
+	}
+
+	return false
+}
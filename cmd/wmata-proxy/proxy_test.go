@@ -0,0 +1,309 @@
+package main
+
+import (
+	"github.com/awiede/wmata-go-sdk/wmata"
+	"github.com/awiede/wmata-go-sdk/wmata/railinfo"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+const linesResponse = `{"Lines":[{"LineCode":"RD","DisplayName":"Red","StartStationCode":"A15","EndStationCode":"B11"}]}`
+
+// fakeUpstream serves canned responses, rejecting requests without the server's API key
+type fakeUpstream struct {
+	requests int32
+	queries  chan string
+	release  chan struct{}
+}
+
+func (upstream *fakeUpstream) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	atomic.AddInt32(&upstream.requests, 1)
+
+	if r.Header.Get(wmata.APIKeyHeader) != "server-key" {
+		http.Error(w, "invalid api key", http.StatusUnauthorized)
+		return
+	}
+
+	if upstream.queries != nil {
+		upstream.queries <- r.URL.RawQuery
+	}
+
+	if upstream.release != nil {
+		<-upstream.release
+	}
+
+	switch r.URL.Path {
+	case "/Rail.svc/json/jLines":
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(linesResponse))
+	case "/Incidents.svc/json/Incidents":
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"Incidents":[]}`))
+	default:
+		http.Error(w, "not found", http.StatusNotFound)
+	}
+}
+
+// setupProxy starts a fake upstream and a proxy in front of it
+func setupProxy(t *testing.T, upstream *fakeUpstream, now func() time.Time) (*httptest.Server, func()) {
+	upstreamServer := httptest.NewServer(upstream)
+	upstreamURL, _ := url.Parse(upstreamServer.URL)
+
+	proxyServer := httptest.NewServer(newProxy(config{
+		upstream:   upstreamURL,
+		apiKey:     "server-key",
+		httpClient: upstreamServer.Client(),
+		corsOrigin: "*",
+		now:        now,
+	}))
+
+	return proxyServer, func() {
+		proxyServer.Close()
+		upstreamServer.Close()
+	}
+}
+
+// proxyClient sends SDK requests to the proxy instead of api.wmata.com
+type proxyClient struct {
+	proxy *url.URL
+}
+
+func (client *proxyClient) Do(req *http.Request) (*http.Response, error) {
+	req.URL.Scheme = client.proxy.Scheme
+	req.URL.Host = client.proxy.Host
+
+	return http.DefaultClient.Do(req)
+}
+
+func TestProxyWithSDK(t *testing.T) {
+	upstream := &fakeUpstream{}
+	proxyServer, closeServers := setupProxy(t, upstream, nil)
+	defer closeServers()
+
+	proxyURL, _ := url.Parse(proxyServer.URL)
+
+	// the SDK client sends no key of its own, the proxy injects the server key
+	railService := railinfo.NewService(&wmata.Client{HTTPClient: &proxyClient{proxy: proxyURL}}, wmata.JSON)
+
+	for i := 0; i < 2; i++ {
+		lines, err := railService.GetLines()
+
+		if err != nil {
+			t.Fatalf("error calling GetLines through proxy: %s", err.Error())
+		}
+
+		if len(lines.Lines) != 1 || lines.Lines[0].LineCode != "RD" {
+			t.Errorf("unexpected lines: %v", lines.Lines)
+		}
+	}
+
+	if upstream.requests != 1 {
+		t.Errorf("expected second request to be served from cache, upstream received %d", upstream.requests)
+	}
+}
+
+func TestProxyKeyAndCache(t *testing.T) {
+	now := time.Date(2019, time.April, 29, 12, 0, 0, 0, time.UTC)
+	upstream := &fakeUpstream{queries: make(chan string, 10)}
+	proxyServer, closeServers := setupProxy(t, upstream, func() time.Time { return now })
+	defer closeServers()
+
+	get := func(path string) *http.Response {
+		response, err := http.Get(proxyServer.URL + path)
+
+		if err != nil {
+			t.Fatalf("error calling proxy: %s", err.Error())
+		}
+
+		return response
+	}
+
+	response := get("/Incidents.svc/json/Incidents?api_key=client-key")
+	body, _ := ioutil.ReadAll(response.Body)
+	response.Body.Close()
+
+	if response.StatusCode != http.StatusOK || string(body) != `{"Incidents":[]}` || response.Header.Get("X-Cache") != "MISS" {
+		t.Errorf("unexpected response: %d %s %s", response.StatusCode, response.Header.Get("X-Cache"), body)
+	}
+
+	if query := <-upstream.queries; query != "" {
+		t.Errorf("expected client api_key to be stripped, upstream received query %q", query)
+	}
+
+	if response.Header.Get("Access-Control-Allow-Origin") != "*" {
+		t.Error("expected CORS header on response")
+	}
+
+	response = get("/Incidents.svc/json/Incidents")
+	response.Body.Close()
+
+	if response.Header.Get("X-Cache") != "HIT" || upstream.requests != 1 {
+		t.Errorf("expected cache hit, got %s with %d upstream requests", response.Header.Get("X-Cache"), upstream.requests)
+	}
+
+	// parameters the endpoint does not accept neither split the cache nor reach upstream
+	response = get("/Incidents.svc/json/Incidents?cachebust=1&LineCode=RD")
+	response.Body.Close()
+
+	if response.Header.Get("X-Cache") != "HIT" || upstream.requests != 1 {
+		t.Errorf("expected unknown parameters to be ignored, got %s with %d upstream requests", response.Header.Get("X-Cache"), upstream.requests)
+	}
+
+	now = now.Add(2 * time.Minute)
+
+	response = get("/Incidents.svc/json/Incidents")
+	response.Body.Close()
+	<-upstream.queries
+
+	if response.Header.Get("X-Cache") != "MISS" || upstream.requests != 2 {
+		t.Errorf("expected expired entry to be refreshed, got %s with %d upstream requests", response.Header.Get("X-Cache"), upstream.requests)
+	}
+
+	response = get("/Rail.svc/json/jMissing")
+	response.Body.Close()
+	<-upstream.queries
+
+	if response.StatusCode != http.StatusNotFound {
+		t.Errorf("expected upstream status to be passed through, got %d", response.StatusCode)
+	}
+}
+
+func TestProxyCacheLimit(t *testing.T) {
+	now := time.Date(2019, time.April, 29, 12, 0, 0, 0, time.UTC)
+	p := newProxy(config{maxCacheEntries: 2, now: func() time.Time { return now }})
+
+	storeAt := func(key string, ttl time.Duration) {
+		p.store(key, &cachedResponse{statusCode: http.StatusOK, stored: now, expires: now.Add(ttl)})
+		now = now.Add(time.Second)
+	}
+
+	storeAt("first", time.Hour)
+	storeAt("second", time.Hour)
+	storeAt("third", time.Hour)
+
+	if _, cached := p.cached("first"); cached || len(p.cache) != 2 {
+		t.Errorf("expected the oldest entry to be evicted, cache holds %d entries", len(p.cache))
+	}
+
+	// expired entries are swept before unexpired ones are evicted
+	storeAt("short", 2*time.Second)
+	now = now.Add(5 * time.Second)
+	storeAt("fourth", time.Hour)
+
+	for _, key := range []string{"third", "fourth"} {
+		if _, cached := p.cached(key); !cached {
+			t.Errorf("expected %s to be cached", key)
+		}
+	}
+
+	if len(p.cache) != 2 {
+		t.Errorf("expected 2 cached entries, got %d", len(p.cache))
+	}
+}
+
+func TestAcceptedQuery(t *testing.T) {
+	query := url.Values{
+		"RouteID":       {"70", "79"},
+		"Date":          {"2019-04-29"},
+		"api_key":       {"client-key"},
+		"cachebust":     {"1"},
+		"IncludeFuture": {"true"},
+	}
+
+	if accepted := acceptedQuery("/Bus.svc/json/jRouteDetails", query).Encode(); accepted != "Date=2019-04-29&RouteID=70" {
+		t.Errorf("unexpected accepted query: %s", accepted)
+	}
+
+	if accepted := acceptedQuery("/StationPrediction.svc/json/GetPrediction/A01", query).Encode(); accepted != "" {
+		t.Errorf("expected no parameters for an endpoint without any, got %s", accepted)
+	}
+}
+
+func TestProxyCoalescing(t *testing.T) {
+	upstream := &fakeUpstream{release: make(chan struct{})}
+	proxyServer, closeServers := setupProxy(t, upstream, nil)
+	defer closeServers()
+
+	var finished sync.WaitGroup
+	statuses := make([]int, 5)
+
+	for i := range statuses {
+		i := i
+		finished.Add(1)
+
+		go func() {
+			defer finished.Done()
+
+			response, err := http.Get(proxyServer.URL + "/Rail.svc/json/jLines")
+
+			if err != nil {
+				return
+			}
+
+			response.Body.Close()
+			statuses[i] = response.StatusCode
+		}()
+	}
+
+	// give every request time to reach the proxy before the upstream responds
+	time.Sleep(50 * time.Millisecond)
+	close(upstream.release)
+	finished.Wait()
+
+	if upstream.requests != 1 {
+		t.Errorf("expected identical requests to share 1 upstream request, got %d", upstream.requests)
+	}
+
+	for i, status := range statuses {
+		if status != http.StatusOK {
+			t.Errorf("unexpected status for request %d: %d", i, status)
+		}
+	}
+}
+
+func TestProxyRejects(t *testing.T) {
+	upstream := &fakeUpstream{}
+	proxyServer, closeServers := setupProxy(t, upstream, nil)
+	defer closeServers()
+
+	testRequests := []struct {
+		method string
+		path   string
+		status int
+	}{
+		{method: http.MethodGet, path: "/Misc/Validate", status: http.StatusNotFound},
+		{method: http.MethodGet, path: "/", status: http.StatusNotFound},
+		{method: http.MethodGet, path: "/Rail.svc/../Misc/Validate", status: http.StatusNotFound},
+		{method: http.MethodGet, path: "/Rail.svc/%2e%2e/Misc/Validate", status: http.StatusNotFound},
+		{method: http.MethodGet, path: "/Rail.svc/json/../../Misc/Validate", status: http.StatusNotFound},
+		{method: http.MethodGet, path: "/Rail.svc//json/jLines", status: http.StatusNotFound},
+		{method: http.MethodPost, path: "/Rail.svc/json/jLines", status: http.StatusMethodNotAllowed},
+		{method: http.MethodOptions, path: "/Rail.svc/json/jLines", status: http.StatusNoContent},
+	}
+
+	for _, request := range testRequests {
+		httpRequest, _ := http.NewRequest(request.method, proxyServer.URL+request.path, nil)
+		response, err := http.DefaultClient.Do(httpRequest)
+
+		if err != nil {
+			t.Errorf("error calling proxy: %s", err.Error())
+			continue
+		}
+
+		response.Body.Close()
+
+		if response.StatusCode != request.status {
+			t.Errorf("unexpected status for %s %s: expected %d, got %d", request.method, request.path, request.status, response.StatusCode)
+		}
+	}
+
+	if upstream.requests != 0 {
+		t.Errorf("expected rejected requests not to reach upstream, got %d", upstream.requests)
+	}
+}
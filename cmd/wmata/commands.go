@@ -0,0 +1,316 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"github.com/awiede/wmata-go-sdk/wmata/buspredictions"
+	"github.com/awiede/wmata-go-sdk/wmata/incidents"
+	"github.com/awiede/wmata-go-sdk/wmata/railboard"
+	"github.com/awiede/wmata-go-sdk/wmata/railinfo"
+	"github.com/awiede/wmata-go-sdk/wmata/railpredictions"
+	"github.com/awiede/wmata-go-sdk/wmata/stopboard"
+	"github.com/awiede/wmata-go-sdk/wmata/trainpositions"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// services holds the API services used by commands, so tests can substitute a mock http client
+type services struct {
+	busPredictions  buspredictions.BusPredictions
+	incidents       incidents.Incidents
+	railBoard       railboard.RailBoard
+	railInfo        railinfo.RailInfo
+	railPredictions railpredictions.RailPredictions
+	stopBoard       stopboard.StopBoard
+	trainPositions  trainpositions.TrainPositions
+	validateAPIKey  func() (int, error)
+}
+
+type command struct {
+	usage       string
+	description string
+	run         func(svc *services, args []string) (*result, error)
+}
+
+var commands = map[string]command{
+	"trains": {
+		usage:       "trains <station...>",
+		description: "next train predictions for one or more station codes",
+		run:         runTrains,
+	},
+	"bus": {
+		usage:       "bus <stop>",
+		description: "next bus predictions for a stop ID",
+		run:         runBus,
+	},
+	"incidents": {
+		usage:       "incidents [rail|bus|outages]",
+		description: "rail incidents, bus incidents or elevator and escalator outages, rail by default",
+		run:         runIncidents,
+	},
+	"stations": {
+		usage:       "stations [line]",
+		description: "stations on a line, or every station",
+		run:         runStations,
+	},
+	"path": {
+		usage:       "path <from> <to>",
+		description: "stations between two stations on the same line",
+		run:         runPath,
+	},
+	"fare": {
+		usage:       "fare <from> <to>",
+		description: "fare, distance and rail time between two stations",
+		run:         runFare,
+	},
+	"positions": {
+		usage:       "positions [line]",
+		description: "live train positions, optionally for a single line",
+		run:         runPositions,
+	},
+	"validate": {
+		usage:       "validate",
+		description: "check that the API key is accepted",
+		run:         runValidate,
+	},
+}
+
+func runTrains(svc *services, args []string) (*result, error) {
+	if len(args) == 0 {
+		return nil, errors.New("at least one station code is required")
+	}
+
+	response, responseErr := svc.railPredictions.GetNextTrains(args)
+
+	if responseErr != nil {
+		return nil, responseErr
+	}
+
+	r := result{header: []string{"STATION", "LINE", "CAR", "DESTINATION", "MIN"}, raw: response}
+
+	for _, train := range response.Trains {
+		r.addRow(train.LocationCode, train.Line, train.Car, train.DestinationName, train.Minutes)
+	}
+
+	return &r, nil
+}
+
+func runBus(svc *services, args []string) (*result, error) {
+	if len(args) != 1 {
+		return nil, errors.New("a stop ID is required")
+	}
+
+	response, responseErr := svc.busPredictions.GetNextBuses(args[0])
+
+	if responseErr != nil {
+		return nil, responseErr
+	}
+
+	r := result{header: []string{"ROUTE", "DIRECTION", "MIN", "VEHICLE", "TRIP"}, raw: response}
+
+	for _, prediction := range response.NextBusPredictions {
+		r.addRow(prediction.RouteID, prediction.DirectionText, strconv.Itoa(prediction.Minutes), prediction.VehicleID, prediction.TripID)
+	}
+
+	return &r, nil
+}
+
+func runIncidents(svc *services, args []string) (*result, error) {
+	kind := "rail"
+
+	if len(args) > 0 {
+		kind = args[0]
+	}
+
+	switch kind {
+	case "rail":
+		response, responseErr := svc.incidents.GetRailIncidents()
+
+		if responseErr != nil {
+			return nil, responseErr
+		}
+
+		r := result{header: []string{"TYPE", "LINES", "UPDATED", "DESCRIPTION"}, raw: response}
+
+		for _, incident := range response.RailIncidents {
+			r.addRow(incident.IncidentType, strings.TrimRight(strings.TrimSpace(incident.LinesAffected), ";"), incident.DateUpdated, incident.Description)
+		}
+
+		return &r, nil
+	case "bus":
+		route := ""
+
+		if len(args) > 1 {
+			route = args[1]
+		}
+
+		response, responseErr := svc.incidents.GetBusIncidents(route)
+
+		if responseErr != nil {
+			return nil, responseErr
+		}
+
+		r := result{header: []string{"TYPE", "ROUTES", "UPDATED", "DESCRIPTION"}, raw: response}
+
+		for _, incident := range response.BusIncidents {
+			r.addRow(incident.IncidentType, strings.Join(incident.RoutesAffected, ";"), incident.DateUpdated, incident.Description)
+		}
+
+		return &r, nil
+	case "outages":
+		station := ""
+
+		if len(args) > 1 {
+			station = args[1]
+		}
+
+		response, responseErr := svc.incidents.GetOutages(station)
+
+		if responseErr != nil {
+			return nil, responseErr
+		}
+
+		r := result{header: []string{"STATION", "NAME", "UNIT", "TYPE", "SINCE", "RETURN", "LOCATION"}, raw: response}
+
+		for _, outage := range response.ElevatorIncidents {
+			r.addRow(outage.StationCode, outage.StationName, outage.UnitName, outage.UnitType, outage.DateOutOfService, outage.EstimatedReturnToService, outage.LocationDescription)
+		}
+
+		return &r, nil
+	default:
+		return nil, errors.New("unknown incident type: " + kind)
+	}
+}
+
+func runStations(svc *services, args []string) (*result, error) {
+	lineCode := ""
+
+	if len(args) > 0 {
+		lineCode = strings.ToUpper(args[0])
+	}
+
+	response, responseErr := svc.railInfo.GetStationList(lineCode)
+
+	if responseErr != nil {
+		return nil, responseErr
+	}
+
+	r := result{header: []string{"CODE", "NAME", "LINES", "TOGETHER"}, raw: response}
+
+	for _, station := range response.Stations {
+		var lines []string
+
+		for _, line := range []string{station.LineCode1, station.LineCode2, station.LineCode3, station.LineCode4} {
+			if line != "" {
+				lines = append(lines, line)
+			}
+		}
+
+		r.addRow(station.StationCode, station.Name, strings.Join(lines, ","), station.StationTogether1)
+	}
+
+	return &r, nil
+}
+
+func runPath(svc *services, args []string) (*result, error) {
+	if len(args) != 2 {
+		return nil, errors.New("from and to station codes are required")
+	}
+
+	response, responseErr := svc.railInfo.GetPathBetweenStations(args[0], args[1])
+
+	if responseErr != nil {
+		return nil, responseErr
+	}
+
+	r := result{header: []string{"SEQ", "LINE", "CODE", "NAME", "DISTANCE"}, raw: response}
+
+	for _, item := range response.Path {
+		r.addRow(strconv.Itoa(item.SequenceNumber), item.LineCode, item.StationCode, item.StationName, strconv.Itoa(item.DistanceToPreviousStation))
+	}
+
+	return &r, nil
+}
+
+func runFare(svc *services, args []string) (*result, error) {
+	if len(args) != 2 {
+		return nil, errors.New("from and to station codes are required")
+	}
+
+	response, responseErr := svc.railInfo.GetStationToStationInformation(args[0], args[1])
+
+	if responseErr != nil {
+		return nil, responseErr
+	}
+
+	r := result{header: []string{"FROM", "TO", "PEAK", "OFF_PEAK", "SENIOR_DISABLED", "MILES", "MINUTES"}, raw: response}
+
+	for _, info := range response.StationToStationInformation {
+		r.addRow(
+			info.SourceStation,
+			info.DestinationStation,
+			fmt.Sprintf("%.2f", info.Fare.PeakTime),
+			fmt.Sprintf("%.2f", info.Fare.OffPeakTime),
+			fmt.Sprintf("%.2f", info.Fare.SeniorDisabled),
+			fmt.Sprintf("%.2f", info.CompositeMiles),
+			strconv.Itoa(info.Time),
+		)
+	}
+
+	return &r, nil
+}
+
+func runPositions(svc *services, args []string) (*result, error) {
+	lineCode := ""
+
+	if len(args) > 0 {
+		lineCode = strings.ToUpper(args[0])
+	}
+
+	response, responseErr := svc.trainPositions.GetLiveTrainPositions()
+
+	if responseErr != nil {
+		return nil, responseErr
+	}
+
+	r := result{header: []string{"TRAIN", "LINE", "DESTINATION", "CARS", "CIRCUIT", "DIRECTION", "SERVICE", "SECONDS"}, raw: response}
+
+	for _, position := range response.Positions {
+		if lineCode != "" && position.LineCode != lineCode {
+			continue
+		}
+
+		r.addRow(
+			position.TrainID,
+			position.LineCode,
+			position.DestinationStationCode,
+			strconv.Itoa(position.CarCount),
+			strconv.Itoa(position.CircuitID),
+			strconv.Itoa(position.DirectionNumber),
+			position.ServiceType,
+			strconv.Itoa(position.SecondsAtLocation),
+		)
+	}
+
+	return &r, nil
+}
+
+func runValidate(svc *services, args []string) (*result, error) {
+	statusCode, validateErr := svc.validateAPIKey()
+
+	// the key could not be checked when no response was received
+	if validateErr != nil && statusCode == 0 {
+		return nil, fmt.Errorf("validation request failed: %s", validateErr)
+	}
+
+	if validateErr != nil {
+		return nil, fmt.Errorf("api key rejected (%d %s): %s", statusCode, http.StatusText(statusCode), validateErr)
+	}
+
+	return &result{
+		header: []string{"STATUS", "VALID"},
+		rows:   [][]string{{strconv.Itoa(statusCode), "true"}},
+		raw:    map[string]interface{}{"StatusCode": statusCode, "Valid": true},
+	}, nil
+}
@@ -0,0 +1,152 @@
+// Command wmata queries the WMATA API from the command line.
+//
+// Usage:
+//
+//	wmata [-format table|json|csv] <command> [arguments]
+//
+// The API key is read from the WMATA_API_KEY environment variable. Run wmata without arguments to list commands.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"github.com/awiede/wmata-go-sdk/wmata"
+	"github.com/awiede/wmata-go-sdk/wmata/businfo"
+	"github.com/awiede/wmata-go-sdk/wmata/buspredictions"
+	"github.com/awiede/wmata-go-sdk/wmata/incidents"
+	"github.com/awiede/wmata-go-sdk/wmata/railboard"
+	"github.com/awiede/wmata-go-sdk/wmata/railinfo"
+	"github.com/awiede/wmata-go-sdk/wmata/railpredictions"
+	"github.com/awiede/wmata-go-sdk/wmata/stopboard"
+	"github.com/awiede/wmata-go-sdk/wmata/trainpositions"
+	"io"
+	"os"
+	"os/signal"
+	"sort"
+	"syscall"
+)
+
+// apiKeyEnv is the environment variable holding the WMATA API key
+const apiKeyEnv = "WMATA_API_KEY"
+
+func main() {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+
+	os.Exit(run(os.Args[1:], os.Getenv, newServices, os.Stdout, os.Stderr, signals))
+}
+
+// newServices creates the API services for a client using the given API key
+func newServices(apiKey string) *services {
+	client := wmata.NewWMATADefaultClient(apiKey)
+	client.RateLimiter = wmata.NewRateLimiter(wmata.DefaultRequestsPerSecond)
+
+	return servicesForClient(client)
+}
+
+func servicesForClient(client *wmata.Client) *services {
+	busInfo := businfo.NewService(client, wmata.JSON)
+	busPredictions := buspredictions.NewService(client, wmata.JSON)
+	railInfo := railinfo.NewService(client, wmata.JSON)
+	railPredictions := railpredictions.NewService(client, wmata.JSON)
+
+	return &services{
+		busPredictions:  busPredictions,
+		incidents:       incidents.NewService(client, wmata.JSON),
+		railBoard:       railboard.NewService(railInfo, railPredictions),
+		railInfo:        railInfo,
+		railPredictions: railPredictions,
+		stopBoard:       stopboard.NewService(busInfo, busPredictions),
+		trainPositions:  trainpositions.NewService(client, wmata.JSON),
+		validateAPIKey:  client.ValidateAPIKey,
+	}
+}
+
+// run executes a command line and returns the process exit code: 0 on success, 1 when a command fails and 2 for
+// invalid usage. The watch command runs until a value is received on signals
+func run(args []string, getenv func(string) string, connect func(apiKey string) *services, stdout, stderr io.Writer, signals <-chan os.Signal) int {
+	flags := flag.NewFlagSet("wmata", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	formatFlag := flags.String("format", string(formatTable), "output format: table, json or csv")
+	flags.Usage = func() { usage(stderr, flags) }
+
+	if parseErr := flags.Parse(args); parseErr != nil {
+		return 2
+	}
+
+	format, formatErr := parseFormat(*formatFlag)
+
+	if formatErr != nil {
+		fmt.Fprintln(stderr, formatErr)
+		return 2
+	}
+
+	if flags.NArg() == 0 {
+		usage(stderr, flags)
+		return 2
+	}
+
+	name, commandArgs := flags.Arg(0), flags.Args()[1:]
+	cmd, exist := commands[name]
+
+	if !exist && name != "watch" {
+		fmt.Fprintf(stderr, "unknown command: %s\n", name)
+		usage(stderr, flags)
+		return 2
+	}
+
+	apiKey := getenv(apiKeyEnv)
+
+	if apiKey == "" {
+		fmt.Fprintf(stderr, "%s environment variable is required\n", apiKeyEnv)
+		return 2
+	}
+
+	if name == "watch" {
+		if format != formatTable {
+			fmt.Fprintln(stderr, "watch: dashboards are only drawn as tables, -format is not supported")
+			return 2
+		}
+
+		return runWatch(connect(apiKey), commandArgs, stdout, stderr, signals)
+	}
+
+	r, runErr := cmd.run(connect(apiKey), commandArgs)
+
+	if runErr != nil {
+		fmt.Fprintf(stderr, "%s: %s\n", name, runErr)
+		return 1
+	}
+
+	if writeErr := r.write(stdout, format); writeErr != nil {
+		fmt.Fprintln(stderr, writeErr)
+		return 1
+	}
+
+	return 0
+}
+
+func usage(w io.Writer, flags *flag.FlagSet) {
+	fmt.Fprintln(w, "usage: wmata [flags] <command> [arguments]")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "commands:")
+
+	names := make([]string, 0, len(commands))
+
+	for name := range commands {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	for _, name := range names {
+		fmt.Fprintf(w, "  %-30s %s\n", commands[name].usage, commands[name].description)
+	}
+
+	fmt.Fprintf(w, "  %s\n  %-30s %s\n", watchUsage, "", "refresh an arrival board, bus stop board or incident list until interrupted")
+
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "flags:")
+	flags.PrintDefaults()
+	fmt.Fprintf(w, "\nthe API key is read from %s\n", apiKeyEnv)
+}
@@ -0,0 +1,303 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"github.com/awiede/wmata-go-sdk/wmata"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testClient is a mock implementation of wmata.HTTPClient interface used for testing purposes
+type testClient struct{}
+
+// ensure testClient implements wmata.HTTPClient interface
+var _ wmata.HTTPClient = (*testClient)(nil)
+
+// Do stubs out an httpClient.Do request
+func (client *testClient) Do(req *http.Request) (*http.Response, error) {
+	testResponses, exist := testData[req.URL.Path]
+
+	if !exist {
+		return nil, errors.New("no test data found")
+	}
+
+	for _, response := range testResponses {
+		if response.rawQuery == req.URL.RawQuery {
+			rr := httptest.NewRecorder()
+			rr.WriteHeader(http.StatusOK)
+			_, writeErr := rr.Write([]byte(response.response))
+
+			return rr.Result(), writeErr
+		}
+	}
+
+	return nil, errors.New("no data found")
+}
+
+type testResponseData struct {
+	rawQuery string
+	response string
+}
+
+var testData = map[string][]testResponseData{
+	"/StationPrediction.svc/json/GetPrediction/A01,C01": {
+		{
+			rawQuery: "",
+			response: `{"Trains":[{"Car":"8","Destination":"Glenmont","DestinationCode":"B11","DestinationName":"Glenmont","Group":"1","Line":"RD","LocationCode":"A01","LocationName":"Metro Center","Min":"ARR"},{"Car":"6","Destination":"Vienna","DestinationCode":"K08","DestinationName":"Vienna/Fairfax-GMU","Group":"2","Line":"OR","LocationCode":"C01","LocationName":"Metro Center","Min":"3"}]}`,
+		},
+	},
+	"/NextBusService.svc/json/jPredictions": {
+		{
+			rawQuery: "StopID=1001195",
+			response: `{"Predictions":[{"DirectionNum":"0","DirectionText":"East to Federal Triangle","Minutes":4,"RouteID":"X2","TripID":"930","VehicleID":"8075"}],"StopName":"H St + 7th St"}`,
+		},
+	},
+	"/Incidents.svc/json/Incidents": {
+		{
+			rawQuery: "",
+			response: `{"Incidents":[{"DateUpdated":"2019-04-29T08:11:00","Description":"Red Line trains single tracking, between Grosvenor and Medical Center.","IncidentID":"1","IncidentType":"Delay","LinesAffected":"RD;"}]}`,
+		},
+	},
+	"/Rail.svc/json/jStationInfo": {
+		{
+			rawQuery: "StationCode=A01",
+			response: `{"Code":"A01","Name":"Metro Center","StationTogether1":"C01","StationTogether2":"","LineCode1":"RD"}`,
+		},
+	},
+	"/Rail.svc/json/jStations": {
+		{
+			rawQuery: "LineCode=RD",
+			response: `{"Stations":[{"Code":"A01","Name":"Metro Center","StationTogether1":"C01","LineCode1":"RD","LineCode2":null},{"Code":"A02","Name":"Farragut North","StationTogether1":"","LineCode1":"RD"}]}`,
+		},
+	},
+	"/Rail.svc/json/jSrcStationToDstStationInfo": {
+		{
+			rawQuery: "FromStationCode=A01&ToStationCode=A15",
+			response: `{"StationToStationInfos":[{"CompositeMiles":13.98,"DestinationStation":"A15","RailFare":{"OffPeakTime":3.85,"PeakTime":6.0,"SeniorDisabled":1.9},"RailTime":31,"SourceStation":"A01"}]}`,
+		},
+	},
+}
+
+// testServices creates services backed by a mock http client
+func testServices(string) *services {
+	return servicesForClient(&wmata.Client{
+		HTTPClient: &testClient{},
+	})
+}
+
+func testEnv(key string) string {
+	if key == apiKeyEnv {
+		return "test-key"
+	}
+
+	return ""
+}
+
+func runTest(args ...string) (int, string, string) {
+	stdout, stderr := bytes.Buffer{}, bytes.Buffer{}
+	code := run(args, testEnv, testServices, &stdout, &stderr, nil)
+
+	return code, stdout.String(), stderr.String()
+}
+
+func TestRun(t *testing.T) {
+	testRequests := []struct {
+		args     []string
+		expected string
+	}{
+		{
+			args: []string{"trains", "A01", "C01"},
+			expected: "STATION  LINE  CAR  DESTINATION         MIN\n" +
+				"A01      RD    8    Glenmont            ARR\n" +
+				"C01      OR    6    Vienna/Fairfax-GMU  3\n",
+		},
+		{
+			args: []string{"-format", "csv", "bus", "1001195"},
+			expected: "ROUTE,DIRECTION,MIN,VEHICLE,TRIP\n" +
+				"X2,East to Federal Triangle,4,8075,930\n",
+		},
+		{
+			args: []string{"-format", "csv", "incidents"},
+			expected: "TYPE,LINES,UPDATED,DESCRIPTION\n" +
+				"Delay,RD,2019-04-29T08:11:00,\"Red Line trains single tracking, between Grosvenor and Medical Center.\"\n",
+		},
+		{
+			args: []string{"-format", "csv", "stations", "rd"},
+			expected: "CODE,NAME,LINES,TOGETHER\n" +
+				"A01,Metro Center,RD,C01\n" +
+				"A02,Farragut North,RD,\n",
+		},
+		{
+			args: []string{"fare", "A01", "A15"},
+			expected: "FROM  TO   PEAK  OFF_PEAK  SENIOR_DISABLED  MILES  MINUTES\n" +
+				"A01   A15  6.00  3.85      1.90             13.98  31\n",
+		},
+		{
+			args: []string{"-format", "json", "bus", "1001195"},
+			expected: "{\n" +
+				"  \"Predictions\": [\n" +
+				"    {\n" +
+				"      \"DirectionNum\": \"0\",\n" +
+				"      \"DirectionText\": \"East to Federal Triangle\",\n" +
+				"      \"Minutes\": 4,\n" +
+				"      \"RouteID\": \"X2\",\n" +
+				"      \"TripID\": \"930\",\n" +
+				"      \"VehicleID\": \"8075\"\n" +
+				"    }\n" +
+				"  ],\n" +
+				"  \"StopName\": \"H St + 7th St\"\n" +
+				"}\n",
+		},
+	}
+
+	for _, request := range testRequests {
+		code, stdout, stderr := runTest(request.args...)
+
+		if code != 0 {
+			t.Errorf("unexpected exit code %d for %v: %s", code, request.args, stderr)
+			continue
+		}
+
+		if stdout != request.expected {
+			t.Errorf("unexpected output for %v:\nexpected:\n%s\ngot:\n%s", request.args, request.expected, stdout)
+		}
+	}
+}
+
+func TestRunErrors(t *testing.T) {
+	testRequests := []struct {
+		args   []string
+		code   int
+		stderr string
+	}{
+		{args: nil, code: 2, stderr: "usage: wmata"},
+		{args: []string{"nope"}, code: 2, stderr: "unknown command: nope"},
+		{args: []string{"-format", "xml", "trains", "A01"}, code: 2, stderr: "invalid output format: xml"},
+		{args: []string{"trains"}, code: 1, stderr: "trains: at least one station code is required"},
+		{args: []string{"incidents", "ferry"}, code: 1, stderr: "incidents: unknown incident type: ferry"},
+		{args: []string{"path", "A01"}, code: 1, stderr: "path: from and to station codes are required"},
+	}
+
+	for _, request := range testRequests {
+		code, _, stderr := runTest(request.args...)
+
+		if code != request.code || !strings.Contains(stderr, request.stderr) {
+			t.Errorf("unexpected result for %v: expected %d %q, got %d %q", request.args, request.code, request.stderr, code, stderr)
+		}
+	}
+
+	stderr := bytes.Buffer{}
+
+	if code := run([]string{"trains", "A01"}, func(string) string { return "" }, testServices, &bytes.Buffer{}, &stderr, nil); code != 2 || !strings.Contains(stderr.String(), apiKeyEnv) {
+		t.Errorf("expected missing API key error, got %d %q", code, stderr.String())
+	}
+}
+
+func TestValidateTransportError(t *testing.T) {
+	stdout, stderr := bytes.Buffer{}, bytes.Buffer{}
+
+	// the test client has no response for the validation request and fails without one
+	code := run([]string{"validate"}, testEnv, testServices, &stdout, &stderr, nil)
+
+	if code != 1 || !strings.Contains(stderr.String(), "validation request failed") {
+		t.Errorf("expected validation request error, got %d %q", code, stderr.String())
+	}
+}
+
+func TestWatch(t *testing.T) {
+	defer func(interval time.Duration) { minWatchInterval = interval }(minWatchInterval)
+	minWatchInterval = time.Millisecond
+
+	stdout, stderr := bytes.Buffer{}, bytes.Buffer{}
+	code := run([]string{"watch", "-interval", "1ms", "-count", "2", "-plain", "board", "A01"}, testEnv, testServices, &stdout, &stderr, nil)
+
+	if code != 0 {
+		t.Errorf("unexpected exit code %d: %s", code, stderr.String())
+		return
+	}
+
+	if strings.Count(stdout.String(), "METRO CENTER  updated") != 2 {
+		t.Errorf("expected 2 refreshes, got:\n%s", stdout.String())
+	}
+
+	if !strings.Contains(stdout.String(), "! RD 8   Glenmont             ARR") || !strings.Contains(stdout.String(), "  OR 6   Vienna/Fairfax-GMU     3") {
+		t.Errorf("unexpected board:\n%s", stdout.String())
+	}
+
+	if strings.Contains(stdout.String(), ansiClear) || strings.Contains(stdout.String(), "* ") {
+		t.Errorf("expected plain output without changes:\n%s", stdout.String())
+	}
+
+	// a pending signal stops the dashboard after the first refresh
+	signals := make(chan os.Signal, 1)
+	signals <- os.Interrupt
+	stdout.Reset()
+
+	if code := run([]string{"watch", "-interval", "1ms", "incidents"}, testEnv, testServices, &stdout, &stderr, signals); code != 0 || strings.Count(stdout.String(), "RAIL INCIDENTS") != 1 {
+		t.Errorf("expected a single refresh before exiting, got %d:\n%s", code, stdout.String())
+	}
+
+	if code := run([]string{"watch", "-interval", "0s", "incidents"}, testEnv, testServices, &stdout, &stderr, nil); code != 2 {
+		t.Errorf("expected interval below the minimum to be rejected, got %d", code)
+	}
+
+	if code := run([]string{"watch", "ferries"}, testEnv, testServices, &stdout, &stderr, nil); code != 2 {
+		t.Errorf("expected unknown dashboard to be rejected, got %d", code)
+	}
+
+	if code := run([]string{"-format", "json", "watch", "incidents"}, testEnv, testServices, &stdout, &stderr, nil); code != 2 {
+		t.Errorf("expected -format to be rejected, got %d", code)
+	}
+
+	// a closed output stops the dashboard
+	if code := run([]string{"watch", "-interval", "1ms", "incidents"}, testEnv, testServices, failingWriter{}, &stderr, nil); code != 1 {
+		t.Errorf("expected write error to stop the dashboard, got %d", code)
+	}
+}
+
+func TestDrawWatch(t *testing.T) {
+	previous := map[string]string{"1": "Delay RD single tracking", "2": "Alert OR delays", "5": "RD 8 Shady Grove"}
+
+	lines := []watchLine{
+		{text: "header"},
+		{key: "1", text: "Delay RD single tracking"},
+		{key: "2", text: "Alert OR delays cleared"},
+		{key: "3", text: "Delay BL track work"},
+		{key: "4", text: "RD 8 Glenmont BRD", alert: true},
+		{key: "5", text: "RD 8 Shady Grove 4", compared: "RD 8 Shady Grove"},
+	}
+
+	out := bytes.Buffer{}
+	if drawErr := drawWatch(&out, "RAIL INCIDENTS", time.Minute, lines, previous, false); drawErr != nil {
+		t.Fatalf("error drawing dashboard: %s", drawErr)
+	}
+
+	expected := []string{
+		"  header",
+		"  Delay RD single tracking",
+		"* " + ansiBold + "Alert OR delays cleared" + ansiReset,
+		"* " + ansiBold + "Delay BL track work" + ansiReset,
+		"! " + ansiReverse + "RD 8 Glenmont BRD" + ansiReset,
+		"  RD 8 Shady Grove 4",
+	}
+
+	if !strings.HasPrefix(out.String(), ansiClear) {
+		t.Error("expected screen to be cleared")
+	}
+
+	if rendered := strings.Split(strings.TrimSpace(out.String()), "\n")[2:]; strings.Join(rendered, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("unexpected lines:\n%s", strings.Join(rendered, "\n"))
+	}
+}
+
+// failingWriter fails every write, like a closed pipe
+type failingWriter struct{}
+
+func (failingWriter) Write([]byte) (int, error) {
+	return 0, errors.New("broken pipe")
+}
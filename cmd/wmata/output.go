@@ -0,0 +1,72 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"io"
+	"strings"
+	"text/tabwriter"
+)
+
+type outputFormat string
+
+const (
+	formatTable outputFormat = "table"
+	formatJSON  outputFormat = "json"
+	formatCSV   outputFormat = "csv"
+)
+
+// parseFormat validates an output format flag value
+func parseFormat(value string) (outputFormat, error) {
+	switch format := outputFormat(strings.ToLower(value)); format {
+	case formatTable, formatJSON, formatCSV:
+		return format, nil
+	default:
+		return "", errors.New("invalid output format: " + value)
+	}
+}
+
+// result is the output of a command: rows for table and CSV output, and the raw API response for JSON output
+type result struct {
+	header []string
+	rows   [][]string
+	raw    interface{}
+}
+
+func (r *result) addRow(values ...string) {
+	r.rows = append(r.rows, values)
+}
+
+// write renders a result in the given format
+func (r *result) write(w io.Writer, format outputFormat) error {
+	switch format {
+	case formatJSON:
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+
+		return encoder.Encode(r.raw)
+	case formatCSV:
+		writer := csv.NewWriter(w)
+
+		if writeErr := writer.Write(r.header); writeErr != nil {
+			return writeErr
+		}
+
+		if writeErr := writer.WriteAll(r.rows); writeErr != nil {
+			return writeErr
+		}
+
+		return writer.Error()
+	default:
+		writer := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+
+		for _, row := range append([][]string{r.header}, r.rows...) {
+			if _, writeErr := io.WriteString(writer, strings.Join(row, "\t")+"\n"); writeErr != nil {
+				return writeErr
+			}
+		}
+
+		return writer.Flush()
+	}
+}
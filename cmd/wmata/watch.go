@@ -0,0 +1,270 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/awiede/wmata-go-sdk/wmata/railpredictions"
+	"github.com/awiede/wmata-go-sdk/wmata/stopboard"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+const (
+	defaultWatchInterval = 30 * time.Second
+	watchUsage           = "watch [-interval d] [-count n] [-plain] board <station> | stop <stop> | incidents"
+)
+
+// minWatchInterval keeps a dashboard left running well within the API's daily request quota
+var minWatchInterval = 5 * time.Second
+
+// ANSI escape sequences used to redraw the dashboard and highlight lines
+const (
+	ansiClear   = "\033[H\033[2J"
+	ansiBold    = "\033[1m"
+	ansiReverse = "\033[7m"
+	ansiReset   = "\033[0m"
+)
+
+// watchLine is a line of a dashboard. Lines with a key are compared against the previous refresh to highlight changes,
+// and alert lines are highlighted on every refresh
+type watchLine struct {
+	key  string
+	text string
+	// compared is the part of the line compared against the previous refresh, the whole text if empty. Fields that
+	// change on every refresh such as minutes are left out so only state changes are highlighted
+	compared string
+	alert    bool
+}
+
+// comparedText returns the text of a line compared against the previous refresh
+func (line watchLine) comparedText() string {
+	if line.compared != "" {
+		return line.compared
+	}
+
+	return line.text
+}
+
+// watchView builds the lines of a dashboard on each refresh
+type watchView func(svc *services) (string, []watchLine, error)
+
+// runWatch redraws a dashboard every interval until a signal is received or count refreshes have been drawn
+func runWatch(svc *services, args []string, stdout, stderr io.Writer, signals <-chan os.Signal) int {
+	flags := flag.NewFlagSet("watch", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	interval := flags.Duration("interval", defaultWatchInterval, "time between refreshes")
+	count := flags.Int("count", 0, "number of refreshes before exiting, 0 to run until interrupted")
+	plain := flags.Bool("plain", false, "disable screen clearing and colors")
+
+	if parseErr := flags.Parse(args); parseErr != nil {
+		return 2
+	}
+
+	if *interval < minWatchInterval {
+		fmt.Fprintf(stderr, "watch: interval must be at least %s\n", minWatchInterval)
+		return 2
+	}
+
+	view, viewErr := parseWatchView(flags.Args())
+
+	if viewErr != nil {
+		fmt.Fprintf(stderr, "watch: %s\nusage: wmata %s\n", viewErr, watchUsage)
+		return 2
+	}
+
+	ticker := time.NewTicker(*interval)
+	defer ticker.Stop()
+
+	var previous map[string]string
+
+	for refresh := 1; ; refresh++ {
+		title, lines, refreshErr := view(svc)
+
+		if refreshErr != nil {
+			// keep the last dashboard's lines so changes are measured against the last successful refresh
+			fmt.Fprintf(stdout, "%s watch: %s\n", time.Now().Format("15:04:05"), refreshErr)
+		} else {
+			if drawErr := drawWatch(stdout, title, *interval, lines, previous, *plain); drawErr != nil {
+				fmt.Fprintf(stderr, "watch: %s\n", drawErr)
+				return 1
+			}
+
+			previous = make(map[string]string, len(lines))
+
+			for _, line := range lines {
+				if line.key != "" {
+					previous[line.key] = line.comparedText()
+				}
+			}
+		}
+
+		if *count > 0 && refresh >= *count {
+			return 0
+		}
+
+		select {
+		case <-signals:
+			fmt.Fprintln(stdout)
+			return 0
+		case <-ticker.C:
+		}
+	}
+}
+
+// parseWatchView selects the dashboard named by the arguments
+func parseWatchView(args []string) (watchView, error) {
+	if len(args) == 0 {
+		return nil, errors.New("a dashboard is required")
+	}
+
+	switch args[0] {
+	case "board":
+		if len(args) != 2 {
+			return nil, errors.New("a station code is required")
+		}
+
+		return func(svc *services) (string, []watchLine, error) {
+			return watchBoard(svc, args[1])
+		}, nil
+	case "stop":
+		if len(args) != 2 {
+			return nil, errors.New("a stop ID is required")
+		}
+
+		return func(svc *services) (string, []watchLine, error) {
+			return watchStop(svc, args[1])
+		}, nil
+	case "incidents":
+		return watchIncidents, nil
+	default:
+		return nil, errors.New("unknown dashboard: " + args[0])
+	}
+}
+
+// drawWatch writes a dashboard, marking lines that are new or changed since the previous refresh with "*" and alert
+// lines with "!". It returns the error of writing the dashboard, so a closed output stops the refreshes
+func drawWatch(w io.Writer, title string, interval time.Duration, lines []watchLine, previous map[string]string, plain bool) error {
+	var text strings.Builder
+
+	if !plain {
+		text.WriteString(ansiClear)
+	}
+
+	fmt.Fprintf(&text, "%s  updated %s, every %s\n\n", title, time.Now().Format("15:04:05"), interval)
+
+	for _, line := range lines {
+		previousText, seen := previous[line.key]
+		changed := previous != nil && line.key != "" && (!seen || previousText != line.comparedText())
+
+		marker, style := " ", ""
+
+		switch {
+		case line.alert:
+			marker, style = "!", ansiReverse
+		case changed:
+			marker, style = "*", ansiBold
+		}
+
+		if plain || style == "" {
+			fmt.Fprintf(&text, "%s %s\n", marker, line.text)
+		} else {
+			fmt.Fprintf(&text, "%s %s%s%s\n", marker, style, line.text, ansiReset)
+		}
+	}
+
+	_, writeErr := io.WriteString(w, text.String())
+
+	return writeErr
+}
+
+// watchBoard lists a station's arrivals by platform, highlighting new trains and alerting on arriving and boarding trains
+func watchBoard(svc *services, stationCode string) (string, []watchLine, error) {
+	board, boardErr := svc.railBoard.GetBoard(stationCode)
+
+	if boardErr != nil {
+		return "", nil, boardErr
+	}
+
+	var lines []watchLine
+
+	for _, platform := range board.Platforms {
+		lines = append(lines, watchLine{text: fmt.Sprintf("%s TRACK %s", platform.StationCode, platform.Group)})
+		seen := make(map[string]int)
+
+		for _, arrival := range platform.Arrivals() {
+			train := arrival.Line + " " + arrival.DestinationCode
+			seen[train]++
+
+			lines = append(lines, watchLine{
+				key:      fmt.Sprintf("%s/%s/%s/%d", platform.StationCode, platform.Group, train, seen[train]),
+				text:     fmt.Sprintf("%-2s %-3s %-20s %3s", arrival.Line, arrival.Car, arrival.DestinationName, arrival.Minutes),
+				compared: fmt.Sprintf("%s %s %s", arrival.Line, arrival.Car, arrival.DestinationName),
+				alert:    arrival.Minutes == railpredictions.MinutesBoarding || arrival.Minutes == railpredictions.MinutesArriving,
+			})
+		}
+	}
+
+	return strings.ToUpper(board.StationName), lines, nil
+}
+
+// watchStop lists the arrivals at a bus stop, highlighting new buses and predictions replacing schedules and alerting on
+// buses due within a minute
+func watchStop(svc *services, stopID string) (string, []watchLine, error) {
+	board, boardErr := svc.stopBoard.GetStopBoard(&stopboard.GetStopBoardRequest{StopID: stopID})
+
+	if boardErr != nil {
+		return "", nil, boardErr
+	}
+
+	var lines []watchLine
+
+	for _, arrival := range board.Arrivals {
+		status := "sched"
+
+		if arrival.Realtime {
+			status = "live"
+		}
+
+		key := arrival.TripID
+
+		if key == "" {
+			key = arrival.RouteID + "/" + arrival.ScheduledTime.Format(time.RFC3339)
+		}
+
+		lines = append(lines, watchLine{
+			key:      key,
+			text:     fmt.Sprintf("%-5s %-40s %3d min %s", arrival.RouteID, arrival.TripDestination, arrival.Minutes, status),
+			compared: fmt.Sprintf("%s %s %s", arrival.RouteID, arrival.TripDestination, status),
+			alert:    arrival.Minutes <= 1,
+		})
+	}
+
+	return board.StopName, lines, nil
+}
+
+// watchIncidents lists rail incidents, highlighting new and updated incidents
+func watchIncidents(svc *services) (string, []watchLine, error) {
+	response, responseErr := svc.incidents.GetRailIncidents()
+
+	if responseErr != nil {
+		return "", nil, responseErr
+	}
+
+	var lines []watchLine
+
+	for _, incident := range response.RailIncidents {
+		lines = append(lines, watchLine{
+			key:  incident.IncidentID,
+			text: fmt.Sprintf("%-10s %-12s %s", incident.IncidentType, strings.TrimRight(strings.TrimSpace(incident.LinesAffected), ";"), incident.Description),
+		})
+	}
+
+	if len(lines) == 0 {
+		lines = append(lines, watchLine{text: "no rail incidents"})
+	}
+
+	return "RAIL INCIDENTS", lines, nil
+}
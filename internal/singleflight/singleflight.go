@@ -0,0 +1,59 @@
+// Package singleflight coalesces concurrent calls that share a key into a single execution.
+package singleflight
+
+import (
+	"sync"
+)
+
+// call is an in-flight or completed Do call
+type call struct {
+	wg    sync.WaitGroup
+	value interface{}
+	err   error
+	dups  int
+}
+
+// Group runs at most one function per key at a time. The zero value is ready to use
+type Group struct {
+	mutex sync.Mutex
+	calls map[string]*call
+}
+
+// Do runs fn for key unless a call for the same key is already running, in which case it waits for that call and
+// returns its result. shared reports whether the result was given to more than one caller
+func (group *Group) Do(key string, fn func() (interface{}, error)) (value interface{}, err error, shared bool) {
+	group.mutex.Lock()
+
+	if group.calls == nil {
+		group.calls = make(map[string]*call)
+	}
+
+	if inFlight, exist := group.calls[key]; exist {
+		inFlight.dups++
+		group.mutex.Unlock()
+		inFlight.wg.Wait()
+
+		return inFlight.value, inFlight.err, true
+	}
+
+	c := &call{}
+	c.wg.Add(1)
+	group.calls[key] = c
+	group.mutex.Unlock()
+
+	// results are released to waiters even if fn panics, so callers never block forever
+	defer func() {
+		group.mutex.Lock()
+		delete(group.calls, key)
+		group.mutex.Unlock()
+		c.wg.Done()
+	}()
+
+	c.value, c.err = fn()
+
+	group.mutex.Lock()
+	shared = c.dups > 0
+	group.mutex.Unlock()
+
+	return c.value, c.err, shared
+}
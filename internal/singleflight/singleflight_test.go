@@ -0,0 +1,73 @@
+package singleflight
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestDo(t *testing.T) {
+	group := Group{}
+
+	value, err, shared := group.Do("key", func() (interface{}, error) {
+		return "value", nil
+	})
+
+	if value != "value" || err != nil || shared {
+		t.Errorf("unexpected result: %v %v %v", value, err, shared)
+	}
+
+	_, err, _ = group.Do("key", func() (interface{}, error) {
+		return nil, errors.New("failed")
+	})
+
+	if err == nil || err.Error() != "failed" {
+		t.Errorf("expected error to be returned, got %v", err)
+	}
+}
+
+func TestDoCoalesces(t *testing.T) {
+	group := Group{}
+	release := make(chan struct{})
+	var calls int32
+	var started, finished sync.WaitGroup
+
+	results := make([]interface{}, 5)
+	errs := make([]error, 5)
+
+	for i := range results {
+		i := i
+		started.Add(1)
+		finished.Add(1)
+
+		go func() {
+			defer finished.Done()
+			started.Done()
+
+			results[i], errs[i], _ = group.Do("key", func() (interface{}, error) {
+				atomic.AddInt32(&calls, 1)
+				<-release
+
+				return nil, errors.New("upstream failed")
+			})
+		}()
+	}
+
+	started.Wait()
+	// give every goroutine time to join the in-flight call before it completes
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	finished.Wait()
+
+	if calls != 1 {
+		t.Errorf("expected 1 call, got %d", calls)
+	}
+
+	for i, err := range errs {
+		if err == nil || err.Error() != "upstream failed" {
+			t.Errorf("expected caller %d to receive the shared error, got %v", i, err)
+		}
+	}
+}
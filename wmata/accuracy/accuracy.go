@@ -0,0 +1,573 @@
+package accuracy
+
+import (
+	"github.com/awiede/wmata-go-sdk/wmata"
+	"github.com/awiede/wmata-go-sdk/wmata/archive"
+	"github.com/awiede/wmata-go-sdk/wmata/businfo"
+	"github.com/awiede/wmata-go-sdk/wmata/buspredictions"
+	"github.com/awiede/wmata-go-sdk/wmata/linearref"
+	"github.com/awiede/wmata-go-sdk/wmata/railheadway"
+	"github.com/awiede/wmata-go-sdk/wmata/railpredictions"
+	"github.com/awiede/wmata-go-sdk/wmata/trainpositions"
+	"math"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Defaults used when a Config field is left at zero
+const (
+	DefaultMaxError            = 15 * time.Minute
+	DefaultMaxOffRouteDistance = 250.0
+)
+
+// DefaultHorizonBounds split predictions into horizons of 0-1, 1-5, 5-10, 10-15, 15-20 and 20+ minutes
+var DefaultHorizonBounds = []int{1, 5, 10, 15, 20}
+
+type Mode string
+
+const (
+	ModeRail Mode = "RAIL"
+	ModeBus  Mode = "BUS"
+)
+
+// Config holds the horizons predictions are grouped by and the limits used when matching them with arrivals
+type Config struct {
+	// HorizonBounds are the minutes, in increasing order, at which one horizon ends and the next starts
+	HorizonBounds []int
+	// MaxError, predictions further than this from the arrival they are matched with are treated as unmatched
+	MaxError time.Duration
+	// MaxOffRouteDistance in meters, bus positions further than this from their route shape are ignored
+	MaxOffRouteDistance float64
+}
+
+// withDefaults returns a copy of the config with zero values replaced by defaults
+func (config Config) withDefaults() Config {
+	if len(config.HorizonBounds) == 0 {
+		config.HorizonBounds = DefaultHorizonBounds
+	}
+
+	if config.MaxError <= 0 {
+		config.MaxError = DefaultMaxError
+	}
+
+	if config.MaxOffRouteDistance <= 0 {
+		config.MaxOffRouteDistance = DefaultMaxOffRouteDistance
+	}
+
+	return config
+}
+
+// horizon returns the index of the horizon a prediction in minutes falls in
+func (config *Config) horizon(minutes int) int {
+	return sort.Search(len(config.HorizonBounds), func(i int) bool {
+		return config.HorizonBounds[i] > minutes
+	})
+}
+
+// horizonLabel returns the name of a horizon, e.g. "1-5" or "20+"
+func (config *Config) horizonLabel(horizon int) string {
+	if horizon == len(config.HorizonBounds) {
+		return strconv.Itoa(config.HorizonBounds[horizon-1]) + "+"
+	}
+
+	from := 0
+
+	if horizon > 0 {
+		from = config.HorizonBounds[horizon-1]
+	}
+
+	return strconv.Itoa(from) + "-" + strconv.Itoa(config.HorizonBounds[horizon])
+}
+
+// Accuracy is the distribution of prediction errors in minutes for a group of predictions in one horizon. Errors are
+// the actual minutes until arrival less the minutes predicted, positive when vehicles arrive later than predicted.
+// As predictions are whole minutes rounded down, errors between 0 and 1 are exact
+type Accuracy struct {
+	Mode Mode `json:"Mode"`
+	// Group is the line code, station code, route ID or stop ID grouped by, empty for every prediction of the mode
+	Group   string `json:"Group"`
+	Horizon string `json:"Horizon"`
+	// Predictions counts the predictions made, of which Matched were matched with an arrival
+	Predictions       int     `json:"Predictions"`
+	Matched           int     `json:"Matched"`
+	MeanError         float64 `json:"MeanError"`
+	MeanAbsoluteError float64 `json:"MeanAbsoluteError"`
+	P10Error          float64 `json:"P10Error"`
+	MedianError       float64 `json:"MedianError"`
+	P90Error          float64 `json:"P90Error"`
+}
+
+// Report holds the accuracy of the predictions logged, each ordered by mode, group and horizon
+type Report struct {
+	Horizons []Accuracy `json:"Horizons"`
+	Lines    []Accuracy `json:"Lines"`
+	Stations []Accuracy `json:"Stations"`
+	Routes   []Accuracy `json:"Routes"`
+	Stops    []Accuracy `json:"Stops"`
+}
+
+// railPrediction is a rail prediction logged at a time
+type railPrediction struct {
+	at      time.Time
+	train   railpredictions.Train
+	minutes int
+}
+
+// busPrediction is a bus prediction for a stop logged at a time
+type busPrediction struct {
+	at         time.Time
+	stopID     string
+	prediction buspredictions.NextBusPrediction
+}
+
+// tripKey identifies a vehicle running a trip
+type tripKey struct {
+	vehicleID string
+	tripID    string
+}
+
+// busTrip is the progress of a vehicle along its route shape during a trip
+type busTrip struct {
+	shape     *linearref.Shape
+	times     []time.Time
+	distances []float64
+}
+
+// arrival returns the time the vehicle first reached a stop, interpolated between the positions either side of it
+func (trip *busTrip) arrival(stopID string) (time.Time, bool) {
+	distance, onRoute := trip.shape.StopDistance(stopID)
+
+	if !onRoute {
+		return time.Time{}, false
+	}
+
+	for i, traveled := range trip.distances {
+		if traveled < distance {
+			continue
+		}
+
+		// the vehicle was first seen beyond the stop
+		if i == 0 {
+			return trip.times[0], traveled == distance
+		}
+
+		fraction := (distance - trip.distances[i-1]) / (traveled - trip.distances[i-1])
+
+		return trip.times[i-1].Add(time.Duration(fraction * float64(trip.times[i].Sub(trip.times[i-1])))), true
+	}
+
+	return time.Time{}, false
+}
+
+// Evaluator logs rail and bus predictions and measures their accuracy against the arrivals seen in train and bus
+// positions. Rail arrivals are derived from train positions with a railheadway.Analyzer, and bus arrivals by following
+// each vehicle along its route shape. It is safe for concurrent use
+type Evaluator struct {
+	config Config
+	trains *railheadway.Analyzer
+
+	mutex           sync.Mutex
+	routes          map[string]*businfo.GetRouteDetailsResponse
+	shapes          map[*businfo.Direction]*linearref.Shape
+	trips           map[tripKey]*busTrip
+	railPredictions []railPrediction
+	busPredictions  []busPrediction
+}
+
+// NewEvaluator returns an Evaluator for trains running on the given standard routes. Routes must be added for bus
+// predictions to be evaluated
+func NewEvaluator(routes []trainpositions.Route, config Config) *Evaluator {
+	return &Evaluator{
+		config: config.withDefaults(),
+		trains: railheadway.NewAnalyzer(routes, railheadway.Config{}),
+		routes: make(map[string]*businfo.GetRouteDetailsResponse),
+		shapes: make(map[*businfo.Direction]*linearref.Shape),
+		trips:  make(map[tripKey]*busTrip),
+	}
+}
+
+// AddRoute loads the shapes of a bus route so vehicles on it can be followed, replacing any previous shapes.
+// Directions without shape points are skipped
+func (evaluator *Evaluator) AddRoute(route *businfo.GetRouteDetailsResponse) {
+	evaluator.mutex.Lock()
+	defer evaluator.mutex.Unlock()
+
+	if previous, exist := evaluator.routes[route.RouteID]; exist {
+		delete(evaluator.shapes, &previous.Direction0)
+		delete(evaluator.shapes, &previous.Direction1)
+	}
+
+	copied := *route
+	evaluator.routes[route.RouteID] = &copied
+
+	for _, direction := range []*businfo.Direction{&copied.Direction0, &copied.Direction1} {
+		if len(direction.Shapes) == 0 {
+			continue
+		}
+
+		evaluator.shapes[direction] = linearref.NewShape(direction)
+	}
+}
+
+// AddNextTrains logs the rail predictions made at a time. Trains without an estimate are ignored
+func (evaluator *Evaluator) AddNextTrains(at time.Time, trains []railpredictions.Train) {
+	evaluator.mutex.Lock()
+	defer evaluator.mutex.Unlock()
+
+	for _, train := range trains {
+		if minutes, estimated := train.ParseMinutes(); estimated {
+			evaluator.railPredictions = append(evaluator.railPredictions, railPrediction{at: at, train: train, minutes: minutes})
+		}
+	}
+}
+
+// AddBusPredictions logs the predictions made at a time for buses arriving at a stop
+func (evaluator *Evaluator) AddBusPredictions(at time.Time, stopID string, predictions []buspredictions.NextBusPrediction) {
+	evaluator.mutex.Lock()
+	defer evaluator.mutex.Unlock()
+
+	for _, prediction := range predictions {
+		evaluator.busPredictions = append(evaluator.busPredictions, busPrediction{at: at, stopID: stopID, prediction: prediction})
+	}
+}
+
+// AddTrainPositions adds a snapshot of train positions taken at a time. Snapshots must be added in the order they
+// were taken
+func (evaluator *Evaluator) AddTrainPositions(at time.Time, positions []trainpositions.TrainPosition) {
+	evaluator.trains.AddPositions(at, positions)
+}
+
+// AddBusPositions adds a snapshot of bus positions taken at a time. The times of the positions are read in Washington
+// time whatever the location of at. Positions on routes that were not added, and positions already added, are ignored
+func (evaluator *Evaluator) AddBusPositions(at time.Time, positions []businfo.BusPosition) {
+	evaluator.mutex.Lock()
+	defer evaluator.mutex.Unlock()
+
+	for _, position := range positions {
+		route, exist := evaluator.routes[position.RouteID]
+
+		if !exist {
+			continue
+		}
+
+		direction, directionErr := linearref.FindDirection(route, &position)
+
+		if directionErr != nil {
+			continue
+		}
+
+		observed, parseErr := time.ParseInLocation(wmata.DateTimeLayout, position.DateTime, wmata.Location)
+
+		if parseErr != nil {
+			continue
+		}
+
+		shape, exist := evaluator.shapes[direction]
+
+		if !exist {
+			continue
+		}
+
+		snapped, snapErr := shape.SnapPosition(&position)
+
+		if snapErr != nil || snapped.DistanceFromShape > evaluator.config.MaxOffRouteDistance {
+			continue
+		}
+
+		key := tripKey{vehicleID: position.VehicleID, tripID: position.TripID}
+		trip, exist := evaluator.trips[key]
+
+		if !exist {
+			trip = &busTrip{shape: shape}
+			evaluator.trips[key] = trip
+		}
+
+		// a position repeated in consecutive snapshots is only added once
+		if len(trip.times) > 0 && !observed.After(trip.times[len(trip.times)-1]) {
+			continue
+		}
+
+		trip.times = append(trip.times, observed)
+		trip.distances = append(trip.distances, snapped.DistanceTraveled)
+	}
+}
+
+// AddArchive adds the rail predictions, train positions and bus positions read by an archive iterator
+func (evaluator *Evaluator) AddArchive(iterator *archive.Iterator) error {
+	for iterator.Next() {
+		snapshot := iterator.Snapshot()
+
+		switch snapshot.Kind {
+		case archive.KindNextTrains:
+			predictions := railpredictions.GetNextTrainResponse{}
+
+			if decodeErr := snapshot.Decode(&predictions); decodeErr != nil {
+				return decodeErr
+			}
+
+			evaluator.AddNextTrains(snapshot.Time, predictions.Trains)
+		case archive.KindTrainPositions:
+			positions := trainpositions.GetLiveTrainPositionsResponse{}
+
+			if decodeErr := snapshot.Decode(&positions); decodeErr != nil {
+				return decodeErr
+			}
+
+			evaluator.AddTrainPositions(snapshot.Time, positions.Positions)
+		case archive.KindBusPositions:
+			positions := businfo.GetPositionsResponse{}
+
+			if decodeErr := snapshot.Decode(&positions); decodeErr != nil {
+				return decodeErr
+			}
+
+			evaluator.AddBusPositions(snapshot.Time, positions.BusPositions)
+		}
+	}
+
+	return iterator.Err()
+}
+
+// outcome is a prediction with the error of the arrival it was matched with
+type outcome struct {
+	mode    Mode
+	line    string
+	place   string
+	horizon int
+	matched bool
+	err     float64
+}
+
+// Report matches every prediction logged with the arrivals seen so far and measures the accuracy of the predictions.
+// Predictions for arrivals after the last positions added are unmatched
+func (evaluator *Evaluator) Report() *Report {
+	passages := evaluator.trains.Passages()
+
+	evaluator.mutex.Lock()
+	defer evaluator.mutex.Unlock()
+
+	outcomes := append(evaluator.railOutcomes(passages), evaluator.busOutcomes()...)
+
+	return &Report{
+		Horizons: evaluator.accuracy(outcomes, func(outcome *outcome) (string, bool) { return "", true }),
+		Lines:    evaluator.accuracy(outcomes, func(outcome *outcome) (string, bool) { return outcome.line, outcome.mode == ModeRail }),
+		Stations: evaluator.accuracy(outcomes, func(outcome *outcome) (string, bool) { return outcome.place, outcome.mode == ModeRail }),
+		Routes:   evaluator.accuracy(outcomes, func(outcome *outcome) (string, bool) { return outcome.line, outcome.mode == ModeBus }),
+		Stops:    evaluator.accuracy(outcomes, func(outcome *outcome) (string, bool) { return outcome.place, outcome.mode == ModeBus }),
+	}
+}
+
+// railOutcomes matches rail predictions with the trains passing their station. Trains are not identified in
+// predictions, so the predictions made at once for a station, line and destination are matched in order of minutes
+// with the trains of that line and destination at or arriving at the station when the predictions were made
+func (evaluator *Evaluator) railOutcomes(passages []railheadway.Passage) []outcome {
+	type stationKey struct {
+		stationCode string
+		lineCode    string
+		destination string
+	}
+
+	arrivals := make(map[stationKey][]railheadway.Passage)
+
+	for _, passage := range passages {
+		key := stationKey{stationCode: passage.StationCode, lineCode: passage.LineCode, destination: passage.DestinationStationCode}
+		arrivals[key] = append(arrivals[key], passage)
+	}
+
+	for _, station := range arrivals {
+		sort.SliceStable(station, func(i, j int) bool {
+			return station[i].Time.Before(station[j].Time)
+		})
+	}
+
+	type predictionKey struct {
+		stationKey
+		at time.Time
+	}
+
+	groups := make(map[predictionKey][]railPrediction)
+	var order []predictionKey
+
+	for _, prediction := range evaluator.railPredictions {
+		key := predictionKey{
+			stationKey: stationKey{stationCode: prediction.train.LocationCode, lineCode: prediction.train.Line, destination: prediction.train.DestinationCode},
+			at:         prediction.at,
+		}
+
+		if _, exist := groups[key]; !exist {
+			order = append(order, key)
+		}
+
+		groups[key] = append(groups[key], prediction)
+	}
+
+	var outcomes []outcome
+
+	for _, key := range order {
+		predictions := groups[key]
+
+		sort.SliceStable(predictions, func(i, j int) bool {
+			return predictions[i].minutes < predictions[j].minutes
+		})
+
+		var candidates []railheadway.Passage
+
+		for _, passage := range arrivals[key.stationKey] {
+			if len(candidates) == len(predictions) {
+				break
+			}
+
+			if passage.Time.After(key.at) || passage.Departure.After(key.at) {
+				candidates = append(candidates, passage)
+			}
+		}
+
+		for i, prediction := range predictions {
+			result := outcome{
+				mode:    ModeRail,
+				line:    prediction.train.Line,
+				place:   prediction.train.LocationCode,
+				horizon: evaluator.config.horizon(prediction.minutes),
+			}
+
+			if i < len(candidates) {
+				result.err, result.matched = evaluator.error(candidates[i].Time.Sub(key.at), prediction.minutes)
+			}
+
+			outcomes = append(outcomes, result)
+		}
+	}
+
+	return outcomes
+}
+
+// busOutcomes matches bus predictions with the vehicle and trip predicted reaching the stop
+func (evaluator *Evaluator) busOutcomes() []outcome {
+	var outcomes []outcome
+
+	for _, prediction := range evaluator.busPredictions {
+		result := outcome{
+			mode:    ModeBus,
+			line:    prediction.prediction.RouteID,
+			place:   prediction.stopID,
+			horizon: evaluator.config.horizon(prediction.prediction.Minutes),
+		}
+
+		if trip, exist := evaluator.trips[tripKey{vehicleID: prediction.prediction.VehicleID, tripID: prediction.prediction.TripID}]; exist {
+			if arrival, arrived := trip.arrival(prediction.stopID); arrived {
+				result.err, result.matched = evaluator.error(arrival.Sub(prediction.at), prediction.prediction.Minutes)
+			}
+		}
+
+		outcomes = append(outcomes, result)
+	}
+
+	return outcomes
+}
+
+// error returns the error in minutes of a prediction, and false if it is too large for the arrival to be the one
+// predicted
+func (evaluator *Evaluator) error(actual time.Duration, predicted int) (float64, bool) {
+	err := actual - time.Duration(predicted)*time.Minute
+
+	if err > evaluator.config.MaxError || err < -evaluator.config.MaxError {
+		return 0, false
+	}
+
+	return err.Minutes(), true
+}
+
+// accuracy summarizes outcomes by mode, the group returned by group and horizon, leaving out outcomes group returns
+// false for
+func (evaluator *Evaluator) accuracy(outcomes []outcome, group func(outcome *outcome) (string, bool)) []Accuracy {
+	type groupKey struct {
+		mode    Mode
+		group   string
+		horizon int
+	}
+
+	groups := make(map[groupKey]*Accuracy)
+	errs := make(map[groupKey][]float64)
+
+	for i := range outcomes {
+		outcome := &outcomes[i]
+		name, included := group(outcome)
+
+		if !included {
+			continue
+		}
+
+		key := groupKey{mode: outcome.mode, group: name, horizon: outcome.horizon}
+
+		if groups[key] == nil {
+			groups[key] = &Accuracy{Mode: outcome.mode, Group: name, Horizon: evaluator.config.horizonLabel(outcome.horizon)}
+		}
+
+		groups[key].Predictions++
+
+		if outcome.matched {
+			groups[key].Matched++
+			errs[key] = append(errs[key], outcome.err)
+		}
+	}
+
+	keys := make([]groupKey, 0, len(groups))
+
+	for key := range groups {
+		keys = append(keys, key)
+	}
+
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].mode != keys[j].mode {
+			// rail first
+			return keys[i].mode == ModeRail
+		}
+
+		if keys[i].group != keys[j].group {
+			return keys[i].group < keys[j].group
+		}
+
+		return keys[i].horizon < keys[j].horizon
+	})
+
+	accuracies := make([]Accuracy, 0, len(keys))
+
+	for _, key := range keys {
+		accuracy := groups[key]
+		sorted := errs[key]
+
+		if len(sorted) > 0 {
+			sort.Float64s(sorted)
+
+			var total, absolute float64
+
+			for _, err := range sorted {
+				total += err
+				absolute += math.Abs(err)
+			}
+
+			accuracy.MeanError = total / float64(len(sorted))
+			accuracy.MeanAbsoluteError = absolute / float64(len(sorted))
+			accuracy.P10Error = percentile(sorted, 0.1)
+			accuracy.MedianError = percentile(sorted, 0.5)
+			accuracy.P90Error = percentile(sorted, 0.9)
+		}
+
+		accuracies = append(accuracies, *accuracy)
+	}
+
+	return accuracies
+}
+
+// percentile returns the nearest rank percentile of sorted values
+func percentile(sorted []float64, fraction float64) float64 {
+	rank := int(math.Ceil(fraction*float64(len(sorted)))) - 1
+
+	if rank < 0 {
+		rank = 0
+	}
+
+	return sorted[rank]
+}
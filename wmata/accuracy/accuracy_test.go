@@ -0,0 +1,383 @@
+package accuracy
+
+import (
+	"errors"
+	"github.com/awiede/wmata-go-sdk/wmata"
+	"github.com/awiede/wmata-go-sdk/wmata/archive"
+	"github.com/awiede/wmata-go-sdk/wmata/businfo"
+	"github.com/awiede/wmata-go-sdk/wmata/buspredictions"
+	"github.com/awiede/wmata-go-sdk/wmata/linearref"
+	"github.com/awiede/wmata-go-sdk/wmata/mocks"
+	"github.com/awiede/wmata-go-sdk/wmata/railpredictions"
+	"github.com/awiede/wmata-go-sdk/wmata/simulator"
+	"github.com/awiede/wmata-go-sdk/wmata/trainpositions"
+	"github.com/awiede/wmata-go-sdk/wmata/wmatatest"
+	"github.com/kr/pretty"
+	"io/ioutil"
+	"math"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// noon is in Washington, like the times of the simulator's bus positions
+var noon = time.Date(2019, 4, 29, 12, 0, 0, 0, wmata.Location)
+
+func trainAt(trainID string, circuitID int) trainpositions.TrainPosition {
+	return trainpositions.TrainPosition{
+		CircuitID:              circuitID,
+		DestinationStationCode: "B01",
+		DirectionNumber:        1,
+		LineCode:               "RD",
+		ServiceType:            "Normal",
+		TrainID:                trainID,
+	}
+}
+
+func prediction(stationCode, destinationCode, minutes string) railpredictions.Train {
+	return railpredictions.Train{DestinationCode: destinationCode, Line: "RD", LocationCode: stationCode, Minutes: minutes}
+}
+
+// busAt returns a position of the northbound route 70 bus running trip 7001 at a distance in meters from a stop, with
+// its time in Washington like WMATA's
+func busAt(t *testing.T, at time.Time, stopID string, offset float64) businfo.BusPosition {
+	route := wmatatest.BusRouteDetails()
+	shape := linearref.NewShape(&route.Direction0)
+	distance, onRoute := shape.StopDistance(stopID)
+
+	if !onRoute {
+		t.Fatalf("stop %s is not on the route", stopID)
+	}
+
+	latitude, longitude := shape.PointAt(distance + offset)
+
+	return businfo.BusPosition{
+		DateTime:      at.In(wmata.Location).Format(wmata.DateTimeLayout),
+		DirectionText: "NORTH",
+		Latitude:      latitude,
+		Longitude:     longitude,
+		RouteID:       "70",
+		TripID:        "7001",
+		VehicleID:     "5418",
+	}
+}
+
+// round rounds errors to thousandths of a minute, as bus arrivals are interpolated
+func round(accuracies []Accuracy) {
+	thousandths := func(value float64) float64 {
+		return math.Round(value*1000) / 1000
+	}
+
+	for i := range accuracies {
+		accuracies[i].MeanError = thousandths(accuracies[i].MeanError)
+		accuracies[i].MeanAbsoluteError = thousandths(accuracies[i].MeanAbsoluteError)
+		accuracies[i].P10Error = thousandths(accuracies[i].P10Error)
+		accuracies[i].MedianError = thousandths(accuracies[i].MedianError)
+		accuracies[i].P90Error = thousandths(accuracies[i].P90Error)
+	}
+}
+
+func TestReport(t *testing.T) {
+	evaluator := NewEvaluator(wmatatest.StandardRoutes().Routes, Config{})
+	evaluator.AddRoute(wmatatest.BusRouteDetails())
+	stopID := wmatatest.BusRouteDetails().Direction0.Stops[1].StopID
+
+	evaluator.AddNextTrains(noon, []railpredictions.Train{
+		prediction("A02", "B01", "2"),
+		prediction("A02", "B01", "8"),
+		prediction("A02", "B01", "---"),
+		// no train to this destination is seen
+		prediction("A02", "A15", "3"),
+	})
+
+	evaluator.AddBusPredictions(noon, stopID, []buspredictions.NextBusPrediction{
+		{RouteID: "70", TripID: "7001", VehicleID: "5418", Minutes: 0},
+		{RouteID: "70", TripID: "7003", VehicleID: "5420", Minutes: 12},
+	})
+
+	// train 101 arrives at A02 at 12:03 and leaves at 12:04, train 102 arrives at 12:10
+	trains := []struct {
+		at        time.Duration
+		positions []trainpositions.TrainPosition
+	}{
+		{0, []trainpositions.TrainPosition{trainAt("101", 1101)}},
+		{time.Minute, []trainpositions.TrainPosition{trainAt("101", 1102)}},
+		{3 * time.Minute, []trainpositions.TrainPosition{trainAt("101", 1103)}},
+		{4 * time.Minute, []trainpositions.TrainPosition{trainAt("101", 1104)}},
+		{5 * time.Minute, []trainpositions.TrainPosition{trainAt("102", 1101)}},
+		{6 * time.Minute, []trainpositions.TrainPosition{trainAt("102", 1102)}},
+		{10 * time.Minute, []trainpositions.TrainPosition{trainAt("102", 1103)}},
+		{11 * time.Minute, []trainpositions.TrainPosition{trainAt("102", 1104)}},
+	}
+
+	for _, snapshot := range trains {
+		evaluator.AddTrainPositions(noon.Add(snapshot.at), snapshot.positions)
+	}
+
+	// the bus reaches the stop at 12:01, a minute after it was predicted to be arriving and 30 seconds after it was
+	// predicted to be a minute away
+	evaluator.AddBusPositions(noon.Add(30*time.Second), []businfo.BusPosition{busAt(t, noon.Add(30*time.Second), stopID, -300)})
+	evaluator.AddBusPredictions(noon.Add(30*time.Second), stopID, []buspredictions.NextBusPrediction{
+		{RouteID: "70", TripID: "7001", VehicleID: "5418", Minutes: 1},
+	})
+	evaluator.AddBusPositions(noon.Add(90*time.Second), []businfo.BusPosition{busAt(t, noon.Add(90*time.Second), stopID, 300)})
+	evaluator.AddBusPositions(noon.Add(90*time.Second), []businfo.BusPosition{busAt(t, noon.Add(90*time.Second), stopID, 300)})
+
+	// train 101 is boarding and train 102 six and a half minutes away
+	evaluator.AddNextTrains(noon.Add(210*time.Second), []railpredictions.Train{
+		prediction("A02", "B01", railpredictions.MinutesBoarding),
+		prediction("A02", "B01", "6"),
+	})
+
+	rail := []Accuracy{
+		{Mode: ModeRail, Horizon: "0-1", Predictions: 1, Matched: 1, MeanError: -0.5, MeanAbsoluteError: 0.5, P10Error: -0.5, MedianError: -0.5, P90Error: -0.5},
+		{Mode: ModeRail, Horizon: "1-5", Predictions: 2, Matched: 1, MeanError: 1, MeanAbsoluteError: 1, P10Error: 1, MedianError: 1, P90Error: 1},
+		{Mode: ModeRail, Horizon: "5-10", Predictions: 2, Matched: 2, MeanError: 1.25, MeanAbsoluteError: 1.25, P10Error: 0.5, MedianError: 0.5, P90Error: 2},
+	}
+
+	bus := []Accuracy{
+		{Mode: ModeBus, Horizon: "0-1", Predictions: 1, Matched: 1, MeanError: 1, MeanAbsoluteError: 1, P10Error: 1, MedianError: 1, P90Error: 1},
+		{Mode: ModeBus, Horizon: "1-5", Predictions: 1, Matched: 1, MeanError: -0.5, MeanAbsoluteError: 0.5, P10Error: -0.5, MedianError: -0.5, P90Error: -0.5},
+		{Mode: ModeBus, Horizon: "10-15", Predictions: 1},
+	}
+
+	grouped := func(group string, accuracies []Accuracy) []Accuracy {
+		copied := make([]Accuracy, len(accuracies))
+
+		for i, accuracy := range accuracies {
+			accuracy.Group = group
+			copied[i] = accuracy
+		}
+
+		return copied
+	}
+
+	expected := Report{
+		Horizons: append(grouped("", rail), grouped("", bus)...),
+		Lines:    grouped("RD", rail),
+		Stations: grouped("A02", rail),
+		Routes:   grouped("70", bus),
+		Stops:    grouped(stopID, bus),
+	}
+
+	report := evaluator.Report()
+
+	for _, accuracies := range [][]Accuracy{report.Horizons, report.Lines, report.Stations, report.Routes, report.Stops} {
+		round(accuracies)
+	}
+
+	if !reflect.DeepEqual(*report, expected) {
+		t.Errorf("unexpected report: %s", pretty.Diff(*report, expected))
+	}
+}
+
+func TestAddRouteWithoutShape(t *testing.T) {
+	route := wmatatest.BusRouteDetails()
+	route.Direction1.Shapes = nil
+
+	evaluator := NewEvaluator(nil, Config{})
+	evaluator.AddRoute(route)
+
+	southbound := busAt(t, noon, route.Direction0.Stops[1].StopID, 0)
+	southbound.DirectionText = "SOUTH"
+	southbound.TripID = "7002"
+
+	evaluator.AddBusPositions(noon, []businfo.BusPosition{southbound, busAt(t, noon, route.Direction0.Stops[1].StopID, 0)})
+
+	if len(evaluator.trips) != 1 {
+		t.Errorf("expected only the northbound trip to be followed, got %d trips", len(evaluator.trips))
+	}
+
+	if _, exist := evaluator.trips[tripKey{vehicleID: "5418", tripID: "7001"}]; !exist {
+		t.Error("expected the northbound trip to be followed")
+	}
+}
+
+func TestAddBusPositionsUTC(t *testing.T) {
+	evaluator := NewEvaluator(nil, Config{})
+	evaluator.AddRoute(wmatatest.BusRouteDetails())
+
+	// 12:00:30 in Washington is 16:00:30 UTC, the location of the snapshot time does not change it
+	at := noon.UTC()
+	position := busAt(t, at, wmatatest.BusRouteDetails().Direction0.Stops[1].StopID, 0)
+	position.DateTime = "2019-04-29T12:00:30"
+
+	evaluator.AddBusPositions(at, []businfo.BusPosition{position})
+
+	trip, exist := evaluator.trips[tripKey{vehicleID: "5418", tripID: "7001"}]
+
+	if !exist || len(trip.times) != 1 || !trip.times[0].Equal(at.Add(30*time.Second)) {
+		t.Errorf("expected the position to be observed at %s, got %# v", at.Add(30*time.Second), pretty.Formatter(trip))
+	}
+}
+
+func TestHorizons(t *testing.T) {
+	config := Config{HorizonBounds: []int{2, 10}}.withDefaults()
+
+	testRequests := []struct {
+		minutes  int
+		expected string
+	}{
+		{minutes: 0, expected: "0-2"},
+		{minutes: 1, expected: "0-2"},
+		{minutes: 2, expected: "2-10"},
+		{minutes: 9, expected: "2-10"},
+		{minutes: 10, expected: "10+"},
+		{minutes: 25, expected: "10+"},
+	}
+
+	for _, request := range testRequests {
+		if label := config.horizonLabel(config.horizon(request.minutes)); label != request.expected {
+			t.Errorf("%d minutes: got horizon %s, expected %s", request.minutes, label, request.expected)
+		}
+	}
+}
+
+func newTestSimulator(t *testing.T) (*simulator.Simulator, *simulator.Network) {
+	network := simulator.Network{
+		StandardRoutes: wmatatest.StandardRoutes().Routes,
+		TrackCircuits:  wmatatest.TrackCircuits().TrackCircuits,
+		Stations:       wmatatest.Stations().Stations,
+		BusRoutes:      []businfo.GetRouteDetailsResponse{*wmatatest.BusRouteDetails()},
+	}
+
+	simulation, simulatorErr := simulator.NewSimulator(&network, simulator.Config{MaxBusDeviation: 5 * time.Minute})
+
+	if simulatorErr != nil {
+		t.Fatalf("error creating simulator: %s", simulatorErr)
+	}
+
+	return simulation, &network
+}
+
+func TestLogger(t *testing.T) {
+	simulation, network := newTestSimulator(t)
+	stopIDs := []string{network.BusRoutes[0].Direction0.Stops[1].StopID, network.BusRoutes[0].Direction1.Stops[2].StopID}
+
+	busInfo := mocks.BusInfo{}
+	busPredictions := mocks.BusPredictions{}
+	railPredictions := mocks.RailPredictions{}
+	trainPositions := mocks.TrainPositions{}
+
+	evaluator := NewEvaluator(network.StandardRoutes, Config{})
+	evaluator.AddRoute(&network.BusRoutes[0])
+
+	logger := NewLogger(Services{
+		BusInfo:         &busInfo,
+		BusPredictions:  &busPredictions,
+		RailPredictions: &railPredictions,
+		TrainPositions:  &trainPositions,
+	}, evaluator, stopIDs)
+
+	// the simulator's predictions are exact, rounded down to whole minutes, for trains and buses running on time or
+	// late by whole minutes
+	for at := noon; at.Before(noon.Add(time.Hour)); at = at.Add(20 * time.Second) {
+		snapshot := simulation.Snapshot(at)
+
+		busInfo.Positions = snapshot.BusPositions.BusPositions
+		busPredictions.NextBuses = snapshot.NextBuses
+		railPredictions.Trains = snapshot.NextTrains.Trains
+		trainPositions.Positions = snapshot.TrainPositions.Positions
+
+		polled := at
+		logger.now = func() time.Time { return polled }
+
+		if pollErr := logger.Poll(); pollErr != nil {
+			t.Fatalf("error polling: %s", pollErr)
+		}
+	}
+
+	report := evaluator.Report()
+
+	if len(report.Horizons) == 0 || report.Horizons[len(report.Horizons)-1].Mode != ModeBus {
+		t.Fatalf("expected rail and bus accuracy, got %# v", pretty.Formatter(report.Horizons))
+	}
+
+	for _, accuracy := range report.Horizons {
+		// predictions made near the end of the hour are for arrivals that were not seen, as are predictions for buses
+		// that were already close to the stop when first seen
+		if accuracy.Matched == 0 {
+			t.Errorf("expected predictions to be matched: %# v", pretty.Formatter(accuracy))
+		}
+
+		// boarding trains arrived up to a minute ago, everything else arrives less than a minute after predicted
+		if accuracy.P10Error <= -1 || accuracy.P90Error >= 1 || accuracy.MeanAbsoluteError >= 1 {
+			t.Errorf("expected errors under a minute: %# v", pretty.Formatter(accuracy))
+		}
+	}
+
+	if len(report.Stops) < 2 {
+		t.Errorf("expected accuracy at both stops, got %# v", pretty.Formatter(report.Stops))
+	}
+
+	trainPositions.FailNext("GetLiveTrainPositions", errors.New("service unavailable"))
+
+	if pollErr := logger.Poll(); pollErr == nil || pollErr.Error() != "service unavailable" {
+		t.Errorf("expected train positions error, got %v", pollErr)
+	}
+
+	if calls := busInfo.CallCount("GetPositions"); calls != 181 {
+		t.Errorf("expected bus positions to be polled after the error, got %d calls", calls)
+	}
+}
+
+func TestAddArchive(t *testing.T) {
+	directory, tempErr := ioutil.TempDir("", "accuracy")
+
+	if tempErr != nil {
+		t.Fatalf("error creating archive directory: %s", tempErr)
+	}
+
+	defer os.RemoveAll(directory)
+
+	writer, openErr := archive.OpenWriter(directory, archive.WriterConfig{})
+
+	if openErr != nil {
+		t.Fatalf("error opening writer: %s", openErr)
+	}
+
+	simulation, network := newTestSimulator(t)
+	expected := NewEvaluator(network.StandardRoutes, Config{})
+
+	for at := noon; at.Before(noon.Add(30 * time.Minute)); at = at.Add(30 * time.Second) {
+		snapshot := simulation.Snapshot(at)
+
+		expected.AddNextTrains(at, snapshot.NextTrains.Trains)
+		expected.AddTrainPositions(at, snapshot.TrainPositions.Positions)
+
+		for kind, response := range map[archive.Kind]interface{}{archive.KindNextTrains: snapshot.NextTrains, archive.KindTrainPositions: snapshot.TrainPositions} {
+			archived, snapshotErr := archive.NewSnapshot(kind, at, response)
+
+			if snapshotErr != nil {
+				t.Fatalf("error creating snapshot: %s", snapshotErr)
+			}
+
+			if writeErr := writer.Write(archived); writeErr != nil {
+				t.Fatalf("error writing snapshot: %s", writeErr)
+			}
+		}
+	}
+
+	if closeErr := writer.Close(); closeErr != nil {
+		t.Fatalf("error closing writer: %s", closeErr)
+	}
+
+	reader, readerErr := archive.OpenReader(directory)
+
+	if readerErr != nil {
+		t.Fatalf("error opening reader: %s", readerErr)
+	}
+
+	evaluator := NewEvaluator(network.StandardRoutes, Config{})
+
+	if addErr := evaluator.AddArchive(reader.All()); addErr != nil {
+		t.Fatalf("error adding archive: %s", addErr)
+	}
+
+	report, expectedReport := evaluator.Report(), expected.Report()
+
+	if len(report.Stations) == 0 || !reflect.DeepEqual(report, expectedReport) {
+		t.Errorf("unexpected report: %s", pretty.Diff(report, expectedReport))
+	}
+}
@@ -0,0 +1,115 @@
+package accuracy
+
+import (
+	"github.com/awiede/wmata-go-sdk/wmata/businfo"
+	"github.com/awiede/wmata-go-sdk/wmata/buspredictions"
+	"github.com/awiede/wmata-go-sdk/wmata/railpredictions"
+	"github.com/awiede/wmata-go-sdk/wmata/trainpositions"
+	"time"
+)
+
+// Services are the services a Logger polls, each of which may be nil to leave out what it provides
+type Services struct {
+	BusInfo         businfo.BusInfo
+	BusPredictions  buspredictions.BusPredictions
+	RailPredictions railpredictions.RailPredictions
+	TrainPositions  trainpositions.TrainPositions
+}
+
+// Logger polls predictions and positions into an Evaluator. Bus predictions are not archived, so a Logger is needed to
+// evaluate them, while rail predictions can also be read from an archive
+type Logger struct {
+	services  Services
+	evaluator *Evaluator
+	stopIDs   []string
+	now       func() time.Time
+}
+
+// NewLogger returns a Logger adding to evaluator, polling bus predictions for the given stops
+func NewLogger(services Services, evaluator *Evaluator, stopIDs []string) *Logger {
+	return &Logger{
+		services:  services,
+		evaluator: evaluator,
+		stopIDs:   append([]string(nil), stopIDs...),
+		now:       time.Now,
+	}
+}
+
+// Poll retrieves the current predictions and positions from each service and adds them to the evaluator. Every
+// service is polled even if one fails, and the first error is returned
+func (logger *Logger) Poll() error {
+	var firstErr error
+
+	fail := func(err error) {
+		if firstErr == nil {
+			firstErr = err
+		}
+	}
+
+	if logger.services.RailPredictions != nil {
+		at := logger.now()
+
+		if predictions, predictionsErr := logger.services.RailPredictions.GetNextTrains(nil); predictionsErr != nil {
+			fail(predictionsErr)
+		} else {
+			logger.evaluator.AddNextTrains(at, predictions.Trains)
+		}
+	}
+
+	if logger.services.TrainPositions != nil {
+		at := logger.now()
+
+		if positions, positionsErr := logger.services.TrainPositions.GetLiveTrainPositions(); positionsErr != nil {
+			fail(positionsErr)
+		} else {
+			logger.evaluator.AddTrainPositions(at, positions.Positions)
+		}
+	}
+
+	if logger.services.BusPredictions != nil && len(logger.stopIDs) > 0 {
+		at := logger.now()
+
+		if batch, batchErr := buspredictions.GetNextBusesForStops(logger.services.BusPredictions, logger.stopIDs, 0); batchErr != nil {
+			fail(batchErr)
+		} else {
+			for _, stopID := range logger.stopIDs {
+				if predictions, exist := batch.Predictions[stopID]; exist {
+					logger.evaluator.AddBusPredictions(at, stopID, predictions.NextBusPredictions)
+				} else {
+					fail(batch.Errors[stopID])
+				}
+			}
+		}
+	}
+
+	if logger.services.BusInfo != nil {
+		at := logger.now()
+
+		if positions, positionsErr := logger.services.BusInfo.GetPositions(nil); positionsErr != nil {
+			fail(positionsErr)
+		} else {
+			logger.evaluator.AddBusPositions(at, positions.BusPositions)
+		}
+	}
+
+	return firstErr
+}
+
+// Run polls straight away and then at every interval until stop is closed, passing the errors of each poll to onError
+// if it is not nil
+func (logger *Logger) Run(interval time.Duration, stop <-chan struct{}, onError func(error)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if pollErr := logger.Poll(); pollErr != nil && onError != nil {
+			onError(pollErr)
+		}
+
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
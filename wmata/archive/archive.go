@@ -0,0 +1,81 @@
+package archive
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/awiede/wmata-go-sdk/wmata/businfo"
+	"github.com/awiede/wmata-go-sdk/wmata/incidents"
+	"github.com/awiede/wmata-go-sdk/wmata/railpredictions"
+	"github.com/awiede/wmata-go-sdk/wmata/trainpositions"
+	"time"
+)
+
+// Kind is the API response a snapshot holds
+type Kind string
+
+const (
+	// KindTrainPositions snapshots hold a trainpositions.GetLiveTrainPositionsResponse
+	KindTrainPositions Kind = "TrainPositions"
+	// KindNextTrains snapshots hold a railpredictions.GetNextTrainResponse for all stations
+	KindNextTrains Kind = "NextTrains"
+	// KindBusPositions snapshots hold a businfo.GetPositionsResponse for all routes
+	KindBusPositions Kind = "BusPositions"
+	// KindRailIncidents snapshots hold an incidents.GetRailIncidentsResponse
+	KindRailIncidents Kind = "RailIncidents"
+	// KindBusIncidents snapshots hold an incidents.GetBusIncidentsResponse for all routes
+	KindBusIncidents Kind = "BusIncidents"
+	// KindOutages snapshots hold an incidents.GetElevatorEscalatorOutagesResponse for all stations
+	KindOutages Kind = "Outages"
+)
+
+// Kinds are all the kinds of snapshot an Archiver polls
+var Kinds = []Kind{KindTrainPositions, KindNextTrains, KindBusPositions, KindRailIncidents, KindBusIncidents, KindOutages}
+
+// Snapshot is an API response taken at a time, stored as one line of JSON in an archive
+type Snapshot struct {
+	Kind Kind      `json:"Kind"`
+	Time time.Time `json:"Time"`
+	// Data is the response encoded as JSON
+	Data json.RawMessage `json:"Data"`
+}
+
+// NewSnapshot returns a snapshot of a response taken at a time
+func NewSnapshot(kind Kind, at time.Time, response interface{}) (*Snapshot, error) {
+	data, marshalErr := json.Marshal(response)
+
+	if marshalErr != nil {
+		return nil, marshalErr
+	}
+
+	return &Snapshot{Kind: kind, Time: at, Data: data}, nil
+}
+
+// Decode decodes the snapshot's response into v
+func (snapshot *Snapshot) Decode(v interface{}) error {
+	return json.Unmarshal(snapshot.Data, v)
+}
+
+// Response decodes the snapshot's response into a new value of its kind's response type, e.g. a
+// *trainpositions.GetLiveTrainPositionsResponse for KindTrainPositions
+func (snapshot *Snapshot) Response() (interface{}, error) {
+	var response interface{}
+
+	switch snapshot.Kind {
+	case KindTrainPositions:
+		response = &trainpositions.GetLiveTrainPositionsResponse{}
+	case KindNextTrains:
+		response = &railpredictions.GetNextTrainResponse{}
+	case KindBusPositions:
+		response = &businfo.GetPositionsResponse{}
+	case KindRailIncidents:
+		response = &incidents.GetRailIncidentsResponse{}
+	case KindBusIncidents:
+		response = &incidents.GetBusIncidentsResponse{}
+	case KindOutages:
+		response = &incidents.GetElevatorEscalatorOutagesResponse{}
+	default:
+		return nil, errors.New("unknown snapshot kind: " + string(snapshot.Kind))
+	}
+
+	return response, snapshot.Decode(response)
+}
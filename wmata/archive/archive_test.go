@@ -0,0 +1,461 @@
+package archive
+
+import (
+	"errors"
+	"fmt"
+	"github.com/awiede/wmata-go-sdk/wmata/businfo"
+	"github.com/awiede/wmata-go-sdk/wmata/incidents"
+	"github.com/awiede/wmata-go-sdk/wmata/mocks"
+	"github.com/awiede/wmata-go-sdk/wmata/railpredictions"
+	"github.com/awiede/wmata-go-sdk/wmata/simulator"
+	"github.com/awiede/wmata-go-sdk/wmata/trainpositions"
+	"github.com/awiede/wmata-go-sdk/wmata/wmatatest"
+	"github.com/kr/pretty"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+var start = time.Date(2019, time.April, 29, 12, 0, 0, 0, time.UTC)
+
+func tempDirectory(t *testing.T) string {
+	directory, tempErr := ioutil.TempDir("", "archive")
+
+	if tempErr != nil {
+		t.Fatal(tempErr)
+	}
+
+	return directory
+}
+
+func testSimulator(t *testing.T) *simulator.Simulator {
+	network := simulator.Network{
+		StandardRoutes: wmatatest.StandardRoutes().Routes,
+		Stations:       wmatatest.Stations().Stations,
+		BusRoutes:      []businfo.GetRouteDetailsResponse{*wmatatest.BusRouteDetails()},
+	}
+
+	simulated, simulatorErr := simulator.NewSimulator(&network, simulator.Config{})
+
+	if simulatorErr != nil {
+		t.Fatal(simulatorErr)
+	}
+
+	return simulated
+}
+
+// writeSimulated writes train positions every 10 seconds and bus positions every 30 seconds for a duration
+func writeSimulated(t *testing.T, writer *Writer, simulated *simulator.Simulator, from time.Time, duration time.Duration) int {
+	written := 0
+
+	for at := from; at.Before(from.Add(duration)); at = at.Add(10 * time.Second) {
+		snapshot, _ := NewSnapshot(KindTrainPositions, at, simulated.LiveTrainPositions(at))
+
+		if writeErr := writer.Write(snapshot); writeErr != nil {
+			t.Fatal(writeErr)
+		}
+
+		written++
+
+		if at.Sub(from)%(30*time.Second) == 0 {
+			snapshot, _ = NewSnapshot(KindBusPositions, at, simulated.BusPositions(nil, at))
+
+			if writeErr := writer.Write(snapshot); writeErr != nil {
+				t.Fatal(writeErr)
+			}
+
+			written++
+		}
+	}
+
+	return written
+}
+
+func readAll(t *testing.T, iterator *Iterator) []*Snapshot {
+	var snapshots []*Snapshot
+
+	for iterator.Next() {
+		snapshots = append(snapshots, iterator.Snapshot())
+	}
+
+	if iterator.Err() != nil {
+		t.Fatal(iterator.Err())
+	}
+
+	return snapshots
+}
+
+func TestWriteAndRange(t *testing.T) {
+	directory := tempDirectory(t)
+	defer os.RemoveAll(directory)
+
+	writer, writerErr := OpenWriter(directory, WriterConfig{MaxSegmentAge: 10 * time.Minute})
+
+	if writerErr != nil {
+		t.Fatal(writerErr)
+	}
+
+	simulated := testSimulator(t)
+	written := writeSimulated(t, writer, simulated, start, time.Hour)
+
+	if closeErr := writer.Close(); closeErr != nil {
+		t.Fatal(closeErr)
+	}
+
+	reader, readerErr := OpenReader(directory)
+
+	if readerErr != nil {
+		t.Fatal(readerErr)
+	}
+
+	segments := reader.Segments()
+
+	if len(segments) != 6 {
+		t.Fatalf("expected a segment every 10 minutes, got %# v", pretty.Formatter(segments))
+	}
+
+	expectedSegment := Segment{
+		File:  "segment-000002.jsonl.gz",
+		Start: start.Add(10 * time.Minute),
+		End:   start.Add(19*time.Minute + 50*time.Second),
+		Count: 80,
+		Kinds: map[Kind]int{KindTrainPositions: 60, KindBusPositions: 20},
+	}
+
+	if !reflect.DeepEqual(segments[1], expectedSegment) {
+		t.Errorf("unexpected segment: %v", pretty.Diff(segments[1], expectedSegment))
+	}
+
+	if all := readAll(t, reader.All()); len(all) != written {
+		t.Errorf("expected %d snapshots, got %d", written, len(all))
+	}
+
+	ranged := readAll(t, reader.Range(start.Add(25*time.Minute), start.Add(35*time.Minute), KindBusPositions))
+
+	if len(ranged) != 20 || !ranged[0].Time.Equal(start.Add(25*time.Minute)) || !ranged[19].Time.Equal(start.Add(34*time.Minute+30*time.Second)) {
+		t.Errorf("unexpected snapshots in range: %# v", pretty.Formatter(ranged))
+	}
+
+	for _, snapshot := range ranged {
+		response, responseErr := snapshot.Response()
+
+		if responseErr != nil {
+			t.Fatal(responseErr)
+		}
+
+		expected := simulated.BusPositions(nil, snapshot.Time)
+
+		if !reflect.DeepEqual(response, expected) {
+			t.Errorf("unexpected response at %s: %v", snapshot.Time, pretty.Diff(response, expected))
+		}
+	}
+
+	// the range only opens the segments covering it
+	iterator := reader.Range(start.Add(25*time.Minute), start.Add(35*time.Minute))
+
+	if !reflect.DeepEqual(iterator.files, []string{"segment-000003.jsonl.gz", "segment-000004.jsonl.gz"}) {
+		t.Errorf("unexpected segments read: %v", iterator.files)
+	}
+
+	iterator.Close()
+
+	if iterator.Next() {
+		t.Error("expected a closed iterator to stop")
+	}
+}
+
+func TestWriteOutOfOrder(t *testing.T) {
+	directory := tempDirectory(t)
+	defer os.RemoveAll(directory)
+
+	writer, _ := OpenWriter(directory, WriterConfig{})
+	defer writer.Close()
+
+	snapshot, _ := NewSnapshot(KindRailIncidents, start, incidents.GetRailIncidentsResponse{})
+
+	if writeErr := writer.Write(snapshot); writeErr != nil {
+		t.Fatal(writeErr)
+	}
+
+	snapshot.Time = start.Add(-time.Second)
+
+	if writeErr := writer.Write(snapshot); writeErr == nil {
+		t.Error("expected an error writing a snapshot older than the last")
+	}
+}
+
+func TestWriteOutOfOrderAfterReopen(t *testing.T) {
+	directory := tempDirectory(t)
+	defer os.RemoveAll(directory)
+
+	writer, _ := OpenWriter(directory, WriterConfig{})
+	snapshot, _ := NewSnapshot(KindRailIncidents, start, incidents.GetRailIncidentsResponse{})
+
+	if writeErr := writer.Write(snapshot); writeErr != nil {
+		t.Fatal(writeErr)
+	}
+
+	writer.Close()
+
+	reopened, openErr := OpenWriter(directory, WriterConfig{})
+
+	if openErr != nil {
+		t.Fatal(openErr)
+	}
+
+	defer reopened.Close()
+
+	snapshot.Time = start.Add(-time.Second)
+
+	if writeErr := reopened.Write(snapshot); writeErr == nil {
+		t.Error("expected an error writing a snapshot older than the last archived before reopening")
+	}
+}
+
+func TestRangeSkipsOutOfOrderSegment(t *testing.T) {
+	directory := tempDirectory(t)
+	defer os.RemoveAll(directory)
+
+	// segments written by separate writers with the second one ahead of the third, as if a clock was wrong
+	for _, at := range []time.Time{start, start.Add(time.Hour), start.Add(time.Minute)} {
+		writer, _ := OpenWriter(directory+"/"+at.Format("150405"), WriterConfig{})
+		snapshot, _ := NewSnapshot(KindRailIncidents, at, incidents.GetRailIncidentsResponse{})
+
+		if writeErr := writer.Write(snapshot); writeErr != nil {
+			t.Fatal(writeErr)
+		}
+
+		writer.Close()
+	}
+
+	for i, name := range []string{"120000", "130000", "120100"} {
+		if renameErr := os.Rename(filepath.Join(directory, name, "segment-000001.jsonl.gz"), filepath.Join(directory, fmt.Sprintf(segmentPattern, i+1))); renameErr != nil {
+			t.Fatal(renameErr)
+		}
+	}
+
+	reader, _ := OpenReader(directory)
+	snapshots := readAll(t, reader.Range(start, start.Add(30*time.Minute)))
+
+	if len(snapshots) != 2 || !snapshots[1].Time.Equal(start.Add(time.Minute)) {
+		t.Errorf("expected the snapshots in range after an out of order segment, got %# v", pretty.Formatter(snapshots))
+	}
+}
+
+func TestRotateBySize(t *testing.T) {
+	directory := tempDirectory(t)
+	defer os.RemoveAll(directory)
+
+	writer, _ := OpenWriter(directory, WriterConfig{MaxSegmentSize: 1000})
+	simulated := testSimulator(t)
+
+	for at := start; at.Before(start.Add(10 * time.Minute)); at = at.Add(time.Minute) {
+		snapshot, _ := NewSnapshot(KindNextTrains, at, simulated.NextTrains(nil, at))
+
+		if writeErr := writer.Write(snapshot); writeErr != nil {
+			t.Fatal(writeErr)
+		}
+	}
+
+	writer.Close()
+
+	reader, _ := OpenReader(directory)
+
+	// every prediction snapshot is larger than the limit, so each one is in a segment of its own
+	if segments := reader.Segments(); len(segments) != 10 || segments[9].Count != 1 {
+		t.Errorf("unexpected segments: %# v", pretty.Formatter(segments))
+	}
+}
+
+func TestCrash(t *testing.T) {
+	directory := tempDirectory(t)
+	defer os.RemoveAll(directory)
+
+	writer, _ := OpenWriter(directory, WriterConfig{})
+	simulated := testSimulator(t)
+	written := writeSimulated(t, writer, simulated, start, 5*time.Minute)
+
+	// the writer crashes part way through writing a snapshot, leaving its segment open and out of the index
+	segment := filepath.Join(directory, "segment-000001.jsonl.gz")
+	info, _ := os.Stat(segment)
+	snapshot, _ := NewSnapshot(KindTrainPositions, start.Add(5*time.Minute), simulated.LiveTrainPositions(start.Add(5*time.Minute)))
+
+	if writeErr := writer.Write(snapshot); writeErr != nil {
+		t.Fatal(writeErr)
+	}
+
+	crashed, _ := os.Stat(segment)
+
+	if truncateErr := os.Truncate(segment, info.Size()+(crashed.Size()-info.Size())/2); truncateErr != nil {
+		t.Fatal(truncateErr)
+	}
+
+	reader, readerErr := OpenReader(directory)
+
+	if readerErr != nil {
+		t.Fatal(readerErr)
+	}
+
+	if segments := reader.Segments(); len(segments) != 1 || segments[0].Count != written || !segments[0].End.Equal(start.Add(4*time.Minute+50*time.Second)) {
+		t.Errorf("unexpected segments after a crash: %# v", pretty.Formatter(segments))
+	}
+
+	// a new writer carries on in a new segment
+	restarted, _ := OpenWriter(directory, WriterConfig{})
+	written += writeSimulated(t, restarted, simulated, start.Add(10*time.Minute), 5*time.Minute)
+	restarted.Close()
+
+	reader, _ = OpenReader(directory)
+	snapshots := readAll(t, reader.All())
+
+	if len(snapshots) != written || len(reader.Segments()) != 2 || reader.Segments()[1].File != "segment-000002.jsonl.gz" {
+		t.Errorf("expected %d snapshots in 2 segments, got %d in %# v", written, len(snapshots), pretty.Formatter(reader.Segments()))
+	}
+
+	for i := 1; i < len(snapshots); i++ {
+		if snapshots[i].Time.Before(snapshots[i-1].Time) {
+			t.Errorf("snapshots out of order at %d", i)
+		}
+	}
+}
+
+func TestReadWhileWriting(t *testing.T) {
+	directory := tempDirectory(t)
+	defer os.RemoveAll(directory)
+
+	writer, _ := OpenWriter(directory, WriterConfig{})
+	defer writer.Close()
+
+	written := writeSimulated(t, writer, testSimulator(t), start, time.Minute)
+
+	reader, _ := OpenReader(directory)
+
+	if snapshots := readAll(t, reader.All(KindTrainPositions, KindBusPositions)); len(snapshots) != written {
+		t.Errorf("expected %d snapshots flushed to the open segment, got %d", written, len(snapshots))
+	}
+
+	if snapshots := readAll(t, reader.All(KindOutages)); len(snapshots) != 0 {
+		t.Errorf("unexpected outage snapshots: %# v", pretty.Formatter(snapshots))
+	}
+}
+
+func TestArchiver(t *testing.T) {
+	directory := tempDirectory(t)
+	defer os.RemoveAll(directory)
+
+	writer, _ := OpenWriter(directory, WriterConfig{})
+	scenario := mocks.RedLineRushHourDisruption()
+	services := Services{
+		BusInfo:         scenario.BusInfo,
+		Incidents:       scenario.Incidents,
+		RailPredictions: scenario.RailPredictions,
+		TrainPositions:  scenario.TrainPositions,
+	}
+
+	if _, archiverErr := NewArchiver(Services{Incidents: scenario.Incidents}, writer, Config{}); archiverErr == nil {
+		t.Error("expected an error scheduling kinds without their services")
+	}
+
+	archiver, archiverErr := NewArchiver(services, writer, Config{})
+
+	if archiverErr != nil {
+		t.Fatal(archiverErr)
+	}
+
+	for i, kind := range Kinds {
+		writer.now = func() time.Time {
+			return start.Add(time.Duration(i) * time.Second)
+		}
+
+		if pollErr := archiver.Poll(kind); pollErr != nil {
+			t.Fatal(pollErr)
+		}
+	}
+
+	writer.Close()
+
+	reader, _ := OpenReader(directory)
+	snapshots := readAll(t, reader.All())
+
+	expected := []interface{}{
+		&trainpositions.GetLiveTrainPositionsResponse{Positions: scenario.TrainPositions.Positions},
+		&railpredictions.GetNextTrainResponse{Trains: scenario.RailPredictions.Trains},
+		&businfo.GetPositionsResponse{BusPositions: scenario.BusInfo.Positions},
+		&incidents.GetRailIncidentsResponse{RailIncidents: scenario.Incidents.RailIncidents},
+		&incidents.GetBusIncidentsResponse{},
+		&incidents.GetElevatorEscalatorOutagesResponse{},
+	}
+
+	if len(snapshots) != len(expected) {
+		t.Fatalf("expected a snapshot of every kind: %# v", pretty.Formatter(snapshots))
+	}
+
+	for i, snapshot := range snapshots {
+		response, _ := snapshot.Response()
+
+		if snapshot.Kind != Kinds[i] || !snapshot.Time.Equal(start.Add(time.Duration(i)*time.Second)) || !reflect.DeepEqual(response, expected[i]) {
+			t.Errorf("unexpected %s snapshot: %v", Kinds[i], pretty.Diff(response, expected[i]))
+		}
+	}
+}
+
+func TestArchiverRun(t *testing.T) {
+	directory := tempDirectory(t)
+	defer os.RemoveAll(directory)
+
+	writer, _ := OpenWriter(directory, WriterConfig{})
+	scenario := mocks.NormalService()
+	down := errors.New("503 service unavailable")
+	scenario.Incidents.Fail("GetRailIncidents", down)
+
+	var mutex sync.Mutex
+	var failures []error
+
+	archiver, archiverErr := NewArchiver(Services{Incidents: scenario.Incidents, TrainPositions: scenario.TrainPositions}, writer, Config{
+		Intervals: map[Kind]time.Duration{
+			KindTrainPositions: 10 * time.Millisecond,
+			KindRailIncidents:  time.Hour,
+		},
+		OnError: func(kind Kind, err error) {
+			mutex.Lock()
+			defer mutex.Unlock()
+
+			failures = append(failures, err)
+		},
+	})
+
+	if archiverErr != nil {
+		t.Fatal(archiverErr)
+	}
+
+	stop := make(chan struct{})
+	stopped := make(chan struct{})
+
+	go func() {
+		archiver.Run(stop)
+		close(stopped)
+	}()
+
+	time.Sleep(100 * time.Millisecond)
+	close(stop)
+	<-stopped
+	writer.Close()
+
+	reader, _ := OpenReader(directory)
+
+	if positions := readAll(t, reader.All(KindTrainPositions)); len(positions) < 3 {
+		t.Errorf("expected train positions to be polled repeatedly, got %d snapshots", len(positions))
+	}
+
+	if railIncidents := readAll(t, reader.All(KindRailIncidents)); len(railIncidents) != 0 {
+		t.Errorf("expected failed polls not to be archived: %# v", pretty.Formatter(railIncidents))
+	}
+
+	if len(failures) != 1 || failures[0] != down {
+		t.Errorf("expected the failed poll to be reported once: %v", failures)
+	}
+}
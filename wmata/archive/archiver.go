@@ -0,0 +1,165 @@
+package archive
+
+import (
+	"errors"
+	"github.com/awiede/wmata-go-sdk/wmata/businfo"
+	"github.com/awiede/wmata-go-sdk/wmata/incidents"
+	"github.com/awiede/wmata-go-sdk/wmata/railpredictions"
+	"github.com/awiede/wmata-go-sdk/wmata/trainpositions"
+	"sync"
+	"time"
+)
+
+// Default poll intervals used when Config.Intervals is nil. Train positions change every few seconds, incidents
+// rarely
+const (
+	DefaultTrainPositionsInterval = 10 * time.Second
+	DefaultNextTrainsInterval     = 20 * time.Second
+	DefaultBusPositionsInterval   = 20 * time.Second
+	DefaultIncidentsInterval      = time.Minute
+)
+
+// Services are the services an Archiver polls, only the services of the kinds polled are needed
+type Services struct {
+	BusInfo         businfo.BusInfo
+	Incidents       incidents.Incidents
+	RailPredictions railpredictions.RailPredictions
+	TrainPositions  trainpositions.TrainPositions
+}
+
+// Config holds the schedule an Archiver polls on
+type Config struct {
+	// Intervals between polls of each kind, kinds without an interval are not polled. Every kind is polled at its
+	// default interval if nil
+	Intervals map[Kind]time.Duration
+	// OnError is called with the errors of polls and writes, which are otherwise dropped. A failed poll is not archived
+	OnError func(kind Kind, err error)
+}
+
+// withDefaults returns a copy of the config with zero values replaced by defaults
+func (config Config) withDefaults() Config {
+	if config.Intervals == nil {
+		config.Intervals = map[Kind]time.Duration{
+			KindTrainPositions: DefaultTrainPositionsInterval,
+			KindNextTrains:     DefaultNextTrainsInterval,
+			KindBusPositions:   DefaultBusPositionsInterval,
+			KindRailIncidents:  DefaultIncidentsInterval,
+			KindBusIncidents:   DefaultIncidentsInterval,
+			KindOutages:        DefaultIncidentsInterval,
+		}
+	}
+
+	if config.OnError == nil {
+		config.OnError = func(Kind, error) {}
+	}
+
+	return config
+}
+
+// Archiver polls services on a schedule and appends every response to a Writer
+type Archiver struct {
+	services Services
+	writer   *Writer
+	config   Config
+}
+
+// NewArchiver returns an Archiver writing to writer. It returns an error if a kind is scheduled without the service
+// that polls it
+func NewArchiver(services Services, writer *Writer, config Config) (*Archiver, error) {
+	archiver := Archiver{
+		services: services,
+		writer:   writer,
+		config:   config.withDefaults(),
+	}
+
+	for kind, interval := range archiver.config.Intervals {
+		if interval <= 0 {
+			return nil, errors.New("interval must be positive for " + string(kind))
+		}
+
+		if !archiver.canPoll(kind) {
+			return nil, errors.New("no service to poll " + string(kind))
+		}
+	}
+
+	return &archiver, nil
+}
+
+// Run polls each scheduled kind straight away and then at its interval until stop is closed, returning once polls in
+// progress have been written
+func (archiver *Archiver) Run(stop <-chan struct{}) {
+	var waitGroup sync.WaitGroup
+
+	for kind, interval := range archiver.config.Intervals {
+		waitGroup.Add(1)
+
+		go func(kind Kind, interval time.Duration) {
+			defer waitGroup.Done()
+
+			ticker := time.NewTicker(interval)
+			defer ticker.Stop()
+
+			for {
+				if pollErr := archiver.Poll(kind); pollErr != nil {
+					archiver.config.OnError(kind, pollErr)
+				}
+
+				select {
+				case <-stop:
+					return
+				case <-ticker.C:
+				}
+			}
+		}(kind, interval)
+	}
+
+	waitGroup.Wait()
+}
+
+// Poll retrieves one response of a kind and appends it to the archive
+func (archiver *Archiver) Poll(kind Kind) error {
+	response, pollErr := archiver.poll(kind)
+
+	if pollErr != nil {
+		return pollErr
+	}
+
+	return archiver.writer.Append(kind, response)
+}
+
+func (archiver *Archiver) poll(kind Kind) (interface{}, error) {
+	if !archiver.canPoll(kind) {
+		return nil, errors.New("no service to poll " + string(kind))
+	}
+
+	switch kind {
+	case KindTrainPositions:
+		return archiver.services.TrainPositions.GetLiveTrainPositions()
+	case KindNextTrains:
+		return archiver.services.RailPredictions.GetNextTrains(nil)
+	case KindBusPositions:
+		return archiver.services.BusInfo.GetPositions(nil)
+	case KindRailIncidents:
+		return archiver.services.Incidents.GetRailIncidents()
+	case KindBusIncidents:
+		return archiver.services.Incidents.GetBusIncidents("")
+	default:
+		return archiver.services.Incidents.GetOutages("")
+	}
+}
+
+// canPoll returns true if the archiver has the service polling a kind
+func (archiver *Archiver) canPoll(kind Kind) bool {
+	switch kind {
+	case KindTrainPositions:
+		return archiver.services.TrainPositions != nil
+	case KindNextTrains:
+		return archiver.services.RailPredictions != nil
+	case KindBusPositions:
+		return archiver.services.BusInfo != nil
+	case KindRailIncidents, KindBusIncidents, KindOutages:
+		return archiver.services.Incidents != nil
+	}
+
+	return false
+}
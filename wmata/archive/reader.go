@@ -0,0 +1,306 @@
+package archive
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Reader reads the snapshots of an archive directory as it was when the reader was opened
+type Reader struct {
+	directory string
+	segments  []Segment
+}
+
+// OpenReader reads the index of an archive directory. Segments missing from the index, such as the segment a writer
+// is still writing or one left by a crash, are scanned to find the times they cover
+func OpenReader(directory string) (*Reader, error) {
+	files, listErr := segmentFiles(directory)
+
+	if listErr != nil {
+		return nil, listErr
+	}
+
+	indexed, indexErr := readIndex(directory)
+
+	if indexErr != nil {
+		return nil, indexErr
+	}
+
+	reader := Reader{directory: directory}
+
+	for _, file := range files {
+		if segment, exist := indexed[file]; exist {
+			reader.segments = append(reader.segments, segment)
+			continue
+		}
+
+		segment, scanErr := reader.scan(file)
+
+		if scanErr != nil {
+			return nil, scanErr
+		}
+
+		if segment.Count > 0 {
+			reader.segments = append(reader.segments, *segment)
+		}
+	}
+
+	return &reader, nil
+}
+
+// Segments returns the segments of the archive in the order they were written
+func (reader *Reader) Segments() []Segment {
+	segments := make([]Segment, len(reader.segments))
+	copy(segments, reader.segments)
+
+	return segments
+}
+
+// Range returns an iterator over the snapshots of the given kinds taken from from, inclusive, to to, exclusive. A zero
+// from or to leaves the range open at that end, and every kind is read when no kinds are given. Only the segments
+// covering the range are read
+func (reader *Reader) Range(from, to time.Time, kinds ...Kind) *Iterator {
+	iterator := Iterator{
+		directory: reader.directory,
+		from:      from,
+		to:        to,
+	}
+
+	if len(kinds) > 0 {
+		iterator.kinds = make(map[Kind]bool)
+
+		for _, kind := range kinds {
+			iterator.kinds[kind] = true
+		}
+	}
+
+	for _, segment := range reader.segments {
+		if !from.IsZero() && segment.End.Before(from) {
+			continue
+		}
+
+		if !to.IsZero() && !segment.Start.Before(to) {
+			continue
+		}
+
+		if iterator.kinds != nil && !iterator.covers(segment) {
+			continue
+		}
+
+		iterator.files = append(iterator.files, segment.File)
+	}
+
+	return &iterator
+}
+
+// All returns an iterator over every snapshot of the given kinds, or of every kind when none are given
+func (reader *Reader) All(kinds ...Kind) *Iterator {
+	return reader.Range(time.Time{}, time.Time{}, kinds...)
+}
+
+// scan reads a segment missing from the index to describe it
+func (reader *Reader) scan(file string) (*Segment, error) {
+	segment := Segment{File: file, Kinds: make(map[Kind]int)}
+	iterator := Iterator{directory: reader.directory, files: []string{file}}
+	defer iterator.Close()
+
+	for iterator.Next() {
+		segment.add(iterator.Snapshot())
+	}
+
+	return &segment, iterator.Err()
+}
+
+// Iterator replays snapshots in the order they were written. Call Next until it returns false, reading each snapshot
+// with Snapshot, then check Err. An incomplete last line, as left in a segment that was being written when its writer
+// crashed, ends the segment
+type Iterator struct {
+	directory string
+	files     []string
+	from      time.Time
+	to        time.Time
+	kinds     map[Kind]bool
+
+	file     *os.File
+	gzip     *gzip.Reader
+	lines    *bufio.Reader
+	snapshot *Snapshot
+	err      error
+	done     bool
+}
+
+// Next advances to the next snapshot, returning false when there are no more snapshots or an error occurred
+func (iterator *Iterator) Next() bool {
+	for !iterator.done {
+		if iterator.lines == nil {
+			if len(iterator.files) == 0 {
+				iterator.done = true
+				break
+			}
+
+			if openErr := iterator.open(iterator.files[0]); openErr != nil {
+				iterator.fail(openErr)
+				break
+			}
+
+			iterator.files = iterator.files[1:]
+		}
+
+		line, readErr := iterator.lines.ReadBytes('\n')
+
+		// a line without a newline was cut off while being written, and a segment that was never closed ends without
+		// the gzip trailer
+		if readErr == io.EOF || readErr == io.ErrUnexpectedEOF {
+			iterator.closeFile()
+			continue
+		}
+
+		if readErr != nil {
+			iterator.fail(readErr)
+			break
+		}
+
+		snapshot := Snapshot{}
+
+		if unmarshalErr := json.Unmarshal(bytes.TrimSpace(line), &snapshot); unmarshalErr != nil {
+			iterator.fail(unmarshalErr)
+			break
+		}
+
+		// snapshots out of range are skipped rather than ending the iteration, so a segment out of order with the
+		// others does not hide the snapshots after it
+		if !iterator.to.IsZero() && !snapshot.Time.Before(iterator.to) {
+			continue
+		}
+
+		if !iterator.from.IsZero() && snapshot.Time.Before(iterator.from) {
+			continue
+		}
+
+		if iterator.kinds != nil && !iterator.kinds[snapshot.Kind] {
+			continue
+		}
+
+		iterator.snapshot = &snapshot
+
+		return true
+	}
+
+	iterator.snapshot = nil
+	iterator.closeFile()
+
+	return false
+}
+
+// Snapshot returns the snapshot Next advanced to
+func (iterator *Iterator) Snapshot() *Snapshot {
+	return iterator.snapshot
+}
+
+// Err returns the error that stopped the iterator, if any
+func (iterator *Iterator) Err() error {
+	return iterator.err
+}
+
+// Close releases the segment being read. It is only needed when the iterator is not read to the end
+func (iterator *Iterator) Close() error {
+	iterator.done = true
+	iterator.closeFile()
+
+	return nil
+}
+
+func (iterator *Iterator) open(file string) error {
+	opened, openErr := os.Open(filepath.Join(iterator.directory, file))
+
+	if openErr != nil {
+		return openErr
+	}
+
+	decompressed, gzipErr := gzip.NewReader(opened)
+
+	if gzipErr != nil {
+		opened.Close()
+
+		// a segment that was created but never flushed is empty
+		if gzipErr == io.EOF {
+			iterator.lines = bufio.NewReader(bytes.NewReader(nil))
+			return nil
+		}
+
+		return gzipErr
+	}
+
+	iterator.file = opened
+	iterator.gzip = decompressed
+	iterator.lines = bufio.NewReader(decompressed)
+
+	return nil
+}
+
+func (iterator *Iterator) closeFile() {
+	if iterator.gzip != nil {
+		iterator.gzip.Close()
+		iterator.gzip = nil
+	}
+
+	if iterator.file != nil {
+		iterator.file.Close()
+		iterator.file = nil
+	}
+
+	iterator.lines = nil
+}
+
+func (iterator *Iterator) fail(err error) {
+	iterator.err = err
+	iterator.done = true
+}
+
+// covers returns true if the segment holds any snapshots of the iterator's kinds
+func (iterator *Iterator) covers(segment Segment) bool {
+	for kind := range iterator.kinds {
+		if segment.Kinds[kind] > 0 {
+			return true
+		}
+	}
+
+	return false
+}
+
+// readIndex returns the segments listed in the index of a directory by file name. A missing index is empty, and an
+// incomplete last line is ignored
+func readIndex(directory string) (map[string]Segment, error) {
+	contents, readErr := ioutil.ReadFile(filepath.Join(directory, indexFile))
+
+	if os.IsNotExist(readErr) {
+		return map[string]Segment{}, nil
+	}
+
+	if readErr != nil {
+		return nil, readErr
+	}
+
+	segments := make(map[string]Segment)
+	lines := bytes.Split(contents, []byte("\n"))
+
+	// the last element is whatever follows the final newline, complete lines always end with one
+	for _, line := range lines[:len(lines)-1] {
+		segment := Segment{}
+
+		if unmarshalErr := json.Unmarshal(line, &segment); unmarshalErr != nil {
+			return nil, unmarshalErr
+		}
+
+		segments[segment.File] = segment
+	}
+
+	return segments, nil
+}
@@ -0,0 +1,271 @@
+package archive
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Default segment limits used when a WriterConfig field is left at zero
+const (
+	DefaultMaxSegmentSize = 64 << 20
+	DefaultMaxSegmentAge  = time.Hour
+)
+
+// indexFile is the name of the index in an archive directory
+const indexFile = "index.jsonl"
+
+// segmentPattern names segment files with a sequence number, so they sort in the order they were written
+const segmentPattern = "segment-%06d.jsonl.gz"
+
+// WriterConfig holds the limits at which a Writer starts a new segment
+type WriterConfig struct {
+	// MaxSegmentSize in bytes of uncompressed JSON, a segment is closed once it holds at least this much
+	MaxSegmentSize int64
+	// MaxSegmentAge, a segment is closed before writing a snapshot taken this long after the segment's first snapshot
+	MaxSegmentAge time.Duration
+}
+
+// withDefaults returns a copy of the config with zero values replaced by defaults
+func (config WriterConfig) withDefaults() WriterConfig {
+	if config.MaxSegmentSize <= 0 {
+		config.MaxSegmentSize = DefaultMaxSegmentSize
+	}
+
+	if config.MaxSegmentAge <= 0 {
+		config.MaxSegmentAge = DefaultMaxSegmentAge
+	}
+
+	return config
+}
+
+// Segment describes one compressed file of an archive
+type Segment struct {
+	File string `json:"File"`
+	// Start and End are the times of the first and last snapshots in the segment
+	Start time.Time `json:"Start"`
+	End   time.Time `json:"End"`
+	Count int       `json:"Count"`
+	// Kinds counts the snapshots of each kind in the segment
+	Kinds map[Kind]int `json:"Kinds"`
+}
+
+// add records a snapshot written to the segment
+func (segment *Segment) add(snapshot *Snapshot) {
+	if segment.Count == 0 {
+		segment.Start = snapshot.Time
+	}
+
+	segment.End = snapshot.Time
+	segment.Count++
+	segment.Kinds[snapshot.Kind]++
+}
+
+// Writer appends snapshots to an archive directory. Snapshots are written as JSON Lines to gzip compressed segment
+// files, which are never changed once closed, and each closed segment is added to an append-only index of the times
+// it covers. Every snapshot is flushed to its segment as it is written, so a reader can see it straight away and a
+// crash loses at most the snapshot being written. It is safe for concurrent use
+type Writer struct {
+	directory string
+	config    WriterConfig
+	now       func() time.Time
+
+	mutex    sync.Mutex
+	sequence int
+	last     time.Time
+	file     *os.File
+	gzip     *gzip.Writer
+	segment  *Segment
+	size     int64
+}
+
+// OpenWriter opens an archive directory for writing, creating it if needed. Snapshots are written to new segments
+// after any already in the directory, and must not be older than the last snapshot already archived
+func OpenWriter(directory string, config WriterConfig) (*Writer, error) {
+	if mkdirErr := os.MkdirAll(directory, 0755); mkdirErr != nil {
+		return nil, mkdirErr
+	}
+
+	files, listErr := segmentFiles(directory)
+
+	if listErr != nil {
+		return nil, listErr
+	}
+
+	writer := Writer{
+		directory: directory,
+		config:    config.withDefaults(),
+		now:       time.Now,
+	}
+
+	if len(files) > 0 {
+		fmt.Sscanf(files[len(files)-1], segmentPattern, &writer.sequence)
+
+		// the reader takes the times of indexed segments from the index and scans the others
+		reader, readerErr := OpenReader(directory)
+
+		if readerErr != nil {
+			return nil, readerErr
+		}
+
+		for _, segment := range reader.segments {
+			if segment.End.After(writer.last) {
+				writer.last = segment.End
+			}
+		}
+	}
+
+	return &writer, nil
+}
+
+// Append writes a snapshot of a response taken now. Snapshots appended concurrently are timed in the order they are
+// written
+func (writer *Writer) Append(kind Kind, response interface{}) error {
+	data, marshalErr := json.Marshal(response)
+
+	if marshalErr != nil {
+		return marshalErr
+	}
+
+	writer.mutex.Lock()
+	defer writer.mutex.Unlock()
+
+	return writer.write(&Snapshot{Kind: kind, Time: writer.now(), Data: data})
+}
+
+// Write writes a snapshot, which must not be older than the last snapshot written
+func (writer *Writer) Write(snapshot *Snapshot) error {
+	writer.mutex.Lock()
+	defer writer.mutex.Unlock()
+
+	return writer.write(snapshot)
+}
+
+// Close closes the current segment and adds it to the index
+func (writer *Writer) Close() error {
+	writer.mutex.Lock()
+	defer writer.mutex.Unlock()
+
+	return writer.closeSegment()
+}
+
+func (writer *Writer) write(snapshot *Snapshot) error {
+	if snapshot.Time.Before(writer.last) {
+		return fmt.Errorf("snapshot at %s is older than the last snapshot at %s", snapshot.Time.Format(time.RFC3339Nano), writer.last.Format(time.RFC3339Nano))
+	}
+
+	line, marshalErr := json.Marshal(snapshot)
+
+	if marshalErr != nil {
+		return marshalErr
+	}
+
+	if writer.segment != nil && (writer.size >= writer.config.MaxSegmentSize || snapshot.Time.Sub(writer.segment.Start) >= writer.config.MaxSegmentAge) {
+		if closeErr := writer.closeSegment(); closeErr != nil {
+			return closeErr
+		}
+	}
+
+	if writer.segment == nil {
+		if openErr := writer.openSegment(); openErr != nil {
+			return openErr
+		}
+	}
+
+	if _, writeErr := writer.gzip.Write(append(line, '\n')); writeErr != nil {
+		return writeErr
+	}
+
+	if flushErr := writer.gzip.Flush(); flushErr != nil {
+		return flushErr
+	}
+
+	writer.size += int64(len(line)) + 1
+	writer.last = snapshot.Time
+	writer.segment.add(snapshot)
+
+	return nil
+}
+
+func (writer *Writer) openSegment() error {
+	writer.sequence++
+	name := fmt.Sprintf(segmentPattern, writer.sequence)
+
+	// segments are never reopened, so an existing file means another writer is using the directory
+	file, openErr := os.OpenFile(filepath.Join(writer.directory, name), os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+
+	if openErr != nil {
+		return openErr
+	}
+
+	writer.file = file
+	writer.gzip = gzip.NewWriter(file)
+	writer.segment = &Segment{File: name, Kinds: make(map[Kind]int)}
+	writer.size = 0
+
+	return nil
+}
+
+func (writer *Writer) closeSegment() error {
+	if writer.segment == nil {
+		return nil
+	}
+
+	segment := writer.segment
+	writer.segment = nil
+
+	if closeErr := writer.gzip.Close(); closeErr != nil {
+		writer.file.Close()
+		return closeErr
+	}
+
+	if closeErr := writer.file.Close(); closeErr != nil {
+		return closeErr
+	}
+
+	line, marshalErr := json.Marshal(segment)
+
+	if marshalErr != nil {
+		return marshalErr
+	}
+
+	index, openErr := os.OpenFile(filepath.Join(writer.directory, indexFile), os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+
+	if openErr != nil {
+		return openErr
+	}
+
+	if _, writeErr := index.Write(append(line, '\n')); writeErr != nil {
+		index.Close()
+		return writeErr
+	}
+
+	return index.Close()
+}
+
+// segmentFiles returns the names of the segment files in a directory in the order they were written
+func segmentFiles(directory string) ([]string, error) {
+	entries, readErr := ioutil.ReadDir(directory)
+
+	if readErr != nil {
+		return nil, readErr
+	}
+
+	var files []string
+
+	// ReadDir sorts entries by name, which is the order segments were written in
+	for _, entry := range entries {
+		var sequence int
+
+		if _, scanErr := fmt.Sscanf(entry.Name(), segmentPattern, &sequence); scanErr == nil && !entry.IsDir() {
+			files = append(files, entry.Name())
+		}
+	}
+
+	return files, nil
+}
@@ -0,0 +1,363 @@
+package busanalytics
+
+import (
+	"errors"
+	"github.com/awiede/wmata-go-sdk/wmata"
+	"github.com/awiede/wmata-go-sdk/wmata/businfo"
+	"github.com/awiede/wmata-go-sdk/wmata/linearref"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Default thresholds used when a Config field is left at zero
+const (
+	DefaultBunchingDistance    = 300.0
+	DefaultBunchingHeadway     = 2 * time.Minute
+	DefaultGapHeadway          = 20 * time.Minute
+	DefaultAverageSpeed        = 5.0
+	DefaultMaxOffRouteDistance = 250.0
+)
+
+type EventType string
+
+const (
+	// EventBunching is reported when two consecutive buses are closer than the bunching thresholds
+	EventBunching EventType = "BUNCHING"
+	// EventGap is reported when two consecutive buses are further apart than the gap thresholds
+	EventGap EventType = "GAP"
+)
+
+// Config holds the thresholds used to detect bunching and gaps
+type Config struct {
+	// BunchingDistance in meters, consecutive buses closer than this are bunched
+	BunchingDistance float64
+	// BunchingHeadway, consecutive buses with an estimated headway shorter than this are bunched
+	BunchingHeadway time.Duration
+	// GapDistance in meters, consecutive buses further apart than this are a gap. Distance gaps are not reported if zero
+	GapDistance float64
+	// GapHeadway, consecutive buses with an estimated headway longer than this are a gap
+	GapHeadway time.Duration
+	// AverageSpeed in meters per second, used to convert the distance between buses into a headway
+	AverageSpeed float64
+	// MaxOffRouteDistance in meters, buses further than this from their route shape are reported as off route
+	MaxOffRouteDistance float64
+}
+
+// withDefaults returns a copy of the config with zero values replaced by defaults
+func (config Config) withDefaults() Config {
+	if config.BunchingDistance <= 0 {
+		config.BunchingDistance = DefaultBunchingDistance
+	}
+
+	if config.BunchingHeadway <= 0 {
+		config.BunchingHeadway = DefaultBunchingHeadway
+	}
+
+	if config.GapHeadway <= 0 {
+		config.GapHeadway = DefaultGapHeadway
+	}
+
+	if config.AverageSpeed <= 0 {
+		config.AverageSpeed = DefaultAverageSpeed
+	}
+
+	if config.MaxOffRouteDistance <= 0 {
+		config.MaxOffRouteDistance = DefaultMaxOffRouteDistance
+	}
+
+	return config
+}
+
+// Report is the result of analyzing a set of bus positions
+type Report struct {
+	GeneratedAt time.Time         `json:"GeneratedAt"`
+	Directions  []DirectionReport `json:"Directions"`
+	Events      []Event           `json:"Events"`
+	// OffRoute holds buses that could not be placed on their route shape, either because the route was not loaded or
+	// the bus was further than MaxOffRouteDistance from the shape
+	OffRoute []businfo.BusPosition `json:"OffRoute"`
+}
+
+// DirectionReport holds the vehicles and headways for one direction of a route
+type DirectionReport struct {
+	RouteID       string `json:"RouteID"`
+	DirectionText string `json:"DirectionText"`
+	// RouteLength is the length of the route shape in meters
+	RouteLength float64 `json:"RouteLength"`
+	// Vehicles are ordered by distance along the route, the furthest along last
+	Vehicles []VehicleProgress `json:"Vehicles"`
+	// Headways are between each pair of consecutive vehicles
+	Headways []Headway `json:"Headways"`
+}
+
+// VehicleProgress is a bus position placed on its route shape
+type VehicleProgress struct {
+	Position businfo.BusPosition `json:"Position"`
+	// DistanceAlongRoute in meters from the start of the route shape
+	DistanceAlongRoute float64 `json:"DistanceAlongRoute"`
+	// DistanceFromRoute in meters between the reported position and the route shape
+	DistanceFromRoute float64 `json:"DistanceFromRoute"`
+	// NextStop is the stop the bus is approaching, nil if it has passed the last stop
+	NextStop *businfo.Stop `json:"NextStop"`
+}
+
+// Headway is the spacing between a bus and the bus ahead of it in the same direction
+type Headway struct {
+	LeadingVehicleID   string        `json:"LeadingVehicleID"`
+	FollowingVehicleID string        `json:"FollowingVehicleID"`
+	Distance           float64       `json:"Distance"`
+	Time               time.Duration `json:"Time"`
+}
+
+// Event is a bunching or gap detection suitable for a dispatch dashboard
+type Event struct {
+	Type          EventType `json:"Type"`
+	RouteID       string    `json:"RouteID"`
+	DirectionText string    `json:"DirectionText"`
+	Headway       Headway   `json:"Headway"`
+	DetectedAt    time.Time `json:"DetectedAt"`
+}
+
+// directionKey identifies a single direction of a route
+type directionKey struct {
+	routeID       string
+	directionText string
+}
+
+// routeDirection is a loaded route direction with its projected shape
+type routeDirection struct {
+	routeID         string
+	directionText   string
+	directionNumber string
+	shape           *linearref.Shape
+}
+
+// Analyzer projects bus positions onto route shapes to detect bunching and gaps. It is safe for concurrent use
+type Analyzer struct {
+	config     Config
+	mutex      sync.RWMutex
+	directions map[directionKey]*routeDirection
+}
+
+// NewAnalyzer returns an Analyzer with no routes loaded
+func NewAnalyzer(config Config) *Analyzer {
+	return &Analyzer{
+		config:     config.withDefaults(),
+		directions: make(map[directionKey]*routeDirection),
+	}
+}
+
+// AddRoute loads both directions of a route so positions on it can be analyzed, replacing any previous shapes
+func (analyzer *Analyzer) AddRoute(route *businfo.GetRouteDetailsResponse) {
+	analyzer.mutex.Lock()
+	defer analyzer.mutex.Unlock()
+
+	for _, direction := range []businfo.Direction{route.Direction0, route.Direction1} {
+		if len(direction.Shapes) == 0 {
+			continue
+		}
+
+		analyzer.directions[directionKey{routeID: route.RouteID, directionText: normalize(direction.DirectionText)}] = &routeDirection{
+			routeID:         route.RouteID,
+			directionText:   direction.DirectionText,
+			directionNumber: direction.DirectionNumber,
+			shape:           linearref.NewShape(&direction),
+		}
+	}
+}
+
+// Analyze places each bus on its route shape, orders buses by distance along route per direction and reports
+// headways, bunching and gaps
+func (analyzer *Analyzer) Analyze(positions []businfo.BusPosition, at time.Time) *Report {
+	analyzer.mutex.RLock()
+	defer analyzer.mutex.RUnlock()
+
+	report := Report{
+		GeneratedAt: at,
+	}
+
+	vehicles := make(map[*routeDirection][]VehicleProgress)
+
+	for _, position := range positions {
+		direction := analyzer.findDirection(&position)
+
+		if direction == nil {
+			report.OffRoute = append(report.OffRoute, position)
+			continue
+		}
+
+		snapped, snapErr := direction.shape.SnapPosition(&position)
+
+		if snapErr != nil || snapped.DistanceFromShape > analyzer.config.MaxOffRouteDistance {
+			report.OffRoute = append(report.OffRoute, position)
+			continue
+		}
+
+		vehicles[direction] = append(vehicles[direction], VehicleProgress{
+			Position:           position,
+			DistanceAlongRoute: snapped.DistanceTraveled,
+			DistanceFromRoute:  snapped.DistanceFromShape,
+			NextStop:           snapped.NextStop,
+		})
+	}
+
+	for direction, progress := range vehicles {
+		sort.SliceStable(progress, func(i, j int) bool {
+			return progress[i].DistanceAlongRoute < progress[j].DistanceAlongRoute
+		})
+
+		directionReport := DirectionReport{
+			RouteID:       direction.routeID,
+			DirectionText: direction.directionText,
+			RouteLength:   direction.shape.Length(),
+			Vehicles:      progress,
+		}
+
+		for i := 1; i < len(progress); i++ {
+			spacing := progress[i].DistanceAlongRoute - progress[i-1].DistanceAlongRoute
+
+			headway := Headway{
+				LeadingVehicleID:   progress[i].Position.VehicleID,
+				FollowingVehicleID: progress[i-1].Position.VehicleID,
+				Distance:           spacing,
+				Time:               time.Duration(spacing / analyzer.config.AverageSpeed * float64(time.Second)),
+			}
+
+			directionReport.Headways = append(directionReport.Headways, headway)
+
+			if eventType, detected := analyzer.classify(&headway); detected {
+				report.Events = append(report.Events, Event{
+					Type:          eventType,
+					RouteID:       direction.routeID,
+					DirectionText: direction.directionText,
+					Headway:       headway,
+					DetectedAt:    at,
+				})
+			}
+		}
+
+		report.Directions = append(report.Directions, directionReport)
+	}
+
+	sort.Slice(report.Directions, func(i, j int) bool {
+		if report.Directions[i].RouteID != report.Directions[j].RouteID {
+			return report.Directions[i].RouteID < report.Directions[j].RouteID
+		}
+
+		return report.Directions[i].DirectionText < report.Directions[j].DirectionText
+	})
+
+	sort.SliceStable(report.Events, func(i, j int) bool {
+		if report.Events[i].RouteID != report.Events[j].RouteID {
+			return report.Events[i].RouteID < report.Events[j].RouteID
+		}
+
+		return report.Events[i].DirectionText < report.Events[j].DirectionText
+	})
+
+	return &report
+}
+
+// classify reports whether a headway is bunched or a gap
+func (analyzer *Analyzer) classify(headway *Headway) (EventType, bool) {
+	switch {
+	case headway.Distance < analyzer.config.BunchingDistance || headway.Time < analyzer.config.BunchingHeadway:
+		return EventBunching, true
+	case headway.Time > analyzer.config.GapHeadway:
+		return EventGap, true
+	case analyzer.config.GapDistance > 0 && headway.Distance > analyzer.config.GapDistance:
+		return EventGap, true
+	default:
+		return "", false
+	}
+}
+
+// findDirection matches a position to a loaded route direction by DirectionText, falling back to the deprecated
+// direction number when the text does not match
+func (analyzer *Analyzer) findDirection(position *businfo.BusPosition) *routeDirection {
+	if direction, exist := analyzer.directions[directionKey{routeID: position.RouteID, directionText: normalize(position.DirectionText)}]; exist {
+		return direction
+	}
+
+	directionNumber := strconv.Itoa(position.DirectionNumber)
+
+	for key, direction := range analyzer.directions {
+		if key.routeID == position.RouteID && direction.directionNumber == directionNumber {
+			return direction
+		}
+	}
+
+	return nil
+}
+
+func normalize(directionText string) string {
+	return strings.ToUpper(strings.TrimSpace(directionText))
+}
+
+// NewService returns a new Service that loads route shapes and positions using an existing BusInfo service
+func NewService(busInfo businfo.BusInfo, config Config) *Service {
+	return &Service{
+		busInfo:      busInfo,
+		analyzer:     NewAnalyzer(config),
+		loadedRoutes: make(map[string]string),
+	}
+}
+
+// Service analyzes live bus positions, loading each route's shape once per service day
+type Service struct {
+	busInfo      businfo.BusInfo
+	analyzer     *Analyzer
+	mutex        sync.Mutex
+	loadedRoutes map[string]string
+}
+
+// AnalyzeRoutes retrieves current positions for the given routes and analyzes them
+func (service *Service) AnalyzeRoutes(routeIDs []string, at time.Time) (*Report, error) {
+	if len(routeIDs) == 0 {
+		return nil, errors.New("at least one routeID is required")
+	}
+
+	var positions []businfo.BusPosition
+
+	for _, routeID := range routeIDs {
+		if loadErr := service.loadRoute(routeID, at); loadErr != nil {
+			return nil, loadErr
+		}
+
+		routePositions, positionsErr := service.busInfo.GetPositions(&businfo.GetPositionsRequest{RouteID: routeID})
+
+		if positionsErr != nil {
+			return nil, positionsErr
+		}
+
+		positions = append(positions, routePositions.BusPositions...)
+	}
+
+	return service.analyzer.Analyze(positions, at), nil
+}
+
+// loadRoute retrieves route details unless they were already loaded for the day of at in Washington
+func (service *Service) loadRoute(routeID string, at time.Time) error {
+	date := at.In(wmata.Location).Format(wmata.DateLayout)
+
+	service.mutex.Lock()
+	defer service.mutex.Unlock()
+
+	if service.loadedRoutes[routeID] == date {
+		return nil
+	}
+
+	route, routeErr := service.busInfo.GetRouteDetails(routeID, date)
+
+	if routeErr != nil {
+		return routeErr
+	}
+
+	service.analyzer.AddRoute(route)
+	service.loadedRoutes[routeID] = date
+
+	return nil
+}
@@ -0,0 +1,203 @@
+package busanalytics
+
+import (
+	"errors"
+	"github.com/awiede/wmata-go-sdk/wmata"
+	"github.com/awiede/wmata-go-sdk/wmata/businfo"
+	"github.com/kr/pretty"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// testClient is a mock implementation of wmata.HTTPClient interface used for testing purposes
+type testClient struct{}
+
+// ensure testClient implements wmata.HTTPClient interface
+var _ wmata.HTTPClient = (*testClient)(nil)
+
+// Do stubs out an httpClient.Do request
+func (client *testClient) Do(req *http.Request) (*http.Response, error) {
+	testResponses, exist := testData[req.URL.Path]
+
+	if !exist {
+		return nil, errors.New("no test data found")
+	}
+
+	for _, response := range testResponses {
+		if response.rawQuery == req.URL.RawQuery {
+			rr := httptest.NewRecorder()
+			rr.WriteHeader(http.StatusOK)
+			_, writeErr := rr.Write([]byte(response.response))
+
+			return rr.Result(), writeErr
+		}
+	}
+
+	return nil, errors.New("no data found")
+}
+
+type testResponseData struct {
+	rawQuery string
+	response string
+}
+
+var testData = map[string][]testResponseData{
+	"/Bus.svc/json/jRouteDetails": {
+		{
+			rawQuery: "Date=2019-04-28&RouteID=X1",
+			response: `{"RouteID":"X1","Name":"X1 - TEST","Direction0":{"TripHeadsign":"EAST END","DirectionText":"EAST","DirectionNum":"0","Shape":[{"Lat":38.9,"Lon":-77.1,"SeqNum":1},{"Lat":38.9,"Lon":-77.05,"SeqNum":2},{"Lat":38.9,"Lon":-77.0,"SeqNum":3}],"Stops":[]},"Direction1":{"TripHeadsign":"WEST END","DirectionText":"WEST","DirectionNum":"1","Shape":[{"Lat":38.9,"Lon":-77.0,"SeqNum":1},{"Lat":38.9,"Lon":-77.1,"SeqNum":2}],"Stops":[]}}`,
+		},
+	},
+	"/Bus.svc/json/jBusPositions": {
+		{
+			rawQuery: "RouteID=X1",
+			response: `{"BusPositions":[{"VehicleID":"2","Lat":38.9001,"Lon":-77.093,"RouteID":"X1","DirectionNum":0,"DirectionText":"EAST"},{"VehicleID":"1","Lat":38.9,"Lon":-77.095,"RouteID":"X1","DirectionNum":0,"DirectionText":"EAST"},{"VehicleID":"9","Lat":38.9,"Lon":-77.02,"RouteID":"X1","DirectionNum":1,"DirectionText":"WEST"}]}`,
+		},
+	},
+}
+
+// testRoute runs east along a line of latitude then back west
+var testRoute = businfo.GetRouteDetailsResponse{
+	RouteID: "X1",
+	Direction0: businfo.Direction{
+		DirectionNumber: "0",
+		DirectionText:   "EAST",
+		Shapes: []businfo.ShapePoint{
+			{Latitude: 38.9, Longitude: -77.0, SequenceNumber: 3},
+			{Latitude: 38.9, Longitude: -77.1, SequenceNumber: 1},
+			{Latitude: 38.9, Longitude: -77.05, SequenceNumber: 2},
+		},
+	},
+	Direction1: businfo.Direction{
+		DirectionNumber: "1",
+		DirectionText:   "WEST",
+		Shapes: []businfo.ShapePoint{
+			{Latitude: 38.9, Longitude: -77.0, SequenceNumber: 1},
+			{Latitude: 38.9, Longitude: -77.1, SequenceNumber: 2},
+		},
+	},
+}
+
+func TestAnalyze(t *testing.T) {
+	analyzer := NewAnalyzer(Config{GapHeadway: 12 * time.Minute})
+	analyzer.AddRoute(&testRoute)
+
+	at := time.Date(2019, 4, 28, 8, 0, 0, 0, time.UTC)
+
+	positions := []businfo.BusPosition{
+		{VehicleID: "3", RouteID: "X1", DirectionText: "EAST", Latitude: 38.9, Longitude: -77.06},
+		{VehicleID: "1", RouteID: "X1", DirectionText: "EAST", Latitude: 38.9, Longitude: -77.095},
+		{VehicleID: "4", RouteID: "X1", DirectionText: "east", Latitude: 38.9, Longitude: -77.005},
+		{VehicleID: "2", RouteID: "X1", DirectionText: "EAST", Latitude: 38.9005, Longitude: -77.093},
+		{VehicleID: "5", RouteID: "X1", DirectionText: "EAST", Latitude: 38.95, Longitude: -77.0},
+		{VehicleID: "6", RouteID: "Z9", DirectionText: "EAST", Latitude: 38.9, Longitude: -77.0},
+		{VehicleID: "7", RouteID: "X1", DirectionText: "WEST", Latitude: 38.9, Longitude: -77.09},
+		{VehicleID: "8", RouteID: "X1", DirectionNumber: 1, Latitude: 38.9, Longitude: -77.03},
+	}
+
+	report := analyzer.Analyze(positions, at)
+
+	if len(report.Directions) != 2 {
+		t.Fatalf("expected 2 directions, got %d", len(report.Directions))
+	}
+
+	east := report.Directions[0]
+
+	var order []string
+
+	for _, vehicle := range east.Vehicles {
+		order = append(order, vehicle.Position.VehicleID)
+	}
+
+	if !reflect.DeepEqual(order, []string{"1", "2", "3", "4"}) {
+		t.Errorf("unexpected vehicle order: %v", order)
+	}
+
+	if math.Abs(east.RouteLength-8663) > 10 {
+		t.Errorf("unexpected route length: %f", east.RouteLength)
+	}
+
+	if math.Abs(east.Vehicles[0].DistanceAlongRoute-433) > 5 {
+		t.Errorf("unexpected distance along route: %f", east.Vehicles[0].DistanceAlongRoute)
+	}
+
+	if math.Abs(east.Vehicles[1].DistanceFromRoute-55.6) > 1 {
+		t.Errorf("unexpected distance from route: %f", east.Vehicles[1].DistanceFromRoute)
+	}
+
+	west := report.Directions[1]
+
+	if len(west.Vehicles) != 2 || west.Vehicles[0].Position.VehicleID != "8" || west.Vehicles[1].Position.VehicleID != "7" {
+		t.Errorf("unexpected west vehicles: %# v", pretty.Formatter(west.Vehicles))
+	}
+
+	type summary struct {
+		Type      EventType
+		Leading   string
+		Following string
+	}
+
+	var events []summary
+
+	for _, event := range report.Events {
+		events = append(events, summary{Type: event.Type, Leading: event.Headway.LeadingVehicleID, Following: event.Headway.FollowingVehicleID})
+
+		if !event.DetectedAt.Equal(at) {
+			t.Errorf("unexpected detection time: %s", event.DetectedAt)
+		}
+	}
+
+	expectedEvents := []summary{
+		{Type: EventBunching, Leading: "2", Following: "1"},
+		{Type: EventGap, Leading: "4", Following: "3"},
+		{Type: EventGap, Leading: "7", Following: "8"},
+	}
+
+	if !reflect.DeepEqual(events, expectedEvents) {
+		t.Error(pretty.Diff(events, expectedEvents))
+	}
+
+	var offRoute []string
+
+	for _, position := range report.OffRoute {
+		offRoute = append(offRoute, position.VehicleID)
+	}
+
+	if !reflect.DeepEqual(offRoute, []string{"5", "6"}) {
+		t.Errorf("unexpected off route vehicles: %v", offRoute)
+	}
+}
+
+func TestAnalyzeRoutes(t *testing.T) {
+	testService := NewService(businfo.NewService(&wmata.Client{HTTPClient: &testClient{}}, wmata.JSON), Config{})
+	at := time.Date(2019, 4, 28, 8, 0, 0, 0, time.UTC)
+
+	if _, err := testService.AnalyzeRoutes(nil, at); err == nil {
+		t.Error("expected error for missing routes")
+	}
+
+	report, err := testService.AnalyzeRoutes([]string{"X1"}, at)
+
+	if err != nil {
+		t.Fatalf("error calling AnalyzeRoutes: %s", err)
+	}
+
+	if len(report.Directions) != 2 || len(report.Events) != 1 || report.Events[0].Type != EventBunching {
+		t.Errorf("unexpected report: %# v", pretty.Formatter(report))
+	}
+
+	if _, err := testService.AnalyzeRoutes([]string{"X1"}, at.AddDate(0, 0, 1)); err == nil || err.Error() != "no data found" {
+		t.Errorf("expected route details to be reloaded for a new service day, got: %v", err)
+	}
+
+	// 10pm on the 28th in Washington is already the 29th in UTC
+	evening := NewService(businfo.NewService(&wmata.Client{HTTPClient: &testClient{}}, wmata.JSON), Config{})
+
+	if _, err := evening.AnalyzeRoutes([]string{"X1"}, time.Date(2019, 4, 29, 2, 0, 0, 0, time.UTC)); err != nil {
+		t.Errorf("expected route details for the day in Washington, got: %v", err)
+	}
+}
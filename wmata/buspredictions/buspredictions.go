@@ -5,10 +5,14 @@ import (
 	"errors"
 	"github.com/awiede/wmata-go-sdk/wmata"
 	"strings"
+	"sync"
 )
 
 const busPredictionsServiceBaseURL = "https://api.wmata.com/NextBusService.svc"
 
+// DefaultConcurrency is the number of stops requested at once by GetNextBusesForStops when no limit is given
+const DefaultConcurrency = 4
+
 type GetNextBusResponse struct {
 	XMLName            xml.Name            `json:"-" xml:"http://www.wmata.com NextBusResponse"`
 	NextBusPredictions []NextBusPrediction `json:"Predictions" xml:"Predictions>NextBusPrediction"`
@@ -24,6 +28,13 @@ type NextBusPrediction struct {
 	VehicleID       string `json:"VehicleID" xml:"VehicleID"`
 }
 
+// GetNextBusesForStopsResponse holds the outcome of a batch of next bus requests keyed by stopID.
+// Each requested stop appears in exactly one of Predictions or Errors
+type GetNextBusesForStopsResponse struct {
+	Predictions map[string]*GetNextBusResponse
+	Errors      map[string]error
+}
+
 // BusPredictions defines the method available in the WMATA "Real-Time Bus Predictions" API
 type BusPredictions interface {
 	GetNextBuses(stopID string) (*GetNextBusResponse, error)
@@ -67,3 +78,62 @@ func (service *Service) GetNextBuses(stopID string) (*GetNextBusResponse, error)
 	return &nextBus, service.client.BuildAndSendGetRequest(service.responseType, requestUrl.String(), map[string]string{"StopID": stopID}, &nextBus)
 
 }
+
+// GetNextBusesForStops retrieves next bus arrival times for several stops from any BusPredictions, sending at most
+// concurrency requests at a time. Duplicate stopIDs are only requested once, and every request made by a Service is
+// subject to its client's rate limiter. A failure for one stop is reported in the response Errors and does not stop
+// the remaining requests
+func GetNextBusesForStops(busPredictions BusPredictions, stopIDs []string, concurrency int) (*GetNextBusesForStopsResponse, error) {
+	if len(stopIDs) == 0 {
+		return nil, errors.New("at least one stopID is required")
+	}
+
+	if concurrency <= 0 {
+		concurrency = DefaultConcurrency
+	}
+
+	var uniqueStopIDs []string
+	seen := make(map[string]bool)
+
+	for _, stopID := range stopIDs {
+		if !seen[stopID] {
+			seen[stopID] = true
+			uniqueStopIDs = append(uniqueStopIDs, stopID)
+		}
+	}
+
+	batch := GetNextBusesForStopsResponse{
+		Predictions: make(map[string]*GetNextBusResponse),
+		Errors:      make(map[string]error),
+	}
+
+	var mutex sync.Mutex
+	var waitGroup sync.WaitGroup
+	slots := make(chan struct{}, concurrency)
+
+	for _, stopID := range uniqueStopIDs {
+		waitGroup.Add(1)
+		slots <- struct{}{}
+
+		go func(stopID string) {
+			defer waitGroup.Done()
+			defer func() { <-slots }()
+
+			nextBus, err := busPredictions.GetNextBuses(stopID)
+
+			mutex.Lock()
+			defer mutex.Unlock()
+
+			if err != nil {
+				batch.Errors[stopID] = err
+				return
+			}
+
+			batch.Predictions[stopID] = nextBus
+		}(stopID)
+	}
+
+	waitGroup.Wait()
+
+	return &batch, nil
+}
@@ -8,7 +8,9 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"reflect"
+	"sync"
 	"testing"
+	"time"
 )
 
 // testClient is a mock implementation of wmata.HTTPClient interface used for testing purposes
@@ -38,6 +40,32 @@ func (client *testClient) Do(req *http.Request) (*http.Response, error) {
 	return nil, errors.New("no data found")
 }
 
+// concurrencyClient wraps testClient and records the largest number of requests in flight at once
+type concurrencyClient struct {
+	testClient
+	mutex       sync.Mutex
+	inFlight    int
+	maxInFlight int
+}
+
+// Do tracks in flight requests and delays each response so concurrent requests overlap
+func (client *concurrencyClient) Do(req *http.Request) (*http.Response, error) {
+	client.mutex.Lock()
+	client.inFlight++
+	if client.inFlight > client.maxInFlight {
+		client.maxInFlight = client.inFlight
+	}
+	client.mutex.Unlock()
+
+	time.Sleep(time.Millisecond * 10)
+
+	client.mutex.Lock()
+	client.inFlight--
+	client.mutex.Unlock()
+
+	return client.testClient.Do(req)
+}
+
 type testResponseData struct {
 	rawQuery             string
 	param                string
@@ -154,3 +182,59 @@ func TestGetNextBuses(t *testing.T) {
 		}
 	}
 }
+
+func TestGetNextBusesForStops(t *testing.T) {
+	testService := setupTestService(wmata.JSON)
+
+	if _, err := GetNextBusesForStops(testService, nil, 0); err == nil || err.Error() != "at least one stopID is required" {
+		t.Errorf("expected stopIDs error, got: %v", err)
+	}
+
+	response, err := GetNextBusesForStops(testService, []string{"1001370", "", "1001370", "404"}, 2)
+
+	if err != nil {
+		t.Fatalf("error calling GetNextBusesForStops: %s", err)
+	}
+
+	expected := testData["/NextBusService.svc/json/jPredictions"][0].unmarshalledResponse
+
+	if len(response.Predictions) != 1 || !reflect.DeepEqual(response.Predictions["1001370"], expected) {
+		t.Error(pretty.Diff(response.Predictions, map[string]interface{}{"1001370": expected}))
+	}
+
+	expectedErrors := map[string]string{
+		"":    "stopID is required",
+		"404": "no data found",
+	}
+
+	if len(response.Errors) != len(expectedErrors) {
+		t.Errorf("unexpected errors: %v", response.Errors)
+	}
+
+	for stopID, message := range expectedErrors {
+		if stopErr, exist := response.Errors[stopID]; !exist || stopErr.Error() != message {
+			t.Errorf("unexpected error for stop %q: %v", stopID, stopErr)
+		}
+	}
+}
+
+func TestGetNextBusesForStopsConcurrency(t *testing.T) {
+	httpClient := &concurrencyClient{}
+	testService := NewService(&wmata.Client{HTTPClient: httpClient}, wmata.JSON)
+
+	stopIDs := []string{"1", "2", "3", "4", "5", "6", "7", "8"}
+
+	response, err := GetNextBusesForStops(testService, stopIDs, 3)
+
+	if err != nil {
+		t.Fatalf("error calling GetNextBusesForStops: %s", err)
+	}
+
+	if len(response.Errors) != len(stopIDs) {
+		t.Errorf("expected an error for every stop, got: %v", response.Errors)
+	}
+
+	if httpClient.maxInFlight > 3 {
+		t.Errorf("concurrency limit exceeded: %d requests in flight", httpClient.maxInFlight)
+	}
+}
@@ -0,0 +1,309 @@
+package cassette
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/awiede/wmata-go-sdk/wmata"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// redacted replaces the API key wherever it appears in a recorded response
+const redacted = "[REDACTED]"
+
+// Mode is how a Replayer answers requests that were not recorded
+type Mode int
+
+const (
+	// Strict fails unmatched requests with an error
+	Strict Mode = iota
+	// Lenient answers unmatched requests with 404 Not Found, the requests are listed by Replayer.Unmatched
+	Lenient
+)
+
+// Request is a recorded request. The query is normalized and never includes the API key
+type Request struct {
+	Method string `json:"Method"`
+	Path   string `json:"Path"`
+	Query  string `json:"Query"`
+}
+
+// Response is a recorded response
+type Response struct {
+	StatusCode int         `json:"StatusCode"`
+	Header     http.Header `json:"Header"`
+	Body       string      `json:"Body"`
+}
+
+// Interaction is a request and the response it received
+type Interaction struct {
+	Request  Request  `json:"Request"`
+	Response Response `json:"Response"`
+}
+
+// Cassette is a list of interactions in the order they were recorded
+type Cassette struct {
+	Interactions []Interaction `json:"Interactions"`
+}
+
+// Load reads a cassette file written by Save
+func Load(path string) (*Cassette, error) {
+	data, readErr := ioutil.ReadFile(path)
+
+	if readErr != nil {
+		return nil, readErr
+	}
+
+	var cassette Cassette
+
+	if unmarshalErr := json.Unmarshal(data, &cassette); unmarshalErr != nil {
+		return nil, unmarshalErr
+	}
+
+	return &cassette, nil
+}
+
+// Save writes the cassette to a file as indented JSON, so changes to recorded responses are easy to review
+func (cassette *Cassette) Save(path string) error {
+	data, marshalErr := json.MarshalIndent(cassette, "", "  ")
+
+	if marshalErr != nil {
+		return marshalErr
+	}
+
+	return ioutil.WriteFile(path, append(data, '\n'), 0644)
+}
+
+// Recorder is a wmata.HTTPClient that sends requests through another client and records each request and response.
+// The API key is never recorded
+type Recorder struct {
+	client   wmata.HTTPClient
+	mutex    sync.Mutex
+	cassette Cassette
+}
+
+var _ wmata.HTTPClient = (*Recorder)(nil)
+
+// NewRecorder returns a Recorder sending requests through client
+func NewRecorder(client wmata.HTTPClient) *Recorder {
+	return &Recorder{client: client}
+}
+
+// Do sends a request and records it with its response. Requests that fail without a response are not recorded
+func (recorder *Recorder) Do(request *http.Request) (*http.Response, error) {
+	response, responseErr := recorder.client.Do(request)
+
+	if responseErr != nil {
+		return nil, responseErr
+	}
+
+	body, readErr := ioutil.ReadAll(response.Body)
+	wmata.CloseResponseBody(response)
+
+	if readErr != nil {
+		return nil, readErr
+	}
+
+	response.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+	recordedBody := string(body)
+
+	if apiKey := apiKey(request); apiKey != "" {
+		recordedBody = strings.Replace(recordedBody, apiKey, redacted, -1)
+	}
+
+	recorder.mutex.Lock()
+	defer recorder.mutex.Unlock()
+
+	recorder.cassette.Interactions = append(recorder.cassette.Interactions, Interaction{
+		Request: newRequest(request),
+		Response: Response{
+			StatusCode: response.StatusCode,
+			Header:     copyHeader(response.Header),
+			Body:       recordedBody,
+		},
+	})
+
+	return response, nil
+}
+
+// Cassette returns the interactions recorded so far
+func (recorder *Recorder) Cassette() *Cassette {
+	recorder.mutex.Lock()
+	defer recorder.mutex.Unlock()
+
+	interactions := make([]Interaction, len(recorder.cassette.Interactions))
+	copy(interactions, recorder.cassette.Interactions)
+
+	return &Cassette{Interactions: interactions}
+}
+
+// Save writes the interactions recorded so far to a cassette file
+func (recorder *Recorder) Save(path string) error {
+	return recorder.Cassette().Save(path)
+}
+
+// Replayer is a wmata.HTTPClient answering requests from a cassette without sending them. Requests match an
+// interaction with the same method, path and query, ignoring the order of query parameters and the API key. When
+// several interactions match they are replayed in the order they were recorded, repeating the last one once all
+// have been replayed
+type Replayer struct {
+	mode      Mode
+	mutex     sync.Mutex
+	responses map[Request][]Response
+	replayed  map[Request]int
+	unmatched []Request
+}
+
+var _ wmata.HTTPClient = (*Replayer)(nil)
+
+// NewReplayer returns a Replayer answering requests from cassette
+func NewReplayer(cassette *Cassette, mode Mode) *Replayer {
+	replayer := Replayer{
+		mode:      mode,
+		responses: make(map[Request][]Response),
+		replayed:  make(map[Request]int),
+	}
+
+	for _, interaction := range cassette.Interactions {
+		// normalize again in case the cassette was edited by hand
+		key := interaction.Request
+		key.Query = normalizeQuery(key.Query)
+		replayer.responses[key] = append(replayer.responses[key], interaction.Response)
+	}
+
+	return &replayer
+}
+
+// Do answers a request with its recorded response
+func (replayer *Replayer) Do(request *http.Request) (*http.Response, error) {
+	key := newRequest(request)
+
+	replayer.mutex.Lock()
+	defer replayer.mutex.Unlock()
+
+	responses, exist := replayer.responses[key]
+
+	if !exist {
+		if replayer.mode == Strict {
+			return nil, fmt.Errorf("no recorded response for %s", describe(key))
+		}
+
+		replayer.unmatched = append(replayer.unmatched, key)
+
+		return newResponse(request, Response{StatusCode: http.StatusNotFound}), nil
+	}
+
+	index := replayer.replayed[key]
+
+	if index >= len(responses) {
+		index = len(responses) - 1
+	}
+
+	replayer.replayed[key]++
+
+	return newResponse(request, responses[index]), nil
+}
+
+// Unmatched returns the requests answered with 404 Not Found because they were not recorded, in Lenient mode
+func (replayer *Replayer) Unmatched() []Request {
+	replayer.mutex.Lock()
+	defer replayer.mutex.Unlock()
+
+	unmatched := make([]Request, len(replayer.unmatched))
+	copy(unmatched, replayer.unmatched)
+
+	return unmatched
+}
+
+// Verify returns an error listing the recorded requests that were never replayed, for tests checking that every
+// recorded request is still sent
+func (replayer *Replayer) Verify() error {
+	replayer.mutex.Lock()
+	defer replayer.mutex.Unlock()
+
+	var missing []string
+
+	for key := range replayer.responses {
+		if replayer.replayed[key] == 0 {
+			missing = append(missing, describe(key))
+		}
+	}
+
+	if len(missing) == 0 {
+		return nil
+	}
+
+	sort.Strings(missing)
+
+	return errors.New("recorded requests were not replayed: " + strings.Join(missing, ", "))
+}
+
+func newRequest(request *http.Request) Request {
+	return Request{
+		Method: request.Method,
+		Path:   request.URL.Path,
+		Query:  normalizeQuery(request.URL.RawQuery),
+	}
+}
+
+func newResponse(request *http.Request, recorded Response) *http.Response {
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", recorded.StatusCode, http.StatusText(recorded.StatusCode)),
+		StatusCode:    recorded.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        copyHeader(recorded.Header),
+		Body:          ioutil.NopCloser(strings.NewReader(recorded.Body)),
+		ContentLength: int64(len(recorded.Body)),
+		Request:       request,
+	}
+}
+
+func copyHeader(header http.Header) http.Header {
+	copied := make(http.Header, len(header))
+
+	for name, values := range header {
+		copied[name] = append([]string(nil), values...)
+	}
+
+	return copied
+}
+
+// normalizeQuery encodes a query without the API key, with parameters sorted by name and then value. Requests built
+// from a map of query parameters send them in any order, so the order is not significant when matching
+func normalizeQuery(rawQuery string) string {
+	// malformed parameters are dropped rather than risk keeping the API key
+	query, _ := url.ParseQuery(rawQuery)
+	query.Del(wmata.APIKeyHeader)
+
+	for _, values := range query {
+		sort.Strings(values)
+	}
+
+	return query.Encode()
+}
+
+// apiKey returns the API key sent in a request's header or query
+func apiKey(request *http.Request) string {
+	if key := request.Header.Get(wmata.APIKeyHeader); key != "" {
+		return key
+	}
+
+	return request.URL.Query().Get(wmata.APIKeyHeader)
+}
+
+func describe(request Request) string {
+	if request.Query == "" {
+		return request.Method + " " + request.Path
+	}
+
+	return request.Method + " " + request.Path + "?" + request.Query
+}
@@ -0,0 +1,210 @@
+package cassette
+
+import (
+	"github.com/awiede/wmata-go-sdk/wmata"
+	"github.com/awiede/wmata-go-sdk/wmata/railinfo"
+	"github.com/awiede/wmata-go-sdk/wmata/railpredictions"
+	"github.com/awiede/wmata-go-sdk/wmata/wmatatest"
+	"github.com/kr/pretty"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestRecordAndReplay(t *testing.T) {
+	server := wmatatest.NewServer(wmatatest.Config{APIKey: "recorder-secret"})
+	defer server.Close()
+
+	recorder := NewRecorder(server.HTTPClient())
+	recordingClient := &wmata.Client{APIKey: server.APIKey(), HTTPClient: recorder}
+
+	recordedLines, linesErr := railinfo.NewService(recordingClient, wmata.XML).GetLines()
+
+	if linesErr != nil {
+		t.Fatal(linesErr)
+	}
+
+	recordedFares, faresErr := railinfo.NewService(recordingClient, wmata.JSON).GetStationToStationInformation("A01", "A03")
+
+	if faresErr != nil {
+		t.Fatal(faresErr)
+	}
+
+	recordedTrains, trainsErr := railpredictions.NewService(recordingClient, wmata.JSON).GetNextTrains([]string{"A01"})
+
+	if trainsErr != nil {
+		t.Fatal(trainsErr)
+	}
+
+	dir, dirErr := ioutil.TempDir("", "cassette")
+
+	if dirErr != nil {
+		t.Fatal(dirErr)
+	}
+
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "rail.json")
+
+	if saveErr := recorder.Save(path); saveErr != nil {
+		t.Fatal(saveErr)
+	}
+
+	data, readErr := ioutil.ReadFile(path)
+
+	if readErr != nil {
+		t.Fatal(readErr)
+	}
+
+	if strings.Contains(string(data), "recorder-secret") || strings.Contains(string(data), wmata.APIKeyHeader) {
+		t.Error("expected the API key to be scrubbed from the cassette")
+	}
+
+	cassette, loadErr := Load(path)
+
+	if loadErr != nil {
+		t.Fatal(loadErr)
+	}
+
+	expectedRequests := []Request{
+		{Method: http.MethodGet, Path: "/Rail.svc/Lines"},
+		{Method: http.MethodGet, Path: "/Rail.svc/json/jSrcStationToDstStationInfo", Query: "FromStationCode=A01&ToStationCode=A03"},
+		{Method: http.MethodGet, Path: "/StationPrediction.svc/json/GetPrediction/A01"},
+	}
+
+	var requests []Request
+
+	for _, interaction := range cassette.Interactions {
+		requests = append(requests, interaction.Request)
+
+		if interaction.Response.StatusCode != http.StatusOK {
+			t.Errorf("unexpected recorded status: %d", interaction.Response.StatusCode)
+		}
+	}
+
+	if !reflect.DeepEqual(requests, expectedRequests) {
+		t.Errorf("unexpected recorded requests: %v", pretty.Diff(requests, expectedRequests))
+	}
+
+	// the server is closed so replayed responses can only come from the cassette
+	server.Close()
+
+	replayer := NewReplayer(cassette, Strict)
+	replayingClient := &wmata.Client{APIKey: "another-key", HTTPClient: replayer}
+
+	lines, linesErr := railinfo.NewService(replayingClient, wmata.XML).GetLines()
+
+	if linesErr != nil || !reflect.DeepEqual(lines, recordedLines) {
+		t.Errorf("unexpected replayed lines: %v %v", pretty.Diff(lines, recordedLines), linesErr)
+	}
+
+	fares, faresErr := railinfo.NewService(replayingClient, wmata.JSON).GetStationToStationInformation("A01", "A03")
+
+	if faresErr != nil || !reflect.DeepEqual(fares, recordedFares) {
+		t.Errorf("unexpected replayed fares: %v %v", pretty.Diff(fares, recordedFares), faresErr)
+	}
+
+	if verifyErr := replayer.Verify(); verifyErr == nil || !strings.Contains(verifyErr.Error(), "GetPrediction/A01") {
+		t.Errorf("expected the prediction request to be reported as not replayed, got %v", verifyErr)
+	}
+
+	trains, trainsErr := railpredictions.NewService(replayingClient, wmata.JSON).GetNextTrains([]string{"A01"})
+
+	if trainsErr != nil || !reflect.DeepEqual(trains, recordedTrains) {
+		t.Errorf("unexpected replayed trains: %v %v", pretty.Diff(trains, recordedTrains), trainsErr)
+	}
+
+	if verifyErr := replayer.Verify(); verifyErr != nil {
+		t.Errorf("unexpected verify error: %s", verifyErr)
+	}
+
+	if _, unmatchedErr := railinfo.NewService(replayingClient, wmata.JSON).GetLines(); unmatchedErr == nil {
+		t.Error("expected an unmatched request to fail in strict mode")
+	}
+}
+
+func TestQueryMatching(t *testing.T) {
+	cassette := &Cassette{Interactions: []Interaction{
+		{
+			Request:  Request{Method: http.MethodGet, Path: "/Bus.svc/json/jStops", Query: "Lat=38.9&Lon=-77.03&Radius=500"},
+			Response: Response{StatusCode: http.StatusOK, Body: `{"Stops":[]}`},
+		},
+	}}
+
+	replayer := NewReplayer(cassette, Strict)
+
+	for _, rawQuery := range []string{
+		"Radius=500&Lat=38.9&Lon=-77.03",
+		"Lon=-77.03&Radius=500&Lat=38.9&api_key=secret",
+	} {
+		request, _ := http.NewRequest(http.MethodGet, "https://api.wmata.com/Bus.svc/json/jStops?"+rawQuery, nil)
+		response, responseErr := replayer.Do(request)
+
+		if responseErr != nil {
+			t.Errorf("%s: unexpected error: %s", rawQuery, responseErr)
+			continue
+		}
+
+		body, _ := ioutil.ReadAll(response.Body)
+
+		if response.StatusCode != http.StatusOK || string(body) != `{"Stops":[]}` {
+			t.Errorf("%s: unexpected response: %d %s", rawQuery, response.StatusCode, body)
+		}
+	}
+
+	request, _ := http.NewRequest(http.MethodGet, "https://api.wmata.com/Bus.svc/json/jStops?Lat=38.9&Lon=-77.03&Radius=1000", nil)
+
+	if _, responseErr := replayer.Do(request); responseErr == nil {
+		t.Error("expected a different query not to match")
+	}
+}
+
+func TestReplayOrder(t *testing.T) {
+	request := Request{Method: http.MethodGet, Path: "/Incidents.svc/json/Incidents"}
+	cassette := &Cassette{Interactions: []Interaction{
+		{Request: request, Response: Response{StatusCode: http.StatusOK, Body: "first"}},
+		{Request: request, Response: Response{StatusCode: http.StatusOK, Body: "second"}},
+	}}
+
+	replayer := NewReplayer(cassette, Strict)
+
+	for _, expected := range []string{"first", "second", "second"} {
+		httpRequest, _ := http.NewRequest(http.MethodGet, "https://api.wmata.com/Incidents.svc/json/Incidents", nil)
+		response, responseErr := replayer.Do(httpRequest)
+
+		if responseErr != nil {
+			t.Fatal(responseErr)
+		}
+
+		body, _ := ioutil.ReadAll(response.Body)
+
+		if string(body) != expected {
+			t.Errorf("expected %s, got %s", expected, body)
+		}
+	}
+}
+
+func TestLenient(t *testing.T) {
+	replayer := NewReplayer(&Cassette{}, Lenient)
+
+	request, _ := http.NewRequest(http.MethodGet, "https://api.wmata.com/Rail.svc/json/jStations?LineCode=RD&api_key=secret", nil)
+	response, responseErr := replayer.Do(request)
+
+	if responseErr != nil {
+		t.Fatal(responseErr)
+	}
+
+	if response.StatusCode != http.StatusNotFound {
+		t.Errorf("expected 404, got %d", response.StatusCode)
+	}
+
+	expected := []Request{{Method: http.MethodGet, Path: "/Rail.svc/json/jStations", Query: "LineCode=RD"}}
+
+	if unmatched := replayer.Unmatched(); !reflect.DeepEqual(unmatched, expected) {
+		t.Errorf("unexpected unmatched requests: %v", pretty.Diff(unmatched, expected))
+	}
+}
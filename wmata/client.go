@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"encoding/xml"
 	"errors"
+	"github.com/awiede/wmata-go-sdk/internal/singleflight"
 	"io/ioutil"
 	"log"
 	"net/http"
@@ -20,8 +21,27 @@ const (
 	LineCodeYellow = "YL"
 
 	APIKeyHeader = "api_key"
+
+	// DateLayout is the layout WMATA uses for date request parameters, e.g. "2019-04-28"
+	DateLayout = "2006-01-02"
+	// DateTimeLayout is the layout WMATA uses for date-time response fields, e.g. "2019-04-28T05:02:42"
+	DateTimeLayout = "2006-01-02T15:04:05"
 )
 
+// Location is the time zone of WMATA dates and times, America/New_York. It is a fixed Eastern Standard Time offset when
+// the time zone database is not available
+var Location = loadLocation()
+
+func loadLocation() *time.Location {
+	newYork, loadErr := time.LoadLocation("America/New_York")
+
+	if loadErr != nil {
+		return time.FixedZone("EST", -5*60*60)
+	}
+
+	return newYork
+}
+
 type ResponseType int
 
 const (
@@ -44,6 +64,12 @@ type HTTPClient interface {
 type Client struct {
 	APIKey     string
 	HTTPClient HTTPClient
+	// RateLimiter is shared by every request sent through the client, requests are not limited if nil
+	RateLimiter RateLimiter
+	// CoalesceRequests makes concurrent identical requests share a single upstream call. Each caller still decodes its
+	// own copy of the response, and an error is returned to every caller that was waiting on the call
+	CoalesceRequests bool
+	inFlight         singleflight.Group
 }
 
 // NewWMATADefaultClient returns a new client to make requests to the WMATA API
@@ -65,8 +91,16 @@ func NewWMATAClient(apiKey string, httpClient http.Client) *Client {
 	}
 }
 
+// waitForRateLimit blocks until the client's rate limiter allows another request
+func (client *Client) waitForRateLimit() {
+	if client.RateLimiter != nil {
+		client.RateLimiter.Wait()
+	}
+}
+
 // ValidateAPIKey sends a validation request to the WMATA API to verify the given API works and that WMATA is available.
-// Returns 200, nil if able to connect and receive a success (200) response from WMATA - otherwise returns status code and error message
+// Returns 200, nil if able to connect and receive a success (200) response from WMATA - otherwise returns status code and error message.
+// The status code is 0 when the request could not be sent
 func (client *Client) ValidateAPIKey() (int, error) {
 	request, requestErr := http.NewRequest(http.MethodGet, "https://api.wmata.com/Misc/Validate", nil)
 
@@ -76,9 +110,16 @@ func (client *Client) ValidateAPIKey() (int, error) {
 
 	request.Header.Add(APIKeyHeader, client.APIKey)
 
+	client.waitForRateLimit()
+
 	response, responseErr := client.HTTPClient.Do(request)
 
 	if responseErr != nil {
+		// no response is returned when the request could not be sent
+		if response == nil {
+			return 0, responseErr
+		}
+
 		return response.StatusCode, responseErr
 	}
 
@@ -119,18 +160,10 @@ func (client *Client) BuildAndSendGetRequest(responseFormat ResponseType, url st
 		request.URL.RawQuery = query.Encode()
 	}
 
-	response, responseErr := client.HTTPClient.Do(request)
+	body, sendErr := client.send(request)
 
-	if responseErr != nil {
-		return responseErr
-	}
-
-	defer CloseResponseBody(response)
-
-	body, readErr := ioutil.ReadAll(response.Body)
-
-	if readErr != nil {
-		return readErr
+	if sendErr != nil {
+		return sendErr
 	}
 
 	switch responseFormat {
@@ -143,3 +176,36 @@ func (client *Client) BuildAndSendGetRequest(responseFormat ResponseType, url st
 	}
 
 }
+
+// send returns the body of the response to a request. When CoalesceRequests is set, requests with the same method and
+// URL, including the query, that are already in flight wait for and share that response body
+func (client *Client) send(request *http.Request) ([]byte, error) {
+	if !client.CoalesceRequests {
+		return client.do(request)
+	}
+
+	body, sendErr, _ := client.inFlight.Do(request.Method+" "+request.URL.String(), func() (interface{}, error) {
+		return client.do(request)
+	})
+
+	if sendErr != nil {
+		return nil, sendErr
+	}
+
+	return body.([]byte), nil
+}
+
+// do sends a request once the rate limiter allows it and reads the response body
+func (client *Client) do(request *http.Request) ([]byte, error) {
+	client.waitForRateLimit()
+
+	response, responseErr := client.HTTPClient.Do(request)
+
+	if responseErr != nil {
+		return nil, responseErr
+	}
+
+	defer CloseResponseBody(response)
+
+	return ioutil.ReadAll(response.Body)
+}
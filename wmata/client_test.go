@@ -8,6 +8,8 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"reflect"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 )
@@ -44,6 +46,13 @@ func (httpClient *testHttpClient) Do(req *http.Request) (*http.Response, error)
 	return nil, errors.New("no data found")
 }
 
+// failingHttpClient fails every request without a response, like a DNS failure or timeout
+type failingHttpClient struct{}
+
+func (failingHttpClient) Do(*http.Request) (*http.Response, error) {
+	return nil, errors.New("dial tcp: lookup api.wmata.com: no such host")
+}
+
 type testResponseData struct {
 	rawQuery             string
 	requestURL           string
@@ -184,6 +193,17 @@ func TestValidateAPIKey(t *testing.T) {
 	}
 }
 
+func TestValidateAPIKeyTransportError(t *testing.T) {
+	wmataClient := Client{
+		APIKey:     "123456789",
+		HTTPClient: failingHttpClient{},
+	}
+
+	if responseStatus, responseErr := wmataClient.ValidateAPIKey(); responseStatus != 0 || responseErr == nil {
+		t.Errorf("expected transport error with status 0, got %d %v", responseStatus, responseErr)
+	}
+}
+
 func TestBuildAndSendGetRequest(t *testing.T) {
 	testRequests, exist := testData["/test"]
 
@@ -216,3 +236,148 @@ func TestBuildAndSendGetRequest(t *testing.T) {
 	}
 
 }
+
+func TestRateLimiter(t *testing.T) {
+	wmataClient := Client{
+		APIKey:      "123456789",
+		HTTPClient:  &testHttpClient{},
+		RateLimiter: NewRateLimiter(100),
+	}
+
+	start := time.Now()
+
+	for i := 0; i < 5; i++ {
+		test := testType{}
+
+		if err := wmataClient.BuildAndSendGetRequest(JSON, "http://foo.bar.test/test", map[string]string{"Test": "true"}, &test); err != nil {
+			t.Errorf("unexpected error: %s", err)
+		}
+	}
+
+	if elapsed := time.Since(start); elapsed < time.Millisecond*40 {
+		t.Errorf("requests were not rate limited, 5 requests took %s", elapsed)
+	}
+
+	unlimited := NewRateLimiter(0)
+	start = time.Now()
+
+	for i := 0; i < 100; i++ {
+		unlimited.Wait()
+	}
+
+	if elapsed := time.Since(start); elapsed > time.Millisecond*10 {
+		t.Errorf("unlimited rate limiter blocked for %s", elapsed)
+	}
+}
+
+// blockingHttpClient counts requests and holds every response until release is closed
+type blockingHttpClient struct {
+	requests int32
+	release  chan struct{}
+	err      error
+}
+
+func (httpClient *blockingHttpClient) Do(req *http.Request) (*http.Response, error) {
+	atomic.AddInt32(&httpClient.requests, 1)
+	<-httpClient.release
+
+	if httpClient.err != nil {
+		return nil, httpClient.err
+	}
+
+	rr := httptest.NewRecorder()
+	rr.WriteHeader(http.StatusOK)
+	_, writeErr := rr.Write([]byte(`{"foo":"coalesced","bar":"response"}`))
+
+	return rr.Result(), writeErr
+}
+
+// sendConcurrently sends count identical requests at once and returns each caller's decoded response and error
+func sendConcurrently(wmataClient *Client, httpClient *blockingHttpClient, count int) ([]*testType, []error) {
+	responses := make([]*testType, count)
+	errs := make([]error, count)
+	var finished sync.WaitGroup
+
+	for i := 0; i < count; i++ {
+		i := i
+		finished.Add(1)
+
+		go func() {
+			defer finished.Done()
+
+			responses[i] = &testType{}
+			errs[i] = wmataClient.BuildAndSendGetRequest(JSON, "http://foo.bar.test/test", map[string]string{"Test": "true", "Other": "1"}, responses[i])
+		}()
+	}
+
+	// give every request time to join the first before it completes
+	time.Sleep(time.Millisecond * 50)
+	close(httpClient.release)
+	finished.Wait()
+
+	return responses, errs
+}
+
+func TestCoalesceRequests(t *testing.T) {
+	httpClient := &blockingHttpClient{release: make(chan struct{})}
+	wmataClient := Client{
+		APIKey:           "123456789",
+		HTTPClient:       httpClient,
+		CoalesceRequests: true,
+	}
+
+	responses, errs := sendConcurrently(&wmataClient, httpClient, 5)
+
+	if httpClient.requests != 1 {
+		t.Errorf("expected 1 upstream request, got %d", httpClient.requests)
+	}
+
+	for i, response := range responses {
+		if errs[i] != nil {
+			t.Errorf("unexpected error: %s", errs[i])
+			continue
+		}
+
+		if !reflect.DeepEqual(response, &testType{Foo: "coalesced", Bar: "response"}) {
+			t.Error(pretty.Diff(response, &testType{Foo: "coalesced", Bar: "response"}))
+		}
+	}
+
+	// each caller decodes its own copy
+	responses[0].Foo = "changed"
+
+	if responses[1].Foo != "coalesced" {
+		t.Error("expected callers not to share a decoded response")
+	}
+
+	failing := &blockingHttpClient{release: make(chan struct{}), err: errors.New("connection reset")}
+	wmataClient = Client{
+		APIKey:           "123456789",
+		HTTPClient:       failing,
+		CoalesceRequests: true,
+	}
+
+	_, errs = sendConcurrently(&wmataClient, failing, 5)
+
+	if failing.requests != 1 {
+		t.Errorf("expected 1 upstream request, got %d", failing.requests)
+	}
+
+	for _, err := range errs {
+		if err == nil || err.Error() != "connection reset" {
+			t.Errorf("expected error to be returned to every caller, got %v", err)
+		}
+	}
+
+	uncoalesced := &blockingHttpClient{release: make(chan struct{})}
+	wmataClient = Client{
+		APIKey:     "123456789",
+		HTTPClient: uncoalesced,
+	}
+
+	sendConcurrently(&wmataClient, uncoalesced, 5)
+
+	if uncoalesced.requests != 5 {
+		t.Errorf("expected requests not to be coalesced by default, got %d upstream requests", uncoalesced.requests)
+	}
+}
@@ -0,0 +1,365 @@
+package fare
+
+import (
+	"errors"
+	"github.com/awiede/wmata-go-sdk/wmata"
+	"github.com/awiede/wmata-go-sdk/wmata/railinfo"
+	"github.com/awiede/wmata-go-sdk/wmata/stationmatrix"
+	"github.com/awiede/wmata-go-sdk/wmata/stationtimes"
+	"math"
+	"sync"
+	"time"
+)
+
+type FareClass string
+
+const (
+	FareClassPeak    FareClass = "PEAK"
+	FareClassOffPeak FareClass = "OFF_PEAK"
+	// FareClassWeekend is only used when Config.WeekendFlatFare is set
+	FareClassWeekend FareClass = "WEEKEND"
+)
+
+type RiderCategory int
+
+const (
+	RiderRegular RiderCategory = iota
+	// RiderSeniorDisabled pays the SeniorDisabled rail fare at all times and a reduced bus fare
+	RiderSeniorDisabled
+	// RiderChild covers children who ride free with a paying adult
+	RiderChild
+)
+
+type Mode int
+
+const (
+	Rail Mode = iota
+	Bus
+)
+
+// Defaults used when a Config field is left at zero
+const (
+	DefaultBusFare                = 2.00
+	DefaultSeniorDisabledBusFare  = 1.00
+	DefaultTransferDiscount       = 2.00
+	DefaultTransferWindow         = 2 * time.Hour
+	DefaultSeniorTransferDiscount = 1.00
+)
+
+// Window is a time of day range during which peak fares apply, expressed as offsets from midnight at the start of the
+// service day. Late night service after midnight belongs to the previous service day, see stationtimes.ServiceDate
+type Window struct {
+	Start time.Duration
+	End   time.Duration
+}
+
+// DefaultPeakWindows are WMATA's weekday peak periods: opening at 5am until 9:30am and 3pm until 7pm
+var DefaultPeakWindows = []Window{
+	{Start: 5 * time.Hour, End: 9*time.Hour + 30*time.Minute},
+	{Start: 15 * time.Hour, End: 19 * time.Hour},
+}
+
+// Config holds the fare rules. Zero values are replaced by the defaults above
+type Config struct {
+	// PeakWindows apply on weekdays that are not holidays
+	PeakWindows []Window
+	// Holidays are dates charged as weekends, only the year, month and day are used
+	Holidays []time.Time
+	// WeekendFlatFare replaces the off-peak fare on weekends and holidays for regular riders when greater than zero
+	WeekendFlatFare float64
+	BusFare         float64
+	// SeniorDisabledBusFare is charged to RiderSeniorDisabled on buses
+	SeniorDisabledBusFare float64
+	// TransferDiscount is taken off a leg when changing between bus and rail within TransferWindow
+	TransferDiscount float64
+	// SeniorTransferDiscount is the transfer discount for RiderSeniorDisabled
+	SeniorTransferDiscount float64
+	TransferWindow         time.Duration
+}
+
+func (config Config) withDefaults() Config {
+	if config.PeakWindows == nil {
+		config.PeakWindows = DefaultPeakWindows
+	}
+
+	if config.BusFare <= 0 {
+		config.BusFare = DefaultBusFare
+	}
+
+	if config.SeniorDisabledBusFare <= 0 {
+		config.SeniorDisabledBusFare = DefaultSeniorDisabledBusFare
+	}
+
+	if config.TransferDiscount <= 0 {
+		config.TransferDiscount = DefaultTransferDiscount
+	}
+
+	if config.SeniorTransferDiscount <= 0 {
+		config.SeniorTransferDiscount = DefaultSeniorTransferDiscount
+	}
+
+	if config.TransferWindow <= 0 {
+		config.TransferWindow = DefaultTransferWindow
+	}
+
+	return config
+}
+
+// JourneyLeg is a single bus or rail ride of a journey
+type JourneyLeg struct {
+	Mode Mode
+	// FromStation and ToStation are station codes, only used for rail legs
+	FromStation string
+	ToStation   string
+	Departure   time.Time
+}
+
+// LegFare is the fare charged for one leg
+type LegFare struct {
+	JourneyLeg
+	FareClass FareClass `json:"FareClass"`
+	BaseFare  float64   `json:"BaseFare"`
+	Discount  float64   `json:"Discount"`
+	Fare      float64   `json:"Fare"`
+}
+
+// JourneyFare is the fare for every leg of a journey and its total
+type JourneyFare struct {
+	Legs  []LegFare `json:"Legs"`
+	Total float64   `json:"Total"`
+}
+
+// Calculator prices rail and bus journeys. The station to station matrix is retrieved with a single request the first
+// time it is needed and cached until Load or SetMatrix is called. It is safe for concurrent use
+type Calculator struct {
+	railInfo railinfo.RailInfo
+	config   Config
+	holidays map[string]bool
+	mutex    sync.RWMutex
+	matrix   *stationmatrix.Matrix
+	// loadMutex makes concurrent first lookups share a single request for the matrix
+	loadMutex sync.Mutex
+}
+
+// NewCalculator returns a Calculator that loads rail fares using an existing RailInfo service
+func NewCalculator(railInfo railinfo.RailInfo, config Config) *Calculator {
+	config = config.withDefaults()
+
+	holidays := make(map[string]bool)
+
+	for _, holiday := range config.Holidays {
+		holidays[holiday.Format(wmata.DateLayout)] = true
+	}
+
+	return &Calculator{
+		railInfo: railInfo,
+		config:   config,
+		holidays: holidays,
+	}
+}
+
+// Load retrieves the fare between every pair of stations, replacing any cached fares
+func (calculator *Calculator) Load() error {
+	matrix, loadErr := stationmatrix.Load(calculator.railInfo)
+
+	if loadErr != nil {
+		return loadErr
+	}
+
+	calculator.SetMatrix(matrix)
+
+	return nil
+}
+
+// SetMatrix replaces the cached fares, such as with a matrix saved for offline use
+func (calculator *Calculator) SetMatrix(matrix *stationmatrix.Matrix) {
+	calculator.mutex.Lock()
+	calculator.matrix = matrix
+	calculator.mutex.Unlock()
+}
+
+// FareClass returns the fare class in effect at departure. Weekends and holidays are off-peak, or FareClassWeekend
+// when a weekend flat fare is configured. Departures after midnight are classed by the service day they belong to, so
+// late night trips are off-peak and a Saturday 1am trip is Friday night service. Departures are classed in Washington
+// time whatever their location
+func (calculator *Calculator) FareClass(departure time.Time) FareClass {
+	departure = departure.In(wmata.Location)
+	serviceDate := stationtimes.ServiceDate(departure)
+	weekday := serviceDate.Weekday()
+
+	if weekday == time.Saturday || weekday == time.Sunday || calculator.holidays[serviceDate.Format(wmata.DateLayout)] {
+		if calculator.config.WeekendFlatFare > 0 {
+			return FareClassWeekend
+		}
+
+		return FareClassOffPeak
+	}
+
+	// wall clock time, so windows keep their hours on the days clocks change
+	sinceMidnight := time.Duration(departure.Hour())*time.Hour + time.Duration(departure.Minute())*time.Minute +
+		time.Duration(departure.Second())*time.Second
+
+	if departure.Day() != serviceDate.Day() {
+		sinceMidnight += 24 * time.Hour
+	}
+
+	for _, window := range calculator.config.PeakWindows {
+		if sinceMidnight >= window.Start && sinceMidnight < window.End {
+			return FareClassPeak
+		}
+	}
+
+	return FareClassOffPeak
+}
+
+// RailFare returns the fare between two stations for a rider departing at the given time
+func (calculator *Calculator) RailFare(fromStation, toStation string, departure time.Time, rider RiderCategory) (float64, FareClass, error) {
+	if fromStation == "" || toStation == "" {
+		return 0, "", errors.New("fromStation and toStation are required parameters")
+	}
+
+	fares, lookupErr := calculator.lookup(fromStation, toStation)
+
+	if lookupErr != nil {
+		return 0, "", lookupErr
+	}
+
+	fareClass := calculator.FareClass(departure)
+
+	switch rider {
+	case RiderChild:
+		return 0, fareClass, nil
+	case RiderSeniorDisabled:
+		return fares.SeniorDisabled, fareClass, nil
+	}
+
+	switch fareClass {
+	case FareClassPeak:
+		return fares.PeakTime, fareClass, nil
+	case FareClassWeekend:
+		return math.Min(fares.OffPeakTime, calculator.config.WeekendFlatFare), fareClass, nil
+	default:
+		return fares.OffPeakTime, fareClass, nil
+	}
+}
+
+// BusFare returns the base bus fare for a rider
+func (calculator *Calculator) BusFare(rider RiderCategory) float64 {
+	switch rider {
+	case RiderChild:
+		return 0
+	case RiderSeniorDisabled:
+		return calculator.config.SeniorDisabledBusFare
+	default:
+		return calculator.config.BusFare
+	}
+}
+
+// JourneyFare prices each leg of a journey in order. Changing between bus and rail within the transfer window takes
+// the transfer discount off the later leg, and bus to bus transfers within the window are free
+func (calculator *Calculator) JourneyFare(legs []JourneyLeg, rider RiderCategory) (*JourneyFare, error) {
+	if len(legs) == 0 {
+		return nil, errors.New("at least one leg is required")
+	}
+
+	journey := JourneyFare{}
+
+	for i, leg := range legs {
+		legFare := LegFare{
+			JourneyLeg: leg,
+		}
+
+		switch leg.Mode {
+		case Rail:
+			fare, fareClass, fareErr := calculator.RailFare(leg.FromStation, leg.ToStation, leg.Departure, rider)
+
+			if fareErr != nil {
+				return nil, fareErr
+			}
+
+			legFare.BaseFare = fare
+			legFare.FareClass = fareClass
+		case Bus:
+			legFare.BaseFare = calculator.BusFare(rider)
+		default:
+			return nil, errors.New("invalid leg mode")
+		}
+
+		if i > 0 && leg.Departure.Sub(legs[i-1].Departure) <= calculator.config.TransferWindow {
+			legFare.Discount = calculator.transferDiscount(legs[i-1].Mode, leg.Mode, legFare.BaseFare, rider)
+		}
+
+		legFare.Fare = roundCents(legFare.BaseFare - legFare.Discount)
+		journey.Total = roundCents(journey.Total + legFare.Fare)
+		journey.Legs = append(journey.Legs, legFare)
+	}
+
+	return &journey, nil
+}
+
+// transferDiscount returns the discount for a transfer between two modes, never more than the leg's base fare
+func (calculator *Calculator) transferDiscount(previous, next Mode, baseFare float64, rider RiderCategory) float64 {
+	switch {
+	case previous == Bus && next == Bus:
+		return baseFare
+	case previous != next:
+		discount := calculator.config.TransferDiscount
+
+		if rider == RiderSeniorDisabled {
+			discount = calculator.config.SeniorTransferDiscount
+		}
+
+		return math.Min(discount, baseFare)
+	default:
+		return 0
+	}
+}
+
+// lookup returns the cached fares between two stations, loading the fare matrix on first use
+func (calculator *Calculator) lookup(fromStation, toStation string) (railinfo.RailFare, error) {
+	if loadErr := calculator.loadIfNeeded(); loadErr != nil {
+		return railinfo.RailFare{}, loadErr
+	}
+
+	calculator.mutex.RLock()
+	defer calculator.mutex.RUnlock()
+
+	if fromStation == toStation {
+		return railinfo.RailFare{}, nil
+	}
+
+	if fares, exist := calculator.matrix.Fare(fromStation, toStation); exist {
+		return fares, nil
+	}
+
+	return railinfo.RailFare{}, errors.New("no fare found between " + fromStation + " and " + toStation)
+}
+
+// loadIfNeeded loads the fare matrix unless it is cached. Concurrent calls wait for the first one instead of each
+// requesting the matrix
+func (calculator *Calculator) loadIfNeeded() error {
+	if calculator.loaded() {
+		return nil
+	}
+
+	calculator.loadMutex.Lock()
+	defer calculator.loadMutex.Unlock()
+
+	if calculator.loaded() {
+		return nil
+	}
+
+	return calculator.Load()
+}
+
+// loaded reports whether the fare matrix is cached
+func (calculator *Calculator) loaded() bool {
+	calculator.mutex.RLock()
+	defer calculator.mutex.RUnlock()
+
+	return calculator.matrix != nil
+}
+
+func roundCents(amount float64) float64 {
+	return math.Round(amount*100) / 100
+}
@@ -0,0 +1,320 @@
+package fare
+
+import (
+	"errors"
+	"github.com/awiede/wmata-go-sdk/wmata"
+	"github.com/awiede/wmata-go-sdk/wmata/railinfo"
+	"github.com/kr/pretty"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// testClient is a mock implementation of wmata.HTTPClient interface used for testing purposes
+type testClient struct {
+	requests int32
+}
+
+// ensure testClient implements wmata.HTTPClient interface
+var _ wmata.HTTPClient = (*testClient)(nil)
+
+// Do stubs out an httpClient.Do request
+func (client *testClient) Do(req *http.Request) (*http.Response, error) {
+	atomic.AddInt32(&client.requests, 1)
+
+	testResponses, exist := testData[req.URL.Path]
+
+	if !exist {
+		return nil, errors.New("no test data found")
+	}
+
+	for _, response := range testResponses {
+		if response.rawQuery == req.URL.RawQuery {
+			rr := httptest.NewRecorder()
+			rr.WriteHeader(http.StatusOK)
+			_, writeErr := rr.Write([]byte(response.response))
+
+			return rr.Result(), writeErr
+		}
+	}
+
+	return nil, errors.New("no data found")
+}
+
+type testResponseData struct {
+	rawQuery string
+	response string
+}
+
+var testData = map[string][]testResponseData{
+	"/Rail.svc/json/jSrcStationToDstStationInfo": {
+		{
+			rawQuery: "",
+			response: `{"StationToStationInfos":[` +
+				`{"CompositeMiles":13.98,"DestinationStation":"A15","RailFare":{"OffPeakTime":3.85,"PeakTime":6.0,"SeniorDisabled":1.9},"RailTime":31,"SourceStation":"A01"},` +
+				`{"CompositeMiles":2.17,"DestinationStation":"C05","RailFare":{"OffPeakTime":2.25,"PeakTime":2.25,"SeniorDisabled":1.1},"RailTime":6,"SourceStation":"A01"}` +
+				`]}`,
+		},
+	},
+}
+
+// setupTestCalculator creates a calculator backed by a rail service with a mock http client
+func setupTestCalculator(config Config) (*Calculator, *testClient) {
+	httpClient := testClient{}
+
+	wmataClient := wmata.Client{
+		HTTPClient: &httpClient,
+	}
+
+	return NewCalculator(railinfo.NewService(&wmataClient, wmata.JSON), config), &httpClient
+}
+
+func TestFareClass(t *testing.T) {
+	holiday := time.Date(2019, time.July, 4, 0, 0, 0, 0, wmata.Location)
+	calculator, _ := setupTestCalculator(Config{Holidays: []time.Time{holiday}})
+	weekendCalculator, _ := setupTestCalculator(Config{Holidays: []time.Time{holiday}, WeekendFlatFare: 2})
+
+	testRequests := []struct {
+		calculator *Calculator
+		departure  time.Time
+		expected   FareClass
+	}{
+		{
+			calculator: calculator,
+			departure:  time.Date(2019, time.July, 1, 5, 30, 0, 0, wmata.Location),
+			expected:   FareClassPeak,
+		},
+		{
+			calculator: calculator,
+			departure:  time.Date(2019, time.July, 1, 9, 30, 0, 0, wmata.Location),
+			expected:   FareClassOffPeak,
+		},
+		{
+			calculator: calculator,
+			departure:  time.Date(2019, time.July, 1, 18, 59, 0, 0, wmata.Location),
+			expected:   FareClassPeak,
+		},
+		{
+			calculator: calculator,
+			departure:  time.Date(2019, time.July, 1, 22, 0, 0, 0, wmata.Location),
+			expected:   FareClassOffPeak,
+		},
+		{
+			calculator: calculator,
+			departure:  time.Date(2019, time.July, 4, 8, 0, 0, 0, wmata.Location),
+			expected:   FareClassOffPeak,
+		},
+		{
+			calculator: calculator,
+			departure:  time.Date(2019, time.July, 6, 8, 0, 0, 0, wmata.Location),
+			expected:   FareClassOffPeak,
+		},
+		{
+			calculator: weekendCalculator,
+			departure:  time.Date(2019, time.July, 6, 8, 0, 0, 0, wmata.Location),
+			expected:   FareClassWeekend,
+		},
+		// late night trips after midnight belong to the previous service day
+		{
+			calculator: calculator,
+			departure:  time.Date(2019, time.July, 2, 0, 30, 0, 0, wmata.Location),
+			expected:   FareClassOffPeak,
+		},
+		{
+			calculator: calculator,
+			departure:  time.Date(2019, time.July, 2, 2, 30, 0, 0, wmata.Location),
+			expected:   FareClassOffPeak,
+		},
+		{
+			calculator: calculator,
+			departure:  time.Date(2019, time.July, 2, 4, 30, 0, 0, wmata.Location),
+			expected:   FareClassOffPeak,
+		},
+		{
+			calculator: calculator,
+			departure:  time.Date(2019, time.July, 2, 5, 0, 0, 0, wmata.Location),
+			expected:   FareClassPeak,
+		},
+		{
+			calculator: weekendCalculator,
+			departure:  time.Date(2019, time.July, 6, 1, 0, 0, 0, wmata.Location),
+			expected:   FareClassOffPeak,
+		},
+		{
+			calculator: weekendCalculator,
+			departure:  time.Date(2019, time.July, 8, 1, 0, 0, 0, wmata.Location),
+			expected:   FareClassWeekend,
+		},
+		{
+			calculator: weekendCalculator,
+			departure:  time.Date(2019, time.July, 5, 1, 0, 0, 0, wmata.Location),
+			expected:   FareClassWeekend,
+		},
+		{
+			calculator: weekendCalculator,
+			departure:  time.Date(2019, time.July, 5, 8, 0, 0, 0, wmata.Location),
+			expected:   FareClassPeak,
+		},
+	}
+
+	for _, request := range testRequests {
+		if fareClass := request.calculator.FareClass(request.departure); fareClass != request.expected {
+			t.Errorf("unexpected fare class at %s: expected %s, got %s", request.departure, request.expected, fareClass)
+		}
+	}
+}
+
+func TestRailFare(t *testing.T) {
+	calculator, httpClient := setupTestCalculator(Config{WeekendFlatFare: 2})
+	peak := time.Date(2019, time.July, 1, 8, 0, 0, 0, wmata.Location)
+	offPeak := time.Date(2019, time.July, 1, 12, 0, 0, 0, wmata.Location)
+	weekend := time.Date(2019, time.July, 6, 12, 0, 0, 0, wmata.Location)
+
+	testRequests := []struct {
+		from      string
+		to        string
+		departure time.Time
+		rider     RiderCategory
+		fare      float64
+		fareClass FareClass
+		err       error
+	}{
+		{from: "A01", to: "A15", departure: peak, rider: RiderRegular, fare: 6.0, fareClass: FareClassPeak},
+		{from: "A15", to: "A01", departure: offPeak, rider: RiderRegular, fare: 3.85, fareClass: FareClassOffPeak},
+		{from: "A01", to: "A15", departure: weekend, rider: RiderRegular, fare: 2, fareClass: FareClassWeekend},
+		{from: "A01", to: "A15", departure: peak, rider: RiderSeniorDisabled, fare: 1.9, fareClass: FareClassPeak},
+		{from: "A01", to: "A15", departure: peak, rider: RiderChild, fare: 0, fareClass: FareClassPeak},
+		{from: "A01", to: "K08", departure: peak, rider: RiderRegular, err: errors.New("no fare found between A01 and K08")},
+		{from: "", to: "A15", departure: peak, rider: RiderRegular, err: errors.New("fromStation and toStation are required parameters")},
+	}
+
+	for _, request := range testRequests {
+		fare, fareClass, err := calculator.RailFare(request.from, request.to, request.departure, request.rider)
+
+		if request.err != nil {
+			if err == nil || err.Error() != request.err.Error() {
+				t.Errorf("unexpected error from %s to %s: expected %v, got %v", request.from, request.to, request.err, err)
+			}
+
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("error calling RailFare from %s to %s: %s", request.from, request.to, err.Error())
+			continue
+		}
+
+		if fare != request.fare || fareClass != request.fareClass {
+			t.Errorf("unexpected fare from %s to %s: expected %.2f %s, got %.2f %s", request.from, request.to, request.fare, request.fareClass, fare, fareClass)
+		}
+	}
+
+	if httpClient.requests != 1 {
+		t.Errorf("expected fare matrix to be loaded with 1 request, got %d", httpClient.requests)
+	}
+}
+
+func TestFareClassUTC(t *testing.T) {
+	calculator, _ := setupTestCalculator(Config{})
+
+	testRequests := []struct {
+		departure time.Time
+		expected  FareClass
+	}{
+		// 03:59 and 04:00 on Tuesday in Washington, both late night service
+		{departure: time.Date(2019, time.July, 2, 7, 59, 0, 0, time.UTC), expected: FareClassOffPeak},
+		{departure: time.Date(2019, time.July, 2, 8, 0, 0, 0, time.UTC), expected: FareClassOffPeak},
+		// the morning peak from 05:00 to 09:30 in Washington
+		{departure: time.Date(2019, time.July, 2, 8, 59, 0, 0, time.UTC), expected: FareClassOffPeak},
+		{departure: time.Date(2019, time.July, 2, 9, 0, 0, 0, time.UTC), expected: FareClassPeak},
+		{departure: time.Date(2019, time.July, 2, 13, 29, 0, 0, time.UTC), expected: FareClassPeak},
+		{departure: time.Date(2019, time.July, 2, 13, 30, 0, 0, time.UTC), expected: FareClassOffPeak},
+		// 02:00 on Saturday in UTC is 22:00 on Friday in Washington, after the evening peak that ends at 19:00
+		{departure: time.Date(2019, time.July, 6, 2, 0, 0, 0, time.UTC), expected: FareClassOffPeak},
+		{departure: time.Date(2019, time.July, 5, 22, 59, 0, 0, time.UTC), expected: FareClassPeak},
+	}
+
+	for _, request := range testRequests {
+		if fareClass := calculator.FareClass(request.departure); fareClass != request.expected {
+			t.Errorf("unexpected fare class at %s: expected %s, got %s", request.departure, request.expected, fareClass)
+		}
+	}
+}
+
+func TestLookupConcurrent(t *testing.T) {
+	calculator, httpClient := setupTestCalculator(Config{})
+
+	var finished sync.WaitGroup
+
+	for i := 0; i < 5; i++ {
+		finished.Add(1)
+
+		go func() {
+			defer finished.Done()
+
+			if _, err := calculator.lookup("A01", "A15"); err != nil {
+				t.Errorf("error looking up fare: %s", err)
+			}
+		}()
+	}
+
+	finished.Wait()
+
+	if requests := atomic.LoadInt32(&httpClient.requests); requests != 1 {
+		t.Errorf("expected concurrent first lookups to share 1 request, got %d", requests)
+	}
+}
+
+func TestJourneyFare(t *testing.T) {
+	calculator, _ := setupTestCalculator(Config{})
+	start := time.Date(2019, time.July, 1, 7, 0, 0, 0, wmata.Location)
+
+	legs := []JourneyLeg{
+		{Mode: Bus, Departure: start},
+		{Mode: Rail, FromStation: "A01", ToStation: "A15", Departure: start.Add(20 * time.Minute)},
+		{Mode: Bus, Departure: start.Add(time.Hour)},
+		{Mode: Bus, Departure: start.Add(90 * time.Minute)},
+		{Mode: Rail, FromStation: "A01", ToStation: "C05", Departure: start.Add(4 * time.Hour)},
+	}
+
+	journey, err := calculator.JourneyFare(legs, RiderRegular)
+
+	if err != nil {
+		t.Errorf("error calling JourneyFare: %s", err.Error())
+		return
+	}
+
+	expected := &JourneyFare{
+		Legs: []LegFare{
+			{JourneyLeg: legs[0], BaseFare: 2, Fare: 2},
+			{JourneyLeg: legs[1], FareClass: FareClassPeak, BaseFare: 6, Discount: 2, Fare: 4},
+			{JourneyLeg: legs[2], BaseFare: 2, Discount: 2, Fare: 0},
+			{JourneyLeg: legs[3], BaseFare: 2, Discount: 2, Fare: 0},
+			{JourneyLeg: legs[4], FareClass: FareClassOffPeak, BaseFare: 2.25, Fare: 2.25},
+		},
+		Total: 8.25,
+	}
+
+	if !reflect.DeepEqual(journey, expected) {
+		t.Error(pretty.Diff(journey, expected))
+	}
+
+	senior, err := calculator.JourneyFare(legs[:2], RiderSeniorDisabled)
+
+	if err != nil {
+		t.Errorf("error calling JourneyFare: %s", err.Error())
+		return
+	}
+
+	if senior.Total != 1.9 || senior.Legs[1].Discount != 1 {
+		t.Errorf("unexpected senior fare: %# v", pretty.Formatter(senior))
+	}
+
+	if _, err := calculator.JourneyFare(nil, RiderRegular); err == nil {
+		t.Error("expected error pricing a journey without legs")
+	}
+}
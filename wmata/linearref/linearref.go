@@ -0,0 +1,238 @@
+package linearref
+
+import (
+	"errors"
+	"github.com/awiede/wmata-go-sdk/wmata/businfo"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// earthRadius is the mean radius of the earth in meters
+const earthRadius = 6371000.0
+
+// Shape is a route direction prepared for linear referencing. All distances are in meters
+type Shape struct {
+	points     []businfo.ShapePoint
+	cumulative []float64
+	stops      []stopLocation
+}
+
+// stopLocation is a stop with its distance along the shape
+type stopLocation struct {
+	stop  businfo.Stop
+	along float64
+}
+
+// Snapped is a coordinate snapped onto a shape
+type Snapped struct {
+	// Latitude and Longitude of the nearest point on the shape
+	Latitude  float64 `json:"Lat"`
+	Longitude float64 `json:"Lon"`
+	// Segment is the index of the shape segment snapped to, segment i runs from shape point i to i+1
+	Segment           int     `json:"Segment"`
+	DistanceTraveled  float64 `json:"DistanceTraveled"`
+	DistanceRemaining float64 `json:"DistanceRemaining"`
+	// DistanceFromShape between the original coordinate and the snapped point
+	DistanceFromShape float64 `json:"DistanceFromShape"`
+	// PreviousStop is the last stop at or before the snapped point, nil before the first stop
+	PreviousStop *businfo.Stop `json:"PreviousStop"`
+	// NextStop is the first stop after the snapped point, nil after the last stop
+	NextStop           *businfo.Stop `json:"NextStop"`
+	DistanceToNextStop float64       `json:"DistanceToNextStop"`
+}
+
+// NewShape orders the direction's shape points by sequence number and places each of its stops along the shape.
+// Stops of a direction without shape points are all placed at 0
+func NewShape(direction *businfo.Direction) *Shape {
+	points := make([]businfo.ShapePoint, len(direction.Shapes))
+	copy(points, direction.Shapes)
+
+	sort.SliceStable(points, func(i, j int) bool {
+		return points[i].SequenceNumber < points[j].SequenceNumber
+	})
+
+	cumulative := make([]float64, len(points))
+
+	for i := 1; i < len(points); i++ {
+		cumulative[i] = cumulative[i-1] + Distance(points[i-1].Latitude, points[i-1].Longitude, points[i].Latitude, points[i].Longitude)
+	}
+
+	shape := Shape{
+		points:     points,
+		cumulative: cumulative,
+	}
+
+	// stops are listed in travel order, so each stop is only matched at or after the previous one, which keeps stops
+	// on routes that double back on themselves in the right place
+	fromSegment := 0
+
+	for _, stop := range direction.Stops {
+		if len(points) == 0 {
+			shape.stops = append(shape.stops, stopLocation{stop: stop})
+			continue
+		}
+
+		projection := shape.project(stop.Latitude, stop.Longitude, fromSegment)
+
+		shape.stops = append(shape.stops, stopLocation{
+			stop:  stop,
+			along: projection.DistanceTraveled,
+		})
+
+		fromSegment = projection.Segment
+	}
+
+	return &shape
+}
+
+// Length returns the total length of the shape
+func (shape *Shape) Length() float64 {
+	if len(shape.cumulative) == 0 {
+		return 0
+	}
+
+	return shape.cumulative[len(shape.cumulative)-1]
+}
+
+// StopDistance returns the distance along the shape of the stop with the given ID
+func (shape *Shape) StopDistance(stopID string) (float64, bool) {
+	for _, location := range shape.stops {
+		if location.stop.StopID == stopID {
+			return location.along, true
+		}
+	}
+
+	return 0, false
+}
+
+// PointAt returns the coordinate at a distance along the shape, clamped to the start and end of the shape
+func (shape *Shape) PointAt(distance float64) (float64, float64) {
+	if len(shape.points) == 0 {
+		return 0, 0
+	}
+
+	// the first shape point at or beyond the distance ends the segment containing it
+	i := sort.SearchFloat64s(shape.cumulative, distance)
+
+	if i == 0 {
+		return shape.points[0].Latitude, shape.points[0].Longitude
+	}
+
+	if i == len(shape.points) {
+		last := shape.points[len(shape.points)-1]
+		return last.Latitude, last.Longitude
+	}
+
+	start, end := shape.points[i-1], shape.points[i]
+	fraction := (distance - shape.cumulative[i-1]) / (shape.cumulative[i] - shape.cumulative[i-1])
+
+	return start.Latitude + fraction*(end.Latitude-start.Latitude), start.Longitude + fraction*(end.Longitude-start.Longitude)
+}
+
+// Snap places a coordinate on the nearest segment of the shape and finds the stops either side of it
+func (shape *Shape) Snap(latitude, longitude float64) (*Snapped, error) {
+	if len(shape.points) == 0 {
+		return nil, errors.New("shape has no points")
+	}
+
+	snapped := shape.project(latitude, longitude, 0)
+
+	for _, location := range shape.stops {
+		stop := location.stop
+
+		if location.along <= snapped.DistanceTraveled {
+			snapped.PreviousStop = &stop
+			continue
+		}
+
+		snapped.NextStop = &stop
+		snapped.DistanceToNextStop = location.along - snapped.DistanceTraveled
+
+		break
+	}
+
+	return snapped, nil
+}
+
+// SnapPosition places a bus position on the shape
+func (shape *Shape) SnapPosition(position *businfo.BusPosition) (*Snapped, error) {
+	return shape.Snap(position.Latitude, position.Longitude)
+}
+
+// project finds the nearest point on the shape to a coordinate, only considering segments from fromSegment onward
+func (shape *Shape) project(latitude, longitude float64, fromSegment int) *Snapped {
+	best := Snapped{
+		Latitude:          shape.points[0].Latitude,
+		Longitude:         shape.points[0].Longitude,
+		DistanceFromShape: Distance(latitude, longitude, shape.points[0].Latitude, shape.points[0].Longitude),
+	}
+
+	// project onto each segment in a flat plane centered on the coordinate, which is accurate at route scale
+	scale := math.Cos(latitude * math.Pi / 180)
+
+	for i := fromSegment + 1; i < len(shape.points); i++ {
+		start, end := shape.points[i-1], shape.points[i]
+
+		ax, ay := (start.Longitude-longitude)*scale, start.Latitude-latitude
+		dx, dy := (end.Longitude-start.Longitude)*scale, end.Latitude-start.Latitude
+
+		fraction := 0.0
+
+		if lengthSquared := dx*dx + dy*dy; lengthSquared > 0 {
+			fraction = math.Max(0, math.Min(1, -(ax*dx+ay*dy)/lengthSquared))
+		}
+
+		snappedLatitude := start.Latitude + fraction*(end.Latitude-start.Latitude)
+		snappedLongitude := start.Longitude + fraction*(end.Longitude-start.Longitude)
+		offset := Distance(latitude, longitude, snappedLatitude, snappedLongitude)
+
+		if i == fromSegment+1 || offset < best.DistanceFromShape {
+			best = Snapped{
+				Latitude:          snappedLatitude,
+				Longitude:         snappedLongitude,
+				Segment:           i - 1,
+				DistanceTraveled:  shape.cumulative[i-1] + fraction*(shape.cumulative[i]-shape.cumulative[i-1]),
+				DistanceFromShape: offset,
+			}
+		}
+	}
+
+	best.DistanceRemaining = shape.Length() - best.DistanceTraveled
+
+	return &best
+}
+
+// FindDirection returns the direction of a route a bus position is traveling in, matched by DirectionText and
+// falling back to the deprecated direction number
+func FindDirection(route *businfo.GetRouteDetailsResponse, position *businfo.BusPosition) (*businfo.Direction, error) {
+	directions := []*businfo.Direction{&route.Direction0, &route.Direction1}
+
+	for _, direction := range directions {
+		if direction.DirectionText != "" && strings.EqualFold(strings.TrimSpace(direction.DirectionText), strings.TrimSpace(position.DirectionText)) {
+			return direction, nil
+		}
+	}
+
+	directionNumber := strconv.Itoa(position.DirectionNumber)
+
+	for _, direction := range directions {
+		if direction.DirectionNumber == directionNumber && len(direction.Shapes) > 0 {
+			return direction, nil
+		}
+	}
+
+	return nil, errors.New("no direction found for position")
+}
+
+// Distance returns the great circle distance in meters between two coordinates
+func Distance(latitude1, longitude1, latitude2, longitude2 float64) float64 {
+	phi1, phi2 := latitude1*math.Pi/180, latitude2*math.Pi/180
+	deltaPhi := (latitude2 - latitude1) * math.Pi / 180
+	deltaLambda := (longitude2 - longitude1) * math.Pi / 180
+
+	a := math.Sin(deltaPhi/2)*math.Sin(deltaPhi/2) + math.Cos(phi1)*math.Cos(phi2)*math.Sin(deltaLambda/2)*math.Sin(deltaLambda/2)
+
+	return 2 * earthRadius * math.Atan2(math.Sqrt(a), math.Sqrt(1-a))
+}
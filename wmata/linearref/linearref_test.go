@@ -0,0 +1,187 @@
+package linearref
+
+import (
+	"github.com/awiede/wmata-go-sdk/wmata/businfo"
+	"math"
+	"testing"
+)
+
+// testDirection runs east along a line of latitude and then doubles back west about 110 meters further north
+var testDirection = businfo.Direction{
+	DirectionNumber: "0",
+	DirectionText:   "LOOP",
+	Shapes: []businfo.ShapePoint{
+		{Latitude: 38.901, Longitude: -77.0, SequenceNumber: 3},
+		{Latitude: 38.9, Longitude: -77.1, SequenceNumber: 1},
+		{Latitude: 38.9, Longitude: -77.0, SequenceNumber: 2},
+		{Latitude: 38.901, Longitude: -77.1, SequenceNumber: 4},
+	},
+	Stops: []businfo.Stop{
+		{StopID: "1", Latitude: 38.9, Longitude: -77.08},
+		{StopID: "2", Latitude: 38.9, Longitude: -77.02},
+		{StopID: "3", Latitude: 38.901, Longitude: -77.02},
+		{StopID: "4", Latitude: 38.901, Longitude: -77.08},
+	},
+}
+
+func approximately(actual, expected, tolerance float64) bool {
+	return math.Abs(actual-expected) <= tolerance
+}
+
+func TestNewShape(t *testing.T) {
+	shape := NewShape(&testDirection)
+	leg := Distance(38.9, -77.1, 38.9, -77.0)
+	turn := Distance(38.9, -77.0, 38.901, -77.0)
+
+	if !approximately(shape.Length(), 2*leg+turn, 1) {
+		t.Errorf("unexpected shape length: %f", shape.Length())
+	}
+
+	expectedStops := map[string]float64{
+		"1": leg * 0.2,
+		"2": leg * 0.8,
+		"3": leg + turn + leg*0.2,
+		"4": leg + turn + leg*0.8,
+	}
+
+	for stopID, expected := range expectedStops {
+		actual, exist := shape.StopDistance(stopID)
+
+		if !exist || !approximately(actual, expected, 5) {
+			t.Errorf("unexpected distance for stop %s: %f", stopID, actual)
+		}
+	}
+
+	if _, exist := shape.StopDistance("5"); exist {
+		t.Error("unexpected distance for unknown stop")
+	}
+}
+
+func TestNewShapeWithoutPoints(t *testing.T) {
+	shape := NewShape(&businfo.Direction{Stops: []businfo.Stop{{StopID: "1"}, {StopID: "2"}}})
+
+	if shape.Length() != 0 {
+		t.Errorf("unexpected shape length: %f", shape.Length())
+	}
+
+	for _, stopID := range []string{"1", "2"} {
+		if actual, exist := shape.StopDistance(stopID); !exist || actual != 0 {
+			t.Errorf("unexpected distance for stop %s: %f", stopID, actual)
+		}
+	}
+
+	if _, snapErr := shape.Snap(38.9, -77.0); snapErr == nil {
+		t.Error("expected an error snapping onto a shape without points")
+	}
+}
+
+func TestSnap(t *testing.T) {
+	shape := NewShape(&testDirection)
+
+	snapped, err := shape.SnapPosition(&businfo.BusPosition{Latitude: 38.8998, Longitude: -77.05})
+
+	if err != nil {
+		t.Fatalf("error snapping position: %s", err)
+	}
+
+	if !approximately(snapped.Latitude, 38.9, 0.00001) || !approximately(snapped.Longitude, -77.05, 0.00001) {
+		t.Errorf("unexpected snapped point: %f, %f", snapped.Latitude, snapped.Longitude)
+	}
+
+	if snapped.Segment != 0 || !approximately(snapped.DistanceTraveled, 4331, 10) || !approximately(snapped.DistanceFromShape, 22, 1) {
+		t.Errorf("unexpected snap: %+v", snapped)
+	}
+
+	if !approximately(snapped.DistanceTraveled+snapped.DistanceRemaining, shape.Length(), 0.001) {
+		t.Errorf("distance traveled and remaining do not add up to the shape length: %+v", snapped)
+	}
+
+	if snapped.PreviousStop == nil || snapped.PreviousStop.StopID != "1" || snapped.NextStop == nil || snapped.NextStop.StopID != "2" {
+		t.Errorf("unexpected stops: %+v, %+v", snapped.PreviousStop, snapped.NextStop)
+	}
+
+	if !approximately(snapped.DistanceToNextStop, 2599, 10) {
+		t.Errorf("unexpected distance to next stop: %f", snapped.DistanceToNextStop)
+	}
+
+	beforeFirst, _ := shape.Snap(38.9, -77.2)
+
+	if beforeFirst.DistanceTraveled != 0 || beforeFirst.PreviousStop != nil || beforeFirst.NextStop.StopID != "1" {
+		t.Errorf("unexpected snap before the first stop: %+v", beforeFirst)
+	}
+
+	afterLast, _ := shape.Snap(38.9012, -77.09)
+
+	if afterLast.Segment != 2 || afterLast.PreviousStop.StopID != "4" || afterLast.NextStop != nil {
+		t.Errorf("unexpected snap after the last stop: %+v", afterLast)
+	}
+
+	if _, err := NewShape(&businfo.Direction{}).Snap(38.9, -77.0); err == nil {
+		t.Error("expected error snapping to an empty shape")
+	}
+}
+
+func TestPointAt(t *testing.T) {
+	shape := NewShape(&testDirection)
+	leg := Distance(38.9, -77.1, 38.9, -77.0)
+
+	testData := []struct {
+		distance  float64
+		latitude  float64
+		longitude float64
+	}{
+		{distance: -10, latitude: 38.9, longitude: -77.1},
+		{distance: leg / 2, latitude: 38.9, longitude: -77.05},
+		{distance: leg, latitude: 38.9, longitude: -77.0},
+		{distance: shape.Length() - leg/4, latitude: 38.901, longitude: -77.075},
+		{distance: shape.Length() + 10, latitude: 38.901, longitude: -77.1},
+	}
+
+	for _, test := range testData {
+		latitude, longitude := shape.PointAt(test.distance)
+
+		if !approximately(latitude, test.latitude, 0.00001) || !approximately(longitude, test.longitude, 0.00001) {
+			t.Errorf("unexpected point at %f: %f, %f", test.distance, latitude, longitude)
+		}
+
+		// points on the shape snap back to the distance they were placed at
+		if test.distance > 0 && test.distance < shape.Length() {
+			if snapped, _ := shape.Snap(latitude, longitude); !approximately(snapped.DistanceTraveled, test.distance, 1) {
+				t.Errorf("point at %f snapped to %f", test.distance, snapped.DistanceTraveled)
+			}
+		}
+	}
+
+	if latitude, longitude := NewShape(&businfo.Direction{}).PointAt(10); latitude != 0 || longitude != 0 {
+		t.Errorf("unexpected point on an empty shape: %f, %f", latitude, longitude)
+	}
+}
+
+func TestFindDirection(t *testing.T) {
+	route := businfo.GetRouteDetailsResponse{
+		Direction0: businfo.Direction{DirectionNumber: "0", DirectionText: "NORTH", Shapes: testDirection.Shapes},
+		Direction1: businfo.Direction{DirectionNumber: "1", DirectionText: "SOUTH", Shapes: testDirection.Shapes},
+	}
+
+	direction, err := FindDirection(&route, &businfo.BusPosition{DirectionText: "south"})
+
+	if err != nil || direction.DirectionText != "SOUTH" {
+		t.Errorf("unexpected direction by text: %v, %v", direction, err)
+	}
+
+	direction, err = FindDirection(&route, &businfo.BusPosition{DirectionNumber: 0})
+
+	if err != nil || direction.DirectionText != "NORTH" {
+		t.Errorf("unexpected direction by number: %v, %v", direction, err)
+	}
+
+	if _, err := FindDirection(&route, &businfo.BusPosition{DirectionText: "WEST", DirectionNumber: 3}); err == nil {
+		t.Error("expected error for unknown direction")
+	}
+}
+
+func TestDistance(t *testing.T) {
+	if actual := Distance(38.898303, -77.028099, 38.898303, -77.021917); !approximately(actual, 535, 5) {
+		t.Errorf("unexpected distance: %f", actual)
+	}
+}
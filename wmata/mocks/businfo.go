@@ -0,0 +1,119 @@
+package mocks
+
+import (
+	"errors"
+	"github.com/awiede/wmata-go-sdk/wmata/businfo"
+)
+
+// BusInfo is a fake businfo.BusInfo answering from its fields. Route details and schedules are keyed by route ID and
+// stop schedules by stop ID, requests for other routes and stops fail
+type BusInfo struct {
+	Recorder
+
+	Positions     []businfo.BusPosition
+	RouteDetails  map[string]*businfo.GetRouteDetailsResponse
+	Routes        []businfo.Route
+	Schedules     map[string]*businfo.GetScheduleResponse
+	StopSchedules map[string]*businfo.GetScheduleAtStopResponse
+	Stops         []businfo.Stop
+}
+
+var _ businfo.BusInfo = (*BusInfo)(nil)
+
+// GetPositions returns the Positions of a route, or of every route when the request has no route ID
+func (mock *BusInfo) GetPositions(request *businfo.GetPositionsRequest) (*businfo.GetPositionsResponse, error) {
+	if err := mock.called("GetPositions", request); err != nil {
+		return nil, err
+	}
+
+	response := businfo.GetPositionsResponse{}
+
+	for _, position := range mock.Positions {
+		if request == nil || request.RouteID == "" || position.RouteID == request.RouteID {
+			response.BusPositions = append(response.BusPositions, position)
+		}
+	}
+
+	return &response, nil
+}
+
+// GetRouteDetails returns the RouteDetails of a route, whatever the date
+func (mock *BusInfo) GetRouteDetails(routeID, date string) (*businfo.GetRouteDetailsResponse, error) {
+	if err := mock.called("GetRouteDetails", routeID, date); err != nil {
+		return nil, err
+	}
+
+	if routeID == "" {
+		return nil, errors.New("routeID is required")
+	}
+
+	details, exist := mock.RouteDetails[routeID]
+
+	if !exist {
+		return nil, errors.New("unknown route: " + routeID)
+	}
+
+	copied := *details
+
+	return &copied, nil
+}
+
+// GetRoutes returns Routes
+func (mock *BusInfo) GetRoutes() (*businfo.GetRoutesResponse, error) {
+	if err := mock.called("GetRoutes"); err != nil {
+		return nil, err
+	}
+
+	return &businfo.GetRoutesResponse{Routes: append([]businfo.Route(nil), mock.Routes...)}, nil
+}
+
+// GetSchedule returns the Schedules of a route, whatever the date and variations requested
+func (mock *BusInfo) GetSchedule(routeID, date string, includeVariations bool) (*businfo.GetScheduleResponse, error) {
+	if err := mock.called("GetSchedule", routeID, date, includeVariations); err != nil {
+		return nil, err
+	}
+
+	if routeID == "" {
+		return nil, errors.New("routeID is required")
+	}
+
+	schedule, exist := mock.Schedules[routeID]
+
+	if !exist {
+		return nil, errors.New("unknown route: " + routeID)
+	}
+
+	copied := *schedule
+
+	return &copied, nil
+}
+
+// GetScheduleAtStop returns the StopSchedules of a stop, whatever the date
+func (mock *BusInfo) GetScheduleAtStop(stopID, date string) (*businfo.GetScheduleAtStopResponse, error) {
+	if err := mock.called("GetScheduleAtStop", stopID, date); err != nil {
+		return nil, err
+	}
+
+	if stopID == "" {
+		return nil, errors.New("stopID is required")
+	}
+
+	schedule, exist := mock.StopSchedules[stopID]
+
+	if !exist {
+		return nil, errors.New("unknown stop: " + stopID)
+	}
+
+	copied := *schedule
+
+	return &copied, nil
+}
+
+// GetStops returns Stops, whatever the location requested
+func (mock *BusInfo) GetStops(request *businfo.GetStopsRequest) (*businfo.GetStopsResponse, error) {
+	if err := mock.called("GetStops", request); err != nil {
+		return nil, err
+	}
+
+	return &businfo.GetStopsResponse{Stops: append([]businfo.Stop(nil), mock.Stops...)}, nil
+}
@@ -0,0 +1,43 @@
+package mocks
+
+import (
+	"errors"
+	"github.com/awiede/wmata-go-sdk/wmata/buspredictions"
+)
+
+// BusPredictions is a fake buspredictions.BusPredictions answering from the predictions of each stop. Requests for
+// stops without predictions fail
+type BusPredictions struct {
+	Recorder
+
+	// NextBuses are the predictions keyed by stop ID
+	NextBuses map[string]*buspredictions.GetNextBusResponse
+}
+
+var _ buspredictions.BusPredictions = (*BusPredictions)(nil)
+
+// GetNextBuses returns the NextBuses of a stop
+func (mock *BusPredictions) GetNextBuses(stopID string) (*buspredictions.GetNextBusResponse, error) {
+	if err := mock.called("GetNextBuses", stopID); err != nil {
+		return nil, err
+	}
+
+	return mock.nextBuses(stopID)
+}
+
+func (mock *BusPredictions) nextBuses(stopID string) (*buspredictions.GetNextBusResponse, error) {
+	if stopID == "" {
+		return nil, errors.New("stopID is required")
+	}
+
+	predictions, exist := mock.NextBuses[stopID]
+
+	if !exist {
+		return nil, errors.New("unknown stop: " + stopID)
+	}
+
+	return &buspredictions.GetNextBusResponse{
+		StopName:           predictions.StopName,
+		NextBusPredictions: append([]buspredictions.NextBusPrediction(nil), predictions.NextBusPredictions...),
+	}, nil
+}
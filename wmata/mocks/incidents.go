@@ -0,0 +1,65 @@
+package mocks
+
+import (
+	"github.com/awiede/wmata-go-sdk/wmata/incidents"
+)
+
+// Incidents is a fake incidents.Incidents answering from its fields, filtered by route or station as the API does
+type Incidents struct {
+	Recorder
+
+	BusIncidents  []incidents.BusIncident
+	Outages       []incidents.ElevatorIncident
+	RailIncidents []incidents.RailIncident
+}
+
+var _ incidents.Incidents = (*Incidents)(nil)
+
+// GetBusIncidents returns the BusIncidents affecting a route, or every incident when route is empty
+func (mock *Incidents) GetBusIncidents(route string) (*incidents.GetBusIncidentsResponse, error) {
+	if err := mock.called("GetBusIncidents", route); err != nil {
+		return nil, err
+	}
+
+	response := incidents.GetBusIncidentsResponse{}
+
+	for _, incident := range mock.BusIncidents {
+		affected := route == ""
+
+		for _, routeAffected := range incident.RoutesAffected {
+			affected = affected || routeAffected == route
+		}
+
+		if affected {
+			response.BusIncidents = append(response.BusIncidents, incident)
+		}
+	}
+
+	return &response, nil
+}
+
+// GetOutages returns the Outages at a station, or at every station when stationCode is empty
+func (mock *Incidents) GetOutages(stationCode string) (*incidents.GetElevatorEscalatorOutagesResponse, error) {
+	if err := mock.called("GetOutages", stationCode); err != nil {
+		return nil, err
+	}
+
+	response := incidents.GetElevatorEscalatorOutagesResponse{}
+
+	for _, outage := range mock.Outages {
+		if stationCode == "" || outage.StationCode == stationCode {
+			response.ElevatorIncidents = append(response.ElevatorIncidents, outage)
+		}
+	}
+
+	return &response, nil
+}
+
+// GetRailIncidents returns RailIncidents
+func (mock *Incidents) GetRailIncidents() (*incidents.GetRailIncidentsResponse, error) {
+	if err := mock.called("GetRailIncidents"); err != nil {
+		return nil, err
+	}
+
+	return &incidents.GetRailIncidentsResponse{RailIncidents: append([]incidents.RailIncident(nil), mock.RailIncidents...)}, nil
+}
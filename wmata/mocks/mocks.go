@@ -0,0 +1,102 @@
+package mocks
+
+import (
+	"sync"
+)
+
+// Call is a method call received by a mock
+type Call struct {
+	// Method is the name of the interface method, e.g. "GetNextTrains"
+	Method string
+	Args   []interface{}
+}
+
+// Recorder records the calls made to a mock and returns the errors injected for them. It is embedded in every mock,
+// and its zero value is ready to use
+type Recorder struct {
+	mutex  sync.Mutex
+	calls  []Call
+	next   map[string][]error
+	always map[string]error
+}
+
+// Calls returns the calls made to method in the order they were made, or every call when method is empty
+func (recorder *Recorder) Calls(method string) []Call {
+	recorder.mutex.Lock()
+	defer recorder.mutex.Unlock()
+
+	var calls []Call
+
+	for _, call := range recorder.calls {
+		if method == "" || call.Method == method {
+			calls = append(calls, call)
+		}
+	}
+
+	return calls
+}
+
+// CallCount returns the number of calls made to method, or to every method when method is empty
+func (recorder *Recorder) CallCount(method string) int {
+	return len(recorder.Calls(method))
+}
+
+// FailNext scripts the results of the next calls to method: each call takes the next error and fails with it, or
+// succeeds when it is nil. Once the errors are used up calls behave as before
+func (recorder *Recorder) FailNext(method string, errs ...error) {
+	recorder.mutex.Lock()
+	defer recorder.mutex.Unlock()
+
+	if recorder.next == nil {
+		recorder.next = make(map[string][]error)
+	}
+
+	recorder.next[method] = append(recorder.next[method], errs...)
+}
+
+// Fail makes every call to method fail with err, or every call to any method when method is empty. A nil err stops
+// the failures. Errors scripted with FailNext take precedence
+func (recorder *Recorder) Fail(method string, err error) {
+	recorder.mutex.Lock()
+	defer recorder.mutex.Unlock()
+
+	if recorder.always == nil {
+		recorder.always = make(map[string]error)
+	}
+
+	if err == nil {
+		delete(recorder.always, method)
+	} else {
+		recorder.always[method] = err
+	}
+}
+
+// Reset forgets the recorded calls and injected errors
+func (recorder *Recorder) Reset() {
+	recorder.mutex.Lock()
+	defer recorder.mutex.Unlock()
+
+	recorder.calls = nil
+	recorder.next = nil
+	recorder.always = nil
+}
+
+// called records a call and returns the error injected for it
+func (recorder *Recorder) called(method string, args ...interface{}) error {
+	recorder.mutex.Lock()
+	defer recorder.mutex.Unlock()
+
+	recorder.calls = append(recorder.calls, Call{Method: method, Args: args})
+
+	if next := recorder.next[method]; len(next) > 0 {
+		recorder.next[method] = next[1:]
+
+		return next[0]
+	}
+
+	if err, exist := recorder.always[method]; exist {
+		return err
+	}
+
+	return recorder.always[""]
+}
@@ -0,0 +1,245 @@
+package mocks
+
+import (
+	"errors"
+	"github.com/awiede/wmata-go-sdk/wmata/businfo"
+	"github.com/awiede/wmata-go-sdk/wmata/buspredictions"
+	"github.com/awiede/wmata-go-sdk/wmata/railboard"
+	"github.com/awiede/wmata-go-sdk/wmata/railinfo"
+	"github.com/awiede/wmata-go-sdk/wmata/railpredictions"
+	"github.com/awiede/wmata-go-sdk/wmata/stopboard"
+	"github.com/kr/pretty"
+	"reflect"
+	"testing"
+)
+
+func TestRecorder(t *testing.T) {
+	mock := &RailPredictions{Trains: []railpredictions.Train{
+		{Line: "RD", LocationCode: "A01", Minutes: "3"},
+		{Line: "RD", LocationCode: "A02", Minutes: "5"},
+	}}
+
+	unavailable := errors.New("service unavailable")
+	timeout := errors.New("timeout")
+
+	mock.FailNext("GetNextTrains", unavailable, nil, timeout)
+
+	expectedErrors := []error{unavailable, nil, timeout, nil}
+
+	for i, expected := range expectedErrors {
+		_, responseErr := mock.GetNextTrains([]string{"A01"})
+
+		if responseErr != expected {
+			t.Errorf("call %d: expected error %v, got %v", i+1, expected, responseErr)
+		}
+	}
+
+	calls := mock.Calls("GetNextTrains")
+
+	if len(calls) != len(expectedErrors) || mock.CallCount("") != len(expectedErrors) {
+		t.Fatalf("expected %d calls, got %d", len(expectedErrors), len(calls))
+	}
+
+	if !reflect.DeepEqual(calls[0], Call{Method: "GetNextTrains", Args: []interface{}{[]string{"A01"}}}) {
+		t.Errorf("unexpected call: %# v", pretty.Formatter(calls[0]))
+	}
+
+	mock.Fail("", unavailable)
+
+	if _, responseErr := mock.GetNextTrains(nil); responseErr != unavailable {
+		t.Errorf("expected every call to fail, got %v", responseErr)
+	}
+
+	mock.Fail("", nil)
+	mock.Fail("GetNextTrains", timeout)
+
+	if _, responseErr := mock.GetNextTrains(nil); responseErr != timeout {
+		t.Errorf("expected calls to the method to fail, got %v", responseErr)
+	}
+
+	mock.Reset()
+
+	response, responseErr := mock.GetNextTrains(nil)
+
+	if responseErr != nil || len(response.Trains) != 2 {
+		t.Errorf("unexpected response after reset: %# v %v", pretty.Formatter(response), responseErr)
+	}
+
+	if mock.CallCount("GetNextTrains") != 1 {
+		t.Errorf("expected reset to forget calls, got %d", mock.CallCount("GetNextTrains"))
+	}
+}
+
+func TestNormalService(t *testing.T) {
+	scenario := NormalService()
+
+	stations, stationsErr := scenario.RailInfo.GetStationList("OR")
+
+	if stationsErr != nil || len(stations.Stations) != 2 {
+		t.Errorf("unexpected orange line stations: %# v %v", pretty.Formatter(stations), stationsErr)
+	}
+
+	information, informationErr := scenario.RailInfo.GetStationInformation("B01")
+
+	if informationErr != nil || information.Name != "Gallery Pl-Chinatown" || information.StationTogether1 != "F01" {
+		t.Errorf("unexpected station information: %# v %v", pretty.Formatter(information), informationErr)
+	}
+
+	if _, unknownErr := scenario.RailInfo.GetStationInformation("Z99"); unknownErr == nil {
+		t.Error("expected an error for an unknown station")
+	}
+
+	path, pathErr := scenario.RailInfo.GetPathBetweenStations("B01", "A02")
+
+	if pathErr != nil || len(path.Path) != 3 || path.Path[2].StationCode != "A02" || path.Path[2].DistanceToPreviousStation != 4408 {
+		t.Errorf("unexpected path: %# v %v", pretty.Formatter(path), pathErr)
+	}
+
+	fares, faresErr := scenario.RailInfo.GetStationToStationInformation("A01", "")
+
+	if faresErr != nil || len(fares.StationToStationInformation) != 4 {
+		t.Errorf("unexpected station to station information: %# v %v", pretty.Formatter(fares), faresErr)
+	}
+
+	positions, positionsErr := scenario.BusInfo.GetPositions(&businfo.GetPositionsRequest{RouteID: "S2"})
+
+	if positionsErr != nil || len(positions.BusPositions) != 1 || positions.BusPositions[0].VehicleID != "6017" {
+		t.Errorf("unexpected bus positions: %# v %v", pretty.Formatter(positions), positionsErr)
+	}
+
+	if _, routeErr := scenario.BusInfo.GetRouteDetails("S2", ""); routeErr == nil {
+		t.Error("expected an error for a route without details")
+	}
+
+	batch, batchErr := buspredictions.GetNextBusesForStops(scenario.BusPredictions, []string{"1001195", "1003043", "9999999"}, 0)
+
+	if batchErr != nil {
+		t.Fatal(batchErr)
+	}
+
+	if len(batch.Predictions["1001195"].NextBusPredictions) != 3 || len(batch.Predictions["1003043"].NextBusPredictions) != 0 || batch.Errors["9999999"] == nil {
+		t.Errorf("unexpected batch: %# v", pretty.Formatter(batch))
+	}
+
+	railIncidents, railIncidentsErr := scenario.Incidents.GetRailIncidents()
+
+	if railIncidentsErr != nil || len(railIncidents.RailIncidents) != 0 {
+		t.Errorf("expected no rail incidents: %# v %v", pretty.Formatter(railIncidents), railIncidentsErr)
+	}
+
+	// responses are copies, so callers can not change the scenario
+	lines, _ := scenario.RailInfo.GetLines()
+	lines.Lines[0].DisplayName = "Changed"
+
+	if scenario.RailInfo.Lines[0].DisplayName != "Blue" {
+		t.Error("expected responses not to share the scenario's data")
+	}
+}
+
+func TestRedLineRushHourDisruption(t *testing.T) {
+	scenario := RedLineRushHourDisruption().Outage("A01", "ESCALATOR", RushHour)
+
+	railIncidents, _ := scenario.Incidents.GetRailIncidents()
+
+	if len(railIncidents.RailIncidents) != 1 || railIncidents.RailIncidents[0].LinesAffected != "RD;" || railIncidents.RailIncidents[0].DateUpdated != "2019-04-29T08:15:00" {
+		t.Errorf("unexpected rail incidents: %# v", pretty.Formatter(railIncidents))
+	}
+
+	outages, _ := scenario.Incidents.GetOutages("A01")
+
+	if len(outages.ElevatorIncidents) != 1 || outages.ElevatorIncidents[0].UnitName != "A01E01" || outages.ElevatorIncidents[0].StationName != "Metro Center" {
+		t.Errorf("unexpected outages: %# v", pretty.Formatter(outages))
+	}
+
+	// application code runs against the scenario without any HTTP
+	board, boardErr := railboard.NewService(scenario.RailInfo, scenario.RailPredictions).GetBoard("A01")
+
+	if boardErr != nil {
+		t.Fatal(boardErr)
+	}
+
+	var minutes []string
+
+	for _, platform := range board.Platforms {
+		for _, arrival := range platform.Arrivals() {
+			minutes = append(minutes, arrival.Line+" "+arrival.Minutes)
+		}
+	}
+
+	expected := []string{"RD 15", "RD 24", "RD 19", "BL 6", "OR BRD"}
+
+	if !reflect.DeepEqual(minutes, expected) {
+		t.Errorf("unexpected board: %v", pretty.Diff(minutes, expected))
+	}
+
+	if scenario.RailPredictions.CallCount("GetNextTrains") != 1 {
+		t.Errorf("expected one prediction request, got %d", scenario.RailPredictions.CallCount("GetNextTrains"))
+	}
+}
+
+func TestBusDelay(t *testing.T) {
+	scenario := NormalService().BusDelay("70", "Buses delayed due to a collision on Georgia Ave.", 10, RushHour)
+
+	board, boardErr := stopboard.NewService(scenario.BusInfo, scenario.BusPredictions).GetStopBoard(&stopboard.GetStopBoardRequest{StopID: "1001195"})
+
+	if boardErr != nil {
+		t.Fatal(boardErr)
+	}
+
+	minutes := make(map[string]int)
+
+	for _, arrival := range board.Arrivals {
+		if arrival.Realtime {
+			minutes[arrival.TripID] = arrival.Minutes
+		}
+	}
+
+	expected := map[string]int{"7003": 13, "7905": 7, "7005": 25}
+
+	if !reflect.DeepEqual(minutes, expected) {
+		t.Errorf("unexpected arrivals: %v", pretty.Diff(minutes, expected))
+	}
+
+	busIncidents, _ := scenario.Incidents.GetBusIncidents("79")
+
+	if len(busIncidents.BusIncidents) != 0 {
+		t.Errorf("expected no incidents on route 79: %# v", pretty.Formatter(busIncidents))
+	}
+
+	positions, _ := scenario.BusInfo.GetPositions(&businfo.GetPositionsRequest{RouteID: "70"})
+
+	if positions.BusPositions[0].Deviation != 11 || positions.BusPositions[1].Deviation != 8 {
+		t.Errorf("unexpected deviations: %# v", pretty.Formatter(positions))
+	}
+}
+
+func TestNoServiceAndUnavailable(t *testing.T) {
+	scenario := NormalService().NoService()
+
+	trains, _ := scenario.RailPredictions.GetNextTrains(nil)
+	positions, _ := scenario.TrainPositions.GetLiveTrainPositions()
+	buses, _ := scenario.BusPredictions.GetNextBuses("1001195")
+
+	if len(trains.Trains) != 0 || len(positions.Positions) != 0 || len(buses.NextBusPredictions) != 0 || buses.StopName == "" {
+		t.Errorf("expected no service: %# v %# v %# v", pretty.Formatter(trains), pretty.Formatter(positions), pretty.Formatter(buses))
+	}
+
+	down := errors.New("503 service unavailable")
+	scenario.Unavailable(down)
+
+	if _, linesErr := scenario.RailInfo.GetLines(); linesErr != down {
+		t.Errorf("expected rail info to be unavailable, got %v", linesErr)
+	}
+
+	if _, routesErr := scenario.BusInfo.GetRoutes(); routesErr != down {
+		t.Errorf("expected bus info to be unavailable, got %v", routesErr)
+	}
+
+	scenario.Reset()
+
+	if _, linesErr := scenario.RailInfo.GetLines(); linesErr != nil {
+		t.Errorf("expected reset to restore service, got %v", linesErr)
+	}
+
+	var _ railinfo.RailInfo = scenario.RailInfo
+}
@@ -0,0 +1,160 @@
+package mocks
+
+import (
+	"errors"
+	"github.com/awiede/wmata-go-sdk/wmata/railinfo"
+)
+
+// StationPair is the pair of stations of a path
+type StationPair struct {
+	From string
+	To   string
+}
+
+// RailInfo is a fake railinfo.RailInfo answering from its fields. Stations, parking, station times and station to
+// station information are filtered by the request the same way as the API
+type RailInfo struct {
+	Recorder
+
+	Lines     []railinfo.LineResponse
+	Stations  []railinfo.GetStationListResponseItem
+	Parking   []railinfo.StationParking
+	Entrances []railinfo.StationEntrance
+	// Paths are the paths between pairs of stations, a pair without a path returns an empty path
+	Paths            map[StationPair][]railinfo.PathItem
+	StationTimes     []railinfo.StationTime
+	StationToStation []railinfo.StationToStation
+}
+
+var _ railinfo.RailInfo = (*RailInfo)(nil)
+
+// GetLines returns Lines
+func (mock *RailInfo) GetLines() (*railinfo.GetLinesResponse, error) {
+	if err := mock.called("GetLines"); err != nil {
+		return nil, err
+	}
+
+	return &railinfo.GetLinesResponse{Lines: append([]railinfo.LineResponse(nil), mock.Lines...)}, nil
+}
+
+// GetParkingInformation returns the Parking at a station, or at every station when stationCode is empty
+func (mock *RailInfo) GetParkingInformation(stationCode string) (*railinfo.GetParkingInformationResponse, error) {
+	if err := mock.called("GetParkingInformation", stationCode); err != nil {
+		return nil, err
+	}
+
+	response := railinfo.GetParkingInformationResponse{}
+
+	for _, parking := range mock.Parking {
+		if stationCode == "" || parking.StationCode == stationCode {
+			response.ParkingInformation = append(response.ParkingInformation, parking)
+		}
+	}
+
+	return &response, nil
+}
+
+// GetPathBetweenStations returns the path in Paths from one station to another
+func (mock *RailInfo) GetPathBetweenStations(fromStation, toStation string) (*railinfo.GetPathBetweenStationsResponse, error) {
+	if err := mock.called("GetPathBetweenStations", fromStation, toStation); err != nil {
+		return nil, err
+	}
+
+	if fromStation == "" || toStation == "" {
+		return nil, errors.New("fromStation and toStation are required parameters")
+	}
+
+	path := mock.Paths[StationPair{From: fromStation, To: toStation}]
+
+	return &railinfo.GetPathBetweenStationsResponse{Path: append([]railinfo.PathItem(nil), path...)}, nil
+}
+
+// GetStationEntrances returns Entrances, whatever the location requested
+func (mock *RailInfo) GetStationEntrances(getStationEntranceRequest *railinfo.GetStationEntrancesRequest) (*railinfo.GetStationEntrancesResponse, error) {
+	if err := mock.called("GetStationEntrances", getStationEntranceRequest); err != nil {
+		return nil, err
+	}
+
+	return &railinfo.GetStationEntrancesResponse{Entrances: append([]railinfo.StationEntrance(nil), mock.Entrances...)}, nil
+}
+
+// GetStationInformation returns the station in Stations with the station code, or an error when there is none
+func (mock *RailInfo) GetStationInformation(stationCode string) (*railinfo.GetStationInformationResponse, error) {
+	if err := mock.called("GetStationInformation", stationCode); err != nil {
+		return nil, err
+	}
+
+	if stationCode == "" {
+		return nil, errors.New("stationCode is a required parameter")
+	}
+
+	for _, station := range mock.Stations {
+		if station.StationCode == stationCode {
+			return &railinfo.GetStationInformationResponse{
+				Address:          station.Address,
+				Latitude:         station.Latitude,
+				LineCode1:        station.LineCode1,
+				LineCode2:        station.LineCode2,
+				Longitude:        station.Longitude,
+				Name:             station.Name,
+				StationCode:      station.StationCode,
+				StationTogether1: station.StationTogether1,
+				StationTogether2: station.StationTogether2,
+			}, nil
+		}
+	}
+
+	return nil, errors.New("unknown station code: " + stationCode)
+}
+
+// GetStationList returns the Stations on a line, or every station when lineCode is empty
+func (mock *RailInfo) GetStationList(lineCode string) (*railinfo.GetStationListResponse, error) {
+	if err := mock.called("GetStationList", lineCode); err != nil {
+		return nil, err
+	}
+
+	response := railinfo.GetStationListResponse{}
+
+	for _, station := range mock.Stations {
+		if lineCode == "" || station.LineCode1 == lineCode || station.LineCode2 == lineCode || station.LineCode3 == lineCode || station.LineCode4 == lineCode {
+			response.Stations = append(response.Stations, station)
+		}
+	}
+
+	return &response, nil
+}
+
+// GetStationTimings returns the StationTimes of a station, or of every station when stationCode is empty
+func (mock *RailInfo) GetStationTimings(stationCode string) (*railinfo.GetStationTimingsResponse, error) {
+	if err := mock.called("GetStationTimings", stationCode); err != nil {
+		return nil, err
+	}
+
+	response := railinfo.GetStationTimingsResponse{}
+
+	for _, times := range mock.StationTimes {
+		if stationCode == "" || times.StationCode == stationCode {
+			response.StationTimes = append(response.StationTimes, times)
+		}
+	}
+
+	return &response, nil
+}
+
+// GetStationToStationInformation returns the StationToStation information matching the stations, an empty station
+// matches any station
+func (mock *RailInfo) GetStationToStationInformation(fromStation, toStation string) (*railinfo.GetStationToStationInformationResponse, error) {
+	if err := mock.called("GetStationToStationInformation", fromStation, toStation); err != nil {
+		return nil, err
+	}
+
+	response := railinfo.GetStationToStationInformationResponse{}
+
+	for _, info := range mock.StationToStation {
+		if (fromStation == "" || info.SourceStation == fromStation) && (toStation == "" || info.DestinationStation == toStation) {
+			response.StationToStationInformation = append(response.StationToStationInformation, info)
+		}
+	}
+
+	return &response, nil
+}
@@ -0,0 +1,37 @@
+package mocks
+
+import (
+	"github.com/awiede/wmata-go-sdk/wmata/railpredictions"
+)
+
+// RailPredictions is a fake railpredictions.RailPredictions answering from Trains, filtered by station as the API does
+type RailPredictions struct {
+	Recorder
+
+	Trains []railpredictions.Train
+}
+
+var _ railpredictions.RailPredictions = (*RailPredictions)(nil)
+
+// GetNextTrains returns the Trains at the stations, or every train when no station codes are passed
+func (mock *RailPredictions) GetNextTrains(stationCodes []string) (*railpredictions.GetNextTrainResponse, error) {
+	if err := mock.called("GetNextTrains", append([]string(nil), stationCodes...)); err != nil {
+		return nil, err
+	}
+
+	requested := make(map[string]bool)
+
+	for _, stationCode := range stationCodes {
+		requested[stationCode] = true
+	}
+
+	response := railpredictions.GetNextTrainResponse{}
+
+	for _, train := range mock.Trains {
+		if len(requested) == 0 || requested[train.LocationCode] {
+			response.Trains = append(response.Trains, train)
+		}
+	}
+
+	return &response, nil
+}
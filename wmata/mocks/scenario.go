@@ -0,0 +1,232 @@
+package mocks
+
+import (
+	"fmt"
+	"github.com/awiede/wmata-go-sdk/wmata"
+	"github.com/awiede/wmata-go-sdk/wmata/businfo"
+	"github.com/awiede/wmata-go-sdk/wmata/buspredictions"
+	"github.com/awiede/wmata-go-sdk/wmata/incidents"
+	"github.com/awiede/wmata-go-sdk/wmata/railinfo"
+	"github.com/awiede/wmata-go-sdk/wmata/wmatatest"
+	"strconv"
+	"time"
+)
+
+// RushHour is the morning rush hour on the day of the wmatatest fixtures, a Monday
+var RushHour = time.Date(2019, time.April, 29, 8, 15, 0, 0, time.UTC)
+
+// Scenario holds a mock of every service, describing one state of the network. Builder methods change the state and
+// return the scenario so they can be chained, e.g. NormalService().Disruption(...).Outage(...)
+type Scenario struct {
+	BusInfo         *BusInfo
+	BusPredictions  *BusPredictions
+	Incidents       *Incidents
+	RailInfo        *RailInfo
+	RailPredictions *RailPredictions
+	TrainPositions  *TrainPositions
+}
+
+// NormalService returns a scenario with the reference data, trains and buses of the wmatatest fixtures and no
+// incidents
+func NormalService() *Scenario {
+	nextBuses := make(map[string]*buspredictions.GetNextBusResponse)
+
+	for _, stop := range wmatatest.BusStops().Stops {
+		nextBuses[stop.StopID] = &buspredictions.GetNextBusResponse{StopName: stop.Name}
+	}
+
+	nextBuses["1001195"] = wmatatest.NextBuses()
+
+	stopSchedule := wmatatest.BusStopSchedule()
+	routeDetails := wmatatest.BusRouteDetails()
+
+	return &Scenario{
+		BusInfo: &BusInfo{
+			Positions:     wmatatest.BusPositions().BusPositions,
+			RouteDetails:  map[string]*businfo.GetRouteDetailsResponse{routeDetails.RouteID: routeDetails},
+			Routes:        wmatatest.BusRoutes().Routes,
+			Schedules:     map[string]*businfo.GetScheduleResponse{routeDetails.RouteID: wmatatest.BusSchedule()},
+			StopSchedules: map[string]*businfo.GetScheduleAtStopResponse{stopSchedule.StopInfo.StopID: stopSchedule},
+			Stops:         wmatatest.BusStops().Stops,
+		},
+		BusPredictions: &BusPredictions{NextBuses: nextBuses},
+		Incidents:      &Incidents{},
+		RailInfo: &RailInfo{
+			Lines:            wmatatest.Lines().Lines,
+			Stations:         wmatatest.Stations().Stations,
+			Parking:          wmatatest.StationParking().ParkingInformation,
+			Entrances:        wmatatest.StationEntrances().Entrances,
+			Paths:            paths(),
+			StationTimes:     wmatatest.StationTimes().StationTimes,
+			StationToStation: wmatatest.StationToStation().StationToStationInformation,
+		},
+		RailPredictions: &RailPredictions{Trains: wmatatest.NextTrains().Trains},
+		TrainPositions: &TrainPositions{
+			Positions:      wmatatest.LiveTrainPositions().Positions,
+			StandardRoutes: wmatatest.StandardRoutes().Routes,
+			TrackCircuits:  wmatatest.TrackCircuits().TrackCircuits,
+		},
+	}
+}
+
+// RedLineRushHourDisruption returns a scenario with Red Line trains running 15 minutes late at RushHour
+func RedLineRushHourDisruption() *Scenario {
+	return NormalService().Disruption(
+		wmata.LineCodeRed,
+		"Red Line: Trains single tracking between Dupont Circle and Gallery Pl due to a disabled train at Farragut North. Expect delays in both directions.",
+		15,
+		RushHour,
+	)
+}
+
+// Disruption adds a delay incident on a line updated at the given time, and delays the line's predictions by delay
+// minutes. Trains on the line are held at their circuits for the length of the delay
+func (scenario *Scenario) Disruption(lineCode, description string, delay int, at time.Time) *Scenario {
+	scenario.Incidents.RailIncidents = append(scenario.Incidents.RailIncidents, incidents.RailIncident{
+		IncidentID:    scenario.incidentID(),
+		IncidentType:  "Delay",
+		Description:   description,
+		LinesAffected: lineCode + ";",
+		DateUpdated:   at.Format(wmata.DateTimeLayout),
+	})
+
+	for i, train := range scenario.RailPredictions.Trains {
+		if train.Line != lineCode {
+			continue
+		}
+
+		if minutes, known := train.ParseMinutes(); known {
+			scenario.RailPredictions.Trains[i].Minutes = strconv.Itoa(minutes + delay)
+		}
+	}
+
+	for i, position := range scenario.TrainPositions.Positions {
+		if position.LineCode == lineCode {
+			scenario.TrainPositions.Positions[i].SecondsAtLocation += delay * 60
+		}
+	}
+
+	return scenario
+}
+
+// Outage adds an elevator or escalator outage at a station, out of service since the given time
+func (scenario *Scenario) Outage(stationCode, unitType string, at time.Time) *Scenario {
+	stationName := stationCode
+
+	for _, station := range scenario.RailInfo.Stations {
+		if station.StationCode == stationCode {
+			stationName = station.Name
+		}
+	}
+
+	// unit names are the station code, the first letter of the unit type and a number, e.g. "A01E02"
+	unitName := fmt.Sprintf("%s%.1s%02d", stationCode, unitType, len(scenario.Incidents.Outages)+1)
+
+	scenario.Incidents.Outages = append(scenario.Incidents.Outages, incidents.ElevatorIncident{
+		UnitName:            unitName,
+		UnitType:            unitType,
+		StationCode:         stationCode,
+		StationName:         stationName,
+		LocationDescription: "Between street and mezzanine",
+		SymptomDescription:  "Service Call",
+		DateOutOfService:    at.Format(wmata.DateTimeLayout),
+		DateUpdated:         at.Format(wmata.DateTimeLayout),
+	})
+
+	return scenario
+}
+
+// BusDelay adds a delay incident on a bus route updated at the given time, and makes the route's buses delay minutes
+// later in predictions and behind schedule in positions
+func (scenario *Scenario) BusDelay(routeID, description string, delay int, at time.Time) *Scenario {
+	scenario.Incidents.BusIncidents = append(scenario.Incidents.BusIncidents, incidents.BusIncident{
+		IncidentID:     scenario.incidentID(),
+		IncidentType:   "Delay",
+		Description:    description,
+		RoutesAffected: []string{routeID},
+		DateUpdated:    at.Format(wmata.DateTimeLayout),
+	})
+
+	for stopID, predictions := range scenario.BusPredictions.NextBuses {
+		delayed := buspredictions.GetNextBusResponse{StopName: predictions.StopName}
+
+		for _, prediction := range predictions.NextBusPredictions {
+			if prediction.RouteID == routeID {
+				prediction.Minutes += delay
+			}
+
+			delayed.NextBusPredictions = append(delayed.NextBusPredictions, prediction)
+		}
+
+		scenario.BusPredictions.NextBuses[stopID] = &delayed
+	}
+
+	for i, position := range scenario.BusInfo.Positions {
+		if position.RouteID == routeID {
+			// WMATA reports buses running late with a positive deviation
+			scenario.BusInfo.Positions[i].Deviation += delay
+		}
+	}
+
+	return scenario
+}
+
+// NoService removes every train and bus prediction and position, as overnight when the system is closed
+func (scenario *Scenario) NoService() *Scenario {
+	scenario.RailPredictions.Trains = nil
+	scenario.TrainPositions.Positions = nil
+	scenario.BusInfo.Positions = nil
+
+	for stopID, predictions := range scenario.BusPredictions.NextBuses {
+		scenario.BusPredictions.NextBuses[stopID] = &buspredictions.GetNextBusResponse{StopName: predictions.StopName}
+	}
+
+	return scenario
+}
+
+// Unavailable makes every call to every service fail with err, as when the API is down or the API key is rejected
+func (scenario *Scenario) Unavailable(err error) *Scenario {
+	for _, recorder := range scenario.recorders() {
+		recorder.Fail("", err)
+	}
+
+	return scenario
+}
+
+// Reset forgets the calls recorded by every mock and the errors injected into them
+func (scenario *Scenario) Reset() {
+	for _, recorder := range scenario.recorders() {
+		recorder.Reset()
+	}
+}
+
+func (scenario *Scenario) recorders() []*Recorder {
+	return []*Recorder{
+		&scenario.BusInfo.Recorder,
+		&scenario.BusPredictions.Recorder,
+		&scenario.Incidents.Recorder,
+		&scenario.RailInfo.Recorder,
+		&scenario.RailPredictions.Recorder,
+		&scenario.TrainPositions.Recorder,
+	}
+}
+
+func (scenario *Scenario) incidentID() string {
+	return fmt.Sprintf("SCENARIO-%d", len(scenario.Incidents.RailIncidents)+len(scenario.Incidents.BusIncidents)+1)
+}
+
+// paths returns the fixture path between every pair of its stations, in both directions
+func paths() map[StationPair][]railinfo.PathItem {
+	pairs := make(map[StationPair][]railinfo.PathItem)
+
+	for _, from := range wmatatest.Path().Path {
+		for _, to := range wmatatest.Path().Path {
+			if from.StationCode != to.StationCode {
+				pair := StationPair{From: from.StationCode, To: to.StationCode}
+				pairs[pair] = wmatatest.PathBetween(pair.From, pair.To).Path
+			}
+		}
+	}
+
+	return pairs
+}
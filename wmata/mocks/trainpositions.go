@@ -0,0 +1,43 @@
+package mocks
+
+import (
+	"github.com/awiede/wmata-go-sdk/wmata/trainpositions"
+)
+
+// TrainPositions is a fake trainpositions.TrainPositions answering from its fields
+type TrainPositions struct {
+	Recorder
+
+	Positions      []trainpositions.TrainPosition
+	StandardRoutes []trainpositions.Route
+	TrackCircuits  []trainpositions.TrackCircuit
+}
+
+var _ trainpositions.TrainPositions = (*TrainPositions)(nil)
+
+// GetLiveTrainPositions returns Positions
+func (mock *TrainPositions) GetLiveTrainPositions() (*trainpositions.GetLiveTrainPositionsResponse, error) {
+	if err := mock.called("GetLiveTrainPositions"); err != nil {
+		return nil, err
+	}
+
+	return &trainpositions.GetLiveTrainPositionsResponse{Positions: append([]trainpositions.TrainPosition(nil), mock.Positions...)}, nil
+}
+
+// GetStandardRoutes returns StandardRoutes
+func (mock *TrainPositions) GetStandardRoutes() (*trainpositions.GetStandardRoutesResponse, error) {
+	if err := mock.called("GetStandardRoutes"); err != nil {
+		return nil, err
+	}
+
+	return &trainpositions.GetStandardRoutesResponse{Routes: append([]trainpositions.Route(nil), mock.StandardRoutes...)}, nil
+}
+
+// GetTrackCircuits returns TrackCircuits
+func (mock *TrainPositions) GetTrackCircuits() (*trainpositions.GetTrackCircuitsResponse, error) {
+	if err := mock.called("GetTrackCircuits"); err != nil {
+		return nil, err
+	}
+
+	return &trainpositions.GetTrackCircuitsResponse{TrackCircuits: append([]trainpositions.TrackCircuit(nil), mock.TrackCircuits...)}, nil
+}
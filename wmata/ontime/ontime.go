@@ -0,0 +1,588 @@
+package ontime
+
+import (
+	"encoding/csv"
+	"github.com/awiede/wmata-go-sdk/wmata"
+	"github.com/awiede/wmata-go-sdk/wmata/archive"
+	"github.com/awiede/wmata-go-sdk/wmata/businfo"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Default thresholds used when a Config field is left at zero. WMATA counts a bus as on time when it leaves a
+// timepoint no more than 2 minutes early and no more than 7 minutes late
+const (
+	DefaultMaxEarly   = 2 * time.Minute
+	DefaultMaxLate    = 7 * time.Minute
+	DefaultWorstTrips = 10
+)
+
+// Config holds the on-time window and the size of the report
+type Config struct {
+	// MaxEarly, buses further ahead of schedule than this are early
+	MaxEarly time.Duration
+	// MaxLate, buses further behind schedule than this are late
+	MaxLate time.Duration
+	// WorstTrips is the number of trips listed in Report.WorstTrips
+	WorstTrips int
+}
+
+// withDefaults returns a copy of the config with zero values replaced by defaults
+func (config Config) withDefaults() Config {
+	if config.MaxEarly <= 0 {
+		config.MaxEarly = DefaultMaxEarly
+	}
+
+	if config.MaxLate <= 0 {
+		config.MaxLate = DefaultMaxLate
+	}
+
+	if config.WorstTrips <= 0 {
+		config.WorstTrips = DefaultWorstTrips
+	}
+
+	return config
+}
+
+// Performance is the on-time performance of the trips of one direction of a route in one hour of the day
+type Performance struct {
+	RouteID       string `json:"RouteID"`
+	DirectionText string `json:"DirectionText"`
+	// Hour of the day of the scheduled times at the timepoints, 0 to 23
+	Hour  int `json:"Hour"`
+	Trips int `json:"Trips"`
+	// Timepoints is the number of scheduled stops buses were observed leaving, each of which is early, on time or late
+	Timepoints    int     `json:"Timepoints"`
+	Early         int     `json:"Early"`
+	OnTime        int     `json:"OnTime"`
+	Late          int     `json:"Late"`
+	OnTimePercent float64 `json:"OnTimePercent"`
+	// AverageDeviation in minutes over the timepoints, positive when buses run late
+	AverageDeviation float64 `json:"AverageDeviation"`
+}
+
+// TripPerformance is the on-time performance of one trip
+type TripPerformance struct {
+	RouteID       string `json:"RouteID"`
+	DirectionText string `json:"DirectionText"`
+	TripID        string `json:"TripID"`
+	// StartTime is the scheduled start of the trip, in the WMATA date time layout
+	StartTime        string  `json:"StartTime"`
+	Timepoints       int     `json:"Timepoints"`
+	OnTime           int     `json:"OnTime"`
+	OnTimePercent    float64 `json:"OnTimePercent"`
+	AverageDeviation float64 `json:"AverageDeviation"`
+	// WorstDeviation is the deviation in minutes furthest from schedule, early or late, at WorstStopID
+	WorstDeviation int    `json:"WorstDeviation"`
+	WorstStopID    string `json:"WorstStopID"`
+}
+
+// Report is the on-time performance of the observed trips
+type Report struct {
+	// Performance is ordered by route, direction and hour
+	Performance []Performance `json:"Performance"`
+	// WorstTrips are the trips furthest from schedule, worst first
+	WorstTrips []TripPerformance `json:"WorstTrips"`
+	// UnscheduledTrips counts trips observed without a scheduled trip, which are not measured
+	UnscheduledTrips int `json:"UnscheduledTrips"`
+}
+
+// tripKey identifies a scheduled trip, trip IDs are only unique within a service day
+type tripKey struct {
+	tripID string
+	date   string
+}
+
+// scheduledTrip is a trip with its stop times parsed and in order
+type scheduledTrip struct {
+	trip  businfo.Trip
+	times []time.Time
+	stops []string
+	end   time.Time
+}
+
+// departure is the last observation of a bus at or after a timepoint, before the next timepoint
+type departure struct {
+	scheduled time.Time
+	observed  time.Time
+	deviation int
+}
+
+// Analyzer matches bus positions with scheduled trips to measure how late buses leave each timepoint. A bus is
+// counted at the last stop its schedule says it should have left, with the deviation it reported last before the next
+// stop. It is safe for concurrent use
+type Analyzer struct {
+	config Config
+
+	mutex       sync.Mutex
+	trips       map[tripKey]*scheduledTrip
+	departures  map[tripKey]map[string]departure
+	unscheduled map[tripKey]bool
+	// seen holds the positions already added by service date, dates before the day before latestDate are pruned
+	seen       map[string]map[string]bool
+	latestDate string
+}
+
+// NewAnalyzer returns an Analyzer with no schedules loaded
+func NewAnalyzer(config Config) *Analyzer {
+	return &Analyzer{
+		config:      config.withDefaults(),
+		trips:       make(map[tripKey]*scheduledTrip),
+		departures:  make(map[tripKey]map[string]departure),
+		unscheduled: make(map[tripKey]bool),
+		seen:        make(map[string]map[string]bool),
+	}
+}
+
+// AddSchedule loads the trips of a route schedule. Schedules for each day positions were observed on are needed
+func (analyzer *Analyzer) AddSchedule(schedule *businfo.GetScheduleResponse) {
+	analyzer.mutex.Lock()
+	defer analyzer.mutex.Unlock()
+
+	for _, trip := range append(append([]businfo.Trip(nil), schedule.Direction0...), schedule.Direction1...) {
+		stopTimes := make([]businfo.StopTime, len(trip.StopTimes))
+		copy(stopTimes, trip.StopTimes)
+
+		sort.SliceStable(stopTimes, func(i, j int) bool {
+			return stopTimes[i].StopSequence < stopTimes[j].StopSequence
+		})
+
+		scheduled := scheduledTrip{trip: trip}
+
+		for _, stopTime := range stopTimes {
+			at, parseErr := time.Parse(wmata.DateTimeLayout, stopTime.Time)
+
+			if parseErr != nil {
+				continue
+			}
+
+			scheduled.times = append(scheduled.times, at)
+			scheduled.stops = append(scheduled.stops, stopTime.StopID)
+		}
+
+		if len(scheduled.times) == 0 {
+			continue
+		}
+
+		scheduled.end = scheduled.times[len(scheduled.times)-1]
+		analyzer.trips[tripKey{tripID: trip.TripID, date: serviceDate(trip.StartTime)}] = &scheduled
+	}
+}
+
+// AddPositions adds observed bus positions. Positions already added, as repeated in consecutive snapshots, are ignored
+func (analyzer *Analyzer) AddPositions(positions []businfo.BusPosition) {
+	analyzer.mutex.Lock()
+	defer analyzer.mutex.Unlock()
+
+	for _, position := range positions {
+		date := serviceDate(position.TripStartTime)
+		observation := position.VehicleID + "/" + position.TripID + "/" + position.DateTime
+
+		if analyzer.seen[date][observation] {
+			continue
+		}
+
+		if analyzer.seen[date] == nil {
+			analyzer.seen[date] = make(map[string]bool)
+			analyzer.pruneSeen(date)
+		}
+
+		analyzer.seen[date][observation] = true
+		analyzer.add(&position)
+	}
+}
+
+// pruneSeen forgets the positions of service dates before the day before a new latest date. Trips of the previous
+// service date can still be running after midnight, older positions are no longer repeated in snapshots
+func (analyzer *Analyzer) pruneSeen(date string) {
+	latest, parseErr := time.Parse(wmata.DateLayout, date)
+
+	if parseErr != nil || date <= analyzer.latestDate {
+		return
+	}
+
+	analyzer.latestDate = date
+	cutoff := latest.AddDate(0, 0, -1).Format(wmata.DateLayout)
+
+	for seenDate := range analyzer.seen {
+		if seenDate < cutoff {
+			delete(analyzer.seen, seenDate)
+		}
+	}
+}
+
+// AddArchive adds the positions of every bus position snapshot read by an archive iterator
+func (analyzer *Analyzer) AddArchive(iterator *archive.Iterator) error {
+	for iterator.Next() {
+		snapshot := iterator.Snapshot()
+
+		if snapshot.Kind != archive.KindBusPositions {
+			continue
+		}
+
+		positions := businfo.GetPositionsResponse{}
+
+		if decodeErr := snapshot.Decode(&positions); decodeErr != nil {
+			return decodeErr
+		}
+
+		analyzer.AddPositions(positions.BusPositions)
+	}
+
+	return iterator.Err()
+}
+
+// Report measures the on-time performance of the positions added so far
+func (analyzer *Analyzer) Report() *Report {
+	analyzer.mutex.Lock()
+	defer analyzer.mutex.Unlock()
+
+	type groupKey struct {
+		routeID       string
+		directionText string
+		hour          int
+	}
+
+	report := Report{UnscheduledTrips: len(analyzer.unscheduled)}
+	groups := make(map[groupKey]*Performance)
+	groupTrips := make(map[groupKey]map[tripKey]bool)
+	deviations := make(map[groupKey]int)
+
+	for key, departures := range analyzer.departures {
+		trip := analyzer.trips[key].trip
+		performance := TripPerformance{
+			RouteID:       trip.RouteID,
+			DirectionText: trip.TripDirection,
+			TripID:        trip.TripID,
+			StartTime:     trip.StartTime,
+		}
+
+		tripDeviation := 0
+
+		// stops are visited in order so that ties for the worst deviation go to the earliest stop
+		for _, stopID := range analyzer.trips[key].stops {
+			departure, observed := departures[stopID]
+
+			if !observed {
+				continue
+			}
+
+			group := groupKey{routeID: trip.RouteID, directionText: trip.TripDirection, hour: departure.scheduled.Hour()}
+
+			if groups[group] == nil {
+				groups[group] = &Performance{RouteID: group.routeID, DirectionText: group.directionText, Hour: group.hour}
+				groupTrips[group] = make(map[tripKey]bool)
+			}
+
+			groupTrips[group][key] = true
+			groups[group].Timepoints++
+			deviations[group] += departure.deviation
+
+			switch analyzer.classify(departure.deviation) {
+			case early:
+				groups[group].Early++
+			case late:
+				groups[group].Late++
+			default:
+				groups[group].OnTime++
+				performance.OnTime++
+			}
+
+			performance.Timepoints++
+			tripDeviation += departure.deviation
+
+			if abs(departure.deviation) > abs(performance.WorstDeviation) || performance.WorstStopID == "" {
+				performance.WorstDeviation = departure.deviation
+				performance.WorstStopID = stopID
+			}
+		}
+
+		performance.OnTimePercent = percent(performance.OnTime, performance.Timepoints)
+		performance.AverageDeviation = average(tripDeviation, performance.Timepoints)
+		report.WorstTrips = append(report.WorstTrips, performance)
+	}
+
+	for group, performance := range groups {
+		performance.Trips = len(groupTrips[group])
+		performance.OnTimePercent = percent(performance.OnTime, performance.Timepoints)
+		performance.AverageDeviation = average(deviations[group], performance.Timepoints)
+		report.Performance = append(report.Performance, *performance)
+	}
+
+	sort.Slice(report.Performance, func(i, j int) bool {
+		a, b := report.Performance[i], report.Performance[j]
+
+		if a.RouteID != b.RouteID {
+			return a.RouteID < b.RouteID
+		}
+
+		if a.DirectionText != b.DirectionText {
+			return a.DirectionText < b.DirectionText
+		}
+
+		return a.Hour < b.Hour
+	})
+
+	sort.Slice(report.WorstTrips, func(i, j int) bool {
+		a, b := report.WorstTrips[i], report.WorstTrips[j]
+
+		if abs(a.WorstDeviation) != abs(b.WorstDeviation) {
+			return abs(a.WorstDeviation) > abs(b.WorstDeviation)
+		}
+
+		if a.OnTimePercent != b.OnTimePercent {
+			return a.OnTimePercent < b.OnTimePercent
+		}
+
+		if a.StartTime != b.StartTime {
+			return a.StartTime < b.StartTime
+		}
+
+		return a.TripID < b.TripID
+	})
+
+	if len(report.WorstTrips) > analyzer.config.WorstTrips {
+		report.WorstTrips = report.WorstTrips[:analyzer.config.WorstTrips]
+	}
+
+	return &report
+}
+
+// add places a position at the last timepoint its trip was scheduled to leave by the time the bus would be at its
+// position if it were on schedule
+func (analyzer *Analyzer) add(position *businfo.BusPosition) {
+	key := tripKey{tripID: position.TripID, date: serviceDate(position.TripStartTime)}
+	trip, scheduled := analyzer.trips[key]
+
+	if !scheduled {
+		analyzer.unscheduled[key] = true
+		return
+	}
+
+	observed, parseErr := time.Parse(wmata.DateTimeLayout, position.DateTime)
+
+	if parseErr != nil {
+		return
+	}
+
+	// WMATA reports buses running late with a positive deviation
+	onSchedule := observed.Add(-time.Duration(position.Deviation) * time.Minute)
+
+	if onSchedule.After(trip.end) {
+		return
+	}
+
+	i := sort.Search(len(trip.times), func(i int) bool {
+		return trip.times[i].After(onSchedule)
+	}) - 1
+
+	// buses waiting to start their trip have not left a timepoint yet
+	if i < 0 {
+		return
+	}
+
+	if analyzer.departures[key] == nil {
+		analyzer.departures[key] = make(map[string]departure)
+	}
+
+	previous, exist := analyzer.departures[key][trip.stops[i]]
+
+	if !exist || !observed.Before(previous.observed) {
+		analyzer.departures[key][trip.stops[i]] = departure{scheduled: trip.times[i], observed: observed, deviation: position.Deviation}
+	}
+}
+
+type classification int
+
+const (
+	onTime classification = iota
+	early
+	late
+)
+
+func (analyzer *Analyzer) classify(deviation int) classification {
+	switch minutes := time.Duration(deviation) * time.Minute; {
+	case minutes < -analyzer.config.MaxEarly:
+		return early
+	case minutes > analyzer.config.MaxLate:
+		return late
+	}
+
+	return onTime
+}
+
+// WriteCSV writes the performance of each route, direction and hour as CSV with a header row
+func (report *Report) WriteCSV(w io.Writer) error {
+	writer := csv.NewWriter(w)
+
+	if writeErr := writer.Write([]string{"RouteID", "DirectionText", "Hour", "Trips", "Timepoints", "Early", "OnTime", "Late", "OnTimePercent", "AverageDeviation"}); writeErr != nil {
+		return writeErr
+	}
+
+	for _, performance := range report.Performance {
+		row := []string{
+			performance.RouteID,
+			performance.DirectionText,
+			strconv.Itoa(performance.Hour),
+			strconv.Itoa(performance.Trips),
+			strconv.Itoa(performance.Timepoints),
+			strconv.Itoa(performance.Early),
+			strconv.Itoa(performance.OnTime),
+			strconv.Itoa(performance.Late),
+			formatFloat(performance.OnTimePercent),
+			formatFloat(performance.AverageDeviation),
+		}
+
+		if writeErr := writer.Write(row); writeErr != nil {
+			return writeErr
+		}
+	}
+
+	writer.Flush()
+
+	return writer.Error()
+}
+
+// WriteTripsCSV writes the worst trips as CSV with a header row
+func (report *Report) WriteTripsCSV(w io.Writer) error {
+	writer := csv.NewWriter(w)
+
+	if writeErr := writer.Write([]string{"RouteID", "DirectionText", "TripID", "StartTime", "Timepoints", "OnTime", "OnTimePercent", "AverageDeviation", "WorstDeviation", "WorstStopID"}); writeErr != nil {
+		return writeErr
+	}
+
+	for _, trip := range report.WorstTrips {
+		row := []string{
+			trip.RouteID,
+			trip.DirectionText,
+			trip.TripID,
+			trip.StartTime,
+			strconv.Itoa(trip.Timepoints),
+			strconv.Itoa(trip.OnTime),
+			formatFloat(trip.OnTimePercent),
+			formatFloat(trip.AverageDeviation),
+			strconv.Itoa(trip.WorstDeviation),
+			trip.WorstStopID,
+		}
+
+		if writeErr := writer.Write(row); writeErr != nil {
+			return writeErr
+		}
+	}
+
+	writer.Flush()
+
+	return writer.Error()
+}
+
+// NewService returns a new Service that loads schedules using an existing BusInfo service
+func NewService(busInfo businfo.BusInfo, config Config) *Service {
+	return &Service{
+		busInfo:  busInfo,
+		analyzer: NewAnalyzer(config),
+		loaded:   make(map[string]bool),
+	}
+}
+
+// Service measures on-time performance from archived bus positions, loading the schedule of each route for each day
+// positions were observed on as they are read. It is safe for concurrent use
+type Service struct {
+	busInfo  businfo.BusInfo
+	analyzer *Analyzer
+	// mutex guards loaded and is held while a schedule loads, so each schedule is only requested once
+	mutex  sync.Mutex
+	loaded map[string]bool
+}
+
+// AnalyzeArchive adds the bus positions read by an archive iterator and reports the on-time performance of every
+// position added so far
+func (service *Service) AnalyzeArchive(iterator *archive.Iterator) (*Report, error) {
+	for iterator.Next() {
+		snapshot := iterator.Snapshot()
+
+		if snapshot.Kind != archive.KindBusPositions {
+			continue
+		}
+
+		positions := businfo.GetPositionsResponse{}
+
+		if decodeErr := snapshot.Decode(&positions); decodeErr != nil {
+			return nil, decodeErr
+		}
+
+		for _, position := range positions.BusPositions {
+			if loadErr := service.loadSchedule(position.RouteID, serviceDate(position.TripStartTime)); loadErr != nil {
+				return nil, loadErr
+			}
+		}
+
+		service.analyzer.AddPositions(positions.BusPositions)
+	}
+
+	if iterator.Err() != nil {
+		return nil, iterator.Err()
+	}
+
+	return service.analyzer.Report(), nil
+}
+
+func (service *Service) loadSchedule(routeID, date string) error {
+	service.mutex.Lock()
+	defer service.mutex.Unlock()
+
+	if routeID == "" || date == "" || service.loaded[routeID+"/"+date] {
+		return nil
+	}
+
+	schedule, scheduleErr := service.busInfo.GetSchedule(routeID, date, true)
+
+	if scheduleErr != nil {
+		return scheduleErr
+	}
+
+	service.analyzer.AddSchedule(schedule)
+	service.loaded[routeID+"/"+date] = true
+
+	return nil
+}
+
+// serviceDate returns the date part of a WMATA date time, e.g. "2019-04-29"
+func serviceDate(dateTime string) string {
+	if len(dateTime) < len(wmata.DateLayout) {
+		return ""
+	}
+
+	return dateTime[:len(wmata.DateLayout)]
+}
+
+func percent(count, total int) float64 {
+	if total == 0 {
+		return 0
+	}
+
+	return float64(count) * 100 / float64(total)
+}
+
+func average(sum, count int) float64 {
+	if count == 0 {
+		return 0
+	}
+
+	return float64(sum) / float64(count)
+}
+
+func abs(value int) int {
+	if value < 0 {
+		return -value
+	}
+
+	return value
+}
+
+func formatFloat(value float64) string {
+	return strconv.FormatFloat(math.Round(value*10)/10, 'f', 1, 64)
+}
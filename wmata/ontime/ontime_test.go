@@ -0,0 +1,403 @@
+package ontime
+
+import (
+	"bytes"
+	"errors"
+	"github.com/awiede/wmata-go-sdk/wmata/archive"
+	"github.com/awiede/wmata-go-sdk/wmata/businfo"
+	"github.com/awiede/wmata-go-sdk/wmata/mocks"
+	"github.com/kr/pretty"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+// testSchedule builds a route 70 schedule for 2019-04-29 with two trips in each direction over three timepoints
+func testSchedule() *businfo.GetScheduleResponse {
+	trip := func(tripID, direction string, stops []string, times ...string) businfo.Trip {
+		trip := businfo.Trip{
+			EndTime:       "2019-04-29T" + times[len(times)-1],
+			RouteID:       "70",
+			StartTime:     "2019-04-29T" + times[0],
+			TripDirection: direction,
+			TripID:        tripID,
+		}
+
+		// listed out of order, stop times are ordered by sequence
+		for i := len(times) - 1; i >= 0; i-- {
+			trip.StopTimes = append(trip.StopTimes, businfo.StopTime{StopID: stops[i], StopSequence: i + 1, Time: "2019-04-29T" + times[i]})
+		}
+
+		return trip
+	}
+
+	north := []string{"1001195", "1001808", "1003043"}
+	south := []string{"1003043", "1001808", "1001195"}
+
+	return &businfo.GetScheduleResponse{
+		Direction0: []businfo.Trip{
+			trip("7001", "NORTH", north, "08:00:00", "08:04:00", "08:38:00"),
+			trip("7003", "NORTH", north, "08:12:00", "08:16:00", "08:50:00"),
+		},
+		Direction1: []businfo.Trip{
+			trip("7002", "SOUTH", south, "08:05:00", "08:41:00", "08:46:00"),
+			trip("7004", "SOUTH", south, "08:55:00", "09:01:00", "09:06:00"),
+		},
+		Name: "70 - GEORGIA AVE-7TH STREET",
+	}
+}
+
+func testPosition(vehicleID, routeID, tripID, tripStart, dateTime string, deviation int) businfo.BusPosition {
+	return businfo.BusPosition{
+		DateTime:      "2019-04-29T" + dateTime,
+		Deviation:     deviation,
+		RouteID:       routeID,
+		TripID:        tripID,
+		TripStartTime: tripStart,
+		VehicleID:     vehicleID,
+	}
+}
+
+// testPositions are snapshots of bus positions, each vehicle appearing in snapshots until it moves again
+var testPositions = [][]businfo.BusPosition{
+	{
+		testPosition("5418", "70", "7001", "2019-04-29T08:00:00", "08:03:12", 1),
+		testPosition("5421", "70", "7002", "2019-04-29T08:05:00", "08:02:48", -2),
+		testPosition("6017", "S2", "2201", "2019-04-29T07:50:00", "08:02:00", 4),
+	},
+	{
+		testPosition("5418", "70", "7001", "2019-04-29T08:00:00", "08:03:12", 1),
+		testPosition("5421", "70", "7002", "2019-04-29T08:05:00", "08:06:00", 0),
+		testPosition("5420", "70", "7003", "2019-04-29T08:12:00", "08:11:00", -3),
+	},
+	{
+		testPosition("5418", "70", "7001", "2019-04-29T08:00:00", "08:10:00", 3),
+		testPosition("5420", "70", "7003", "2019-04-29T08:12:00", "08:20:00", 0),
+	},
+	{
+		testPosition("5418", "70", "7001", "2019-04-29T08:00:00", "08:30:00", 9),
+		testPosition("5420", "70", "7003", "2019-04-29T08:12:00", "08:49:00", 0),
+	},
+	{
+		testPosition("5418", "70", "7001", "2019-04-29T08:00:00", "08:46:00", 8),
+		testPosition("5422", "70", "7004", "2019-04-29T08:55:00", "09:03:00", 2),
+		// a trip ID from another day's schedule
+		testPosition("5423", "70", "7001", "2019-04-30T08:00:00", "09:03:00", 0),
+	},
+	{
+		// past the end of its trip
+		testPosition("5418", "70", "7001", "2019-04-29T08:00:00", "08:50:00", 0),
+	},
+}
+
+var expectedReport = Report{
+	Performance: []Performance{
+		{
+			RouteID:          "70",
+			DirectionText:    "NORTH",
+			Hour:             8,
+			Trips:            2,
+			Timepoints:       5,
+			Early:            1,
+			OnTime:           2,
+			Late:             2,
+			OnTimePercent:    40,
+			AverageDeviation: 3,
+		},
+		{
+			RouteID:          "70",
+			DirectionText:    "SOUTH",
+			Hour:             8,
+			Trips:            1,
+			Timepoints:       1,
+			OnTime:           1,
+			OnTimePercent:    100,
+			AverageDeviation: 0,
+		},
+		{
+			RouteID:          "70",
+			DirectionText:    "SOUTH",
+			Hour:             9,
+			Trips:            1,
+			Timepoints:       1,
+			OnTime:           1,
+			OnTimePercent:    100,
+			AverageDeviation: 2,
+		},
+	},
+	WorstTrips: []TripPerformance{
+		{
+			RouteID:          "70",
+			DirectionText:    "NORTH",
+			TripID:           "7001",
+			StartTime:        "2019-04-29T08:00:00",
+			Timepoints:       3,
+			OnTime:           1,
+			OnTimePercent:    100.0 / 3,
+			AverageDeviation: 6,
+			WorstDeviation:   9,
+			WorstStopID:      "1001808",
+		},
+		{
+			RouteID:          "70",
+			DirectionText:    "NORTH",
+			TripID:           "7003",
+			StartTime:        "2019-04-29T08:12:00",
+			Timepoints:       2,
+			OnTime:           1,
+			OnTimePercent:    50,
+			AverageDeviation: -1.5,
+			WorstDeviation:   -3,
+			WorstStopID:      "1001195",
+		},
+		{
+			RouteID:          "70",
+			DirectionText:    "SOUTH",
+			TripID:           "7004",
+			StartTime:        "2019-04-29T08:55:00",
+			Timepoints:       1,
+			OnTime:           1,
+			OnTimePercent:    100,
+			AverageDeviation: 2,
+			WorstDeviation:   2,
+			WorstStopID:      "1001808",
+		},
+	},
+	UnscheduledTrips: 2,
+}
+
+func TestReport(t *testing.T) {
+	analyzer := NewAnalyzer(Config{WorstTrips: 3})
+	analyzer.AddSchedule(testSchedule())
+
+	for _, positions := range testPositions {
+		analyzer.AddPositions(positions)
+	}
+
+	report := analyzer.Report()
+
+	if !reflect.DeepEqual(*report, expectedReport) {
+		t.Errorf("unexpected report: %s", pretty.Diff(*report, expectedReport))
+	}
+}
+
+func TestSeenPruned(t *testing.T) {
+	analyzer := NewAnalyzer(Config{})
+
+	position := func(tripStart string) []businfo.BusPosition {
+		return []businfo.BusPosition{{VehicleID: "7001", TripID: "7001", DateTime: tripStart, TripStartTime: tripStart}}
+	}
+
+	analyzer.AddPositions(position("2019-04-29T23:50:00"))
+	analyzer.AddPositions(position("2019-04-30T08:00:00"))
+
+	if len(analyzer.seen) != 2 {
+		t.Errorf("expected positions of the previous service date to be kept, got %d dates", len(analyzer.seen))
+	}
+
+	analyzer.AddPositions(position("2019-05-01T08:00:00"))
+
+	if _, exist := analyzer.seen["2019-04-29"]; exist || len(analyzer.seen) != 2 {
+		t.Errorf("expected positions of past service dates to be pruned, got %d dates", len(analyzer.seen))
+	}
+
+	// late positions of a pruned date are still added
+	analyzer.AddPositions(position("2019-04-29T23:50:00"))
+
+	if len(analyzer.seen) != 3 || analyzer.latestDate != "2019-05-01" {
+		t.Errorf("unexpected seen dates after a late position: %d, latest %s", len(analyzer.seen), analyzer.latestDate)
+	}
+}
+
+func TestOnTimeWindow(t *testing.T) {
+	testRequests := []struct {
+		config    Config
+		deviation int
+		expected  classification
+	}{
+		{deviation: -2, expected: onTime},
+		{deviation: -3, expected: early},
+		{deviation: 7, expected: onTime},
+		{deviation: 8, expected: late},
+		{config: Config{MaxEarly: time.Minute, MaxLate: 5 * time.Minute}, deviation: -2, expected: early},
+		{config: Config{MaxEarly: time.Minute, MaxLate: 5 * time.Minute}, deviation: 6, expected: late},
+	}
+
+	for i, request := range testRequests {
+		if classified := NewAnalyzer(request.config).classify(request.deviation); classified != request.expected {
+			t.Errorf("request %d: deviation %d classified as %d, expected %d", i, request.deviation, classified, request.expected)
+		}
+	}
+}
+
+func TestWriteCSV(t *testing.T) {
+	var buffer bytes.Buffer
+
+	if writeErr := expectedReport.WriteCSV(&buffer); writeErr != nil {
+		t.Fatalf("error writing csv: %s", writeErr)
+	}
+
+	expected := "RouteID,DirectionText,Hour,Trips,Timepoints,Early,OnTime,Late,OnTimePercent,AverageDeviation\n" +
+		"70,NORTH,8,2,5,1,2,2,40.0,3.0\n" +
+		"70,SOUTH,8,1,1,0,1,0,100.0,0.0\n" +
+		"70,SOUTH,9,1,1,0,1,0,100.0,2.0\n"
+
+	if buffer.String() != expected {
+		t.Errorf("unexpected csv:\n%s\nexpected:\n%s", buffer.String(), expected)
+	}
+
+	buffer.Reset()
+
+	if writeErr := expectedReport.WriteTripsCSV(&buffer); writeErr != nil {
+		t.Fatalf("error writing trips csv: %s", writeErr)
+	}
+
+	expected = "RouteID,DirectionText,TripID,StartTime,Timepoints,OnTime,OnTimePercent,AverageDeviation,WorstDeviation,WorstStopID\n" +
+		"70,NORTH,7001,2019-04-29T08:00:00,3,1,33.3,6.0,9,1001808\n" +
+		"70,NORTH,7003,2019-04-29T08:12:00,2,1,50.0,-1.5,-3,1001195\n" +
+		"70,SOUTH,7004,2019-04-29T08:55:00,1,1,100.0,2.0,2,1001808\n"
+
+	if buffer.String() != expected {
+		t.Errorf("unexpected trips csv:\n%s\nexpected:\n%s", buffer.String(), expected)
+	}
+}
+
+// writeArchive writes the test positions to a new archive, one snapshot a minute, with a rail snapshot between each
+func writeArchive(t *testing.T) (string, *archive.Reader) {
+	directory, tempErr := ioutil.TempDir("", "ontime")
+
+	if tempErr != nil {
+		t.Fatalf("error creating archive directory: %s", tempErr)
+	}
+
+	writer, openErr := archive.OpenWriter(directory, archive.WriterConfig{})
+
+	if openErr != nil {
+		t.Fatalf("error opening writer: %s", openErr)
+	}
+
+	start := time.Date(2019, 4, 29, 12, 0, 0, 0, time.UTC)
+
+	for i, positions := range testPositions {
+		snapshots := []*archive.Snapshot{
+			{Kind: archive.KindRailIncidents, Time: start.Add(time.Duration(i) * time.Minute), Data: []byte(`{"Incidents":[]}`)},
+		}
+
+		busSnapshot, snapshotErr := archive.NewSnapshot(archive.KindBusPositions, start.Add(time.Duration(i)*time.Minute), &businfo.GetPositionsResponse{BusPositions: positions})
+
+		if snapshotErr != nil {
+			t.Fatalf("error creating snapshot: %s", snapshotErr)
+		}
+
+		for _, snapshot := range append(snapshots, busSnapshot) {
+			if writeErr := writer.Write(snapshot); writeErr != nil {
+				t.Fatalf("error writing snapshot: %s", writeErr)
+			}
+		}
+	}
+
+	if closeErr := writer.Close(); closeErr != nil {
+		t.Fatalf("error closing writer: %s", closeErr)
+	}
+
+	reader, readerErr := archive.OpenReader(directory)
+
+	if readerErr != nil {
+		t.Fatalf("error opening reader: %s", readerErr)
+	}
+
+	return directory, reader
+}
+
+func TestAddArchive(t *testing.T) {
+	directory, reader := writeArchive(t)
+	defer os.RemoveAll(directory)
+
+	analyzer := NewAnalyzer(Config{WorstTrips: 3})
+	analyzer.AddSchedule(testSchedule())
+
+	if addErr := analyzer.AddArchive(reader.All()); addErr != nil {
+		t.Fatalf("error adding archive: %s", addErr)
+	}
+
+	if report := analyzer.Report(); !reflect.DeepEqual(*report, expectedReport) {
+		t.Errorf("unexpected report: %s", pretty.Diff(*report, expectedReport))
+	}
+}
+
+func TestService(t *testing.T) {
+	directory, reader := writeArchive(t)
+	defer os.RemoveAll(directory)
+
+	busInfo := mocks.BusInfo{
+		Schedules: map[string]*businfo.GetScheduleResponse{
+			"70": testSchedule(),
+			"S2": {Name: "S2 - 16TH STREET"},
+		},
+	}
+
+	service := NewService(&busInfo, Config{WorstTrips: 3})
+	report, analyzeErr := service.AnalyzeArchive(reader.All(archive.KindBusPositions))
+
+	if analyzeErr != nil {
+		t.Fatalf("error analyzing archive: %s", analyzeErr)
+	}
+
+	if !reflect.DeepEqual(*report, expectedReport) {
+		t.Errorf("unexpected report: %s", pretty.Diff(*report, expectedReport))
+	}
+
+	// one schedule per route and service day
+	expectedCalls := []mocks.Call{
+		{Method: "GetSchedule", Args: []interface{}{"70", "2019-04-29", true}},
+		{Method: "GetSchedule", Args: []interface{}{"S2", "2019-04-29", true}},
+		{Method: "GetSchedule", Args: []interface{}{"70", "2019-04-30", true}},
+	}
+
+	if calls := busInfo.Calls("GetSchedule"); !reflect.DeepEqual(calls, expectedCalls) {
+		t.Errorf("unexpected calls: %s", pretty.Diff(calls, expectedCalls))
+	}
+
+	busInfo.Fail("GetSchedule", errors.New("service unavailable"))
+
+	if _, analyzeErr := NewService(&busInfo, Config{}).AnalyzeArchive(reader.All()); analyzeErr == nil {
+		t.Error("expected error loading schedule")
+	}
+}
+
+func TestServiceConcurrent(t *testing.T) {
+	directory, reader := writeArchive(t)
+	defer os.RemoveAll(directory)
+
+	busInfo := mocks.BusInfo{
+		Schedules: map[string]*businfo.GetScheduleResponse{
+			"70": testSchedule(),
+			"S2": {Name: "S2 - 16TH STREET"},
+		},
+	}
+
+	service := NewService(&busInfo, Config{})
+	wait := sync.WaitGroup{}
+
+	for i := 0; i < 4; i++ {
+		wait.Add(1)
+
+		go func() {
+			defer wait.Done()
+
+			if _, analyzeErr := service.AnalyzeArchive(reader.All(archive.KindBusPositions)); analyzeErr != nil {
+				t.Errorf("error analyzing archive: %s", analyzeErr)
+			}
+		}()
+	}
+
+	wait.Wait()
+
+	// each schedule is requested once however many archives are analyzed at the same time
+	if calls := busInfo.Calls("GetSchedule"); len(calls) != 3 {
+		t.Errorf("expected 3 schedule requests, got %d", len(calls))
+	}
+}
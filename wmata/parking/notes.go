@@ -0,0 +1,103 @@
+package parking
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	shortTermSpacesPattern = regexp.MustCompile(`(?i)(\d+) short term metered spaces`)
+	meteredSpacesPattern   = regexp.MustCompile(`(?i)(\d+) spaces metered for (\d+)-hr\.? max\.? @ \$(\d+(?:\.\d+)?) per (\d+) mins`)
+	hoursPattern           = regexp.MustCompile(`(?i)available from (\d{1,2}(?::\d{2})? ?[AP]M) to (\d{1,2}(?::\d{2})? ?[AP]M)`)
+	clockPattern           = regexp.MustCompile(`(?i)^(\d{1,2})(?::(\d{2}))? ?([AP]M)$`)
+)
+
+// MeteredSpaces is a group of metered spaces sharing a time limit and rate
+type MeteredSpaces struct {
+	Count    int           `json:"Count"`
+	MaxHours int           `json:"MaxHours"`
+	Rate     float64       `json:"Rate"`
+	RatePer  time.Duration `json:"RatePer"`
+}
+
+// Notes holds the details that could be recognized in a station's free text parking notes. The original text is kept
+// on the station's parking information
+type Notes struct {
+	// ShortTermMeteredSpaces is the total of all "N short term metered spaces" mentions, such as at Kiss & Ride lots
+	ShortTermMeteredSpaces int             `json:"ShortTermMeteredSpaces"`
+	MeteredSpaces          []MeteredSpaces `json:"MeteredSpaces"`
+	// Opens and Closes are offsets from midnight, Closes may be over 24 hours when parking is available past midnight
+	Opens    time.Duration `json:"Opens"`
+	Closes   time.Duration `json:"Closes"`
+	HasHours bool          `json:"HasHours"`
+}
+
+// ParseNotes extracts metered space counts, rates and hours of availability from parking notes such as
+// "101 spaces metered for 12-hr. max @ $1.00 per 60 mins. Parking available from 8:30 AM to 2 AM."
+func ParseNotes(notes string) Notes {
+	parsed := Notes{}
+
+	for _, match := range shortTermSpacesPattern.FindAllStringSubmatch(notes, -1) {
+		count, _ := strconv.Atoi(match[1])
+		parsed.ShortTermMeteredSpaces += count
+	}
+
+	for _, match := range meteredSpacesPattern.FindAllStringSubmatch(notes, -1) {
+		count, _ := strconv.Atoi(match[1])
+		maxHours, _ := strconv.Atoi(match[2])
+		rate, _ := strconv.ParseFloat(match[3], 64)
+		minutes, _ := strconv.Atoi(match[4])
+
+		parsed.MeteredSpaces = append(parsed.MeteredSpaces, MeteredSpaces{
+			Count:    count,
+			MaxHours: maxHours,
+			Rate:     rate,
+			RatePer:  time.Duration(minutes) * time.Minute,
+		})
+	}
+
+	if match := hoursPattern.FindStringSubmatch(notes); match != nil {
+		opens, opensOK := parseClock(match[1])
+		closes, closesOK := parseClock(match[2])
+
+		if opensOK && closesOK {
+			if closes <= opens {
+				closes += 24 * time.Hour
+			}
+
+			parsed.Opens, parsed.Closes, parsed.HasHours = opens, closes, true
+		}
+	}
+
+	return parsed
+}
+
+// parseClock converts a time such as "8:30 AM" or "2 AM" into an offset from midnight
+func parseClock(value string) (time.Duration, bool) {
+	match := clockPattern.FindStringSubmatch(strings.TrimSpace(value))
+
+	if match == nil {
+		return 0, false
+	}
+
+	hour, _ := strconv.Atoi(match[1])
+	minute := 0
+
+	if match[2] != "" {
+		minute, _ = strconv.Atoi(match[2])
+	}
+
+	if hour < 1 || hour > 12 || minute > 59 {
+		return 0, false
+	}
+
+	hour %= 12
+
+	if strings.EqualFold(match[3], "PM") {
+		hour += 12
+	}
+
+	return time.Duration(hour)*time.Hour + time.Duration(minute)*time.Minute, true
+}
@@ -0,0 +1,225 @@
+package parking
+
+import (
+	"errors"
+	"github.com/awiede/wmata-go-sdk/wmata"
+	"github.com/awiede/wmata-go-sdk/wmata/railinfo"
+	"sort"
+	"sync"
+	"time"
+)
+
+type RiderType int
+
+const (
+	// Rider is a customer who exits the lot with the same SmarTrip card used to ride Metrorail
+	Rider RiderType = iota
+	NonRider
+)
+
+// Config holds the days on which parking is free in addition to Sundays
+type Config struct {
+	// Holidays are dates with free parking, only the year, month and day are used
+	Holidays []time.Time
+}
+
+// Station is a station catalog entry merged with its parking information. Parking and Notes are nil for stations
+// without parking
+type Station struct {
+	railinfo.GetStationListResponseItem
+	Parking *railinfo.StationParking `json:"Parking"`
+	Notes   *Notes                   `json:"ParsedNotes"`
+}
+
+// Capacity returns the number of all day and short term spaces at the station
+func (station *Station) Capacity() int {
+	if station.Parking == nil {
+		return 0
+	}
+
+	return station.Parking.AllDay.TotalCount + station.Parking.ShortTerm.TotalCount
+}
+
+// Service answers parking questions using parking information for every station, retrieved with a single request the
+// first time it is needed. It is safe for concurrent use
+type Service struct {
+	railInfo railinfo.RailInfo
+	holidays map[string]bool
+	mutex    sync.Mutex
+	parking  map[string]*railinfo.StationParking
+}
+
+// NewService returns a Service that loads parking information using an existing RailInfo service
+func NewService(railInfo railinfo.RailInfo, config Config) *Service {
+	holidays := make(map[string]bool)
+
+	for _, holiday := range config.Holidays {
+		holidays[holiday.Format(wmata.DateLayout)] = true
+	}
+
+	return &Service{
+		railInfo: railInfo,
+		holidays: holidays,
+	}
+}
+
+// Load retrieves parking information for every station, replacing any cached information
+func (service *Service) Load() error {
+	response, responseErr := service.railInfo.GetParkingInformation("")
+
+	if responseErr != nil {
+		return responseErr
+	}
+
+	parking := make(map[string]*railinfo.StationParking, len(response.ParkingInformation))
+
+	for i := range response.ParkingInformation {
+		parking[response.ParkingInformation[i].StationCode] = &response.ParkingInformation[i]
+	}
+
+	service.mutex.Lock()
+	service.parking = parking
+	service.mutex.Unlock()
+
+	return nil
+}
+
+// GetParking returns a station's parking information, or false when the station has no parking
+func (service *Service) GetParking(stationCode string) (*railinfo.StationParking, bool, error) {
+	parking, loadErr := service.loaded()
+
+	if loadErr != nil {
+		return nil, false, loadErr
+	}
+
+	stationParking, exist := parking[stationCode]
+
+	if !exist || stationParking.AllDay.TotalCount+stationParking.ShortTerm.TotalCount == 0 {
+		return nil, false, nil
+	}
+
+	return stationParking, true, nil
+}
+
+// Cost returns the cost to park all day at a station on the given date. Parking is free on Sundays and holidays, and
+// Saturday rates apply on Saturdays
+func (service *Service) Cost(stationCode string, date time.Time, rider RiderType) (float64, error) {
+	if stationCode == "" {
+		return 0, errors.New("stationCode is required")
+	}
+
+	parking, exist, parkingErr := service.GetParking(stationCode)
+
+	if parkingErr != nil {
+		return 0, parkingErr
+	}
+
+	if !exist || parking.AllDay.TotalCount == 0 {
+		return 0, errors.New("no all day parking at station " + stationCode)
+	}
+
+	switch {
+	case date.Weekday() == time.Sunday || service.holidays[date.Format(wmata.DateLayout)]:
+		return 0, nil
+	case date.Weekday() == time.Saturday && rider == Rider:
+		return parking.AllDay.SaturdayRiderCost, nil
+	case date.Weekday() == time.Saturday:
+		return parking.AllDay.SaturdayNonRiderCost, nil
+	case rider == Rider:
+		return parking.AllDay.RiderCost, nil
+	default:
+		return parking.AllDay.NonRiderCost, nil
+	}
+}
+
+// Catalog returns the stations on a line, or every station when lineCode is empty, merged with their parking
+// information
+func (service *Service) Catalog(lineCode string) ([]Station, error) {
+	stations, stationsErr := service.railInfo.GetStationList(lineCode)
+
+	if stationsErr != nil {
+		return nil, stationsErr
+	}
+
+	parking, loadErr := service.loaded()
+
+	if loadErr != nil {
+		return nil, loadErr
+	}
+
+	return Merge(stations.Stations, parking), nil
+}
+
+// StationsWithParking returns the stations on a line that have parking, ordered by capacity from largest to smallest
+func (service *Service) StationsWithParking(lineCode string) ([]Station, error) {
+	if lineCode == "" {
+		return nil, errors.New("lineCode is required")
+	}
+
+	catalog, catalogErr := service.Catalog(lineCode)
+
+	if catalogErr != nil {
+		return nil, catalogErr
+	}
+
+	var stations []Station
+
+	for _, station := range catalog {
+		if station.Capacity() > 0 {
+			stations = append(stations, station)
+		}
+	}
+
+	sort.SliceStable(stations, func(i, j int) bool {
+		if stations[i].Capacity() != stations[j].Capacity() {
+			return stations[i].Capacity() > stations[j].Capacity()
+		}
+
+		return stations[i].StationCode < stations[j].StationCode
+	})
+
+	return stations, nil
+}
+
+// Merge attaches parking information, keyed by station code, to a station list and parses the parking notes
+func Merge(stations []railinfo.GetStationListResponseItem, parking map[string]*railinfo.StationParking) []Station {
+	merged := make([]Station, 0, len(stations))
+
+	for _, station := range stations {
+		entry := Station{
+			GetStationListResponseItem: station,
+		}
+
+		if stationParking, exist := parking[station.StationCode]; exist && stationParking.AllDay.TotalCount+stationParking.ShortTerm.TotalCount > 0 {
+			parkingCopy := *stationParking
+			notes := ParseNotes(stationParking.Notes + " " + stationParking.ShortTerm.Notes)
+
+			entry.Parking = &parkingCopy
+			entry.Notes = &notes
+		}
+
+		merged = append(merged, entry)
+	}
+
+	return merged
+}
+
+// loaded returns the cached parking information, loading it on first use
+func (service *Service) loaded() (map[string]*railinfo.StationParking, error) {
+	service.mutex.Lock()
+	parking := service.parking
+	service.mutex.Unlock()
+
+	if parking != nil {
+		return parking, nil
+	}
+
+	if loadErr := service.Load(); loadErr != nil {
+		return nil, loadErr
+	}
+
+	service.mutex.Lock()
+	defer service.mutex.Unlock()
+
+	return service.parking, nil
+}
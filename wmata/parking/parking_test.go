@@ -0,0 +1,175 @@
+package parking
+
+import (
+	"errors"
+	"github.com/awiede/wmata-go-sdk/wmata"
+	"github.com/awiede/wmata-go-sdk/wmata/railinfo"
+	"github.com/kr/pretty"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// testClient is a mock implementation of wmata.HTTPClient interface used for testing purposes
+type testClient struct{}
+
+// ensure testClient implements wmata.HTTPClient interface
+var _ wmata.HTTPClient = (*testClient)(nil)
+
+// Do stubs out an httpClient.Do request
+func (client *testClient) Do(req *http.Request) (*http.Response, error) {
+	testResponses, exist := testData[req.URL.Path]
+
+	if !exist {
+		return nil, errors.New("no test data found")
+	}
+
+	for _, response := range testResponses {
+		if response.rawQuery == req.URL.RawQuery {
+			rr := httptest.NewRecorder()
+			rr.WriteHeader(http.StatusOK)
+			_, writeErr := rr.Write([]byte(response.response))
+
+			return rr.Result(), writeErr
+		}
+	}
+
+	return nil, errors.New("no data found")
+}
+
+type testResponseData struct {
+	rawQuery string
+	response string
+}
+
+const viennaNotes = "North Kiss & Ride - 45 short term metered spaces. South Kiss & Ride - 26 short term metered spaces.  101 spaces metered for 12-hr. max @ $1.00 per 60 mins. 17 spaces metered for 7-hr. max. @ $1.00 per 60 mins. Parking available from 8:30 AM to 2 AM."
+
+var testData = map[string][]testResponseData{
+	"/Rail.svc/json/jStationParking": {
+		{
+			rawQuery: "StationCode=",
+			response: `{"StationsParking":[` +
+				`{"Code":"B08","Notes":"Parking is available at Montgomery County lots and garages.","AllDayParking":{"TotalCount":0,"RiderCost":null,"NonRiderCost":null,"SaturdayRiderCost":null,"SaturdayNonRiderCost":null},"ShortTermParking":{"TotalCount":0,"Notes":null}},` +
+				`{"Code":"K08","Notes":"` + viennaNotes + `","AllDayParking":{"TotalCount":5169,"RiderCost":4.95,"NonRiderCost":4.95,"SaturdayRiderCost":0,"SaturdayNonRiderCost":0},"ShortTermParking":{"TotalCount":71,"Notes":"Parking available in section B between 8:30 AM - 3:30 PM and 7 PM - 2 AM, in section D between 10 AM - 2 PM."}},` +
+				`{"Code":"K06","Notes":null,"AllDayParking":{"TotalCount":1320,"RiderCost":4.45,"NonRiderCost":10.0,"SaturdayRiderCost":2.0,"SaturdayNonRiderCost":3.0},"ShortTermParking":{"TotalCount":0,"Notes":null}}` +
+				`]}`,
+		},
+	},
+	"/Rail.svc/json/jStations": {
+		{
+			rawQuery: "LineCode=OR",
+			response: `{"Stations":[{"Code":"C01","Name":"Metro Center","LineCode1":"BL"},{"Code":"K06","Name":"West Falls Church","LineCode1":"OR"},{"Code":"K08","Name":"Vienna/Fairfax-GMU","LineCode1":"OR"}]}`,
+		},
+	},
+}
+
+// setupTestService creates a service struct backed by a rail service with a mock http client
+func setupTestService() *Service {
+	wmataClient := wmata.Client{
+		HTTPClient: &testClient{},
+	}
+
+	return NewService(railinfo.NewService(&wmataClient, wmata.JSON), Config{Holidays: []time.Time{time.Date(2019, time.July, 4, 0, 0, 0, 0, time.UTC)}})
+}
+
+func TestParseNotes(t *testing.T) {
+	notes := ParseNotes(viennaNotes)
+
+	expected := Notes{
+		ShortTermMeteredSpaces: 71,
+		MeteredSpaces: []MeteredSpaces{
+			{Count: 101, MaxHours: 12, Rate: 1, RatePer: time.Hour},
+			{Count: 17, MaxHours: 7, Rate: 1, RatePer: time.Hour},
+		},
+		Opens:    8*time.Hour + 30*time.Minute,
+		Closes:   26 * time.Hour,
+		HasHours: true,
+	}
+
+	if !reflect.DeepEqual(notes, expected) {
+		t.Error(pretty.Diff(notes, expected))
+	}
+
+	if notes := ParseNotes("Parking is available at Montgomery County lots and garages."); !reflect.DeepEqual(notes, Notes{}) {
+		t.Errorf("expected nothing parsed from notes without details: %# v", pretty.Formatter(notes))
+	}
+}
+
+func TestCost(t *testing.T) {
+	service := setupTestService()
+
+	testRequests := []struct {
+		stationCode string
+		date        time.Time
+		rider       RiderType
+		expected    float64
+		err         bool
+	}{
+		{stationCode: "K06", date: time.Date(2019, time.July, 1, 0, 0, 0, 0, time.UTC), rider: Rider, expected: 4.45},
+		{stationCode: "K06", date: time.Date(2019, time.July, 1, 0, 0, 0, 0, time.UTC), rider: NonRider, expected: 10},
+		{stationCode: "K06", date: time.Date(2019, time.July, 6, 0, 0, 0, 0, time.UTC), rider: Rider, expected: 2},
+		{stationCode: "K06", date: time.Date(2019, time.July, 6, 0, 0, 0, 0, time.UTC), rider: NonRider, expected: 3},
+		{stationCode: "K06", date: time.Date(2019, time.July, 7, 0, 0, 0, 0, time.UTC), rider: NonRider, expected: 0},
+		{stationCode: "K06", date: time.Date(2019, time.July, 4, 0, 0, 0, 0, time.UTC), rider: Rider, expected: 0},
+		{stationCode: "B08", date: time.Date(2019, time.July, 1, 0, 0, 0, 0, time.UTC), rider: Rider, err: true},
+		{stationCode: "", date: time.Date(2019, time.July, 1, 0, 0, 0, 0, time.UTC), rider: Rider, err: true},
+	}
+
+	for _, request := range testRequests {
+		cost, err := service.Cost(request.stationCode, request.date, request.rider)
+
+		if request.err {
+			if err == nil {
+				t.Errorf("expected error for station %q", request.stationCode)
+			}
+
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("error calling Cost: %s", err.Error())
+			continue
+		}
+
+		if cost != request.expected {
+			t.Errorf("unexpected cost at %s on %s: expected %.2f, got %.2f", request.stationCode, request.date.Format("Mon"), request.expected, cost)
+		}
+	}
+}
+
+func TestStationsWithParking(t *testing.T) {
+	service := setupTestService()
+
+	stations, err := service.StationsWithParking("OR")
+
+	if err != nil {
+		t.Errorf("error calling StationsWithParking: %s", err.Error())
+		return
+	}
+
+	if len(stations) != 2 || stations[0].StationCode != "K08" || stations[1].StationCode != "K06" {
+		t.Errorf("unexpected stations: %# v", pretty.Formatter(stations))
+		return
+	}
+
+	if stations[0].Capacity() != 5240 || stations[0].Notes == nil || stations[0].Notes.ShortTermMeteredSpaces != 71 {
+		t.Errorf("unexpected parking at K08: %# v", pretty.Formatter(stations[0]))
+	}
+
+	catalog, err := service.Catalog("OR")
+
+	if err != nil {
+		t.Errorf("error calling Catalog: %s", err.Error())
+		return
+	}
+
+	if len(catalog) != 3 || catalog[0].Parking != nil || catalog[0].Notes != nil {
+		t.Errorf("expected Metro Center without parking: %# v", pretty.Formatter(catalog))
+	}
+
+	if _, err := service.StationsWithParking(""); err == nil {
+		t.Error("expected error without a line code")
+	}
+}
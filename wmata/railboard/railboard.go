@@ -0,0 +1,243 @@
+package railboard
+
+import (
+	"errors"
+	"github.com/awiede/wmata-go-sdk/wmata/railinfo"
+	"github.com/awiede/wmata-go-sdk/wmata/railpredictions"
+	"math"
+	"sort"
+)
+
+// RailBoard defines the methods available to build arrival boards for rail stations
+type RailBoard interface {
+	GetBoard(stationCode string) (*Board, error)
+}
+
+var _ RailBoard = (*Service)(nil)
+
+// NewService returns a new RailBoard service built on top of existing RailInfo and RailPredictions services
+func NewService(railInfo railinfo.RailInfo, railPredictions railpredictions.RailPredictions) *Service {
+	return &Service{
+		railInfo:        railInfo,
+		railPredictions: railPredictions,
+	}
+}
+
+// Service builds station arrival boards from realtime rail predictions
+type Service struct {
+	railInfo        railinfo.RailInfo
+	railPredictions railpredictions.RailPredictions
+}
+
+// Board is the arrival board for a station complex, combining every platform of stations linked by StationTogether
+type Board struct {
+	StationName  string     `json:"StationName"`
+	StationCodes []string   `json:"StationCodes"`
+	Platforms    []Platform `json:"Platforms"`
+}
+
+// Platform holds the arrivals for one platform group (track) at one station code
+type Platform struct {
+	StationCode string `json:"StationCode"`
+	Group       string `json:"Group"`
+	Lines       []Line `json:"Lines"`
+}
+
+// Line holds the arrivals for a single line on a platform
+type Line struct {
+	LineCode string    `json:"LineCode"`
+	Arrivals []Arrival `json:"Arrivals"`
+}
+
+// Arrival is a train prediction with its parsed arrival time
+type Arrival struct {
+	railpredictions.Train
+	// MinutesUntil is the parsed arrival in minutes, zero for arriving and boarding trains
+	MinutesUntil int `json:"MinutesUntil"`
+	// Estimated is false when WMATA reported no arrival estimate for the train
+	Estimated bool `json:"Estimated"`
+}
+
+// Arrivals returns every arrival on the platform across all lines, in the order trains will reach the platform
+func (platform *Platform) Arrivals() []Arrival {
+	var arrivals []Arrival
+
+	for _, line := range platform.Lines {
+		arrivals = append(arrivals, line.Arrivals...)
+	}
+
+	sortArrivals(arrivals)
+
+	return arrivals
+}
+
+// GetBoard retrieves predictions for a station and any station it shares a complex with, e.g. Metro Center A01 and C01
+func (service *Service) GetBoard(stationCode string) (*Board, error) {
+	if stationCode == "" {
+		return nil, errors.New("stationCode is a required parameter")
+	}
+
+	station, stationErr := service.railInfo.GetStationInformation(stationCode)
+
+	if stationErr != nil {
+		return nil, stationErr
+	}
+
+	stationCodes := []string{stationCode}
+
+	for _, together := range []string{station.StationTogether1, station.StationTogether2} {
+		if together != "" && together != stationCode {
+			stationCodes = append(stationCodes, together)
+		}
+	}
+
+	predictions, predictionsErr := service.railPredictions.GetNextTrains(stationCodes)
+
+	if predictionsErr != nil {
+		return nil, predictionsErr
+	}
+
+	return BuildBoard(station.Name, stationCodes, predictions.Trains), nil
+}
+
+// BuildBoard groups the trains predicted at any of the given station codes by station, platform group and line
+func BuildBoard(stationName string, stationCodes []string, trains []railpredictions.Train) *Board {
+	board := Board{
+		StationName:  stationName,
+		StationCodes: stationCodes,
+	}
+
+	includedCodes := make(map[string]bool)
+
+	for _, stationCode := range stationCodes {
+		includedCodes[stationCode] = true
+	}
+
+	type platformKey struct {
+		stationCode string
+		group       string
+	}
+
+	platformLines := make(map[platformKey]map[string][]Arrival)
+
+	for _, train := range trains {
+		if !includedCodes[train.LocationCode] {
+			continue
+		}
+
+		key := platformKey{stationCode: train.LocationCode, group: train.Group}
+
+		if platformLines[key] == nil {
+			platformLines[key] = make(map[string][]Arrival)
+		}
+
+		minutes, estimated := train.ParseMinutes()
+
+		platformLines[key][train.Line] = append(platformLines[key][train.Line], Arrival{
+			Train:        train,
+			MinutesUntil: minutes,
+			Estimated:    estimated,
+		})
+	}
+
+	for key, lines := range platformLines {
+		platform := Platform{
+			StationCode: key.stationCode,
+			Group:       key.group,
+		}
+
+		for lineCode, arrivals := range lines {
+			sortArrivals(arrivals)
+
+			platform.Lines = append(platform.Lines, Line{
+				LineCode: lineCode,
+				Arrivals: arrivals,
+			})
+		}
+
+		sort.Slice(platform.Lines, func(i, j int) bool {
+			return platform.Lines[i].LineCode < platform.Lines[j].LineCode
+		})
+
+		board.Platforms = append(board.Platforms, platform)
+	}
+
+	sort.Slice(board.Platforms, func(i, j int) bool {
+		if board.Platforms[i].StationCode != board.Platforms[j].StationCode {
+			return board.Platforms[i].StationCode < board.Platforms[j].StationCode
+		}
+
+		return board.Platforms[i].Group < board.Platforms[j].Group
+	})
+
+	return &board
+}
+
+// BuildBoards builds a board for every station complex in the station list that has predictions, which is useful with
+// predictions for all stations. Stations linked through StationTogether1 share a single board
+func BuildBoards(stations []railinfo.GetStationListResponseItem, trains []railpredictions.Train) []Board {
+	stationsByCode := make(map[string]railinfo.GetStationListResponseItem)
+
+	for _, station := range stations {
+		stationsByCode[station.StationCode] = station
+	}
+
+	predictedCodes := make(map[string]bool)
+
+	for _, train := range trains {
+		predictedCodes[train.LocationCode] = true
+	}
+
+	var boards []Board
+	visited := make(map[string]bool)
+
+	for _, station := range stations {
+		if visited[station.StationCode] {
+			continue
+		}
+
+		var stationCodes []string
+		hasPredictions := false
+
+		for _, stationCode := range []string{station.StationCode, station.StationTogether1, station.StationTogether2} {
+			if stationCode == "" || visited[stationCode] {
+				continue
+			}
+
+			if _, exist := stationsByCode[stationCode]; !exist {
+				continue
+			}
+
+			visited[stationCode] = true
+			stationCodes = append(stationCodes, stationCode)
+			hasPredictions = hasPredictions || predictedCodes[stationCode]
+		}
+
+		if hasPredictions {
+			sort.Strings(stationCodes)
+			boards = append(boards, *BuildBoard(station.Name, stationCodes, trains))
+		}
+	}
+
+	return boards
+}
+
+// sortArrivals orders boarding trains first, then arriving trains, then by minutes, with unestimated trains last
+func sortArrivals(arrivals []Arrival) {
+	sort.SliceStable(arrivals, func(i, j int) bool {
+		return arrivalRank(&arrivals[i]) < arrivalRank(&arrivals[j])
+	})
+}
+
+func arrivalRank(arrival *Arrival) int {
+	switch {
+	case !arrival.Estimated:
+		return math.MaxInt32
+	case arrival.Minutes == railpredictions.MinutesBoarding:
+		return -2
+	case arrival.Minutes == railpredictions.MinutesArriving:
+		return -1
+	default:
+		return arrival.MinutesUntil
+	}
+}
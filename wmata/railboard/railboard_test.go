@@ -0,0 +1,214 @@
+package railboard
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"github.com/awiede/wmata-go-sdk/wmata"
+	"github.com/awiede/wmata-go-sdk/wmata/railinfo"
+	"github.com/awiede/wmata-go-sdk/wmata/railpredictions"
+	"github.com/kr/pretty"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+// testClient is a mock implementation of wmata.HTTPClient interface used for testing purposes
+type testClient struct{}
+
+// ensure testClient implements wmata.HTTPClient interface
+var _ wmata.HTTPClient = (*testClient)(nil)
+
+// Do stubs out an httpClient.Do request
+func (client *testClient) Do(req *http.Request) (*http.Response, error) {
+	testResponses, exist := testData[req.URL.Path]
+
+	if !exist {
+		return nil, errors.New("no test data found")
+	}
+
+	for _, response := range testResponses {
+		if response.rawQuery == req.URL.RawQuery {
+			rr := httptest.NewRecorder()
+			rr.WriteHeader(http.StatusOK)
+			_, writeErr := rr.Write([]byte(response.response))
+
+			return rr.Result(), writeErr
+		}
+	}
+
+	return nil, errors.New("no data found")
+}
+
+type testResponseData struct {
+	rawQuery string
+	response string
+}
+
+const metroCenterPredictions = `{"Trains":[` +
+	`{"Car":"8","Destination":"Glenmont","DestinationCode":"B11","DestinationName":"Glenmont","Group":"1","Line":"RD","LocationCode":"A01","LocationName":"Metro Center","Min":"5"},` +
+	`{"Car":"6","Destination":"Shady Gr","DestinationCode":"A15","DestinationName":"Shady Grove","Group":"2","Line":"RD","LocationCode":"A01","LocationName":"Metro Center","Min":"BRD"},` +
+	`{"Car":"8","Destination":"Glenmont","DestinationCode":"B11","DestinationName":"Glenmont","Group":"1","Line":"RD","LocationCode":"A01","LocationName":"Metro Center","Min":"ARR"},` +
+	`{"Car":"-","Destination":"Train","DestinationCode":"","DestinationName":"Train","Group":"1","Line":"--","LocationCode":"A01","LocationName":"Metro Center","Min":"---"},` +
+	`{"Car":"8","Destination":"Largo","DestinationCode":"G05","DestinationName":"Downtown Largo","Group":"1","Line":"SV","LocationCode":"C01","LocationName":"Metro Center","Min":"7"},` +
+	`{"Car":"6","Destination":"Vienna","DestinationCode":"K08","DestinationName":"Vienna/Fairfax-GMU","Group":"2","Line":"OR","LocationCode":"C01","LocationName":"Metro Center","Min":"3"},` +
+	`{"Car":"8","Destination":"NewCrltn","DestinationCode":"D13","DestinationName":"New Carrollton","Group":"1","Line":"OR","LocationCode":"C01","LocationName":"Metro Center","Min":"2"}` +
+	`]}`
+
+var testData = map[string][]testResponseData{
+	"/Rail.svc/json/jStationInfo": {
+		{
+			rawQuery: "StationCode=A01",
+			response: `{"Code":"A01","Name":"Metro Center","StationTogether1":"C01","StationTogether2":"","LineCode1":"RD","LineCode2":null,"LineCode3":null,"LineCode4":null,"Lat":38.898303,"Lon":-77.028099,"Address":{"Street":"607 13th St. NW","City":"Washington","State":"DC","Zip":"20005"}}`,
+		},
+	},
+	"/StationPrediction.svc/json/GetPrediction/A01,C01": {
+		{
+			rawQuery: "",
+			response: metroCenterPredictions,
+		},
+	},
+}
+
+// setupTestService creates a service struct backed by rail services with a mock http client
+func setupTestService() *Service {
+	wmataClient := wmata.Client{
+		HTTPClient: &testClient{},
+	}
+
+	return NewService(railinfo.NewService(&wmataClient, wmata.JSON), railpredictions.NewService(&wmataClient, wmata.JSON))
+}
+
+// summarize reduces a board to station code, group, line and minutes for comparison
+func summarize(board *Board) []string {
+	var summary []string
+
+	for _, platform := range board.Platforms {
+		for _, line := range platform.Lines {
+			for _, arrival := range line.Arrivals {
+				summary = append(summary, platform.StationCode+"/"+platform.Group+"/"+line.LineCode+"/"+arrival.Minutes)
+			}
+		}
+	}
+
+	return summary
+}
+
+func TestGetBoard(t *testing.T) {
+	testService := setupTestService()
+
+	if _, err := testService.GetBoard(""); err == nil {
+		t.Error("expected error for empty station code")
+	}
+
+	board, err := testService.GetBoard("A01")
+
+	if err != nil {
+		t.Fatalf("error calling GetBoard: %s", err)
+	}
+
+	if board.StationName != "Metro Center" || !reflect.DeepEqual(board.StationCodes, []string{"A01", "C01"}) {
+		t.Errorf("unexpected station: %s %v", board.StationName, board.StationCodes)
+	}
+
+	expected := []string{
+		"A01/1/--/---",
+		"A01/1/RD/ARR",
+		"A01/1/RD/5",
+		"A01/2/RD/BRD",
+		"C01/1/OR/2",
+		"C01/1/SV/7",
+		"C01/2/OR/3",
+	}
+
+	if actual := summarize(board); !reflect.DeepEqual(actual, expected) {
+		t.Error(pretty.Diff(actual, expected))
+	}
+
+	platformArrivals := board.Platforms[0].Arrivals()
+
+	var minutes []string
+
+	for _, arrival := range platformArrivals {
+		minutes = append(minutes, arrival.Minutes)
+	}
+
+	if !reflect.DeepEqual(minutes, []string{"ARR", "5", "---"}) {
+		t.Errorf("unexpected platform order: %v", minutes)
+	}
+}
+
+func TestBuildBoards(t *testing.T) {
+	prediction := railpredictions.GetNextTrainResponse{}
+
+	if err := json.Unmarshal([]byte(metroCenterPredictions), &prediction); err != nil {
+		t.Fatal(err)
+	}
+
+	stations := []railinfo.GetStationListResponseItem{
+		{StationCode: "A01", Name: "Metro Center", StationTogether1: "C01"},
+		{StationCode: "A02", Name: "Farragut North"},
+		{StationCode: "C01", Name: "Metro Center", StationTogether1: "A01"},
+	}
+
+	boards := BuildBoards(stations, prediction.Trains)
+
+	if len(boards) != 1 {
+		t.Fatalf("expected a single combined board, got %d", len(boards))
+	}
+
+	if !reflect.DeepEqual(boards[0].StationCodes, []string{"A01", "C01"}) || len(boards[0].Platforms) != 4 {
+		t.Errorf("unexpected board: %# v", pretty.Formatter(boards[0]))
+	}
+}
+
+func TestRender(t *testing.T) {
+	prediction := railpredictions.GetNextTrainResponse{}
+
+	if err := json.Unmarshal([]byte(metroCenterPredictions), &prediction); err != nil {
+		t.Fatal(err)
+	}
+
+	board := BuildBoard("Metro Center", []string{"A01"}, prediction.Trains)
+
+	var text bytes.Buffer
+
+	if err := board.Render(&text, Text); err != nil {
+		t.Fatalf("error rendering text: %s", err)
+	}
+
+	expected := "METRO CENTER A01 TRACK 1\n" +
+		"LN CAR DEST             MIN\n" +
+		"RD 8   Glenmont         ARR\n" +
+		"RD 8   Glenmont           5\n" +
+		"-- -   Train            ---\n" +
+		"\n" +
+		"METRO CENTER A01 TRACK 2\n" +
+		"LN CAR DEST             MIN\n" +
+		"RD 6   Shady Gr         BRD\n"
+
+	if text.String() != expected {
+		t.Errorf("unexpected text board:\n%s", text.String())
+	}
+
+	var encoded bytes.Buffer
+
+	if err := board.Render(&encoded, JSON); err != nil {
+		t.Fatalf("error rendering json: %s", err)
+	}
+
+	decoded := Board{}
+
+	if err := json.Unmarshal(encoded.Bytes(), &decoded); err != nil {
+		t.Fatalf("error decoding rendered json: %s", err)
+	}
+
+	if !reflect.DeepEqual(&decoded, board) {
+		t.Error(pretty.Diff(&decoded, board))
+	}
+
+	if err := board.Render(&encoded, Format(5)); err == nil || err.Error() != "invalid render format" {
+		t.Errorf("expected invalid format error, got: %v", err)
+	}
+}
@@ -0,0 +1,69 @@
+package railboard
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Format selects the layout used to render a board
+type Format int
+
+const (
+	// Text renders a fixed width layout similar to the passenger information displays in stations
+	Text Format = iota
+	// JSON renders the board structure as indented JSON
+	JSON
+)
+
+// destinationWidth is the number of characters the destination column is padded or truncated to
+const destinationWidth = 16
+
+// Render writes the board to w in the given format
+func (board *Board) Render(w io.Writer, format Format) error {
+	switch format {
+	case Text:
+		return board.renderText(w)
+	case JSON:
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+
+		return encoder.Encode(board)
+	default:
+		return errors.New("invalid render format")
+	}
+}
+
+// renderText writes one section per platform listing trains in arrival order, e.g.
+//
+//	METRO CENTER A01 TRACK 1
+//	LN CAR DEST             MIN
+//	RD 8   Glenmont         BRD
+func (board *Board) renderText(w io.Writer) error {
+	var text strings.Builder
+
+	for i, platform := range board.Platforms {
+		if i > 0 {
+			text.WriteString("\n")
+		}
+
+		fmt.Fprintf(&text, "%s %s TRACK %s\n", strings.ToUpper(board.StationName), platform.StationCode, platform.Group)
+		fmt.Fprintf(&text, "%-2s %-3s %-*s %3s\n", "LN", "CAR", destinationWidth, "DEST", "MIN")
+
+		for _, arrival := range platform.Arrivals() {
+			destination := arrival.Destination
+
+			if len(destination) > destinationWidth {
+				destination = destination[:destinationWidth]
+			}
+
+			fmt.Fprintf(&text, "%-2s %-3s %-*s %3s\n", arrival.Line, arrival.Car, destinationWidth, destination, arrival.Minutes)
+		}
+	}
+
+	_, writeErr := io.WriteString(w, text.String())
+
+	return writeErr
+}
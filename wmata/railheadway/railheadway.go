@@ -0,0 +1,498 @@
+package railheadway
+
+import (
+	"github.com/awiede/wmata-go-sdk/wmata/archive"
+	"github.com/awiede/wmata-go-sdk/wmata/trainpositions"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Defaults used when a Config field is left at zero
+const (
+	DefaultScheduledHeadway    = 8 * time.Minute
+	DefaultGapRatio            = 1.5
+	DefaultMaxHeadway          = time.Hour
+	DefaultHoldDuration        = 3 * time.Minute
+	DefaultMaxSnapshotInterval = 2 * time.Minute
+)
+
+// Config holds the scheduled service and the thresholds used to detect gaps and holds
+type Config struct {
+	// ScheduledHeadway is the scheduled time between trains of a line in each direction
+	ScheduledHeadway time.Duration
+	// LineScheduledHeadways overrides ScheduledHeadway for individual line codes
+	LineScheduledHeadways map[string]time.Duration
+	// GapRatio, headways longer than the scheduled headway times this are gaps
+	GapRatio float64
+	// MaxHeadway, longer times between trains are breaks in service, such as overnight, and are not headways
+	MaxHeadway time.Duration
+	// HoldDuration, trains staying on a circuit at least this long are held
+	HoldDuration time.Duration
+	// MaxSnapshotInterval, stations are only interpolated between positions of a train seen at most this far apart
+	MaxSnapshotInterval time.Duration
+}
+
+// withDefaults returns a copy of the config with zero values replaced by defaults
+func (config Config) withDefaults() Config {
+	if config.ScheduledHeadway <= 0 {
+		config.ScheduledHeadway = DefaultScheduledHeadway
+	}
+
+	if config.GapRatio <= 0 {
+		config.GapRatio = DefaultGapRatio
+	}
+
+	if config.MaxHeadway <= 0 {
+		config.MaxHeadway = DefaultMaxHeadway
+	}
+
+	if config.HoldDuration <= 0 {
+		config.HoldDuration = DefaultHoldDuration
+	}
+
+	if config.MaxSnapshotInterval <= 0 {
+		config.MaxSnapshotInterval = DefaultMaxSnapshotInterval
+	}
+
+	return config
+}
+
+// scheduledHeadway returns the scheduled headway of a line
+func (config *Config) scheduledHeadway(lineCode string) time.Duration {
+	if headway, exist := config.LineScheduledHeadways[lineCode]; exist && headway > 0 {
+		return headway
+	}
+
+	return config.ScheduledHeadway
+}
+
+// Passage is a train arriving at a station
+type Passage struct {
+	TrainID                string    `json:"TrainID"`
+	LineCode               string    `json:"LineCode"`
+	DirectionNumber        int       `json:"DirectionNumber"`
+	DestinationStationCode string    `json:"DestinationStationCode"`
+	StationCode            string    `json:"StationCode"`
+	Time                   time.Time `json:"Time"`
+	// Departure is when the train left the station, zero until it is seen beyond the station
+	Departure time.Time `json:"Departure"`
+	// Interpolated is true when the train was not seen at the station, which it passed between two snapshots
+	Interpolated bool `json:"Interpolated"`
+}
+
+// StationHeadways is the service observed at a station for one line and direction
+type StationHeadways struct {
+	LineCode         string        `json:"LineCode"`
+	DirectionNumber  int           `json:"DirectionNumber"`
+	StationCode      string        `json:"StationCode"`
+	Passages         int           `json:"Passages"`
+	Headways         int           `json:"Headways"`
+	AverageHeadway   time.Duration `json:"AverageHeadway"`
+	MaxHeadway       time.Duration `json:"MaxHeadway"`
+	ScheduledHeadway time.Duration `json:"ScheduledHeadway"`
+	Gaps             int           `json:"Gaps"`
+	// RegularPercent is the share of headways that are not gaps
+	RegularPercent float64 `json:"RegularPercent"`
+}
+
+// Gap is a headway longer than scheduled service allows
+type Gap struct {
+	LineCode        string `json:"LineCode"`
+	DirectionNumber int    `json:"DirectionNumber"`
+	StationCode     string `json:"StationCode"`
+	// PreviousTrainID passed the station at Start and TrainID at End
+	PreviousTrainID  string        `json:"PreviousTrainID"`
+	TrainID          string        `json:"TrainID"`
+	Start            time.Time     `json:"Start"`
+	End              time.Time     `json:"End"`
+	Headway          time.Duration `json:"Headway"`
+	ScheduledHeadway time.Duration `json:"ScheduledHeadway"`
+}
+
+// Hold is a train staying on a circuit for at least the hold duration
+type Hold struct {
+	TrainID         string `json:"TrainID"`
+	LineCode        string `json:"LineCode"`
+	DirectionNumber int    `json:"DirectionNumber"`
+	CircuitID       int    `json:"CircuitID"`
+	// StationCode is empty for holds between stations
+	StationCode string        `json:"StationCode"`
+	Start       time.Time     `json:"Start"`
+	Duration    time.Duration `json:"Duration"`
+}
+
+// Report is the reliability of the service observed
+type Report struct {
+	// Stations are ordered by line, direction and the order trains pass them
+	Stations []StationHeadways `json:"Stations"`
+	// Gaps and Holds are ordered by start time
+	Gaps  []Gap  `json:"Gaps"`
+	Holds []Hold `json:"Holds"`
+}
+
+// route is a standard route with its circuits in order
+type route struct {
+	lineCode  string
+	direction int
+	circuits  []trainpositions.StandardTrackCircuit
+}
+
+// routeCircuit is the place of a circuit on a route
+type routeCircuit struct {
+	route int
+	index int
+}
+
+// stationKey identifies the service at a station for one line and direction
+type stationKey struct {
+	lineCode    string
+	direction   int
+	stationCode string
+}
+
+// train is the last place a train was seen
+type train struct {
+	circuit     routeCircuit
+	circuitID   int
+	seen        time.Time
+	stationCode string
+	// passage is the index of the passage at the station the train is leaving, or -1
+	passage int
+	// hold is the index of the train's hold on its circuit, or -1
+	hold int
+}
+
+// Analyzer derives the times trains pass stations from snapshots of train positions along the standard routes, and
+// measures the headways between them. It is safe for concurrent use
+type Analyzer struct {
+	config   Config
+	routes   []route
+	circuits map[int][]routeCircuit
+	order    map[stationKey]int
+
+	mutex    sync.Mutex
+	trains   map[string]*train
+	passages []Passage
+	holds    []Hold
+}
+
+// NewAnalyzer returns an Analyzer for trains running on the given standard routes
+func NewAnalyzer(routes []trainpositions.Route, config Config) *Analyzer {
+	analyzer := Analyzer{
+		config:   config.withDefaults(),
+		circuits: make(map[int][]routeCircuit),
+		order:    make(map[stationKey]int),
+		trains:   make(map[string]*train),
+	}
+
+	for i, standard := range routes {
+		circuits := make([]trainpositions.StandardTrackCircuit, len(standard.TrackCircuits))
+		copy(circuits, standard.TrackCircuits)
+
+		sort.Slice(circuits, func(i, j int) bool {
+			return circuits[i].SequenceNumber < circuits[j].SequenceNumber
+		})
+
+		analyzer.routes = append(analyzer.routes, route{lineCode: standard.LineCode, direction: standard.TrackNumber, circuits: circuits})
+
+		for j, circuit := range circuits {
+			analyzer.circuits[circuit.CircuitID] = append(analyzer.circuits[circuit.CircuitID], routeCircuit{route: i, index: j})
+
+			if circuit.StationCode == "" {
+				continue
+			}
+
+			key := stationKey{lineCode: standard.LineCode, direction: standard.TrackNumber, stationCode: circuit.StationCode}
+
+			if _, exist := analyzer.order[key]; !exist {
+				analyzer.order[key] = j
+			}
+		}
+	}
+
+	return &analyzer
+}
+
+// Load returns an Analyzer for the standard routes returned by the TrainPositions service
+func Load(trainPositions trainpositions.TrainPositions, config Config) (*Analyzer, error) {
+	routes, routesErr := trainPositions.GetStandardRoutes()
+
+	if routesErr != nil {
+		return nil, routesErr
+	}
+
+	return NewAnalyzer(routes.Routes, config), nil
+}
+
+// AddPositions adds a snapshot of train positions taken at a time. Snapshots must be added in the order they were
+// taken. Trains without passengers and trains on circuits that are not on a standard route of their line are ignored
+func (analyzer *Analyzer) AddPositions(at time.Time, positions []trainpositions.TrainPosition) {
+	analyzer.mutex.Lock()
+	defer analyzer.mutex.Unlock()
+
+	for _, position := range positions {
+		if position.LineCode == "" || position.ServiceType == "NoPassengers" {
+			continue
+		}
+
+		circuit, onRoute := analyzer.locate(&position)
+
+		if !onRoute {
+			continue
+		}
+
+		analyzer.move(at, &position, circuit)
+	}
+}
+
+// AddArchive adds every train position snapshot read by an archive iterator
+func (analyzer *Analyzer) AddArchive(iterator *archive.Iterator) error {
+	for iterator.Next() {
+		snapshot := iterator.Snapshot()
+
+		if snapshot.Kind != archive.KindTrainPositions {
+			continue
+		}
+
+		positions := trainpositions.GetLiveTrainPositionsResponse{}
+
+		if decodeErr := snapshot.Decode(&positions); decodeErr != nil {
+			return decodeErr
+		}
+
+		analyzer.AddPositions(snapshot.Time, positions.Positions)
+	}
+
+	return iterator.Err()
+}
+
+// Passages returns the times trains passed stations, ordered by train and time
+func (analyzer *Analyzer) Passages() []Passage {
+	analyzer.mutex.Lock()
+	defer analyzer.mutex.Unlock()
+
+	passages := make([]Passage, len(analyzer.passages))
+	copy(passages, analyzer.passages)
+
+	sort.SliceStable(passages, func(i, j int) bool {
+		if passages[i].TrainID != passages[j].TrainID {
+			return passages[i].TrainID < passages[j].TrainID
+		}
+
+		return passages[i].Time.Before(passages[j].Time)
+	})
+
+	return passages
+}
+
+// Report measures the headways between the trains passing each station and lists the gaps and holds seen
+func (analyzer *Analyzer) Report() *Report {
+	analyzer.mutex.Lock()
+	defer analyzer.mutex.Unlock()
+
+	stations := make(map[stationKey][]Passage)
+
+	for _, passage := range analyzer.passages {
+		key := stationKey{lineCode: passage.LineCode, direction: passage.DirectionNumber, stationCode: passage.StationCode}
+		stations[key] = append(stations[key], passage)
+	}
+
+	report := Report{Holds: make([]Hold, len(analyzer.holds))}
+	copy(report.Holds, analyzer.holds)
+
+	for key, passages := range stations {
+		sort.SliceStable(passages, func(i, j int) bool {
+			return passages[i].Time.Before(passages[j].Time)
+		})
+
+		scheduled := analyzer.config.scheduledHeadway(key.lineCode)
+		gapHeadway := time.Duration(float64(scheduled) * analyzer.config.GapRatio)
+		headways := StationHeadways{
+			LineCode:         key.lineCode,
+			DirectionNumber:  key.direction,
+			StationCode:      key.stationCode,
+			Passages:         len(passages),
+			ScheduledHeadway: scheduled,
+		}
+
+		var total time.Duration
+
+		for i := 1; i < len(passages); i++ {
+			headway := passages[i].Time.Sub(passages[i-1].Time)
+
+			if headway > analyzer.config.MaxHeadway {
+				continue
+			}
+
+			headways.Headways++
+			total += headway
+
+			if headway > headways.MaxHeadway {
+				headways.MaxHeadway = headway
+			}
+
+			if headway > gapHeadway {
+				headways.Gaps++
+				report.Gaps = append(report.Gaps, Gap{
+					LineCode:         key.lineCode,
+					DirectionNumber:  key.direction,
+					StationCode:      key.stationCode,
+					PreviousTrainID:  passages[i-1].TrainID,
+					TrainID:          passages[i].TrainID,
+					Start:            passages[i-1].Time,
+					End:              passages[i].Time,
+					Headway:          headway,
+					ScheduledHeadway: scheduled,
+				})
+			}
+		}
+
+		if headways.Headways > 0 {
+			headways.AverageHeadway = total / time.Duration(headways.Headways)
+			headways.RegularPercent = float64(headways.Headways-headways.Gaps) * 100 / float64(headways.Headways)
+		}
+
+		report.Stations = append(report.Stations, headways)
+	}
+
+	sort.Slice(report.Stations, func(i, j int) bool {
+		a, b := report.Stations[i], report.Stations[j]
+
+		if a.LineCode != b.LineCode {
+			return a.LineCode < b.LineCode
+		}
+
+		if a.DirectionNumber != b.DirectionNumber {
+			return a.DirectionNumber < b.DirectionNumber
+		}
+
+		return analyzer.order[stationKey{a.LineCode, a.DirectionNumber, a.StationCode}] <
+			analyzer.order[stationKey{b.LineCode, b.DirectionNumber, b.StationCode}]
+	})
+
+	sort.SliceStable(report.Gaps, func(i, j int) bool {
+		a, b := report.Gaps[i], report.Gaps[j]
+
+		if !a.Start.Equal(b.Start) {
+			return a.Start.Before(b.Start)
+		}
+
+		if a.LineCode != b.LineCode {
+			return a.LineCode < b.LineCode
+		}
+
+		if a.DirectionNumber != b.DirectionNumber {
+			return a.DirectionNumber < b.DirectionNumber
+		}
+
+		return a.StationCode < b.StationCode
+	})
+
+	sort.SliceStable(report.Holds, func(i, j int) bool {
+		return report.Holds[i].Start.Before(report.Holds[j].Start)
+	})
+
+	return &report
+}
+
+// locate finds the circuit of a position on a standard route of the train's line and direction
+func (analyzer *Analyzer) locate(position *trainpositions.TrainPosition) (routeCircuit, bool) {
+	for _, circuit := range analyzer.circuits[position.CircuitID] {
+		route := &analyzer.routes[circuit.route]
+
+		if route.lineCode == position.LineCode && route.direction == position.DirectionNumber {
+			return circuit, true
+		}
+	}
+
+	return routeCircuit{}, false
+}
+
+// move records the stations a train passed since it was last seen and any hold on its circuit
+func (analyzer *Analyzer) move(at time.Time, position *trainpositions.TrainPosition, circuit routeCircuit) {
+	route := &analyzer.routes[circuit.route]
+	arrived := at.Add(-time.Duration(position.SecondsAtLocation) * time.Second)
+	previous, seen := analyzer.trains[position.TrainID]
+
+	if !seen || previous.circuitID != position.CircuitID {
+		current := train{circuit: circuit, circuitID: position.CircuitID, passage: -1, hold: -1}
+
+		if seen {
+			current.passage = previous.passage
+		}
+
+		if seen && previous.circuit.route == circuit.route {
+			current.stationCode = previous.stationCode
+		}
+
+		// stations between the circuit the train was last seen on and its circuit now were passed in between
+		if seen && previous.circuit.route == circuit.route && previous.circuit.index < circuit.index && at.Sub(previous.seen) <= analyzer.config.MaxSnapshotInterval {
+			if arrived.Before(previous.seen) {
+				arrived = previous.seen
+			}
+
+			steps := circuit.index - previous.circuit.index
+
+			for i := previous.circuit.index + 1; i < circuit.index; i++ {
+				passed := previous.seen.Add(arrived.Sub(previous.seen) * time.Duration(i-previous.circuit.index) / time.Duration(steps))
+				analyzer.pass(&current, position, route, route.circuits[i].StationCode, passed, true)
+			}
+		}
+
+		analyzer.pass(&current, position, route, route.circuits[circuit.index].StationCode, arrived, false)
+		analyzer.trains[position.TrainID] = &current
+		previous = &current
+	}
+
+	previous.seen = at
+
+	if time.Duration(position.SecondsAtLocation)*time.Second < analyzer.config.HoldDuration {
+		return
+	}
+
+	if previous.hold < 0 {
+		previous.hold = len(analyzer.holds)
+		analyzer.holds = append(analyzer.holds, Hold{
+			TrainID:         position.TrainID,
+			LineCode:        route.lineCode,
+			DirectionNumber: route.direction,
+			CircuitID:       position.CircuitID,
+			StationCode:     route.circuits[circuit.index].StationCode,
+			Start:           arrived,
+		})
+	}
+
+	if duration := time.Duration(position.SecondsAtLocation) * time.Second; duration > analyzer.holds[previous.hold].Duration {
+		analyzer.holds[previous.hold].Duration = duration
+	}
+}
+
+// pass records a train arriving at a circuit, and at a station once however many circuits the station's platform spans
+func (analyzer *Analyzer) pass(current *train, position *trainpositions.TrainPosition, route *route, stationCode string, at time.Time, interpolated bool) {
+	if stationCode != "" && stationCode == current.stationCode {
+		return
+	}
+
+	if current.passage >= 0 {
+		analyzer.passages[current.passage].Departure = at
+		current.passage = -1
+	}
+
+	current.stationCode = stationCode
+
+	if stationCode == "" {
+		return
+	}
+
+	current.passage = len(analyzer.passages)
+	analyzer.passages = append(analyzer.passages, Passage{
+		TrainID:                position.TrainID,
+		LineCode:               route.lineCode,
+		DirectionNumber:        route.direction,
+		DestinationStationCode: position.DestinationStationCode,
+		StationCode:            stationCode,
+		Time:                   at,
+		Interpolated:           interpolated,
+	})
+}
@@ -0,0 +1,315 @@
+package railheadway
+
+import (
+	"errors"
+	"github.com/awiede/wmata-go-sdk/wmata/archive"
+	"github.com/awiede/wmata-go-sdk/wmata/mocks"
+	"github.com/awiede/wmata-go-sdk/wmata/simulator"
+	"github.com/awiede/wmata-go-sdk/wmata/trainpositions"
+	"github.com/awiede/wmata-go-sdk/wmata/wmatatest"
+	"github.com/kr/pretty"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// noon is a multiple of the simulator's default train headway, so the first train of each route leaves on the hour
+var noon = time.Date(2019, 4, 29, 12, 0, 0, 0, time.UTC)
+
+func position(trainID, lineCode string, direction, circuitID, seconds int) trainpositions.TrainPosition {
+	return trainpositions.TrainPosition{
+		CircuitID:              circuitID,
+		DestinationStationCode: map[int]string{1: "B01", 2: "A03"}[direction],
+		DirectionNumber:        direction,
+		LineCode:               lineCode,
+		SecondsAtLocation:      seconds,
+		ServiceType:            "Normal",
+		TrainID:                trainID,
+	}
+}
+
+func TestPassagesAndHolds(t *testing.T) {
+	analyzer := NewAnalyzer(wmatatest.StandardRoutes().Routes, Config{})
+
+	snapshots := []struct {
+		at        time.Duration
+		positions []trainpositions.TrainPosition
+	}{
+		{
+			at: 0,
+			positions: []trainpositions.TrainPosition{
+				position("101", "RD", 1, 1101, 20),
+				position("102", "RD", 2, 2103, 41),
+				{TrainID: "103", CircuitID: 2101, DirectionNumber: 2, SecondsAtLocation: 320, ServiceType: "NoPassengers"},
+				// not on a Blue Line route
+				position("104", "BL", 1, 1101, 0),
+			},
+		},
+		{at: 30 * time.Second, positions: []trainpositions.TrainPosition{position("101", "RD", 1, 1102, 5)}},
+		// passed A02 between snapshots
+		{at: 100 * time.Second, positions: []trainpositions.TrainPosition{position("101", "RD", 1, 1105, 10)}},
+		{at: 300 * time.Second, positions: []trainpositions.TrainPosition{position("101", "RD", 1, 1105, 210)}},
+		{at: 330 * time.Second, positions: []trainpositions.TrainPosition{position("101", "RD", 1, 1105, 240)}},
+		{at: 340 * time.Second, positions: []trainpositions.TrainPosition{position("101", "RD", 1, 1106, 0)}},
+		{at: 600 * time.Second, positions: []trainpositions.TrainPosition{position("101", "RD", 1, 1107, 10)}},
+	}
+
+	for _, snapshot := range snapshots {
+		analyzer.AddPositions(noon.Add(snapshot.at), snapshot.positions)
+	}
+
+	expectedPassages := []Passage{
+		{TrainID: "101", LineCode: "RD", DirectionNumber: 1, DestinationStationCode: "B01", StationCode: "A03", Time: noon.Add(-20 * time.Second), Departure: noon.Add(25 * time.Second)},
+		{TrainID: "101", LineCode: "RD", DirectionNumber: 1, DestinationStationCode: "B01", StationCode: "A02", Time: noon.Add(50 * time.Second), Departure: noon.Add(70 * time.Second), Interpolated: true},
+		{TrainID: "101", LineCode: "RD", DirectionNumber: 1, DestinationStationCode: "B01", StationCode: "A01", Time: noon.Add(90 * time.Second), Departure: noon.Add(340 * time.Second)},
+		{TrainID: "101", LineCode: "RD", DirectionNumber: 1, DestinationStationCode: "B01", StationCode: "B01", Time: noon.Add(590 * time.Second)},
+		{TrainID: "102", LineCode: "RD", DirectionNumber: 2, DestinationStationCode: "A03", StationCode: "A01", Time: noon.Add(-41 * time.Second)},
+	}
+
+	if passages := analyzer.Passages(); !reflect.DeepEqual(passages, expectedPassages) {
+		t.Errorf("unexpected passages: %s", pretty.Diff(passages, expectedPassages))
+	}
+
+	expectedHolds := []Hold{
+		{
+			TrainID:         "101",
+			LineCode:        "RD",
+			DirectionNumber: 1,
+			CircuitID:       1105,
+			StationCode:     "A01",
+			Start:           noon.Add(90 * time.Second),
+			Duration:        240 * time.Second,
+		},
+	}
+
+	if holds := analyzer.Report().Holds; !reflect.DeepEqual(holds, expectedHolds) {
+		t.Errorf("unexpected holds: %s", pretty.Diff(holds, expectedHolds))
+	}
+}
+
+func newTestSimulator(t *testing.T) *simulator.Simulator {
+	network := simulator.Network{
+		StandardRoutes: wmatatest.StandardRoutes().Routes,
+		TrackCircuits:  wmatatest.TrackCircuits().TrackCircuits,
+		Stations:       wmatatest.Stations().Stations,
+	}
+
+	trains, simulatorErr := simulator.NewSimulator(&network, simulator.Config{})
+
+	if simulatorErr != nil {
+		t.Fatalf("error creating simulator: %s", simulatorErr)
+	}
+
+	return trains
+}
+
+// simulate adds two hours of simulated train positions sampled once a minute, which is too rarely to see every train at
+// every station, leaving out the trains skip returns true for
+func simulate(t *testing.T, analyzer *Analyzer, skip func(trainID string) bool) {
+	trains := newTestSimulator(t)
+
+	for at := noon; at.Before(noon.Add(2 * time.Hour)); at = at.Add(time.Minute) {
+		var positions []trainpositions.TrainPosition
+
+		for _, position := range trains.LiveTrainPositions(at).Positions {
+			if !skip(position.TrainID) {
+				positions = append(positions, position)
+			}
+		}
+
+		analyzer.AddPositions(at, positions)
+	}
+}
+
+func TestHeadways(t *testing.T) {
+	analyzer := NewAnalyzer(wmatatest.StandardRoutes().Routes, Config{LineScheduledHeadways: map[string]time.Duration{"RD": 6 * time.Minute}})
+	simulate(t, analyzer, func(string) bool { return false })
+
+	report := analyzer.Report()
+
+	if len(report.Gaps) != 0 || len(report.Holds) != 0 {
+		t.Errorf("expected no gaps or holds, got %# v", pretty.Formatter(report))
+	}
+
+	var stationCodes []string
+
+	for _, station := range report.Stations {
+		stationCodes = append(stationCodes, strconv.Itoa(station.DirectionNumber)+station.StationCode)
+
+		// trains are simulated on schedule, so the scheduled headway is seen whenever trains are sampled
+		if station.AverageHeadway != 8*time.Minute || station.MaxHeadway != 8*time.Minute || station.ScheduledHeadway != 6*time.Minute || station.RegularPercent != 100 {
+			t.Errorf("unexpected headways: %# v", pretty.Formatter(station))
+		}
+	}
+
+	expectedStationCodes := []string{"1A03", "1A02", "1A01", "1B01", "2B01", "2A01", "2A02", "2A03"}
+
+	if !reflect.DeepEqual(stationCodes, expectedStationCodes) {
+		t.Errorf("unexpected station order: %s", pretty.Diff(stationCodes, expectedStationCodes))
+	}
+
+	interpolated := 0
+
+	for _, passage := range analyzer.Passages() {
+		if passage.Interpolated {
+			interpolated++
+		}
+	}
+
+	if interpolated == 0 {
+		t.Error("expected passages between snapshots to be interpolated")
+	}
+}
+
+func TestGaps(t *testing.T) {
+	// the sixth train on track 1 after noon does not run
+	k := noon.Unix()/int64(simulator.DefaultTrainHeadway/time.Second) + 5
+	missing := strconv.FormatInt(1000+k%1000, 10)
+
+	analyzer := NewAnalyzer(wmatatest.StandardRoutes().Routes, Config{})
+	simulate(t, analyzer, func(trainID string) bool { return trainID == missing })
+
+	report := analyzer.Report()
+
+	if len(report.Gaps) != 4 {
+		t.Fatalf("expected a gap at each track 1 station, got %# v", pretty.Formatter(report.Gaps))
+	}
+
+	for i, gap := range report.Gaps {
+		expected := Gap{
+			LineCode:         "RD",
+			DirectionNumber:  1,
+			StationCode:      []string{"A03", "A02", "A01", "B01"}[i],
+			PreviousTrainID:  strconv.FormatInt(1000+(k-1)%1000, 10),
+			TrainID:          strconv.FormatInt(1000+(k+1)%1000, 10),
+			Start:            gap.End.Add(-16 * time.Minute),
+			End:              gap.End,
+			Headway:          16 * time.Minute,
+			ScheduledHeadway: DefaultScheduledHeadway,
+		}
+
+		if !reflect.DeepEqual(gap, expected) {
+			t.Errorf("unexpected gap: %s", pretty.Diff(gap, expected))
+		}
+	}
+
+	for _, station := range report.Stations {
+		if station.DirectionNumber == 1 && (station.Gaps != 1 || station.MaxHeadway != 16*time.Minute || station.RegularPercent == 100) {
+			t.Errorf("expected a gap: %# v", pretty.Formatter(station))
+		}
+	}
+}
+
+func TestMaxHeadway(t *testing.T) {
+	analyzer := NewAnalyzer(wmatatest.StandardRoutes().Routes, Config{MaxHeadway: 20 * time.Minute})
+
+	analyzer.AddPositions(noon, []trainpositions.TrainPosition{position("101", "RD", 1, 1101, 0)})
+	analyzer.AddPositions(noon.Add(10*time.Minute), []trainpositions.TrainPosition{position("102", "RD", 1, 1101, 0)})
+	// the next train after a break in service
+	analyzer.AddPositions(noon.Add(time.Hour), []trainpositions.TrainPosition{position("103", "RD", 1, 1101, 0)})
+
+	expected := []StationHeadways{
+		{
+			LineCode:         "RD",
+			DirectionNumber:  1,
+			StationCode:      "A03",
+			Passages:         3,
+			Headways:         1,
+			AverageHeadway:   10 * time.Minute,
+			MaxHeadway:       10 * time.Minute,
+			ScheduledHeadway: DefaultScheduledHeadway,
+			RegularPercent:   100,
+		},
+	}
+
+	if stations := analyzer.Report().Stations; !reflect.DeepEqual(stations, expected) {
+		t.Errorf("unexpected stations: %s", pretty.Diff(stations, expected))
+	}
+}
+
+func TestAddArchive(t *testing.T) {
+	directory, tempErr := ioutil.TempDir("", "railheadway")
+
+	if tempErr != nil {
+		t.Fatalf("error creating archive directory: %s", tempErr)
+	}
+
+	defer os.RemoveAll(directory)
+
+	writer, openErr := archive.OpenWriter(directory, archive.WriterConfig{})
+
+	if openErr != nil {
+		t.Fatalf("error opening writer: %s", openErr)
+	}
+
+	trains := newTestSimulator(t)
+
+	for at := noon; at.Before(noon.Add(time.Hour)); at = at.Add(20 * time.Second) {
+		snapshot := trains.Snapshot(at)
+
+		for _, kind := range []archive.Kind{archive.KindTrainPositions, archive.KindNextTrains} {
+			response := interface{}(snapshot.TrainPositions)
+
+			if kind == archive.KindNextTrains {
+				response = snapshot.NextTrains
+			}
+
+			archived, snapshotErr := archive.NewSnapshot(kind, at, response)
+
+			if snapshotErr != nil {
+				t.Fatalf("error creating snapshot: %s", snapshotErr)
+			}
+
+			if writeErr := writer.Write(archived); writeErr != nil {
+				t.Fatalf("error writing snapshot: %s", writeErr)
+			}
+		}
+	}
+
+	if closeErr := writer.Close(); closeErr != nil {
+		t.Fatalf("error closing writer: %s", closeErr)
+	}
+
+	reader, readerErr := archive.OpenReader(directory)
+
+	if readerErr != nil {
+		t.Fatalf("error opening reader: %s", readerErr)
+	}
+
+	trainPositions := mocks.TrainPositions{StandardRoutes: wmatatest.StandardRoutes().Routes}
+	analyzer, loadErr := Load(&trainPositions, Config{})
+
+	if loadErr != nil {
+		t.Fatalf("error loading analyzer: %s", loadErr)
+	}
+
+	if addErr := analyzer.AddArchive(reader.All()); addErr != nil {
+		t.Fatalf("error adding archive: %s", addErr)
+	}
+
+	expected := NewAnalyzer(wmatatest.StandardRoutes().Routes, Config{})
+
+	for at := noon; at.Before(noon.Add(time.Hour)); at = at.Add(20 * time.Second) {
+		expected.AddPositions(at, trains.LiveTrainPositions(at).Positions)
+	}
+
+	// archived times lose their location, compare in UTC
+	passages := analyzer.Passages()
+
+	for i := range passages {
+		passages[i].Time = passages[i].Time.UTC()
+	}
+
+	if expectedPassages := expected.Passages(); len(expectedPassages) == 0 || !reflect.DeepEqual(passages, expectedPassages) {
+		t.Errorf("unexpected passages: %s", pretty.Diff(passages, expectedPassages))
+	}
+
+	trainPositions.Fail("GetStandardRoutes", errors.New("service unavailable"))
+
+	if _, loadErr := Load(&trainPositions, Config{}); loadErr == nil {
+		t.Error("expected error loading standard routes")
+	}
+}
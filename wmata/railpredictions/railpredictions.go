@@ -3,11 +3,19 @@ package railpredictions
 import (
 	"encoding/xml"
 	"github.com/awiede/wmata-go-sdk/wmata"
+	"strconv"
 	"strings"
 )
 
 const railPredictionsServiceBaseURL = "https://api.wmata.com/StationPrediction.svc"
 
+const (
+	// MinutesArriving is reported in Train.Minutes when a train is arriving at the station
+	MinutesArriving = "ARR"
+	// MinutesBoarding is reported in Train.Minutes when a train is boarding at the station
+	MinutesBoarding = "BRD"
+)
+
 type GetNextTrainResponse struct {
 	XMLName xml.Name `json:"-" xml:"http://www.wmata.com AIMPredictionResp"`
 	Trains  []Train  `json:"Trains" xml:"Trains>AIMPredictionTrainInfo"`
@@ -25,6 +33,23 @@ type Train struct {
 	Minutes         string `json:"Min" xml:"Min"`
 }
 
+// ParseMinutes returns the number of minutes until the train reaches the station, with arriving and boarding trains
+// reported as zero. The boolean is false when WMATA has no estimate for the train, e.g. "---" or an empty value
+func (train *Train) ParseMinutes() (int, bool) {
+	switch train.Minutes {
+	case MinutesArriving, MinutesBoarding:
+		return 0, true
+	}
+
+	minutes, parseErr := strconv.Atoi(train.Minutes)
+
+	if parseErr != nil {
+		return 0, false
+	}
+
+	return minutes, true
+}
+
 // RailPredictions defines the method available in the WMATA "Real-Time Rail Predictions" API
 type RailPredictions interface {
 	GetNextTrains(stationCodes []string) (*GetNextTrainResponse, error)
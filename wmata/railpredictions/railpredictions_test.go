@@ -9264,3 +9264,26 @@ func TestGetNextTrains(t *testing.T) {
 		}
 	}
 }
+
+func TestParseMinutes(t *testing.T) {
+	tests := []struct {
+		minutes  string
+		expected int
+		ok       bool
+	}{
+		{minutes: "5", expected: 5, ok: true},
+		{minutes: MinutesArriving, expected: 0, ok: true},
+		{minutes: MinutesBoarding, expected: 0, ok: true},
+		{minutes: "---", expected: 0, ok: false},
+		{minutes: "", expected: 0, ok: false},
+	}
+
+	for _, test := range tests {
+		train := Train{Minutes: test.minutes}
+		minutes, ok := train.ParseMinutes()
+
+		if minutes != test.expected || ok != test.ok {
+			t.Errorf("unexpected result parsing %q: %d, %t", test.minutes, minutes, ok)
+		}
+	}
+}
@@ -0,0 +1,57 @@
+package wmata
+
+import (
+	"sync"
+	"time"
+)
+
+// DefaultRequestsPerSecond is the request rate allowed by the WMATA default API tier
+const DefaultRequestsPerSecond = 10
+
+// RateLimiter limits how often requests are sent to the WMATA API
+type RateLimiter interface {
+	// Wait blocks until the next request is allowed to be sent
+	Wait()
+}
+
+// NewRateLimiter returns a RateLimiter that spaces requests evenly so no more than requestsPerSecond are sent each second.
+// A requestsPerSecond of zero or less returns a RateLimiter that never blocks
+func NewRateLimiter(requestsPerSecond int) RateLimiter {
+	if requestsPerSecond <= 0 {
+		return &intervalRateLimiter{}
+	}
+
+	return &intervalRateLimiter{
+		interval: time.Second / time.Duration(requestsPerSecond),
+	}
+}
+
+// intervalRateLimiter hands out request slots at a fixed interval and is safe for concurrent use
+type intervalRateLimiter struct {
+	mutex    sync.Mutex
+	interval time.Duration
+	next     time.Time
+}
+
+func (limiter *intervalRateLimiter) Wait() {
+	if limiter.interval == 0 {
+		return
+	}
+
+	limiter.mutex.Lock()
+
+	now := time.Now()
+
+	if limiter.next.Before(now) {
+		limiter.next = now
+	}
+
+	wait := limiter.next.Sub(now)
+	limiter.next = limiter.next.Add(limiter.interval)
+
+	limiter.mutex.Unlock()
+
+	if wait > 0 {
+		time.Sleep(wait)
+	}
+}
@@ -0,0 +1,187 @@
+package simulator
+
+import (
+	"fmt"
+	"github.com/awiede/wmata-go-sdk/wmata"
+	"github.com/awiede/wmata-go-sdk/wmata/businfo"
+	"github.com/awiede/wmata-go-sdk/wmata/buspredictions"
+	"github.com/awiede/wmata-go-sdk/wmata/linearref"
+	"hash/fnv"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// busDirection is one direction of a bus route prepared for moving buses along its shape
+type busDirection struct {
+	index     int
+	offset    time.Duration
+	routeID   string
+	direction *businfo.Direction
+	shape     *linearref.Shape
+	// duration is the time a bus takes to run the whole shape
+	duration time.Duration
+	// maxDelay is the latest a trip starts after its scheduled time
+	maxDelay time.Duration
+	speed    float64
+}
+
+func newBusDirection(routeID string, direction *businfo.Direction, config Config) *busDirection {
+	shape := linearref.NewShape(direction)
+
+	return &busDirection{
+		routeID:   routeID,
+		direction: direction,
+		shape:     shape,
+		duration:  travelTime(shape.Length(), config.BusSpeed),
+		maxDelay:  config.MaxBusDeviation / time.Minute * time.Minute,
+		speed:     config.BusSpeed,
+	}
+}
+
+// BusPositions returns the position of every bus at a time, filtered by route and radius as the API does when request
+// is not nil
+func (simulator *Simulator) BusPositions(request *businfo.GetPositionsRequest, at time.Time) *businfo.GetPositionsResponse {
+	if request == nil {
+		request = &businfo.GetPositionsRequest{}
+	}
+
+	response := businfo.GetPositionsResponse{}
+	headway := simulator.config.BusHeadway
+
+	for _, direction := range simulator.busDirections {
+		if request.RouteID != "" && direction.routeID != request.RouteID {
+			continue
+		}
+
+		// buses on the route started within the time it takes to run it, or up to the latest delay before that
+		first, last := departures(at.Add(-direction.duration-direction.maxDelay+1), at, headway, direction.offset)
+
+		for k := last; k >= first; k-- {
+			elapsed := at.Sub(departure(k, headway, direction.offset).Add(direction.delay(k)))
+
+			if elapsed < 0 || elapsed >= direction.duration {
+				continue
+			}
+
+			position := direction.bus(k, headway, at)
+			position.Latitude, position.Longitude = direction.shape.PointAt(elapsed.Seconds() * direction.speed)
+
+			if request.Radius > 0 && linearref.Distance(request.Latitude, request.Longitude, position.Latitude, position.Longitude) > request.Radius {
+				continue
+			}
+
+			response.BusPositions = append(response.BusPositions, position)
+		}
+	}
+
+	return &response
+}
+
+// NextBuses returns the predictions for buses arriving at a stop at a time. The response is empty for unknown stops
+func (simulator *Simulator) NextBuses(stopID string, at time.Time) *buspredictions.GetNextBusResponse {
+	response := buspredictions.GetNextBusResponse{StopName: simulator.stopNames[stopID]}
+	headway := simulator.config.BusHeadway
+
+	var remainders []time.Duration
+
+	for _, direction := range simulator.busDirections {
+		distance, onRoute := direction.shape.StopDistance(stopID)
+
+		if !onRoute {
+			continue
+		}
+
+		travel := travelTime(distance, direction.speed)
+
+		// buses that have not passed the stop yet and reach it within the prediction horizon
+		first, last := departures(
+			at.Add(-travel-direction.maxDelay),
+			at.Add(simulator.config.PredictionHorizon-travel),
+			headway,
+			direction.offset,
+		)
+
+		for k := first; k <= last; k++ {
+			remaining := departure(k, headway, direction.offset).Add(direction.delay(k) + travel).Sub(at)
+
+			if remaining < 0 || remaining > simulator.config.PredictionHorizon {
+				continue
+			}
+
+			bus := direction.bus(k, headway, at)
+
+			response.NextBusPredictions = append(response.NextBusPredictions, buspredictions.NextBusPrediction{
+				DirectionNumber: direction.direction.DirectionNumber,
+				DirectionText:   direction.direction.DirectionText,
+				Minutes:         int(remaining / time.Minute),
+				RouteID:         direction.routeID,
+				TripID:          bus.TripID,
+				VehicleID:       bus.VehicleID,
+			})
+
+			remainders = append(remainders, remaining)
+		}
+	}
+
+	sort.Sort(byRemaining{predictions: response.NextBusPredictions, remainders: remainders})
+
+	return &response
+}
+
+// bus returns the position of departure k reported at a time with everything but its location filled in
+func (direction *busDirection) bus(k int64, headway time.Duration, at time.Time) businfo.BusPosition {
+	start := departure(k, headway, direction.offset).In(at.Location())
+	directionNumber, _ := strconv.Atoi(direction.direction.DirectionNumber)
+
+	return businfo.BusPosition{
+		BlockNumber:     fmt.Sprintf("%s-%02d", direction.routeID, mod(k, 100)),
+		DateTime:        at.Format(wmata.DateTimeLayout),
+		Deviation:       int(direction.delay(k) / time.Minute),
+		DirectionNumber: directionNumber,
+		DirectionText:   direction.direction.DirectionText,
+		RouteID:         direction.routeID,
+		TripEndTime:     start.Add(direction.duration).Format(wmata.DateTimeLayout),
+		TripDestination: direction.direction.TripDestination,
+		TripID:          strconv.FormatInt(int64(direction.index+1)*10000000+mod(k, 10000000), 10),
+		TripStartTime:   start.Format(wmata.DateTimeLayout),
+		VehicleID:       strconv.FormatInt(1000+int64(direction.index)*100+mod(k, 100), 10),
+	}
+}
+
+// delay returns how late departure k starts, a whole number of minutes up to maxDelay picked by hashing the trip so
+// it is the same at every time
+func (direction *busDirection) delay(k int64) time.Duration {
+	if direction.maxDelay <= 0 {
+		return 0
+	}
+
+	hash := fnv.New32a()
+	fmt.Fprintf(hash, "%s/%s/%d", direction.routeID, direction.direction.DirectionNumber, k)
+
+	return time.Duration(hash.Sum32()%uint32(direction.maxDelay/time.Minute+1)) * time.Minute
+}
+
+// travelTime returns the time taken to cover a distance in meters at a speed in meters per second
+func travelTime(distance, speed float64) time.Duration {
+	return time.Duration(distance / speed * float64(time.Second))
+}
+
+// byRemaining sorts predictions by the time until the bus reaches the stop
+type byRemaining struct {
+	predictions []buspredictions.NextBusPrediction
+	remainders  []time.Duration
+}
+
+func (sorter byRemaining) Len() int {
+	return len(sorter.predictions)
+}
+
+func (sorter byRemaining) Less(i, j int) bool {
+	return sorter.remainders[i] < sorter.remainders[j]
+}
+
+func (sorter byRemaining) Swap(i, j int) {
+	sorter.predictions[i], sorter.predictions[j] = sorter.predictions[j], sorter.predictions[i]
+	sorter.remainders[i], sorter.remainders[j] = sorter.remainders[j], sorter.remainders[i]
+}
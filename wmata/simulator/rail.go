@@ -0,0 +1,204 @@
+package simulator
+
+import (
+	"fmt"
+	"github.com/awiede/wmata-go-sdk/wmata/railpredictions"
+	"github.com/awiede/wmata-go-sdk/wmata/trainpositions"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// trainRoute is a standard route with the time a train takes to reach each of its circuits
+type trainRoute struct {
+	index    int
+	offset   time.Duration
+	lineCode string
+	track    int
+	circuits []trainpositions.StandardTrackCircuit
+	// arrivals are the times after starting the route at which a train reaches each circuit
+	arrivals []time.Duration
+	// stays are the times a train spends on each circuit, including the dwell at stations
+	stays []time.Duration
+	// duration is the time a train takes to run the whole route
+	duration    time.Duration
+	destination string
+}
+
+func newTrainRoute(route *trainpositions.Route, config Config) *trainRoute {
+	circuits := orderCircuits(route.TrackCircuits)
+
+	trainRoute := trainRoute{
+		lineCode: route.LineCode,
+		track:    route.TrackNumber,
+		circuits: circuits,
+		arrivals: make([]time.Duration, len(circuits)),
+		stays:    make([]time.Duration, len(circuits)),
+	}
+
+	for i, circuit := range circuits {
+		trainRoute.arrivals[i] = trainRoute.duration
+		trainRoute.stays[i] = config.CircuitTime
+
+		if circuit.StationCode != "" {
+			trainRoute.stays[i] += config.DwellTime
+			trainRoute.destination = circuit.StationCode
+		}
+
+		trainRoute.duration += trainRoute.stays[i]
+	}
+
+	return &trainRoute
+}
+
+// LiveTrainPositions returns the position of every train at a time
+func (simulator *Simulator) LiveTrainPositions(at time.Time) *trainpositions.GetLiveTrainPositionsResponse {
+	response := trainpositions.GetLiveTrainPositionsResponse{}
+	headway := simulator.config.TrainHeadway
+
+	for _, route := range simulator.trainRoutes {
+		// trains on the route started within the time it takes to run it
+		first, last := departures(at.Add(-route.duration+1), at, headway, route.offset)
+
+		for k := last; k >= first; k-- {
+			elapsed := at.Sub(departure(k, headway, route.offset))
+
+			i := sort.Search(len(route.arrivals), func(i int) bool {
+				return route.arrivals[i] > elapsed
+			}) - 1
+
+			position := route.train(k)
+			position.CircuitID = route.circuits[i].CircuitID
+			position.SecondsAtLocation = int((elapsed - route.arrivals[i]) / time.Second)
+
+			response.Positions = append(response.Positions, position)
+		}
+	}
+
+	return &response
+}
+
+// NextTrains returns the predictions for trains arriving at the given stations at a time, or at every station when
+// no station codes are given. Trains are not predicted at the station they terminate at
+func (simulator *Simulator) NextTrains(stationCodes []string, at time.Time) *railpredictions.GetNextTrainResponse {
+	if len(stationCodes) == 0 {
+		stationCodes = simulator.stationCodes()
+	}
+
+	response := railpredictions.GetNextTrainResponse{}
+	headway := simulator.config.TrainHeadway
+
+	for _, stationCode := range stationCodes {
+		type arrival struct {
+			train     railpredictions.Train
+			remaining time.Duration
+		}
+
+		var arrivals []arrival
+
+		for _, route := range simulator.trainRoutes {
+			for j, circuit := range route.circuits {
+				if circuit.StationCode != stationCode || circuit.StationCode == route.destination {
+					continue
+				}
+
+				// trains that have not left the station yet and reach it within the prediction horizon
+				first, last := departures(
+					at.Add(-route.arrivals[j]-route.stays[j]+1),
+					at.Add(simulator.config.PredictionHorizon-route.arrivals[j]),
+					headway,
+					route.offset,
+				)
+
+				for k := first; k <= last; k++ {
+					remaining := departure(k, headway, route.offset).Add(route.arrivals[j]).Sub(at)
+
+					position := route.train(k)
+
+					train := railpredictions.Train{
+						Car:             strconv.Itoa(position.CarCount),
+						Destination:     simulator.stationName(route.destination),
+						DestinationCode: route.destination,
+						DestinationName: simulator.stationName(route.destination),
+						Group:           strconv.Itoa(route.track),
+						Line:            route.lineCode,
+						LocationCode:    stationCode,
+						LocationName:    simulator.stationName(stationCode),
+						Minutes:         predictedMinutes(remaining),
+					}
+
+					arrivals = append(arrivals, arrival{train: train, remaining: remaining})
+				}
+			}
+		}
+
+		sort.SliceStable(arrivals, func(i, j int) bool {
+			return arrivals[i].remaining < arrivals[j].remaining
+		})
+
+		for _, arrival := range arrivals {
+			response.Trains = append(response.Trains, arrival.train)
+		}
+	}
+
+	return &response
+}
+
+// train returns the position of departure k with everything but its location filled in
+func (route *trainRoute) train(k int64) trainpositions.TrainPosition {
+	carCount := 8
+
+	if mod(k, 2) == 1 {
+		carCount = 6
+	}
+
+	return trainpositions.TrainPosition{
+		CarCount:               carCount,
+		DestinationStationCode: route.destination,
+		DirectionNumber:        route.track,
+		LineCode:               route.lineCode,
+		ServiceType:            "Normal",
+		TrainID:                strconv.FormatInt(int64(route.index+1)*1000+mod(k, 1000), 10),
+		TrainNumber:            fmt.Sprintf("%d%02d", route.track, mod(k, 100)),
+	}
+}
+
+// predictedMinutes formats the time until a train reaches a station as WMATA does, boarding once the train is at the
+// station and arriving within the last minute
+func predictedMinutes(remaining time.Duration) string {
+	switch {
+	case remaining <= 0:
+		return railpredictions.MinutesBoarding
+	case remaining < time.Minute:
+		return railpredictions.MinutesArriving
+	}
+
+	return strconv.Itoa(int(remaining / time.Minute))
+}
+
+// stationCodes returns the code of every station on a train route, sorted
+func (simulator *Simulator) stationCodes() []string {
+	seen := make(map[string]bool)
+	var stationCodes []string
+
+	for _, route := range simulator.trainRoutes {
+		for _, circuit := range route.circuits {
+			if circuit.StationCode != "" && !seen[circuit.StationCode] {
+				seen[circuit.StationCode] = true
+				stationCodes = append(stationCodes, circuit.StationCode)
+			}
+		}
+	}
+
+	sort.Strings(stationCodes)
+
+	return stationCodes
+}
+
+func (simulator *Simulator) stationName(stationCode string) string {
+	if name, exist := simulator.stationNames[stationCode]; exist {
+		return name
+	}
+
+	return stationCode
+}
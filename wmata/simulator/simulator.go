@@ -0,0 +1,355 @@
+package simulator
+
+import (
+	"fmt"
+	"github.com/awiede/wmata-go-sdk/wmata/businfo"
+	"github.com/awiede/wmata-go-sdk/wmata/buspredictions"
+	"github.com/awiede/wmata-go-sdk/wmata/railinfo"
+	"github.com/awiede/wmata-go-sdk/wmata/railpredictions"
+	"github.com/awiede/wmata-go-sdk/wmata/trainpositions"
+	"github.com/awiede/wmata-go-sdk/wmata/wmatatest"
+	"net/http"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Default settings used when a Config field is left at zero
+const (
+	DefaultCircuitTime       = 20 * time.Second
+	DefaultDwellTime         = 30 * time.Second
+	DefaultTrainHeadway      = 8 * time.Minute
+	DefaultBusHeadway        = 12 * time.Minute
+	DefaultBusSpeed          = 5.0
+	DefaultPredictionHorizon = 30 * time.Minute
+)
+
+// Config holds the timings vehicles are moved with
+type Config struct {
+	// CircuitTime is the time a train takes to cross one track circuit
+	CircuitTime time.Duration
+	// DwellTime is the time a train stands at a station, on top of the time taken to cross the station's circuit
+	DwellTime time.Duration
+	// TrainHeadway is the time between trains starting each standard route
+	TrainHeadway time.Duration
+	// BusHeadway is the time between buses starting each direction of each route
+	BusHeadway time.Duration
+	// BusSpeed in meters per second, buses move along their route shape at this speed
+	BusSpeed float64
+	// MaxBusDeviation, each bus trip starts late by a whole number of minutes up to MaxBusDeviation, which is reported
+	// as the bus's deviation. Buses run on time if zero
+	MaxBusDeviation time.Duration
+	// PredictionHorizon, trains and buses further than this from a station or stop are not predicted
+	PredictionHorizon time.Duration
+}
+
+// withDefaults returns a copy of the config with zero values replaced by defaults
+func (config Config) withDefaults() Config {
+	if config.CircuitTime <= 0 {
+		config.CircuitTime = DefaultCircuitTime
+	}
+
+	if config.DwellTime <= 0 {
+		config.DwellTime = DefaultDwellTime
+	}
+
+	if config.TrainHeadway <= 0 {
+		config.TrainHeadway = DefaultTrainHeadway
+	}
+
+	if config.BusHeadway <= 0 {
+		config.BusHeadway = DefaultBusHeadway
+	}
+
+	if config.BusSpeed <= 0 {
+		config.BusSpeed = DefaultBusSpeed
+	}
+
+	if config.PredictionHorizon <= 0 {
+		config.PredictionHorizon = DefaultPredictionHorizon
+	}
+
+	return config
+}
+
+// Network is the reference data vehicles are moved over
+type Network struct {
+	// StandardRoutes are the circuits trains run over, one train route per line and track
+	StandardRoutes []trainpositions.Route
+	// TrackCircuits are used to check that consecutive circuits of each standard route are neighbors, routes are not
+	// checked if empty
+	TrackCircuits []trainpositions.TrackCircuit
+	// Stations name the stations and destinations of rail predictions, which fall back to station codes
+	Stations []railinfo.GetStationListResponseItem
+	// BusRoutes are the routes buses run on, along the shape of each direction
+	BusRoutes []businfo.GetRouteDetailsResponse
+}
+
+// LoadNetwork retrieves the standard routes, track circuits and stations, and the details of the given bus routes
+func LoadNetwork(trainPositions trainpositions.TrainPositions, railInfo railinfo.RailInfo, busInfo businfo.BusInfo, routeIDs []string) (*Network, error) {
+	routes, routesErr := trainPositions.GetStandardRoutes()
+
+	if routesErr != nil {
+		return nil, routesErr
+	}
+
+	circuits, circuitsErr := trainPositions.GetTrackCircuits()
+
+	if circuitsErr != nil {
+		return nil, circuitsErr
+	}
+
+	stations, stationsErr := railInfo.GetStationList("")
+
+	if stationsErr != nil {
+		return nil, stationsErr
+	}
+
+	network := Network{
+		StandardRoutes: routes.Routes,
+		TrackCircuits:  circuits.TrackCircuits,
+		Stations:       stations.Stations,
+	}
+
+	for _, routeID := range routeIDs {
+		details, detailsErr := busInfo.GetRouteDetails(routeID, "")
+
+		if detailsErr != nil {
+			return nil, detailsErr
+		}
+
+		network.BusRoutes = append(network.BusRoutes, *details)
+	}
+
+	return &network, nil
+}
+
+// Snapshot holds the live data of every vehicle at one time
+type Snapshot struct {
+	Time           time.Time
+	TrainPositions *trainpositions.GetLiveTrainPositionsResponse
+	NextTrains     *railpredictions.GetNextTrainResponse
+	BusPositions   *businfo.GetPositionsResponse
+	// NextBuses holds the predictions at every stop of the bus routes, by stop ID
+	NextBuses map[string]*buspredictions.GetNextBusResponse
+}
+
+// Simulator moves synthetic trains along standard routes and synthetic buses along route shapes. Vehicles start each
+// route at fixed headways counted from the Unix epoch, so the network's state is a function of time alone: positions
+// and predictions for the same time are consistent with each other, and repeatable. It is safe for concurrent use
+type Simulator struct {
+	config        Config
+	network       Network
+	trainRoutes   []*trainRoute
+	busDirections []*busDirection
+	stationNames  map[string]string
+	stopNames     map[string]string
+}
+
+// NewSimulator returns a Simulator moving vehicles over network, which must not be changed afterwards. It returns an
+// error if network has track circuits and a standard route runs between circuits that are not neighbors
+func NewSimulator(network *Network, config Config) (*Simulator, error) {
+	simulator := Simulator{
+		config:       config.withDefaults(),
+		network:      *network,
+		stationNames: make(map[string]string),
+		stopNames:    make(map[string]string),
+	}
+
+	if checkErr := checkRoutes(network.StandardRoutes, network.TrackCircuits); checkErr != nil {
+		return nil, checkErr
+	}
+
+	for _, station := range network.Stations {
+		simulator.stationNames[station.StationCode] = station.Name
+	}
+
+	for i := range simulator.network.StandardRoutes {
+		if len(simulator.network.StandardRoutes[i].TrackCircuits) == 0 {
+			continue
+		}
+
+		simulator.trainRoutes = append(simulator.trainRoutes, newTrainRoute(&simulator.network.StandardRoutes[i], simulator.config))
+	}
+
+	for i := range simulator.network.BusRoutes {
+		route := &simulator.network.BusRoutes[i]
+
+		for _, direction := range []*businfo.Direction{&route.Direction0, &route.Direction1} {
+			if len(direction.Shapes) == 0 {
+				continue
+			}
+
+			simulator.busDirections = append(simulator.busDirections, newBusDirection(route.RouteID, direction, simulator.config))
+
+			for _, stop := range direction.Stops {
+				simulator.stopNames[stop.StopID] = stop.Name
+			}
+		}
+	}
+
+	// stagger the routes so their vehicles do not all start at once
+	for i, route := range simulator.trainRoutes {
+		route.index = i
+		route.offset = simulator.config.TrainHeadway * time.Duration(i) / time.Duration(len(simulator.trainRoutes))
+	}
+
+	for i, direction := range simulator.busDirections {
+		direction.index = i
+		direction.offset = simulator.config.BusHeadway * time.Duration(i) / time.Duration(len(simulator.busDirections))
+	}
+
+	return &simulator, nil
+}
+
+// Snapshot returns the positions of every train and bus and the predictions at every station and stop at a time
+func (simulator *Simulator) Snapshot(at time.Time) *Snapshot {
+	snapshot := Snapshot{
+		Time:           at,
+		TrainPositions: simulator.LiveTrainPositions(at),
+		NextTrains:     simulator.NextTrains(nil, at),
+		BusPositions:   simulator.BusPositions(nil, at),
+		NextBuses:      make(map[string]*buspredictions.GetNextBusResponse),
+	}
+
+	for stopID := range simulator.stopNames {
+		snapshot.NextBuses[stopID] = simulator.NextBuses(stopID, at)
+	}
+
+	return &snapshot
+}
+
+// Serve makes a wmatatest server answer the train position, rail prediction, bus position and bus prediction
+// endpoints from the simulator at the time returned by clock, or the current time if clock is nil. The server's
+// standard routes and track circuits are replaced by the network's when it has any
+func (simulator *Simulator) Serve(server *wmatatest.Server, clock func() time.Time) {
+	if clock == nil {
+		clock = time.Now
+	}
+
+	server.SetResponse("/TrainPositions/TrainPositions", wmatatest.ResponseFunc(func(_ *http.Request) interface{} {
+		return simulator.LiveTrainPositions(clock())
+	}))
+
+	server.SetResponse("/StationPrediction.svc/json/GetPrediction", wmatatest.ResponseFunc(func(request *http.Request) interface{} {
+		// predictions are requested for a comma separated list of station codes at the end of the path, or "All"
+		var stationCodes []string
+
+		if tail := path.Base(request.URL.Path); tail != "All" {
+			stationCodes = strings.Split(tail, ",")
+		}
+
+		return simulator.NextTrains(stationCodes, clock())
+	}))
+
+	server.SetResponse("/Bus.svc/json/jBusPositions", wmatatest.ResponseFunc(func(request *http.Request) interface{} {
+		query := request.URL.Query()
+		positionsRequest := businfo.GetPositionsRequest{RouteID: query.Get("RouteID")}
+
+		positionsRequest.Latitude, _ = strconv.ParseFloat(query.Get("Lat"), 64)
+		positionsRequest.Longitude, _ = strconv.ParseFloat(query.Get("Lon"), 64)
+		positionsRequest.Radius, _ = strconv.ParseFloat(query.Get("Radius"), 64)
+
+		return simulator.BusPositions(&positionsRequest, clock())
+	}))
+
+	server.SetResponse("/NextBusService.svc/json/jPredictions", wmatatest.ResponseFunc(func(request *http.Request) interface{} {
+		return simulator.NextBuses(request.URL.Query().Get("StopID"), clock())
+	}))
+
+	if len(simulator.network.StandardRoutes) > 0 {
+		server.SetResponse("/TrainPositions/StandardRoutes", &trainpositions.GetStandardRoutesResponse{Routes: simulator.network.StandardRoutes})
+	}
+
+	if len(simulator.network.TrackCircuits) > 0 {
+		server.SetResponse("/TrainPositions/TrackCircuits", &trainpositions.GetTrackCircuitsResponse{TrackCircuits: simulator.network.TrackCircuits})
+	}
+}
+
+// AcceleratedClock returns a clock that starts at start and runs speed times faster than real time, for demos that
+// should show a whole service day in minutes
+func AcceleratedClock(start time.Time, speed float64) func() time.Time {
+	began := time.Now()
+
+	return func() time.Time {
+		return start.Add(time.Duration(float64(time.Since(began)) * speed))
+	}
+}
+
+// checkRoutes returns an error if consecutive circuits of a route are not neighbors of each other
+func checkRoutes(routes []trainpositions.Route, circuits []trainpositions.TrackCircuit) error {
+	if len(circuits) == 0 {
+		return nil
+	}
+
+	neighbors := make(map[int]map[int]bool)
+
+	for _, circuit := range circuits {
+		neighbors[circuit.CircuitID] = make(map[int]bool)
+
+		for _, neighbor := range circuit.Neighbors {
+			for _, circuitID := range neighbor.CircuitIDs {
+				neighbors[circuit.CircuitID][circuitID] = true
+			}
+		}
+	}
+
+	for _, route := range routes {
+		ordered := orderCircuits(route.TrackCircuits)
+
+		for i, circuit := range ordered {
+			if _, exist := neighbors[circuit.CircuitID]; !exist {
+				return fmt.Errorf("unknown circuit %d on %s track %d", circuit.CircuitID, route.LineCode, route.TrackNumber)
+			}
+
+			if i > 0 && !neighbors[ordered[i-1].CircuitID][circuit.CircuitID] {
+				return fmt.Errorf("circuit %d does not neighbor circuit %d on %s track %d", circuit.CircuitID, ordered[i-1].CircuitID, route.LineCode, route.TrackNumber)
+			}
+		}
+	}
+
+	return nil
+}
+
+// orderCircuits returns a copy of a route's circuits ordered by sequence number
+func orderCircuits(circuits []trainpositions.StandardTrackCircuit) []trainpositions.StandardTrackCircuit {
+	ordered := make([]trainpositions.StandardTrackCircuit, len(circuits))
+	copy(ordered, circuits)
+
+	sort.SliceStable(ordered, func(i, j int) bool {
+		return ordered[i].SequenceNumber < ordered[j].SequenceNumber
+	})
+
+	return ordered
+}
+
+// departures returns the first and last index of the departures in [from, to]. Departure k is at offset plus k
+// headways after the Unix epoch
+func departures(from, to time.Time, headway, offset time.Duration) (int64, int64) {
+	first := -floorDiv(-(from.UnixNano() - int64(offset)), int64(headway))
+	last := floorDiv(to.UnixNano()-int64(offset), int64(headway))
+
+	return first, last
+}
+
+// departure returns the time of departure k
+func departure(k int64, headway, offset time.Duration) time.Time {
+	return time.Unix(0, k*int64(headway)+int64(offset))
+}
+
+func floorDiv(a, b int64) int64 {
+	quotient := a / b
+
+	if a%b != 0 && (a < 0) != (b < 0) {
+		quotient--
+	}
+
+	return quotient
+}
+
+// mod returns the non-negative remainder of k divided by n, used to number vehicles
+func mod(k int64, n int64) int64 {
+	return (k%n + n) % n
+}
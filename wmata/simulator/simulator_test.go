@@ -0,0 +1,423 @@
+package simulator
+
+import (
+	"github.com/awiede/wmata-go-sdk/wmata"
+	"github.com/awiede/wmata-go-sdk/wmata/businfo"
+	"github.com/awiede/wmata-go-sdk/wmata/buspredictions"
+	"github.com/awiede/wmata-go-sdk/wmata/linearref"
+	"github.com/awiede/wmata-go-sdk/wmata/railinfo"
+	"github.com/awiede/wmata-go-sdk/wmata/railpredictions"
+	"github.com/awiede/wmata-go-sdk/wmata/trainpositions"
+	"github.com/awiede/wmata-go-sdk/wmata/wmatatest"
+	"github.com/kr/pretty"
+	"math"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// noon is a multiple of the train headway after the Unix epoch, so the first train route's train leaves at noon
+var noon = time.Date(2019, time.April, 29, 12, 0, 0, 0, time.UTC)
+
+var testConfig = Config{
+	CircuitTime:  20 * time.Second,
+	DwellTime:    30 * time.Second,
+	TrainHeadway: 8 * time.Minute,
+	BusHeadway:   10 * time.Minute,
+	BusSpeed:     10,
+}
+
+func testNetwork() *Network {
+	return &Network{
+		StandardRoutes: wmatatest.StandardRoutes().Routes,
+		TrackCircuits:  wmatatest.TrackCircuits().TrackCircuits,
+		Stations:       wmatatest.Stations().Stations,
+		BusRoutes:      []businfo.GetRouteDetailsResponse{*wmatatest.BusRouteDetails()},
+	}
+}
+
+func newTestSimulator(t *testing.T, config Config) *Simulator {
+	simulator, simulatorErr := NewSimulator(testNetwork(), config)
+
+	if simulatorErr != nil {
+		t.Fatal(simulatorErr)
+	}
+
+	return simulator
+}
+
+func TestLiveTrainPositions(t *testing.T) {
+	simulator := newTestSimulator(t, testConfig)
+
+	// track 1 runs A03, A02, A01 and B01 with a circuit between each pair of stations, so a train reaches its circuits
+	// 0s, 50s, 70s, 120s, 140s, 190s and 210s after starting and finishes the route after 260s. Track 2 starts half a
+	// headway later
+	testData := []struct {
+		at       time.Duration
+		expected []trainpositions.TrainPosition
+	}{
+		{
+			at: 130 * time.Second,
+			expected: []trainpositions.TrainPosition{
+				{CarCount: 8, CircuitID: 1104, DestinationStationCode: "B01", DirectionNumber: 1, LineCode: "RD", SecondsAtLocation: 10, ServiceType: "Normal", TrainID: "1790", TrainNumber: "190"},
+			},
+		},
+		{
+			at: 250 * time.Second,
+			expected: []trainpositions.TrainPosition{
+				{CarCount: 8, CircuitID: 1107, DestinationStationCode: "B01", DirectionNumber: 1, LineCode: "RD", SecondsAtLocation: 40, ServiceType: "Normal", TrainID: "1790", TrainNumber: "190"},
+				{CarCount: 8, CircuitID: 2101, DestinationStationCode: "A03", DirectionNumber: 2, LineCode: "RD", SecondsAtLocation: 10, ServiceType: "Normal", TrainID: "2790", TrainNumber: "290"},
+			},
+		},
+		{
+			at: 260 * time.Second,
+			expected: []trainpositions.TrainPosition{
+				{CarCount: 8, CircuitID: 2101, DestinationStationCode: "A03", DirectionNumber: 2, LineCode: "RD", SecondsAtLocation: 20, ServiceType: "Normal", TrainID: "2790", TrainNumber: "290"},
+			},
+		},
+		{
+			at: 480 * time.Second,
+			expected: []trainpositions.TrainPosition{
+				{CarCount: 6, CircuitID: 1101, DestinationStationCode: "B01", DirectionNumber: 1, LineCode: "RD", SecondsAtLocation: 0, ServiceType: "Normal", TrainID: "1791", TrainNumber: "191"},
+				{CarCount: 8, CircuitID: 2107, DestinationStationCode: "A03", DirectionNumber: 2, LineCode: "RD", SecondsAtLocation: 30, ServiceType: "Normal", TrainID: "2790", TrainNumber: "290"},
+			},
+		},
+	}
+
+	for _, test := range testData {
+		response := simulator.LiveTrainPositions(noon.Add(test.at))
+
+		if !reflect.DeepEqual(response.Positions, test.expected) {
+			t.Errorf("unexpected positions at %s: %v", test.at, pretty.Diff(response.Positions, test.expected))
+		}
+	}
+}
+
+func TestNextTrains(t *testing.T) {
+	simulator := newTestSimulator(t, testConfig)
+
+	response := simulator.NextTrains([]string{"A01", "B01"}, noon.Add(130*time.Second))
+
+	var actual []string
+
+	for _, train := range response.Trains {
+		actual = append(actual, train.LocationCode+" "+train.Group+" "+train.DestinationName+" "+train.Minutes)
+	}
+
+	// track 1 trains terminate at B01 so are not predicted there
+	expected := []string{
+		"A01 1 Gallery Pl-Chinatown ARR",
+		"A01 2 Dupont Circle 3",
+		"A01 1 Gallery Pl-Chinatown 8",
+		"A01 2 Dupont Circle 11",
+		"A01 1 Gallery Pl-Chinatown 16",
+		"A01 2 Dupont Circle 19",
+		"A01 1 Gallery Pl-Chinatown 24",
+		"A01 2 Dupont Circle 27",
+		"B01 2 Dupont Circle 1",
+		"B01 2 Dupont Circle 9",
+		"B01 2 Dupont Circle 17",
+		"B01 2 Dupont Circle 25",
+	}
+
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("unexpected predictions: %v", pretty.Diff(actual, expected))
+	}
+
+	first := railpredictions.Train{
+		Car: "8", Destination: "Gallery Pl-Chinatown", DestinationCode: "B01", DestinationName: "Gallery Pl-Chinatown",
+		Group: "1", Line: "RD", LocationCode: "A01", LocationName: "Metro Center", Minutes: railpredictions.MinutesArriving,
+	}
+
+	if !reflect.DeepEqual(response.Trains[0], first) {
+		t.Errorf("unexpected prediction: %v", pretty.Diff(response.Trains[0], first))
+	}
+
+	all := simulator.NextTrains(nil, noon.Add(130*time.Second))
+
+	// every station but the terminals is served by both tracks
+	if len(all.Trains) != 8+7+4+4 || all.Trains[0].LocationCode != "A01" || all.Trains[len(all.Trains)-1].LocationCode != "B01" {
+		t.Errorf("unexpected predictions at every station: %# v", pretty.Formatter(all))
+	}
+}
+
+// TestTrainConsistency checks that trains boarding at a station are on the station's circuit and that trains arriving
+// anywhere but the start of a route are between the previous station and the station
+func TestTrainConsistency(t *testing.T) {
+	simulator := newTestSimulator(t, testConfig)
+
+	// nextStations maps each circuit to the station a train on it reaches next, and stations the circuits of stations
+	nextStations := make(map[int]string)
+	stations := make(map[int]string)
+	origins := make(map[string]bool)
+
+	for _, route := range wmatatest.StandardRoutes().Routes {
+		next := ""
+		origins[route.TrackCircuits[0].StationCode] = true
+
+		for i := len(route.TrackCircuits) - 1; i >= 0; i-- {
+			circuit := route.TrackCircuits[i]
+			nextStations[circuit.CircuitID] = next
+
+			if circuit.StationCode != "" {
+				stations[circuit.CircuitID] = circuit.StationCode
+				next = circuit.StationCode
+			}
+		}
+	}
+
+	for at := noon; at.Before(noon.Add(16 * time.Minute)); at = at.Add(5 * time.Second) {
+		boarding := make(map[string]int)
+		arriving := make(map[string]int)
+
+		for _, train := range simulator.NextTrains(nil, at).Trains {
+			switch train.Minutes {
+			case railpredictions.MinutesBoarding:
+				boarding[train.LocationCode]++
+			case railpredictions.MinutesArriving:
+				arriving[train.LocationCode]++
+			}
+		}
+
+		atStation := make(map[string]int)
+		approaching := make(map[string]int)
+
+		for _, position := range simulator.LiveTrainPositions(at).Positions {
+			if stationCode := stations[position.CircuitID]; stationCode != "" && stationCode != position.DestinationStationCode {
+				atStation[stationCode]++
+			}
+
+			approaching[nextStations[position.CircuitID]]++
+		}
+
+		if !reflect.DeepEqual(boarding, atStation) {
+			t.Errorf("boarding trains at %s do not match positions: %v", at, pretty.Diff(boarding, atStation))
+		}
+
+		for stationCode, count := range arriving {
+			if !origins[stationCode] && approaching[stationCode] < count {
+				t.Errorf("%d trains arriving at %s at %s but %d approaching", count, stationCode, at, approaching[stationCode])
+			}
+		}
+	}
+}
+
+func TestBuses(t *testing.T) {
+	config := testConfig
+	config.MaxBusDeviation = 5 * time.Minute
+
+	simulator := newTestSimulator(t, config)
+	route := wmatatest.BusRouteDetails()
+	shapes := map[string]*linearref.Shape{
+		route.Direction0.DirectionText: linearref.NewShape(&route.Direction0),
+		route.Direction1.DirectionText: linearref.NewShape(&route.Direction1),
+	}
+
+	deviations := make(map[int]bool)
+
+	for at := noon; at.Before(noon.Add(time.Hour)); at = at.Add(time.Minute) {
+		positions := simulator.BusPositions(&businfo.GetPositionsRequest{RouteID: "70"}, at)
+		buses := make(map[string]businfo.BusPosition)
+
+		for _, position := range positions.BusPositions {
+			snapped, _ := shapes[position.DirectionText].SnapPosition(&position)
+
+			if snapped.DistanceFromShape > 1 {
+				t.Errorf("bus %s is %fm from its route", position.VehicleID, snapped.DistanceFromShape)
+			}
+
+			if position.DateTime != at.Format(wmata.DateTimeLayout) || position.TripDestination == "" || position.RouteID != "70" {
+				t.Errorf("unexpected position: %# v", pretty.Formatter(position))
+			}
+
+			deviations[position.Deviation] = true
+			buses[position.VehicleID] = position
+		}
+
+		// each predicted bus that has started its trip is as far from the stop as the prediction says
+		for _, stop := range route.Direction0.Stops {
+			predictions := simulator.NextBuses(stop.StopID, at)
+
+			if predictions.StopName != stop.Name {
+				t.Errorf("unexpected stop name: %s", predictions.StopName)
+			}
+
+			for i, prediction := range predictions.NextBusPredictions {
+				if i > 0 && prediction.Minutes < predictions.NextBusPredictions[i-1].Minutes {
+					t.Errorf("predictions at %s are not in order: %# v", stop.StopID, pretty.Formatter(predictions))
+				}
+
+				bus, started := buses[prediction.VehicleID]
+
+				if !started {
+					continue
+				}
+
+				if bus.TripID != prediction.TripID || bus.DirectionText != prediction.DirectionText {
+					t.Errorf("prediction %# v does not match bus %# v", pretty.Formatter(prediction), pretty.Formatter(bus))
+				}
+
+				snapped, _ := shapes[bus.DirectionText].SnapPosition(&bus)
+				stopDistance, _ := shapes[bus.DirectionText].StopDistance(stop.StopID)
+				minutes := (stopDistance - snapped.DistanceTraveled) / config.BusSpeed / 60
+
+				if math.Abs(minutes-float64(prediction.Minutes)-0.5) > 0.51 {
+					t.Errorf("bus %s predicted in %d minutes is %.2f minutes from stop %s", bus.VehicleID, prediction.Minutes, minutes, stop.StopID)
+				}
+			}
+		}
+	}
+
+	if len(deviations) < 2 {
+		t.Errorf("expected buses to deviate from schedule: %v", deviations)
+	}
+
+	for deviation := range deviations {
+		if deviation < 0 || deviation > 5 {
+			t.Errorf("unexpected deviation: %d", deviation)
+		}
+	}
+
+	if empty := simulator.NextBuses("9999999", noon); len(empty.NextBusPredictions) != 0 || empty.StopName != "" {
+		t.Errorf("unexpected predictions at an unknown stop: %# v", pretty.Formatter(empty))
+	}
+
+	if none := simulator.BusPositions(&businfo.GetPositionsRequest{RouteID: "S2"}, noon); len(none.BusPositions) != 0 {
+		t.Errorf("unexpected positions on a route without a shape: %# v", pretty.Formatter(none))
+	}
+
+	nearby := simulator.BusPositions(&businfo.GetPositionsRequest{Latitude: 38.893564, Longitude: -77.021875, Radius: 1}, noon)
+
+	for _, position := range nearby.BusPositions {
+		if linearref.Distance(38.893564, -77.021875, position.Latitude, position.Longitude) > 1 {
+			t.Errorf("unexpected position outside radius: %# v", pretty.Formatter(position))
+		}
+	}
+}
+
+func TestSnapshot(t *testing.T) {
+	simulator := newTestSimulator(t, testConfig)
+	at := noon.Add(7 * time.Minute)
+
+	snapshot := simulator.Snapshot(at)
+
+	if !reflect.DeepEqual(snapshot.TrainPositions, simulator.LiveTrainPositions(at)) || !reflect.DeepEqual(snapshot.NextTrains, simulator.NextTrains(nil, at)) || !reflect.DeepEqual(snapshot.BusPositions, simulator.BusPositions(nil, at)) {
+		t.Errorf("unexpected snapshot: %# v", pretty.Formatter(snapshot))
+	}
+
+	if len(snapshot.NextBuses) != len(wmatatest.BusStops().Stops) || !reflect.DeepEqual(snapshot.NextBuses["1001195"], simulator.NextBuses("1001195", at)) {
+		t.Errorf("unexpected bus predictions: %# v", pretty.Formatter(snapshot.NextBuses))
+	}
+
+	// the state of the network depends on the time alone
+	again := newTestSimulator(t, testConfig).Snapshot(at)
+
+	if !reflect.DeepEqual(snapshot, again) {
+		t.Errorf("snapshots differ: %v", pretty.Diff(snapshot, again))
+	}
+}
+
+func TestNewSimulatorBrokenRoute(t *testing.T) {
+	network := testNetwork()
+	network.StandardRoutes[0].TrackCircuits[3].CircuitID = 2104
+
+	if _, simulatorErr := NewSimulator(network, Config{}); simulatorErr == nil {
+		t.Error("expected an error for a route between circuits that are not neighbors")
+	}
+
+	network.StandardRoutes[0].TrackCircuits[3].CircuitID = 9999
+
+	if _, simulatorErr := NewSimulator(network, Config{}); simulatorErr == nil {
+		t.Error("expected an error for a route over an unknown circuit")
+	}
+
+	network.TrackCircuits = nil
+
+	if _, simulatorErr := NewSimulator(network, Config{}); simulatorErr != nil {
+		t.Errorf("expected routes not to be checked without track circuits, got %s", simulatorErr)
+	}
+}
+
+func TestServe(t *testing.T) {
+	server := wmatatest.NewServer(wmatatest.Config{})
+	defer server.Close()
+
+	client := server.Client()
+
+	network, networkErr := LoadNetwork(trainpositions.NewService(client, wmata.JSON), railinfo.NewService(client, wmata.JSON), businfo.NewService(client, wmata.JSON), []string{"70"})
+
+	if networkErr != nil {
+		t.Fatal(networkErr)
+	}
+
+	if !reflect.DeepEqual(network, testNetwork()) {
+		t.Errorf("unexpected network: %v", pretty.Diff(network, testNetwork()))
+	}
+
+	simulator, simulatorErr := NewSimulator(network, testConfig)
+
+	if simulatorErr != nil {
+		t.Fatal(simulatorErr)
+	}
+
+	at := noon.Add(130 * time.Second)
+	simulator.Serve(server, func() time.Time {
+		return at
+	})
+
+	positions, positionsErr := trainpositions.NewService(client, wmata.XML).GetLiveTrainPositions()
+
+	if positionsErr != nil {
+		t.Fatal(positionsErr)
+	}
+
+	if !reflect.DeepEqual(positions.Positions, simulator.LiveTrainPositions(at).Positions) {
+		t.Errorf("unexpected train positions: %v", pretty.Diff(positions.Positions, simulator.LiveTrainPositions(at).Positions))
+	}
+
+	trains, trainsErr := railpredictions.NewService(client, wmata.JSON).GetNextTrains([]string{"A02", "A01"})
+
+	if trainsErr != nil {
+		t.Fatal(trainsErr)
+	}
+
+	if !reflect.DeepEqual(trains, simulator.NextTrains([]string{"A02", "A01"}, at)) {
+		t.Errorf("unexpected train predictions: %v", pretty.Diff(trains, simulator.NextTrains([]string{"A02", "A01"}, at)))
+	}
+
+	allTrains, _ := railpredictions.NewService(client, wmata.JSON).GetNextTrains(nil)
+
+	if !reflect.DeepEqual(allTrains, simulator.NextTrains(nil, at)) {
+		t.Errorf("unexpected train predictions at every station: %v", pretty.Diff(allTrains, simulator.NextTrains(nil, at)))
+	}
+
+	buses, busesErr := businfo.NewService(client, wmata.JSON).GetPositions(&businfo.GetPositionsRequest{RouteID: "70"})
+
+	if busesErr != nil {
+		t.Fatal(busesErr)
+	}
+
+	if !reflect.DeepEqual(buses, simulator.BusPositions(&businfo.GetPositionsRequest{RouteID: "70"}, at)) {
+		t.Errorf("unexpected bus positions: %v", pretty.Diff(buses, simulator.BusPositions(nil, at)))
+	}
+
+	nextBuses, nextBusesErr := buspredictions.NewService(client, wmata.JSON).GetNextBuses("1001808")
+
+	if nextBusesErr != nil {
+		t.Fatal(nextBusesErr)
+	}
+
+	if !reflect.DeepEqual(nextBuses, simulator.NextBuses("1001808", at)) {
+		t.Errorf("unexpected bus predictions: %v", pretty.Diff(nextBuses, simulator.NextBuses("1001808", at)))
+	}
+}
+
+func TestAcceleratedClock(t *testing.T) {
+	clock := AcceleratedClock(noon, 60)
+
+	time.Sleep(50 * time.Millisecond)
+
+	if elapsed := clock().Sub(noon); elapsed < 3*time.Second || elapsed > time.Minute {
+		t.Errorf("unexpected accelerated time: %s", elapsed)
+	}
+}
@@ -0,0 +1,123 @@
+package stationmatrix
+
+import (
+	"github.com/awiede/wmata-go-sdk/wmata/railinfo"
+	"math"
+	"sort"
+)
+
+type ChangeType string
+
+const (
+	ChangeAdded    ChangeType = "ADDED"
+	ChangeRemoved  ChangeType = "REMOVED"
+	ChangeModified ChangeType = "MODIFIED"
+)
+
+// Change is a station pair that differs between two matrices. Old is nil for added pairs and New is nil for removed
+// pairs
+type Change struct {
+	Type               ChangeType                 `json:"Type"`
+	SourceStation      string                     `json:"SourceStation"`
+	DestinationStation string                     `json:"DestinationStation"`
+	Old                *railinfo.StationToStation `json:"Old"`
+	New                *railinfo.StationToStation `json:"New"`
+}
+
+// FareChanged reports whether any fare differs between the old and new pair, including pairs that were added or removed.
+// Fares are compared in cents
+func (change *Change) FareChanged() bool {
+	if change.Old == nil || change.New == nil {
+		return true
+	}
+
+	return roundFare(change.Old.Fare) != roundFare(change.New.Fare)
+}
+
+// Diff returns the station pairs added, removed or modified between two snapshots, ordered by source then destination
+// station. Only pairs listed in either snapshot are compared, a pair filled in from its reverse is never reported on its
+// own. Miles are compared in hundredths and fares in cents, as they are stored by MarshalBinary
+func Diff(before, after *Matrix) []Change {
+	var changes []Change
+
+	compared := make(map[[2]string]bool)
+
+	for _, matrix := range []*Matrix{before, after} {
+		for _, info := range matrix.listed() {
+			pair := [2]string{info.SourceStation, info.DestinationStation}
+
+			if compared[pair] {
+				continue
+			}
+
+			compared[pair] = true
+
+			oldInfo, oldExist := before.Lookup(info.SourceStation, info.DestinationStation)
+			newInfo, newExist := after.Lookup(info.SourceStation, info.DestinationStation)
+			change := Change{
+				SourceStation:      info.SourceStation,
+				DestinationStation: info.DestinationStation,
+			}
+
+			switch {
+			case !newExist:
+				change.Type = ChangeRemoved
+				change.Old = &oldInfo
+			case !oldExist:
+				change.Type = ChangeAdded
+				change.New = &newInfo
+			case round(oldInfo) != round(newInfo):
+				change.Type = ChangeModified
+				change.Old = &oldInfo
+				change.New = &newInfo
+			default:
+				continue
+			}
+
+			changes = append(changes, change)
+		}
+	}
+
+	sort.SliceStable(changes, func(i, j int) bool {
+		if changes[i].SourceStation != changes[j].SourceStation {
+			return changes[i].SourceStation < changes[j].SourceStation
+		}
+
+		return changes[i].DestinationStation < changes[j].DestinationStation
+	})
+
+	return changes
+}
+
+// FareChanges returns only the changes from Diff that affect fares
+func FareChanges(before, after *Matrix) []Change {
+	var changes []Change
+
+	for _, change := range Diff(before, after) {
+		if change.FareChanged() {
+			changes = append(changes, change)
+		}
+	}
+
+	return changes
+}
+
+// round returns a pair with its miles rounded to hundredths and its fares to cents
+func round(info railinfo.StationToStation) railinfo.StationToStation {
+	info.CompositeMiles = hundredths(info.CompositeMiles)
+	info.Fare = roundFare(info.Fare)
+
+	return info
+}
+
+func roundFare(fare railinfo.RailFare) railinfo.RailFare {
+	return railinfo.RailFare{
+		OffPeakTime:    hundredths(fare.OffPeakTime),
+		PeakTime:       hundredths(fare.PeakTime),
+		SeniorDisabled: hundredths(fare.SeniorDisabled),
+	}
+}
+
+func hundredths(value float64) float64 {
+	return math.Round(value*100) / 100
+}
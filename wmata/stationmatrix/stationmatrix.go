@@ -0,0 +1,330 @@
+package stationmatrix
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"github.com/awiede/wmata-go-sdk/wmata/railinfo"
+	"io"
+	"math"
+	"sort"
+)
+
+// binaryMagic and binaryVersion prefix the binary encoding of a Matrix
+const (
+	binaryMagic   = "WMSM"
+	binaryVersion = 1
+)
+
+// entry is a single cell of the matrix
+type entry struct {
+	info    railinfo.StationToStation
+	present bool
+	// synthesized cells are the reverse of a pair the API only listed in one direction
+	synthesized bool
+}
+
+// Matrix holds the distance, travel time and fares between station pairs, indexed for constant time lookups
+type Matrix struct {
+	codes   []string
+	index   map[string]int
+	entries []entry
+}
+
+// New builds a matrix from station to station information. A pair only listed in one direction is also used for the
+// reverse direction, though only the pairs listed are encoded and compared by Diff
+func New(stationToStation []railinfo.StationToStation) *Matrix {
+	codeSet := make(map[string]bool)
+
+	for _, info := range stationToStation {
+		codeSet[info.SourceStation] = true
+		codeSet[info.DestinationStation] = true
+	}
+
+	codes := make([]string, 0, len(codeSet))
+
+	for code := range codeSet {
+		codes = append(codes, code)
+	}
+
+	sort.Strings(codes)
+
+	matrix := Matrix{
+		codes:   codes,
+		index:   make(map[string]int, len(codes)),
+		entries: make([]entry, len(codes)*len(codes)),
+	}
+
+	for i, code := range codes {
+		matrix.index[code] = i
+	}
+
+	for _, info := range stationToStation {
+		matrix.entries[matrix.cell(info.SourceStation, info.DestinationStation)] = entry{info: info, present: true}
+	}
+
+	for _, info := range stationToStation {
+		reverse := matrix.cell(info.DestinationStation, info.SourceStation)
+
+		if matrix.entries[reverse].present {
+			continue
+		}
+
+		info.SourceStation, info.DestinationStation = info.DestinationStation, info.SourceStation
+		matrix.entries[reverse] = entry{info: info, present: true, synthesized: true}
+	}
+
+	return &matrix
+}
+
+// Load retrieves station to station information for every pair of stations with a single request
+func Load(railInfo railinfo.RailInfo) (*Matrix, error) {
+	response, responseErr := railInfo.GetStationToStationInformation("", "")
+
+	if responseErr != nil {
+		return nil, responseErr
+	}
+
+	return New(response.StationToStationInformation), nil
+}
+
+// StationCodes returns the station codes in the matrix in alphabetical order
+func (matrix *Matrix) StationCodes() []string {
+	return append([]string(nil), matrix.codes...)
+}
+
+// Len returns the number of station pairs in the matrix
+func (matrix *Matrix) Len() int {
+	count := 0
+
+	for _, cell := range matrix.entries {
+		if cell.present {
+			count++
+		}
+	}
+
+	return count
+}
+
+// Lookup returns the station to station information between two stations
+func (matrix *Matrix) Lookup(from, to string) (railinfo.StationToStation, bool) {
+	i, fromExist := matrix.index[from]
+	j, toExist := matrix.index[to]
+
+	if !fromExist || !toExist {
+		return railinfo.StationToStation{}, false
+	}
+
+	cell := matrix.entries[i*len(matrix.codes)+j]
+
+	return cell.info, cell.present
+}
+
+// Miles returns the composite miles between two stations
+func (matrix *Matrix) Miles(from, to string) (float64, bool) {
+	info, exist := matrix.Lookup(from, to)
+
+	return info.CompositeMiles, exist
+}
+
+// Time returns the estimated rail time in minutes between two stations
+func (matrix *Matrix) Time(from, to string) (int, bool) {
+	info, exist := matrix.Lookup(from, to)
+
+	return info.Time, exist
+}
+
+// Fare returns the fares between two stations
+func (matrix *Matrix) Fare(from, to string) (railinfo.RailFare, bool) {
+	info, exist := matrix.Lookup(from, to)
+
+	return info.Fare, exist
+}
+
+// StationToStation returns every pair in the matrix ordered by source then destination station
+func (matrix *Matrix) StationToStation() []railinfo.StationToStation {
+	var infos []railinfo.StationToStation
+
+	for _, cell := range matrix.entries {
+		if cell.present {
+			infos = append(infos, cell.info)
+		}
+	}
+
+	return infos
+}
+
+// listed returns the pairs in the matrix that were not synthesized from their reverse, ordered by source then
+// destination station
+func (matrix *Matrix) listed() []railinfo.StationToStation {
+	var infos []railinfo.StationToStation
+
+	for _, cell := range matrix.entries {
+		if cell.present && !cell.synthesized {
+			infos = append(infos, cell.info)
+		}
+	}
+
+	return infos
+}
+
+// MarshalJSON encodes the pairs listed in the matrix in the same shape as a GetStationToStationInformation response, so
+// a saved matrix can be read back with either UnmarshalJSON or railinfo.GetStationToStationInformationResponse
+func (matrix *Matrix) MarshalJSON() ([]byte, error) {
+	return json.Marshal(railinfo.GetStationToStationInformationResponse{
+		StationToStationInformation: matrix.listed(),
+	})
+}
+
+// UnmarshalJSON decodes a matrix from a GetStationToStationInformation response
+func (matrix *Matrix) UnmarshalJSON(data []byte) error {
+	response := railinfo.GetStationToStationInformationResponse{}
+
+	if unmarshalErr := json.Unmarshal(data, &response); unmarshalErr != nil {
+		return unmarshalErr
+	}
+
+	*matrix = *New(response.StationToStationInformation)
+
+	return nil
+}
+
+// MarshalBinary encodes the matrix compactly: the station codes followed by one fixed size record per listed pair
+// holding miles in hundredths, rail time in minutes and fares in cents
+func (matrix *Matrix) MarshalBinary() ([]byte, error) {
+	if len(matrix.codes) > math.MaxUint16 {
+		return nil, errors.New("too many stations to encode")
+	}
+
+	buffer := bytes.Buffer{}
+	buffer.WriteString(binaryMagic)
+	buffer.WriteByte(binaryVersion)
+
+	writeErr := binary.Write(&buffer, binary.BigEndian, uint16(len(matrix.codes)))
+
+	for _, code := range matrix.codes {
+		if len(code) > math.MaxUint8 {
+			return nil, errors.New("station code too long to encode: " + code)
+		}
+
+		buffer.WriteByte(byte(len(code)))
+		buffer.WriteString(code)
+	}
+
+	if writeErr == nil {
+		writeErr = binary.Write(&buffer, binary.BigEndian, uint32(len(matrix.listed())))
+	}
+
+	for i, cell := range matrix.entries {
+		if !cell.present || cell.synthesized || writeErr != nil {
+			continue
+		}
+
+		writeErr = binary.Write(&buffer, binary.BigEndian, binaryRecord{
+			From:           uint16(i / len(matrix.codes)),
+			To:             uint16(i % len(matrix.codes)),
+			CompositeMiles: uint32(math.Round(cell.info.CompositeMiles * 100)),
+			Time:           uint16(cell.info.Time),
+			PeakTime:       uint16(math.Round(cell.info.Fare.PeakTime * 100)),
+			OffPeakTime:    uint16(math.Round(cell.info.Fare.OffPeakTime * 100)),
+			SeniorDisabled: uint16(math.Round(cell.info.Fare.SeniorDisabled * 100)),
+		})
+	}
+
+	if writeErr != nil {
+		return nil, writeErr
+	}
+
+	return buffer.Bytes(), nil
+}
+
+// UnmarshalBinary decodes a matrix written by MarshalBinary
+func (matrix *Matrix) UnmarshalBinary(data []byte) error {
+	reader := bytes.NewReader(data)
+	header := make([]byte, len(binaryMagic)+1)
+
+	if _, readErr := io.ReadFull(reader, header); readErr != nil || string(header[:len(binaryMagic)]) != binaryMagic {
+		return errors.New("invalid station matrix encoding")
+	}
+
+	if header[len(binaryMagic)] != binaryVersion {
+		return errors.New("unsupported station matrix version")
+	}
+
+	var stationCount uint16
+
+	if readErr := binary.Read(reader, binary.BigEndian, &stationCount); readErr != nil {
+		return readErr
+	}
+
+	codes := make([]string, stationCount)
+
+	for i := range codes {
+		length, readErr := reader.ReadByte()
+
+		if readErr != nil {
+			return readErr
+		}
+
+		code := make([]byte, length)
+
+		if _, readErr := io.ReadFull(reader, code); readErr != nil {
+			return readErr
+		}
+
+		codes[i] = string(code)
+	}
+
+	var pairCount uint32
+
+	if readErr := binary.Read(reader, binary.BigEndian, &pairCount); readErr != nil {
+		return readErr
+	}
+
+	infos := make([]railinfo.StationToStation, 0, pairCount)
+
+	for i := uint32(0); i < pairCount; i++ {
+		record := binaryRecord{}
+
+		if readErr := binary.Read(reader, binary.BigEndian, &record); readErr != nil {
+			return readErr
+		}
+
+		if int(record.From) >= len(codes) || int(record.To) >= len(codes) {
+			return errors.New("invalid station matrix encoding")
+		}
+
+		infos = append(infos, railinfo.StationToStation{
+			CompositeMiles:     float64(record.CompositeMiles) / 100,
+			DestinationStation: codes[record.To],
+			Fare: railinfo.RailFare{
+				OffPeakTime:    float64(record.OffPeakTime) / 100,
+				PeakTime:       float64(record.PeakTime) / 100,
+				SeniorDisabled: float64(record.SeniorDisabled) / 100,
+			},
+			Time:          int(record.Time),
+			SourceStation: codes[record.From],
+		})
+	}
+
+	*matrix = *New(infos)
+
+	return nil
+}
+
+// binaryRecord is the fixed size encoding of one station pair
+type binaryRecord struct {
+	From           uint16
+	To             uint16
+	CompositeMiles uint32
+	Time           uint16
+	PeakTime       uint16
+	OffPeakTime    uint16
+	SeniorDisabled uint16
+}
+
+// cell returns the index into entries for a station pair known to be in the matrix
+func (matrix *Matrix) cell(from, to string) int {
+	return matrix.index[from]*len(matrix.codes) + matrix.index[to]
+}
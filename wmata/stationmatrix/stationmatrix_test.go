@@ -0,0 +1,238 @@
+package stationmatrix
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/awiede/wmata-go-sdk/wmata"
+	"github.com/awiede/wmata-go-sdk/wmata/railinfo"
+	"github.com/kr/pretty"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+// testClient is a mock implementation of wmata.HTTPClient interface used for testing purposes
+type testClient struct{}
+
+// ensure testClient implements wmata.HTTPClient interface
+var _ wmata.HTTPClient = (*testClient)(nil)
+
+// Do stubs out an httpClient.Do request
+func (client *testClient) Do(req *http.Request) (*http.Response, error) {
+	testResponses, exist := testData[req.URL.Path]
+
+	if !exist {
+		return nil, errors.New("no test data found")
+	}
+
+	for _, response := range testResponses {
+		if response.rawQuery == req.URL.RawQuery {
+			rr := httptest.NewRecorder()
+			rr.WriteHeader(http.StatusOK)
+			_, writeErr := rr.Write([]byte(response.response))
+
+			return rr.Result(), writeErr
+		}
+	}
+
+	return nil, errors.New("no data found")
+}
+
+type testResponseData struct {
+	rawQuery string
+	response string
+}
+
+var testData = map[string][]testResponseData{
+	"/Rail.svc/json/jSrcStationToDstStationInfo": {
+		{
+			rawQuery: "",
+			response: `{"StationToStationInfos":[` +
+				`{"CompositeMiles":13.98,"DestinationStation":"A15","RailFare":{"OffPeakTime":3.85,"PeakTime":6.0,"SeniorDisabled":1.9},"RailTime":31,"SourceStation":"A01"},` +
+				`{"CompositeMiles":13.98,"DestinationStation":"A01","RailFare":{"OffPeakTime":3.85,"PeakTime":6.0,"SeniorDisabled":1.9},"RailTime":31,"SourceStation":"A15"},` +
+				`{"CompositeMiles":2.17,"DestinationStation":"C05","RailFare":{"OffPeakTime":2.25,"PeakTime":2.25,"SeniorDisabled":1.1},"RailTime":6,"SourceStation":"A01"}` +
+				`]}`,
+		},
+	},
+}
+
+// loadTestMatrix loads a matrix through a rail service with a mock http client
+func loadTestMatrix(t *testing.T) *Matrix {
+	wmataClient := wmata.Client{
+		HTTPClient: &testClient{},
+	}
+
+	matrix, err := Load(railinfo.NewService(&wmataClient, wmata.JSON))
+
+	if err != nil {
+		t.Fatalf("error calling Load: %s", err.Error())
+	}
+
+	return matrix
+}
+
+func TestLookup(t *testing.T) {
+	matrix := loadTestMatrix(t)
+
+	if codes := matrix.StationCodes(); !reflect.DeepEqual(codes, []string{"A01", "A15", "C05"}) {
+		t.Errorf("unexpected station codes: %v", codes)
+	}
+
+	// C05 to A01 is only listed in one direction and is filled in from the reverse pair
+	if matrix.Len() != 4 {
+		t.Errorf("expected 4 pairs, got %d", matrix.Len())
+	}
+
+	if miles, exist := matrix.Miles("A01", "A15"); !exist || miles != 13.98 {
+		t.Errorf("unexpected miles from A01 to A15: %v %v", miles, exist)
+	}
+
+	if minutes, exist := matrix.Time("C05", "A01"); !exist || minutes != 6 {
+		t.Errorf("unexpected time from C05 to A01: %v %v", minutes, exist)
+	}
+
+	if fare, exist := matrix.Fare("C05", "A01"); !exist || fare != (railinfo.RailFare{OffPeakTime: 2.25, PeakTime: 2.25, SeniorDisabled: 1.1}) {
+		t.Errorf("unexpected fare from C05 to A01: %v %v", fare, exist)
+	}
+
+	if info, exist := matrix.Lookup("C05", "A01"); !exist || info.SourceStation != "C05" || info.DestinationStation != "A01" {
+		t.Errorf("unexpected reverse pair: %# v", pretty.Formatter(info))
+	}
+
+	if _, exist := matrix.Lookup("A15", "C05"); exist {
+		t.Error("expected no pair between A15 and C05")
+	}
+
+	if _, exist := matrix.Lookup("A01", "K08"); exist {
+		t.Error("expected no pair for unknown station K08")
+	}
+}
+
+func TestSerialization(t *testing.T) {
+	matrix := loadTestMatrix(t)
+
+	jsonData, err := json.Marshal(matrix)
+
+	if err != nil {
+		t.Errorf("error marshaling JSON: %s", err.Error())
+		return
+	}
+
+	fromJSON := Matrix{}
+
+	if err := json.Unmarshal(jsonData, &fromJSON); err != nil {
+		t.Errorf("error unmarshaling JSON: %s", err.Error())
+		return
+	}
+
+	if !reflect.DeepEqual(&fromJSON, matrix) {
+		t.Error(pretty.Diff(&fromJSON, matrix))
+	}
+
+	binaryData, err := matrix.MarshalBinary()
+
+	if err != nil {
+		t.Errorf("error marshaling binary: %s", err.Error())
+		return
+	}
+
+	if len(binaryData) >= len(jsonData) {
+		t.Errorf("expected binary encoding to be smaller than JSON: %d >= %d", len(binaryData), len(jsonData))
+	}
+
+	fromBinary := Matrix{}
+
+	if err := fromBinary.UnmarshalBinary(binaryData); err != nil {
+		t.Errorf("error unmarshaling binary: %s", err.Error())
+		return
+	}
+
+	if !reflect.DeepEqual(&fromBinary, matrix) {
+		t.Error(pretty.Diff(&fromBinary, matrix))
+	}
+
+	if err := fromBinary.UnmarshalBinary([]byte("nope")); err == nil {
+		t.Error("expected error unmarshaling invalid binary data")
+	}
+}
+
+func TestDiff(t *testing.T) {
+	before := New([]railinfo.StationToStation{
+		{SourceStation: "A01", DestinationStation: "A15", CompositeMiles: 13.98, Time: 31, Fare: railinfo.RailFare{OffPeakTime: 3.85, PeakTime: 6.0, SeniorDisabled: 1.9}},
+		{SourceStation: "A01", DestinationStation: "C05", CompositeMiles: 2.17, Time: 6, Fare: railinfo.RailFare{OffPeakTime: 2.25, PeakTime: 2.25, SeniorDisabled: 1.1}},
+	})
+
+	after := New([]railinfo.StationToStation{
+		{SourceStation: "A01", DestinationStation: "A15", CompositeMiles: 13.98, Time: 31, Fare: railinfo.RailFare{OffPeakTime: 4.0, PeakTime: 6.0, SeniorDisabled: 2.0}},
+		{SourceStation: "A01", DestinationStation: "C05", CompositeMiles: 2.17, Time: 7, Fare: railinfo.RailFare{OffPeakTime: 2.25, PeakTime: 2.25, SeniorDisabled: 1.1}},
+	})
+
+	// the reverse pairs filled in from A01 are not reported again
+	changes := Diff(before, after)
+
+	if len(changes) != 2 {
+		t.Errorf("expected 4 changes, got %# v", pretty.Formatter(changes))
+		return
+	}
+
+	for _, change := range changes {
+		if change.Type != ChangeModified {
+			t.Errorf("unexpected change type from %s to %s: %s", change.SourceStation, change.DestinationStation, change.Type)
+		}
+	}
+
+	fareChanges := FareChanges(before, after)
+
+	if len(fareChanges) != 1 || fareChanges[0].SourceStation != "A01" || fareChanges[0].DestinationStation != "A15" {
+		t.Errorf("unexpected fare changes: %# v", pretty.Formatter(fareChanges))
+	}
+
+	added := Diff(New(nil), before)
+
+	if len(added) != 2 || added[0].Type != ChangeAdded || added[0].Old != nil || added[0].New.DestinationStation != "A15" {
+		t.Errorf("unexpected added changes: %# v", pretty.Formatter(added))
+	}
+
+	removed := Diff(before, New(nil))
+
+	if len(removed) != 2 || removed[0].Type != ChangeRemoved || removed[0].New != nil || !removed[0].FareChanged() {
+		t.Errorf("unexpected removed changes: %# v", pretty.Formatter(removed))
+	}
+}
+
+func TestDiffNoPhantomChanges(t *testing.T) {
+	before := New([]railinfo.StationToStation{
+		{SourceStation: "A01", DestinationStation: "C05", CompositeMiles: 2.17, Time: 6, Fare: railinfo.RailFare{OffPeakTime: 2.25, PeakTime: 2.25, SeniorDisabled: 1.1}},
+	})
+
+	// the API now lists the reverse pair too, with float noise the binary encoding rounds away
+	after := New([]railinfo.StationToStation{
+		{SourceStation: "A01", DestinationStation: "C05", CompositeMiles: 2.1700001, Time: 6, Fare: railinfo.RailFare{OffPeakTime: 2.25, PeakTime: 2.2500001, SeniorDisabled: 1.1}},
+		{SourceStation: "C05", DestinationStation: "A01", CompositeMiles: 2.17, Time: 6, Fare: railinfo.RailFare{OffPeakTime: 2.25, PeakTime: 2.25, SeniorDisabled: 1.0999999}},
+	})
+
+	if changes := Diff(before, after); len(changes) != 0 {
+		t.Errorf("expected no changes, got %# v", pretty.Formatter(changes))
+	}
+
+	binaryData, err := after.MarshalBinary()
+
+	if err != nil {
+		t.Fatalf("error marshaling binary: %s", err.Error())
+	}
+
+	fromBinary := Matrix{}
+
+	if err := fromBinary.UnmarshalBinary(binaryData); err != nil {
+		t.Fatalf("error unmarshaling binary: %s", err.Error())
+	}
+
+	if changes := Diff(after, &fromBinary); len(changes) != 0 {
+		t.Errorf("expected no changes after a binary round trip, got %# v", pretty.Formatter(changes))
+	}
+
+	if changes := FareChanges(before, &fromBinary); len(changes) != 0 {
+		t.Errorf("expected no fare changes after a binary round trip, got %# v", pretty.Formatter(changes))
+	}
+}
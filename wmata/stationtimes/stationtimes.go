@@ -0,0 +1,333 @@
+package stationtimes
+
+import (
+	"errors"
+	"github.com/awiede/wmata-go-sdk/wmata"
+	"github.com/awiede/wmata-go-sdk/wmata/railinfo"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ServiceDayStartHour is the hour before which times belong to the previous service day, e.g. a 00:49 last train
+// departs on the calendar day after the service day it is listed under. Hours are in Washington, see wmata.Location
+const ServiceDayStartHour = 4
+
+// Train is a first or last train toward a destination station
+type Train struct {
+	DestinationStation string    `json:"DestinationStation"`
+	Time               time.Time `json:"Time"`
+}
+
+// ServiceDay is a station's opening time and first and last trains for one service day, with times resolved to
+// calendar dates. Trains are ordered by time
+type ServiceDay struct {
+	StationCode string    `json:"StationCode"`
+	StationName string    `json:"StationName"`
+	Date        time.Time `json:"Date"`
+	// Opening is zero when the opening time is missing from the timings
+	Opening     time.Time `json:"Opening"`
+	FirstTrains []Train   `json:"FirstTrains"`
+	LastTrains  []Train   `json:"LastTrains"`
+}
+
+// LastTrainCheck is the result of checking whether a rider reaches the platform before the last train leaves
+type LastTrainCheck struct {
+	DestinationStation string    `json:"DestinationStation"`
+	LastTrain          time.Time `json:"LastTrain"`
+	// ArriveAt is when the rider reaches the platform
+	ArriveAt time.Time `json:"ArriveAt"`
+	// Slack is the time between ArriveAt and LastTrain, negative when the train is missed
+	Slack      time.Duration `json:"Slack"`
+	MakesTrain bool          `json:"MakesTrain"`
+}
+
+// ServiceDate returns midnight in Washington of the service day containing at, whatever the location of at
+func ServiceDate(at time.Time) time.Time {
+	at = at.In(wmata.Location)
+	serviceDate := time.Date(at.Year(), at.Month(), at.Day(), 0, 0, 0, 0, wmata.Location)
+
+	if at.Hour() < ServiceDayStartHour {
+		serviceDate = serviceDate.AddDate(0, 0, -1)
+	}
+
+	return serviceDate
+}
+
+// ParseTime converts an "HH:MM" time from station timings into a time in Washington on the calendar date of
+// serviceDate, placing times before ServiceDayStartHour on the following calendar day
+func ParseTime(serviceDate time.Time, value string) (time.Time, error) {
+	parts := strings.Split(value, ":")
+
+	if len(parts) != 2 {
+		return time.Time{}, errors.New("invalid station time: " + value)
+	}
+
+	hour, hourErr := strconv.Atoi(parts[0])
+	minute, minuteErr := strconv.Atoi(parts[1])
+
+	if hourErr != nil || minuteErr != nil || hour < 0 || hour > 23 || minute < 0 || minute > 59 {
+		return time.Time{}, errors.New("invalid station time: " + value)
+	}
+
+	if hour < ServiceDayStartHour {
+		hour += 24
+	}
+
+	return time.Date(serviceDate.Year(), serviceDate.Month(), serviceDate.Day(), hour, minute, 0, 0, wmata.Location), nil
+}
+
+// DayItem returns the timings listed for a weekday
+func DayItem(timing *railinfo.StationTime, weekday time.Weekday) *railinfo.StationDayItem {
+	switch weekday {
+	case time.Monday:
+		return &timing.Monday
+	case time.Tuesday:
+		return &timing.Tuesday
+	case time.Wednesday:
+		return &timing.Wednesday
+	case time.Thursday:
+		return &timing.Thursday
+	case time.Friday:
+		return &timing.Friday
+	case time.Saturday:
+		return &timing.Saturday
+	default:
+		return &timing.Sunday
+	}
+}
+
+// ForTime returns the service day containing at, with times in Washington. Trains with times that cannot be parsed are skipped
+func ForTime(timing *railinfo.StationTime, at time.Time) (*ServiceDay, error) {
+	if timing == nil {
+		return nil, errors.New("station timing is required")
+	}
+
+	serviceDate := ServiceDate(at)
+	dayItem := DayItem(timing, serviceDate.Weekday())
+
+	day := ServiceDay{
+		StationCode: timing.StationCode,
+		StationName: timing.StationName,
+		Date:        serviceDate,
+		FirstTrains: parseTrains(serviceDate, dayItem.FirstTrains),
+		LastTrains:  parseTrains(serviceDate, dayItem.LastTrains),
+	}
+
+	if opening, openingErr := ParseTime(serviceDate, dayItem.OpeningTime); openingErr == nil {
+		day.Opening = opening
+	}
+
+	return &day, nil
+}
+
+// IsOpen reports whether the station is open at the given time: after it opens and no later than its last train.
+// Stations without an opening time are treated as opening at their first train
+func (day *ServiceDay) IsOpen(at time.Time) bool {
+	opening := day.Opening
+	first, last, found := day.Window(nil)
+
+	if !found {
+		return false
+	}
+
+	if opening.IsZero() {
+		opening = first
+	}
+
+	return !at.Before(opening) && !at.After(last)
+}
+
+// FirstTrain returns the first train toward a destination station
+func (day *ServiceDay) FirstTrain(destinationStation string) (Train, bool) {
+	return findTrain(day.FirstTrains, destinationStation)
+}
+
+// LastTrain returns the last train toward a destination station
+func (day *ServiceDay) LastTrain(destinationStation string) (Train, bool) {
+	return findTrain(day.LastTrains, destinationStation)
+}
+
+// Destinations returns the destination station codes with a first or last train, in alphabetical order
+func (day *ServiceDay) Destinations() []string {
+	seen := make(map[string]bool)
+	var destinations []string
+
+	for _, trains := range [][]Train{day.FirstTrains, day.LastTrains} {
+		for _, train := range trains {
+			if !seen[train.DestinationStation] {
+				seen[train.DestinationStation] = true
+				destinations = append(destinations, train.DestinationStation)
+			}
+		}
+	}
+
+	sort.Strings(destinations)
+
+	return destinations
+}
+
+// Window returns the earliest first train and latest last train, counting only trains bound for destinations accepted
+// by include. A nil include accepts every destination
+func (day *ServiceDay) Window(include func(destinationStation string) bool) (time.Time, time.Time, bool) {
+	var first, last time.Time
+
+	for _, train := range day.FirstTrains {
+		if (include == nil || include(train.DestinationStation)) && (first.IsZero() || train.Time.Before(first)) {
+			first = train.Time
+		}
+	}
+
+	for _, train := range day.LastTrains {
+		if (include == nil || include(train.DestinationStation)) && train.Time.After(last) {
+			last = train.Time
+		}
+	}
+
+	return first, last, !first.IsZero() && !last.IsZero()
+}
+
+// CheckLastTrain reports whether a rider who needs travelTime from at to reach the platform makes the last train
+// toward a destination station. An empty destination checks the latest last train in any direction
+func (day *ServiceDay) CheckLastTrain(destinationStation string, at time.Time, travelTime time.Duration) (*LastTrainCheck, error) {
+	var lastTrain Train
+	var found bool
+
+	if destinationStation == "" {
+		for _, train := range day.LastTrains {
+			if !found || train.Time.After(lastTrain.Time) {
+				lastTrain, found = train, true
+			}
+		}
+	} else {
+		lastTrain, found = day.LastTrain(destinationStation)
+	}
+
+	if !found {
+		return nil, errors.New("no last train found toward " + destinationStation)
+	}
+
+	arriveAt := at.Add(travelTime)
+
+	return &LastTrainCheck{
+		DestinationStation: lastTrain.DestinationStation,
+		LastTrain:          lastTrain.Time,
+		ArriveAt:           arriveAt,
+		Slack:              lastTrain.Time.Sub(arriveAt),
+		MakesTrain:         !arriveAt.After(lastTrain.Time),
+	}, nil
+}
+
+// Service retrieves station timings and caches them per station, since they rarely change
+type Service struct {
+	railInfo railinfo.RailInfo
+	mutex    sync.Mutex
+	timings  map[string]*railinfo.StationTime
+}
+
+// NewService returns a Service that loads timings using an existing RailInfo service
+func NewService(railInfo railinfo.RailInfo) *Service {
+	return &Service{
+		railInfo: railInfo,
+		timings:  make(map[string]*railinfo.StationTime),
+	}
+}
+
+// GetServiceDay returns a station's service day containing at
+func (service *Service) GetServiceDay(stationCode string, at time.Time) (*ServiceDay, error) {
+	timing, timingErr := service.GetStationTime(stationCode)
+
+	if timingErr != nil {
+		return nil, timingErr
+	}
+
+	return ForTime(timing, at)
+}
+
+// GetStationTime returns a station's timings, retrieving them on first use
+func (service *Service) GetStationTime(stationCode string) (*railinfo.StationTime, error) {
+	if stationCode == "" {
+		return nil, errors.New("stationCode is required")
+	}
+
+	service.mutex.Lock()
+	timing, exist := service.timings[stationCode]
+	service.mutex.Unlock()
+
+	if exist {
+		return timing, nil
+	}
+
+	response, responseErr := service.railInfo.GetStationTimings(stationCode)
+
+	if responseErr != nil {
+		return nil, responseErr
+	}
+
+	if len(response.StationTimes) == 0 {
+		return nil, errors.New("no station timings found for " + stationCode)
+	}
+
+	timing = &response.StationTimes[0]
+
+	service.mutex.Lock()
+	service.timings[stationCode] = timing
+	service.mutex.Unlock()
+
+	return timing, nil
+}
+
+// IsOpen reports whether a station is open at the given time
+func (service *Service) IsOpen(stationCode string, at time.Time) (bool, error) {
+	day, dayErr := service.GetServiceDay(stationCode, at)
+
+	if dayErr != nil {
+		return false, dayErr
+	}
+
+	return day.IsOpen(at), nil
+}
+
+// CheckLastTrain reports whether a rider who needs travelTime from at to reach a station's platform makes the last
+// train toward a destination station
+func (service *Service) CheckLastTrain(stationCode, destinationStation string, at time.Time, travelTime time.Duration) (*LastTrainCheck, error) {
+	day, dayErr := service.GetServiceDay(stationCode, at)
+
+	if dayErr != nil {
+		return nil, dayErr
+	}
+
+	return day.CheckLastTrain(destinationStation, at, travelTime)
+}
+
+func parseTrains(serviceDate time.Time, trains []railinfo.StationTrainInformation) []Train {
+	var parsed []Train
+
+	for _, train := range trains {
+		departure, parseErr := ParseTime(serviceDate, train.Time)
+
+		if parseErr != nil {
+			continue
+		}
+
+		parsed = append(parsed, Train{DestinationStation: train.DestinationStation, Time: departure})
+	}
+
+	sort.SliceStable(parsed, func(i, j int) bool {
+		return parsed[i].Time.Before(parsed[j].Time)
+	})
+
+	return parsed
+}
+
+func findTrain(trains []Train, destinationStation string) (Train, bool) {
+	for _, train := range trains {
+		if train.DestinationStation == destinationStation {
+			return train, true
+		}
+	}
+
+	return Train{}, false
+}
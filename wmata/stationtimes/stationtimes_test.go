@@ -0,0 +1,299 @@
+package stationtimes
+
+import (
+	"errors"
+	"github.com/awiede/wmata-go-sdk/wmata"
+	"github.com/awiede/wmata-go-sdk/wmata/railinfo"
+	"github.com/kr/pretty"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// testClient is a mock implementation of wmata.HTTPClient interface used for testing purposes
+type testClient struct {
+	requests int
+}
+
+// ensure testClient implements wmata.HTTPClient interface
+var _ wmata.HTTPClient = (*testClient)(nil)
+
+// Do stubs out an httpClient.Do request
+func (client *testClient) Do(req *http.Request) (*http.Response, error) {
+	client.requests++
+
+	testResponses, exist := testData[req.URL.Path]
+
+	if !exist {
+		return nil, errors.New("no test data found")
+	}
+
+	for _, response := range testResponses {
+		if response.rawQuery == req.URL.RawQuery {
+			rr := httptest.NewRecorder()
+			rr.WriteHeader(http.StatusOK)
+			_, writeErr := rr.Write([]byte(response.response))
+
+			return rr.Result(), writeErr
+		}
+	}
+
+	return nil, errors.New("no data found")
+}
+
+type testResponseData struct {
+	rawQuery string
+	response string
+}
+
+const metroCenterDay = `{"OpeningTime":"05:00","FirstTrains":[{"Time":"05:14","DestinationStation":"B11"},{"Time":"05:09","DestinationStation":"A15"}],"LastTrains":[{"Time":"00:31","DestinationStation":"B11"},{"Time":"23:57","DestinationStation":"A15"}]}`
+
+const metroCenterWeekend = `{"OpeningTime":"07:00","FirstTrains":[{"Time":"07:14","DestinationStation":"B11"},{"Time":"07:09","DestinationStation":"A15"}],"LastTrains":[{"Time":"01:31","DestinationStation":"B11"},{"Time":"01:27","DestinationStation":"A15"}]}`
+
+var testData = map[string][]testResponseData{
+	"/Rail.svc/json/jStationTimes": {
+		{
+			rawQuery: "StationCode=A01",
+			response: `{"StationTimes":[{"Code":"A01","StationName":"Metro Center",` +
+				`"Monday":` + metroCenterDay + `,"Tuesday":` + metroCenterDay + `,"Wednesday":` + metroCenterDay + `,"Thursday":` + metroCenterDay + `,` +
+				`"Friday":` + metroCenterWeekend + `,"Saturday":` + metroCenterWeekend + `,"Sunday":` + metroCenterDay + `}]}`,
+		},
+		{
+			rawQuery: "StationCode=Z99",
+			response: `{"StationTimes":[]}`,
+		},
+	},
+}
+
+// setupTestService creates a service struct backed by a rail service with a mock http client
+func setupTestService() (*Service, *testClient) {
+	httpClient := testClient{}
+
+	wmataClient := wmata.Client{
+		HTTPClient: &httpClient,
+	}
+
+	return NewService(railinfo.NewService(&wmataClient, wmata.JSON)), &httpClient
+}
+
+func TestParseTime(t *testing.T) {
+	serviceDate := time.Date(2019, time.April, 29, 0, 0, 0, 0, wmata.Location)
+
+	testRequests := []struct {
+		value    string
+		expected time.Time
+		err      bool
+	}{
+		{value: "05:09", expected: time.Date(2019, time.April, 29, 5, 9, 0, 0, wmata.Location)},
+		{value: "23:57", expected: time.Date(2019, time.April, 29, 23, 57, 0, 0, wmata.Location)},
+		{value: "00:31", expected: time.Date(2019, time.April, 30, 0, 31, 0, 0, wmata.Location)},
+		{value: "03:05", expected: time.Date(2019, time.April, 30, 3, 5, 0, 0, wmata.Location)},
+		{value: "", err: true},
+		{value: "25:00", err: true},
+		{value: "5pm", err: true},
+	}
+
+	for _, request := range testRequests {
+		parsed, err := ParseTime(serviceDate, request.value)
+
+		if request.err {
+			if err == nil {
+				t.Errorf("expected error parsing %q", request.value)
+			}
+
+			continue
+		}
+
+		if err != nil || !parsed.Equal(request.expected) {
+			t.Errorf("unexpected time parsing %q: expected %s, got %s (%v)", request.value, request.expected, parsed, err)
+		}
+	}
+
+	if date := ServiceDate(time.Date(2019, time.April, 30, 1, 0, 0, 0, wmata.Location)); !date.Equal(serviceDate) {
+		t.Errorf("expected 1am to belong to the previous service day, got %s", date)
+	}
+}
+
+func TestGetServiceDay(t *testing.T) {
+	service, httpClient := setupTestService()
+
+	// 12:30am on a Saturday is still Friday's service day
+	day, err := service.GetServiceDay("A01", time.Date(2019, time.May, 4, 0, 30, 0, 0, wmata.Location))
+
+	if err != nil {
+		t.Errorf("error calling GetServiceDay: %s", err.Error())
+		return
+	}
+
+	friday := time.Date(2019, time.May, 3, 0, 0, 0, 0, wmata.Location)
+
+	expected := &ServiceDay{
+		StationCode: "A01",
+		StationName: "Metro Center",
+		Date:        friday,
+		Opening:     friday.Add(7 * time.Hour),
+		FirstTrains: []Train{
+			{DestinationStation: "A15", Time: friday.Add(7*time.Hour + 9*time.Minute)},
+			{DestinationStation: "B11", Time: friday.Add(7*time.Hour + 14*time.Minute)},
+		},
+		LastTrains: []Train{
+			{DestinationStation: "A15", Time: friday.Add(25*time.Hour + 27*time.Minute)},
+			{DestinationStation: "B11", Time: friday.Add(25*time.Hour + 31*time.Minute)},
+		},
+	}
+
+	if !reflect.DeepEqual(day, expected) {
+		t.Error(pretty.Diff(day, expected))
+	}
+
+	if destinations := day.Destinations(); !reflect.DeepEqual(destinations, []string{"A15", "B11"}) {
+		t.Errorf("unexpected destinations: %v", destinations)
+	}
+
+	if train, exist := day.FirstTrain("B11"); !exist || !train.Time.Equal(friday.Add(7*time.Hour+14*time.Minute)) {
+		t.Errorf("unexpected first train toward B11: %v", train)
+	}
+
+	if _, exist := day.LastTrain("K08"); exist {
+		t.Error("expected no last train toward K08")
+	}
+
+	if _, err := service.GetServiceDay("A01", time.Date(2019, time.May, 6, 12, 0, 0, 0, wmata.Location)); err != nil {
+		t.Errorf("error calling GetServiceDay: %s", err.Error())
+	}
+
+	if httpClient.requests != 1 {
+		t.Errorf("expected station timings to be cached after 1 request, got %d", httpClient.requests)
+	}
+
+	if _, err := service.GetServiceDay("Z99", time.Now()); err == nil {
+		t.Error("expected error for a station without timings")
+	}
+}
+
+func TestIsOpen(t *testing.T) {
+	service, _ := setupTestService()
+
+	testRequests := []struct {
+		at       time.Time
+		expected bool
+	}{
+		// Monday
+		{at: time.Date(2019, time.April, 29, 4, 59, 0, 0, wmata.Location), expected: false},
+		{at: time.Date(2019, time.April, 29, 5, 0, 0, 0, wmata.Location), expected: true},
+		{at: time.Date(2019, time.April, 30, 0, 31, 0, 0, wmata.Location), expected: true},
+		{at: time.Date(2019, time.April, 30, 0, 45, 0, 0, wmata.Location), expected: false},
+		// Saturday morning after Friday's late service
+		{at: time.Date(2019, time.May, 4, 1, 15, 0, 0, wmata.Location), expected: true},
+		{at: time.Date(2019, time.May, 4, 6, 30, 0, 0, wmata.Location), expected: false},
+	}
+
+	for _, request := range testRequests {
+		open, err := service.IsOpen("A01", request.at)
+
+		if err != nil {
+			t.Errorf("error calling IsOpen: %s", err.Error())
+			continue
+		}
+
+		if open != request.expected {
+			t.Errorf("unexpected open status at %s: expected %t, got %t", request.at, request.expected, open)
+		}
+	}
+}
+
+func TestUTC(t *testing.T) {
+	service, _ := setupTestService()
+
+	// 00:30 and 00:45 on Tuesday in Washington are still Monday's service day
+	if date := ServiceDate(time.Date(2019, time.April, 30, 4, 30, 0, 0, time.UTC)); !date.Equal(time.Date(2019, time.April, 29, 0, 0, 0, 0, wmata.Location)) {
+		t.Errorf("unexpected service date for a UTC time: %s", date)
+	}
+
+	testRequests := []struct {
+		at       time.Time
+		expected bool
+	}{
+		{at: time.Date(2019, time.April, 30, 4, 30, 0, 0, time.UTC), expected: true},
+		{at: time.Date(2019, time.April, 30, 4, 45, 0, 0, time.UTC), expected: false},
+		{at: time.Date(2019, time.April, 30, 9, 0, 0, 0, time.UTC), expected: true},
+	}
+
+	for _, request := range testRequests {
+		if open, err := service.IsOpen("A01", request.at); err != nil || open != request.expected {
+			t.Errorf("unexpected open status at %s: expected %t, got %t (%v)", request.at, request.expected, open, err)
+		}
+	}
+}
+
+func TestCheckLastTrain(t *testing.T) {
+	service, _ := setupTestService()
+	at := time.Date(2019, time.April, 29, 23, 40, 0, 0, wmata.Location)
+
+	check, err := service.CheckLastTrain("A01", "A15", at, 15*time.Minute)
+
+	if err != nil {
+		t.Errorf("error calling CheckLastTrain: %s", err.Error())
+		return
+	}
+
+	expected := &LastTrainCheck{
+		DestinationStation: "A15",
+		LastTrain:          time.Date(2019, time.April, 29, 23, 57, 0, 0, wmata.Location),
+		ArriveAt:           at.Add(15 * time.Minute),
+		Slack:              2 * time.Minute,
+		MakesTrain:         true,
+	}
+
+	if !reflect.DeepEqual(check, expected) {
+		t.Error(pretty.Diff(check, expected))
+	}
+
+	if check, _ := service.CheckLastTrain("A01", "A15", at, 20*time.Minute); check.MakesTrain || check.Slack != -3*time.Minute {
+		t.Errorf("expected to miss the last train: %# v", pretty.Formatter(check))
+	}
+
+	if check, _ := service.CheckLastTrain("A01", "", at, 20*time.Minute); !check.MakesTrain || check.DestinationStation != "B11" {
+		t.Errorf("expected to make the last train toward B11: %# v", pretty.Formatter(check))
+	}
+
+	if _, err := service.CheckLastTrain("A01", "K08", at, 0); err == nil {
+		t.Error("expected error for a destination without a last train")
+	}
+}
+
+func TestWindow(t *testing.T) {
+	timing := railinfo.StationTime{
+		Monday: railinfo.StationDayItem{
+			FirstTrains: []railinfo.StationTrainInformation{{Time: "05:00", DestinationStation: "J03"}, {Time: "05:10", DestinationStation: "D01"}},
+			LastTrains:  []railinfo.StationTrainInformation{{Time: "00:30", DestinationStation: "J03"}, {Time: "23:40", DestinationStation: "D01"}},
+		},
+	}
+
+	// 1am on a Tuesday still belongs to Monday's service day
+	day, _ := ForTime(&timing, time.Date(2019, 4, 30, 1, 0, 0, 0, wmata.Location))
+	first, last, found := day.Window(nil)
+
+	if !found || !first.Equal(time.Date(2019, 4, 29, 5, 0, 0, 0, wmata.Location)) || !last.Equal(time.Date(2019, 4, 30, 0, 30, 0, 0, wmata.Location)) {
+		t.Errorf("unexpected service window: %s - %s", first, last)
+	}
+
+	day, _ = ForTime(&timing, time.Date(2019, 4, 29, 12, 0, 0, 0, wmata.Location))
+	_, last, _ = day.Window(func(code string) bool { return code == "D01" })
+
+	if !last.Equal(time.Date(2019, 4, 29, 23, 40, 0, 0, wmata.Location)) {
+		t.Errorf("unexpected last train toward D01: %s", last)
+	}
+
+	day, _ = ForTime(&timing, time.Date(2019, 4, 30, 12, 0, 0, 0, wmata.Location))
+
+	if _, _, found := day.Window(nil); found {
+		t.Error("expected no service window on a day without timings")
+	}
+
+	if _, err := ForTime(nil, time.Now()); err == nil {
+		t.Error("expected error without station timing")
+	}
+}
@@ -0,0 +1,237 @@
+package stopboard
+
+import (
+	"errors"
+	"github.com/awiede/wmata-go-sdk/wmata"
+	"github.com/awiede/wmata-go-sdk/wmata/businfo"
+	"github.com/awiede/wmata-go-sdk/wmata/buspredictions"
+	"sort"
+	"time"
+)
+
+// DefaultWindow is how far ahead of the request time scheduled arrivals are included when no window is given
+const DefaultWindow = time.Hour
+
+// StopBoard defines the methods available to build combined arrival boards for bus stops
+type StopBoard interface {
+	GetStopBoard(request *GetStopBoardRequest) (*GetStopBoardResponse, error)
+}
+
+var _ StopBoard = (*Service)(nil)
+
+// NewService returns a new StopBoard service built on top of existing BusInfo and BusPredictions services
+func NewService(busInfo businfo.BusInfo, busPredictions buspredictions.BusPredictions) *Service {
+	return &Service{
+		busInfo:        busInfo,
+		busPredictions: busPredictions,
+	}
+}
+
+// Service combines realtime predictions, stop schedules and bus positions into a single arrival board
+type Service struct {
+	busInfo        businfo.BusInfo
+	busPredictions buspredictions.BusPredictions
+}
+
+// GetStopBoardRequest wraps a request for a stop arrival board
+type GetStopBoardRequest struct {
+	StopID string
+	// Time is the moment the board is built for, in any location. Defaults to time.Now()
+	Time time.Time
+	// Window limits scheduled-only arrivals to those due within this duration of Time. Defaults to DefaultWindow
+	Window time.Duration
+}
+
+type GetStopBoardResponse struct {
+	StopID      string    `json:"StopID"`
+	StopName    string    `json:"StopName"`
+	GeneratedAt time.Time `json:"GeneratedAt"`
+	Arrivals    []Arrival `json:"Arrivals"`
+}
+
+// Arrival is a single trip expected at the stop, either predicted in realtime or known only from the schedule
+type Arrival struct {
+	RouteID         string `json:"RouteID"`
+	DirectionText   string `json:"DirectionText"`
+	TripDestination string `json:"TripDestination"`
+	TripID          string `json:"TripID"`
+	VehicleID       string `json:"VehicleID"`
+	// Realtime is true when the arrival came from a realtime prediction, false when it is scheduled-only
+	Realtime bool `json:"Realtime"`
+	// ScheduledTime is the zero time when the predicted trip could not be found in the stop schedule
+	ScheduledTime time.Time `json:"ScheduledTime"`
+	ExpectedTime  time.Time `json:"ExpectedTime"`
+	Minutes       int       `json:"Minutes"`
+	// Lateness is ExpectedTime minus ScheduledTime, only set for realtime arrivals that matched a scheduled trip
+	Lateness time.Duration `json:"Lateness"`
+	// Vehicle is the last known position of the bus serving this trip, nil if none was reported
+	Vehicle *businfo.BusPosition `json:"Vehicle"`
+}
+
+// Scheduled reports whether the arrival was matched to a trip in the stop schedule
+func (arrival *Arrival) Scheduled() bool {
+	return !arrival.ScheduledTime.IsZero()
+}
+
+// GetStopBoard merges next bus predictions with the scheduled arrivals at a stop by TripID and attaches the last
+// known position of each vehicle
+func (service *Service) GetStopBoard(request *GetStopBoardRequest) (*GetStopBoardResponse, error) {
+	if request == nil || request.StopID == "" {
+		return nil, errors.New("stopID is required")
+	}
+
+	now := request.Time
+	if now.IsZero() {
+		now = time.Now()
+	}
+
+	window := request.Window
+	if window <= 0 {
+		window = DefaultWindow
+	}
+
+	predictions, predictionsErr := service.busPredictions.GetNextBuses(request.StopID)
+
+	if predictionsErr != nil {
+		return nil, predictionsErr
+	}
+
+	schedule, scheduleErr := service.busInfo.GetScheduleAtStop(request.StopID, now.In(wmata.Location).Format(wmata.DateLayout))
+
+	if scheduleErr != nil {
+		return nil, scheduleErr
+	}
+
+	board := GetStopBoardResponse{
+		StopID:      request.StopID,
+		StopName:    predictions.StopName,
+		GeneratedAt: now,
+	}
+
+	if board.StopName == "" {
+		board.StopName = schedule.StopInfo.Name
+	}
+
+	scheduledTrips := make(map[string]businfo.ScheduleArrival)
+
+	for _, scheduled := range schedule.ScheduleArrivals {
+		scheduledTrips[scheduled.TripID] = scheduled
+	}
+
+	predictedTrips := make(map[string]bool)
+
+	for _, prediction := range predictions.NextBusPredictions {
+		arrival := Arrival{
+			RouteID:       prediction.RouteID,
+			DirectionText: prediction.DirectionText,
+			TripID:        prediction.TripID,
+			VehicleID:     prediction.VehicleID,
+			Realtime:      true,
+			ExpectedTime:  now.Add(time.Duration(prediction.Minutes) * time.Minute),
+			Minutes:       prediction.Minutes,
+		}
+
+		if scheduled, exist := scheduledTrips[prediction.TripID]; exist {
+			arrival.TripDestination = scheduled.TripDestination
+
+			if scheduledTime, parseErr := time.ParseInLocation(wmata.DateTimeLayout, scheduled.ScheduleTime, wmata.Location); parseErr == nil {
+				arrival.ScheduledTime = scheduledTime
+				arrival.Lateness = arrival.ExpectedTime.Sub(scheduledTime)
+			}
+		}
+
+		predictedTrips[prediction.TripID] = true
+		board.Arrivals = append(board.Arrivals, arrival)
+	}
+
+	for _, scheduled := range schedule.ScheduleArrivals {
+		if predictedTrips[scheduled.TripID] {
+			continue
+		}
+
+		scheduledTime, parseErr := time.ParseInLocation(wmata.DateTimeLayout, scheduled.ScheduleTime, wmata.Location)
+
+		if parseErr != nil {
+			return nil, parseErr
+		}
+
+		if scheduledTime.Before(now) || scheduledTime.After(now.Add(window)) {
+			continue
+		}
+
+		board.Arrivals = append(board.Arrivals, Arrival{
+			RouteID:         scheduled.RouteID,
+			DirectionText:   scheduled.TripDirection,
+			TripDestination: scheduled.TripDestination,
+			TripID:          scheduled.TripID,
+			ScheduledTime:   scheduledTime,
+			ExpectedTime:    scheduledTime,
+			Minutes:         int(scheduledTime.Sub(now) / time.Minute),
+		})
+	}
+
+	if attachErr := service.attachVehicles(board.Arrivals); attachErr != nil {
+		return nil, attachErr
+	}
+
+	sort.SliceStable(board.Arrivals, func(i, j int) bool {
+		return board.Arrivals[i].ExpectedTime.Before(board.Arrivals[j].ExpectedTime)
+	})
+
+	return &board, nil
+}
+
+// attachVehicles looks up bus positions for every route on the board and links them to arrivals by VehicleID,
+// falling back to TripID for scheduled-only arrivals
+func (service *Service) attachVehicles(arrivals []Arrival) error {
+	var routeIDs []string
+	seenRoutes := make(map[string]bool)
+
+	for _, arrival := range arrivals {
+		if !seenRoutes[arrival.RouteID] {
+			seenRoutes[arrival.RouteID] = true
+			routeIDs = append(routeIDs, arrival.RouteID)
+		}
+	}
+
+	byVehicle := make(map[string]businfo.BusPosition)
+	byTrip := make(map[string]businfo.BusPosition)
+
+	for _, routeID := range routeIDs {
+		positions, positionsErr := service.busInfo.GetPositions(&businfo.GetPositionsRequest{RouteID: routeID})
+
+		if positionsErr != nil {
+			return positionsErr
+		}
+
+		for _, position := range positions.BusPositions {
+			byVehicle[position.VehicleID] = position
+			byTrip[position.TripID] = position
+		}
+	}
+
+	for i := range arrivals {
+		position, exist := byVehicle[arrivals[i].VehicleID]
+
+		if !exist || arrivals[i].VehicleID == "" {
+			position, exist = byTrip[arrivals[i].TripID]
+		}
+
+		if !exist {
+			continue
+		}
+
+		vehicle := position
+		arrivals[i].Vehicle = &vehicle
+
+		if arrivals[i].VehicleID == "" {
+			arrivals[i].VehicleID = position.VehicleID
+		}
+
+		if arrivals[i].TripDestination == "" {
+			arrivals[i].TripDestination = position.TripDestination
+		}
+	}
+
+	return nil
+}
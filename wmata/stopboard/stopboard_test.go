@@ -0,0 +1,152 @@
+package stopboard
+
+import (
+	"errors"
+	"github.com/awiede/wmata-go-sdk/wmata"
+	"github.com/awiede/wmata-go-sdk/wmata/businfo"
+	"github.com/awiede/wmata-go-sdk/wmata/buspredictions"
+	"github.com/kr/pretty"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// testClient is a mock implementation of wmata.HTTPClient interface used for testing purposes
+type testClient struct{}
+
+// ensure testClient implements wmata.HTTPClient interface
+var _ wmata.HTTPClient = (*testClient)(nil)
+
+// Do stubs out an httpClient.Do request
+func (client *testClient) Do(req *http.Request) (*http.Response, error) {
+	testResponses, exist := testData[req.URL.Path]
+
+	if !exist {
+		return nil, errors.New("no test data found")
+	}
+
+	for _, response := range testResponses {
+		if response.rawQuery == req.URL.RawQuery {
+			rr := httptest.NewRecorder()
+			rr.WriteHeader(http.StatusOK)
+			_, writeErr := rr.Write([]byte(response.response))
+
+			return rr.Result(), writeErr
+		}
+	}
+
+	return nil, errors.New("no data found")
+}
+
+type testResponseData struct {
+	rawQuery string
+	response string
+}
+
+var testData = map[string][]testResponseData{
+	"/NextBusService.svc/json/jPredictions": {
+		{
+			rawQuery: "StopID=1001370",
+			response: `{"StopName":"37th St Nw + O St Nw","Predictions":[{"RouteID":"G2","DirectionText":"East to Ledroit Park - Howard University","DirectionNum":"0","Minutes":2,"VehicleID":"3072","TripID":"939584010"},{"RouteID":"G2","DirectionText":"East to Ledroit Park - Howard University","DirectionNum":"0","Minutes":38,"VehicleID":"3081","TripID":"939585010"},{"RouteID":"D6","DirectionText":"East to Sibley Hospital","DirectionNum":"0","Minutes":12,"VehicleID":"5301","TripID":"111111111"}]}`,
+		},
+	},
+	"/Bus.svc/json/jStopSchedule": {
+		{
+			rawQuery: "Date=2019-04-28&StopID=1001370",
+			response: `{"ScheduleArrivals":[{"ScheduleTime":"2019-04-28T07:30:00","DirectionNum":"0","StartTime":"2019-04-28T07:20:00","EndTime":"2019-04-28T08:00:00","RouteID":"G2","TripDirectionText":"EAST","TripHeadsign":"LEDROIT PARK","TripID":"939583010"},{"ScheduleTime":"2019-04-28T07:59:00","DirectionNum":"0","StartTime":"2019-04-28T07:50:00","EndTime":"2019-04-28T08:30:00","RouteID":"G2","TripDirectionText":"EAST","TripHeadsign":"LEDROIT PARK","TripID":"939584010"},{"ScheduleTime":"2019-04-28T08:20:00","DirectionNum":"0","StartTime":"2019-04-28T08:10:00","EndTime":"2019-04-28T08:50:00","RouteID":"G2","TripDirectionText":"EAST","TripHeadsign":"LEDROIT PARK","TripID":"939584510"},{"ScheduleTime":"2019-04-28T08:35:00","DirectionNum":"0","StartTime":"2019-04-28T08:25:00","EndTime":"2019-04-28T09:05:00","RouteID":"G2","TripDirectionText":"EAST","TripHeadsign":"LEDROIT PARK","TripID":"939585010"},{"ScheduleTime":"2019-04-28T10:00:00","DirectionNum":"0","StartTime":"2019-04-28T09:50:00","EndTime":"2019-04-28T10:30:00","RouteID":"G2","TripDirectionText":"EAST","TripHeadsign":"LEDROIT PARK","TripID":"939586010"}],"Stop":{"StopID":"1001370","Name":"37TH ST NW + O ST NW","Lon":-77.071301,"Lat":38.908557,"Routes":["D6","G2"]}}`,
+		},
+	},
+	"/Bus.svc/json/jBusPositions": {
+		{
+			rawQuery: "RouteID=G2",
+			response: `{"BusPositions":[{"VehicleID":"3072","Lat":38.9071,"Lon":-77.0719,"Deviation":3,"DateTime":"2019-04-28T07:59:30","TripID":"939584010","RouteID":"G2","DirectionNum":0,"DirectionText":"EAST","TripHeadsign":"LEDROIT PARK","TripStartTime":"2019-04-28T07:50:00","TripEndTime":"2019-04-28T08:30:00","BlockNumber":"G2-1"},{"VehicleID":"4000","Lat":38.9101,"Lon":-77.0801,"Deviation":-1,"DateTime":"2019-04-28T07:59:40","TripID":"939584510","RouteID":"G2","DirectionNum":0,"DirectionText":"EAST","TripHeadsign":"LEDROIT PARK","TripStartTime":"2019-04-28T08:10:00","TripEndTime":"2019-04-28T08:50:00","BlockNumber":"G2-2"}]}`,
+		},
+		{
+			rawQuery: "RouteID=D6",
+			response: `{"BusPositions":[]}`,
+		},
+	},
+}
+
+// setupTestService creates a service struct backed by bus services with a mock http client
+func setupTestService() *Service {
+	wmataClient := wmata.Client{
+		HTTPClient: &testClient{},
+	}
+
+	return NewService(businfo.NewService(&wmataClient, wmata.JSON), buspredictions.NewService(&wmataClient, wmata.JSON))
+}
+
+func TestGetStopBoard(t *testing.T) {
+	testService := setupTestService()
+	if _, err := testService.GetStopBoard(&GetStopBoardRequest{}); err == nil || err.Error() != "stopID is required" {
+		t.Errorf("expected stopID error, got: %v", err)
+	}
+
+	// schedule times are in Washington whatever the location of the request time
+	local := time.Date(2019, 4, 28, 8, 0, 0, 0, wmata.Location)
+
+	for _, now := range []time.Time{local, local.UTC()} {
+		testGetStopBoardAt(t, testService, now)
+	}
+}
+
+// testGetStopBoardAt checks the board at 8:00 in Washington for a request time in any location
+func testGetStopBoardAt(t *testing.T, testService *Service, now time.Time) {
+	board, err := testService.GetStopBoard(&GetStopBoardRequest{StopID: "1001370", Time: now})
+
+	if err != nil {
+		t.Fatalf("error calling GetStopBoard at %s: %s", now, err)
+	}
+
+	if board.StopName != "37th St Nw + O St Nw" {
+		t.Errorf("unexpected stop name: %s", board.StopName)
+	}
+
+	type summary struct {
+		TripID    string
+		VehicleID string
+		Realtime  bool
+		Scheduled bool
+		Minutes   int
+		Lateness  time.Duration
+		Deviation int
+	}
+
+	var actual []summary
+
+	for _, arrival := range board.Arrivals {
+		item := summary{
+			TripID:    arrival.TripID,
+			VehicleID: arrival.VehicleID,
+			Realtime:  arrival.Realtime,
+			Scheduled: arrival.Scheduled(),
+			Minutes:   arrival.Minutes,
+			Lateness:  arrival.Lateness,
+			Deviation: -100,
+		}
+
+		if arrival.Vehicle != nil {
+			item.Deviation = arrival.Vehicle.Deviation
+		}
+
+		actual = append(actual, item)
+	}
+
+	expected := []summary{
+		{TripID: "939584010", VehicleID: "3072", Realtime: true, Scheduled: true, Minutes: 2, Lateness: 3 * time.Minute, Deviation: 3},
+		{TripID: "111111111", VehicleID: "5301", Realtime: true, Scheduled: false, Minutes: 12, Deviation: -100},
+		{TripID: "939584510", VehicleID: "4000", Realtime: false, Scheduled: true, Minutes: 20, Deviation: -1},
+		{TripID: "939585010", VehicleID: "3081", Realtime: true, Scheduled: true, Minutes: 38, Lateness: 3 * time.Minute, Deviation: -100},
+	}
+
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("unexpected arrivals at %s: %v", now, pretty.Diff(actual, expected))
+	}
+
+	if board.Arrivals[2].TripDestination != "LEDROIT PARK" {
+		t.Errorf("unexpected trip destination: %s", board.Arrivals[2].TripDestination)
+	}
+}
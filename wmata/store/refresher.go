@@ -0,0 +1,395 @@
+package store
+
+import (
+	"errors"
+	"github.com/awiede/wmata-go-sdk/wmata"
+	"github.com/awiede/wmata-go-sdk/wmata/businfo"
+	"github.com/awiede/wmata-go-sdk/wmata/railinfo"
+	"github.com/awiede/wmata-go-sdk/wmata/trainpositions"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Defaults used when a Config field is left at zero. Reference data rarely changes, schedules are refreshed more often
+// so changes for the day are picked up
+const (
+	DefaultReferenceMaxAge = 24 * time.Hour
+	DefaultScheduleMaxAge  = 6 * time.Hour
+	DefaultInterval        = time.Minute
+	DefaultMaxRequests     = 10
+	DefaultScheduleDays    = 1
+)
+
+// Services are the services a Refresher requests, only the services of the datasets refreshed are needed
+type Services struct {
+	BusInfo        businfo.BusInfo
+	RailInfo       railinfo.RailInfo
+	TrainPositions trainpositions.TrainPositions
+}
+
+// Config holds the schedule a Refresher keeps a store up to date on
+type Config struct {
+	// MaxAges are how old the entries of each dataset can get before they are refreshed, datasets without a max age
+	// are not refreshed. Every dataset is refreshed at its default max age if nil
+	MaxAges map[Dataset]time.Duration
+	// Interval between refresh passes made by Run
+	Interval time.Duration
+	// MaxRequests limits the route details and schedules requested in one pass, so refreshing every route is spread
+	// over several passes. Datasets of the whole network are always refreshed when stale
+	MaxRequests int
+	// RouteIDs are the routes whose details and schedules are kept, every route in the store if nil
+	RouteIDs []string
+	// ScheduleDays is the number of days of schedules kept from the current day. Schedules of other days are removed
+	ScheduleDays int
+	// Location the current day is taken in. Defaults to wmata.Location, the day in Washington
+	Location *time.Location
+	// OnError is called with the entry and error of each failed request or write, which are otherwise only returned
+	// by Refresh. The previous data of a failed entry is kept and it is retried on the next pass
+	OnError func(entry Entry, err error)
+}
+
+// withDefaults returns a copy of the config with zero values replaced by defaults
+func (config Config) withDefaults() Config {
+	if config.MaxAges == nil {
+		config.MaxAges = make(map[Dataset]time.Duration)
+
+		for _, dataset := range Datasets {
+			config.MaxAges[dataset] = DefaultReferenceMaxAge
+		}
+
+		config.MaxAges[DatasetSchedules] = DefaultScheduleMaxAge
+	}
+
+	if config.Interval <= 0 {
+		config.Interval = DefaultInterval
+	}
+
+	if config.MaxRequests <= 0 {
+		config.MaxRequests = DefaultMaxRequests
+	}
+
+	if config.ScheduleDays <= 0 {
+		config.ScheduleDays = DefaultScheduleDays
+	}
+
+	if config.Location == nil {
+		config.Location = wmata.Location
+	}
+
+	if config.OnError == nil {
+		config.OnError = func(Entry, error) {}
+	}
+
+	return config
+}
+
+// Refresher keeps a store up to date by requesting the entries older than their dataset's max age
+type Refresher struct {
+	store    *Store
+	services Services
+	config   Config
+	now      func() time.Time
+
+	// mutex keeps passes from running at the same time
+	mutex sync.Mutex
+	// attempted holds when route details and schedules were last requested, so entries that keep failing do not
+	// starve the others
+	attempted map[Entry]time.Time
+}
+
+// NewRefresher returns a Refresher updating store. It returns an error if a dataset is refreshed without the service
+// it is requested from
+func NewRefresher(store *Store, services Services, config Config) (*Refresher, error) {
+	refresher := Refresher{
+		store:     store,
+		services:  services,
+		config:    config.withDefaults(),
+		now:       time.Now,
+		attempted: make(map[Entry]time.Time),
+	}
+
+	for dataset, maxAge := range refresher.config.MaxAges {
+		if maxAge <= 0 {
+			return nil, errors.New("max age must be positive for " + string(dataset))
+		}
+
+		if !known(dataset) {
+			return nil, errors.New("unknown dataset: " + string(dataset))
+		}
+
+		if !refresher.canRefresh(dataset) {
+			return nil, errors.New("no service to refresh " + string(dataset))
+		}
+	}
+
+	return &refresher, nil
+}
+
+// Run refreshes the store straight away and then every interval until stop is closed, returning once the pass in
+// progress is done
+func (refresher *Refresher) Run(stop <-chan struct{}) {
+	ticker := time.NewTicker(refresher.config.Interval)
+	defer ticker.Stop()
+
+	for {
+		// errors are passed to OnError
+		_ = refresher.Refresh()
+
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Refresh makes one pass: it requests every stale dataset of the whole network, then the stale route details and
+// schedules of the kept routes oldest first up to MaxRequests, and removes the route details and schedules no longer
+// kept. It returns the first error, every error is also passed to OnError
+func (refresher *Refresher) Refresh() error {
+	refresher.mutex.Lock()
+	defer refresher.mutex.Unlock()
+
+	at := refresher.now()
+
+	var firstErr error
+
+	fail := func(entry Entry, err error) {
+		refresher.config.OnError(entry, err)
+
+		if firstErr == nil {
+			firstErr = err
+		}
+	}
+
+	for _, dataset := range Datasets {
+		entry := Entry{Dataset: dataset}
+
+		if dataset == DatasetRouteDetails || dataset == DatasetSchedules || !refresher.stale(entry, at) {
+			continue
+		}
+
+		if refreshErr := refresher.refresh(entry, at); refreshErr != nil {
+			fail(entry, refreshErr)
+		}
+	}
+
+	kept, routesKnown := refresher.kept(at)
+
+	if !routesKnown {
+		return firstErr
+	}
+
+	for _, entry := range refresher.store.Entries() {
+		if (entry.Dataset == DatasetRouteDetails || entry.Dataset == DatasetSchedules) && !kept[entry] {
+			if _, refreshed := refresher.config.MaxAges[entry.Dataset]; !refreshed {
+				continue
+			}
+
+			if removeErr := refresher.store.remove(entry); removeErr != nil {
+				fail(entry, removeErr)
+			}
+		}
+	}
+
+	for entry := range refresher.attempted {
+		if !kept[entry] {
+			delete(refresher.attempted, entry)
+		}
+	}
+
+	var stale []Entry
+
+	for entry := range kept {
+		if refresher.stale(entry, at) {
+			stale = append(stale, entry)
+		}
+	}
+
+	// entries never requested have the zero time and come first
+	sort.Slice(stale, func(i, j int) bool {
+		attemptedI, attemptedJ := refresher.lastAttempt(stale[i]), refresher.lastAttempt(stale[j])
+
+		if !attemptedI.Equal(attemptedJ) {
+			return attemptedI.Before(attemptedJ)
+		}
+
+		return stale[i].String() < stale[j].String()
+	})
+
+	if len(stale) > refresher.config.MaxRequests {
+		stale = stale[:refresher.config.MaxRequests]
+	}
+
+	for _, entry := range stale {
+		refresher.attempted[entry] = at
+
+		if refreshErr := refresher.refresh(entry, at); refreshErr != nil {
+			fail(entry, refreshErr)
+		}
+	}
+
+	return firstErr
+}
+
+// kept returns the route details and schedule entries that should be in the store at a time, false if they are
+// unknown because no routes were given and the store has none yet
+func (refresher *Refresher) kept(at time.Time) (map[Entry]bool, bool) {
+	routeIDs := refresher.config.RouteIDs
+
+	if routeIDs == nil {
+		if _, exist := refresher.store.Refreshed(Entry{Dataset: DatasetRoutes}); !exist {
+			return nil, false
+		}
+
+		for _, route := range refresher.store.Routes() {
+			routeIDs = append(routeIDs, route.RouteID)
+		}
+	}
+
+	_, routeDetails := refresher.config.MaxAges[DatasetRouteDetails]
+	_, schedules := refresher.config.MaxAges[DatasetSchedules]
+
+	day := at.In(refresher.config.Location)
+	kept := make(map[Entry]bool)
+
+	for _, routeID := range routeIDs {
+		if routeDetails {
+			kept[Entry{Dataset: DatasetRouteDetails, RouteID: routeID}] = true
+		}
+
+		if !schedules {
+			continue
+		}
+
+		for i := 0; i < refresher.config.ScheduleDays; i++ {
+			date := day.AddDate(0, 0, i).Format(wmata.DateLayout)
+			kept[Entry{Dataset: DatasetSchedules, RouteID: routeID, Date: date}] = true
+		}
+	}
+
+	return kept, true
+}
+
+// lastAttempt returns when an entry was last requested or refreshed, the zero time if never
+func (refresher *Refresher) lastAttempt(entry Entry) time.Time {
+	last, _ := refresher.store.Refreshed(entry)
+
+	if attempted := refresher.attempted[entry]; attempted.After(last) {
+		return attempted
+	}
+
+	return last
+}
+
+// stale returns true if an entry is refreshed and was never refreshed or is older than its dataset's max age
+func (refresher *Refresher) stale(entry Entry, at time.Time) bool {
+	maxAge, refreshed := refresher.config.MaxAges[entry.Dataset]
+
+	if !refreshed {
+		return false
+	}
+
+	last, exist := refresher.store.Refreshed(entry)
+
+	return !exist || at.Sub(last) >= maxAge
+}
+
+// refresh requests an entry and writes it to the store
+func (refresher *Refresher) refresh(entry Entry, at time.Time) error {
+	value, requestErr := refresher.request(entry)
+
+	if requestErr != nil {
+		return requestErr
+	}
+
+	return refresher.store.put(entry, value, at)
+}
+
+// request returns the value of an entry from its service, of the type returned by newValue
+func (refresher *Refresher) request(entry Entry) (interface{}, error) {
+	if !refresher.canRefresh(entry.Dataset) {
+		return nil, errors.New("no service to refresh " + string(entry.Dataset))
+	}
+
+	switch entry.Dataset {
+	case DatasetLines:
+		response, err := refresher.services.RailInfo.GetLines()
+
+		if err != nil {
+			return nil, err
+		}
+
+		return &response.Lines, nil
+	case DatasetStations:
+		response, err := refresher.services.RailInfo.GetStationList(wmata.LineCodeAll)
+
+		if err != nil {
+			return nil, err
+		}
+
+		return &response.Stations, nil
+	case DatasetEntrances:
+		response, err := refresher.services.RailInfo.GetStationEntrances(nil)
+
+		if err != nil {
+			return nil, err
+		}
+
+		return &response.Entrances, nil
+	case DatasetTrackCircuits:
+		response, err := refresher.services.TrainPositions.GetTrackCircuits()
+
+		if err != nil {
+			return nil, err
+		}
+
+		return &response.TrackCircuits, nil
+	case DatasetRoutes:
+		response, err := refresher.services.BusInfo.GetRoutes()
+
+		if err != nil {
+			return nil, err
+		}
+
+		return &response.Routes, nil
+	case DatasetStops:
+		response, err := refresher.services.BusInfo.GetStops(nil)
+
+		if err != nil {
+			return nil, err
+		}
+
+		return &response.Stops, nil
+	case DatasetRouteDetails:
+		return refresher.services.BusInfo.GetRouteDetails(entry.RouteID, "")
+	default:
+		return refresher.services.BusInfo.GetSchedule(entry.RouteID, entry.Date, true)
+	}
+}
+
+// canRefresh returns true if the refresher has the service a dataset is requested from
+func (refresher *Refresher) canRefresh(dataset Dataset) bool {
+	switch dataset {
+	case DatasetLines, DatasetStations, DatasetEntrances:
+		return refresher.services.RailInfo != nil
+	case DatasetTrackCircuits:
+		return refresher.services.TrainPositions != nil
+	case DatasetRoutes, DatasetStops, DatasetRouteDetails, DatasetSchedules:
+		return refresher.services.BusInfo != nil
+	}
+
+	return false
+}
+
+// known returns true if a dataset is one of Datasets
+func known(dataset Dataset) bool {
+	for _, knownDataset := range Datasets {
+		if dataset == knownDataset {
+			return true
+		}
+	}
+
+	return false
+}
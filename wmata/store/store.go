@@ -0,0 +1,622 @@
+package store
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/awiede/wmata-go-sdk/wmata/businfo"
+	"github.com/awiede/wmata-go-sdk/wmata/railinfo"
+	"github.com/awiede/wmata-go-sdk/wmata/trainpositions"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Dataset is a kind of reference data kept in a store
+type Dataset string
+
+const (
+	// DatasetLines holds the railinfo lines
+	DatasetLines Dataset = "Lines"
+	// DatasetStations holds the railinfo stations of every line
+	DatasetStations Dataset = "Stations"
+	// DatasetEntrances holds the railinfo entrances of every station
+	DatasetEntrances Dataset = "Entrances"
+	// DatasetTrackCircuits holds the trainpositions track circuits
+	DatasetTrackCircuits Dataset = "TrackCircuits"
+	// DatasetRoutes holds the businfo routes
+	DatasetRoutes Dataset = "Routes"
+	// DatasetStops holds the businfo stops of every route
+	DatasetStops Dataset = "Stops"
+	// DatasetRouteDetails holds the businfo route details of each route, one entry per route
+	DatasetRouteDetails Dataset = "RouteDetails"
+	// DatasetSchedules holds the businfo schedules of each route, one entry per route and date
+	DatasetSchedules Dataset = "Schedules"
+)
+
+// Datasets are all the datasets of a store, in the order a Refresher refreshes them
+var Datasets = []Dataset{
+	DatasetLines,
+	DatasetStations,
+	DatasetEntrances,
+	DatasetTrackCircuits,
+	DatasetRoutes,
+	DatasetStops,
+	DatasetRouteDetails,
+	DatasetSchedules,
+}
+
+// indexFile is the name of the file recording when each entry of a store was refreshed
+const indexFile = "index.json"
+
+// Entry identifies the data refreshed by one request: a whole dataset, the route details of a route, or the schedule
+// of a route on a date
+type Entry struct {
+	Dataset Dataset `json:"Dataset"`
+	// RouteID is only set for DatasetRouteDetails and DatasetSchedules
+	RouteID string `json:"RouteID,omitempty"`
+	// Date is only set for DatasetSchedules, in wmata.DateLayout
+	Date string `json:"Date,omitempty"`
+}
+
+// String returns the entry as a path, e.g. "Schedules/70/2019-04-29"
+func (entry Entry) String() string {
+	path := string(entry.Dataset)
+
+	if entry.RouteID != "" {
+		path += "/" + entry.RouteID
+	}
+
+	if entry.Date != "" {
+		path += "/" + entry.Date
+	}
+
+	return path
+}
+
+// file returns the path of the entry's file relative to the store directory
+func (entry Entry) file() string {
+	switch entry.Dataset {
+	case DatasetRouteDetails:
+		return filepath.Join(string(entry.Dataset), url.PathEscape(entry.RouteID)+".json")
+	case DatasetSchedules:
+		return filepath.Join(string(entry.Dataset), url.PathEscape(entry.RouteID), entry.Date+".json")
+	}
+
+	return string(entry.Dataset) + ".json"
+}
+
+// valid returns an error if the entry does not name data kept in a store
+func (entry Entry) valid() error {
+	switch entry.Dataset {
+	case DatasetLines, DatasetStations, DatasetEntrances, DatasetTrackCircuits, DatasetRoutes, DatasetStops:
+		if entry.RouteID != "" || entry.Date != "" {
+			return errors.New("unexpected route or date for " + entry.String())
+		}
+	case DatasetRouteDetails:
+		if entry.RouteID == "" || entry.Date != "" {
+			return errors.New("route details need a route and no date: " + entry.String())
+		}
+	case DatasetSchedules:
+		if entry.RouteID == "" || entry.Date == "" {
+			return errors.New("schedules need a route and a date: " + entry.String())
+		}
+	default:
+		return errors.New("unknown dataset: " + string(entry.Dataset))
+	}
+
+	return nil
+}
+
+// indexRecord is an entry and the time it was refreshed, as written to the index file
+type indexRecord struct {
+	Entry
+	Refreshed time.Time `json:"Refreshed"`
+}
+
+// Store keeps WMATA reference data in a directory, one JSON file per entry plus an index of when each entry was
+// refreshed. Files are replaced atomically, so a store interrupted while writing keeps the previous data. Everything is
+// also held in memory to answer queries. It is safe for concurrent use
+type Store struct {
+	directory string
+
+	mutex         sync.RWMutex
+	refreshed     map[Entry]time.Time
+	lines         []railinfo.LineResponse
+	stations      []railinfo.GetStationListResponseItem
+	entrances     []railinfo.StationEntrance
+	trackCircuits []trainpositions.TrackCircuit
+	routes        []businfo.Route
+	stops         []businfo.Stop
+	routeDetails  map[string]*businfo.GetRouteDetailsResponse
+	schedules     map[string]map[string]*businfo.GetScheduleResponse
+}
+
+// Open returns the store kept in a directory, creating the directory if needed and loading any data already in it
+func Open(directory string) (*Store, error) {
+	if mkdirErr := os.MkdirAll(directory, 0755); mkdirErr != nil {
+		return nil, mkdirErr
+	}
+
+	store := Store{
+		directory:    directory,
+		refreshed:    make(map[Entry]time.Time),
+		routeDetails: make(map[string]*businfo.GetRouteDetailsResponse),
+		schedules:    make(map[string]map[string]*businfo.GetScheduleResponse),
+	}
+
+	data, readErr := ioutil.ReadFile(filepath.Join(directory, indexFile))
+
+	if os.IsNotExist(readErr) {
+		return &store, nil
+	}
+
+	if readErr != nil {
+		return nil, readErr
+	}
+
+	var records []indexRecord
+
+	if unmarshalErr := json.Unmarshal(data, &records); unmarshalErr != nil {
+		return nil, unmarshalErr
+	}
+
+	for _, record := range records {
+		if validErr := record.Entry.valid(); validErr != nil {
+			return nil, validErr
+		}
+
+		value := newValue(record.Entry)
+
+		data, readErr := ioutil.ReadFile(filepath.Join(directory, record.Entry.file()))
+
+		if readErr != nil {
+			return nil, readErr
+		}
+
+		if unmarshalErr := json.Unmarshal(data, value); unmarshalErr != nil {
+			return nil, unmarshalErr
+		}
+
+		store.set(record.Entry, value)
+		store.refreshed[record.Entry] = record.Refreshed
+	}
+
+	return &store, nil
+}
+
+// Directory returns the directory the store is kept in
+func (store *Store) Directory() string {
+	return store.directory
+}
+
+// newValue returns a pointer to a new value of the type an entry holds
+func newValue(entry Entry) interface{} {
+	switch entry.Dataset {
+	case DatasetLines:
+		return &[]railinfo.LineResponse{}
+	case DatasetStations:
+		return &[]railinfo.GetStationListResponseItem{}
+	case DatasetEntrances:
+		return &[]railinfo.StationEntrance{}
+	case DatasetTrackCircuits:
+		return &[]trainpositions.TrackCircuit{}
+	case DatasetRoutes:
+		return &[]businfo.Route{}
+	case DatasetStops:
+		return &[]businfo.Stop{}
+	case DatasetRouteDetails:
+		return &businfo.GetRouteDetailsResponse{}
+	default:
+		return &businfo.GetScheduleResponse{}
+	}
+}
+
+// set keeps the value of an entry in memory, value is a pointer of the type returned by newValue
+func (store *Store) set(entry Entry, value interface{}) {
+	switch entry.Dataset {
+	case DatasetLines:
+		store.lines = *value.(*[]railinfo.LineResponse)
+	case DatasetStations:
+		store.stations = *value.(*[]railinfo.GetStationListResponseItem)
+	case DatasetEntrances:
+		store.entrances = *value.(*[]railinfo.StationEntrance)
+	case DatasetTrackCircuits:
+		store.trackCircuits = *value.(*[]trainpositions.TrackCircuit)
+	case DatasetRoutes:
+		store.routes = *value.(*[]businfo.Route)
+	case DatasetStops:
+		store.stops = *value.(*[]businfo.Stop)
+	case DatasetRouteDetails:
+		store.routeDetails[entry.RouteID] = value.(*businfo.GetRouteDetailsResponse)
+	case DatasetSchedules:
+		if store.schedules[entry.RouteID] == nil {
+			store.schedules[entry.RouteID] = make(map[string]*businfo.GetScheduleResponse)
+		}
+
+		store.schedules[entry.RouteID][entry.Date] = value.(*businfo.GetScheduleResponse)
+	}
+}
+
+// unset forgets the value of an entry
+func (store *Store) unset(entry Entry) {
+	switch entry.Dataset {
+	case DatasetRouteDetails:
+		delete(store.routeDetails, entry.RouteID)
+	case DatasetSchedules:
+		delete(store.schedules[entry.RouteID], entry.Date)
+
+		if len(store.schedules[entry.RouteID]) == 0 {
+			delete(store.schedules, entry.RouteID)
+		}
+	default:
+		store.set(entry, newValue(entry))
+	}
+}
+
+// put writes the value of an entry refreshed at a time, value is a pointer of the type returned by newValue
+func (store *Store) put(entry Entry, value interface{}, at time.Time) error {
+	if validErr := entry.valid(); validErr != nil {
+		return validErr
+	}
+
+	data, marshalErr := json.Marshal(value)
+
+	if marshalErr != nil {
+		return marshalErr
+	}
+
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	if writeErr := store.writeFile(entry.file(), data); writeErr != nil {
+		return writeErr
+	}
+
+	previous, existed := store.refreshed[entry]
+	store.refreshed[entry] = at
+
+	if writeErr := store.writeIndex(); writeErr != nil {
+		if existed {
+			store.refreshed[entry] = previous
+		} else {
+			delete(store.refreshed, entry)
+		}
+
+		return writeErr
+	}
+
+	store.set(entry, value)
+
+	return nil
+}
+
+// remove deletes an entry and its file
+func (store *Store) remove(entry Entry) error {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	refreshed, exist := store.refreshed[entry]
+
+	if !exist {
+		return nil
+	}
+
+	delete(store.refreshed, entry)
+
+	if writeErr := store.writeIndex(); writeErr != nil {
+		store.refreshed[entry] = refreshed
+		return writeErr
+	}
+
+	store.unset(entry)
+
+	if removeErr := os.Remove(filepath.Join(store.directory, entry.file())); removeErr != nil && !os.IsNotExist(removeErr) {
+		return removeErr
+	}
+
+	return nil
+}
+
+// writeIndex writes the refresh times of every entry, sorted so the file only changes where entries do
+func (store *Store) writeIndex() error {
+	records := make([]indexRecord, 0, len(store.refreshed))
+
+	for entry, refreshed := range store.refreshed {
+		records = append(records, indexRecord{Entry: entry, Refreshed: refreshed})
+	}
+
+	sort.Slice(records, func(i, j int) bool {
+		return records[i].Entry.String() < records[j].Entry.String()
+	})
+
+	data, marshalErr := json.MarshalIndent(records, "", "  ")
+
+	if marshalErr != nil {
+		return marshalErr
+	}
+
+	return store.writeFile(indexFile, append(data, '\n'))
+}
+
+// writeFile replaces a file of the store by writing a temporary file next to it and renaming it
+func (store *Store) writeFile(name string, data []byte) error {
+	path := filepath.Join(store.directory, name)
+
+	if mkdirErr := os.MkdirAll(filepath.Dir(path), 0755); mkdirErr != nil {
+		return mkdirErr
+	}
+
+	file, tempErr := ioutil.TempFile(filepath.Dir(path), ".tmp-")
+
+	if tempErr != nil {
+		return tempErr
+	}
+
+	if _, writeErr := file.Write(data); writeErr != nil {
+		file.Close()
+		os.Remove(file.Name())
+		return writeErr
+	}
+
+	if closeErr := file.Close(); closeErr != nil {
+		os.Remove(file.Name())
+		return closeErr
+	}
+
+	if renameErr := os.Rename(file.Name(), path); renameErr != nil {
+		os.Remove(file.Name())
+		return renameErr
+	}
+
+	return nil
+}
+
+// Refreshed returns when an entry was last refreshed, false if it is not in the store
+func (store *Store) Refreshed(entry Entry) (time.Time, bool) {
+	store.mutex.RLock()
+	defer store.mutex.RUnlock()
+
+	refreshed, exist := store.refreshed[entry]
+
+	return refreshed, exist
+}
+
+// Entries returns every entry in the store, sorted by their paths
+func (store *Store) Entries() []Entry {
+	store.mutex.RLock()
+	defer store.mutex.RUnlock()
+
+	entries := make([]Entry, 0, len(store.refreshed))
+
+	for entry := range store.refreshed {
+		entries = append(entries, entry)
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].String() < entries[j].String()
+	})
+
+	return entries
+}
+
+// Lines returns every line
+func (store *Store) Lines() []railinfo.LineResponse {
+	store.mutex.RLock()
+	defer store.mutex.RUnlock()
+
+	return append([]railinfo.LineResponse(nil), store.lines...)
+}
+
+// Line returns a line by its code, false if it is unknown
+func (store *Store) Line(lineCode string) (railinfo.LineResponse, bool) {
+	store.mutex.RLock()
+	defer store.mutex.RUnlock()
+
+	for _, line := range store.lines {
+		if line.LineCode == lineCode {
+			return line, true
+		}
+	}
+
+	return railinfo.LineResponse{}, false
+}
+
+// Stations returns every station
+func (store *Store) Stations() []railinfo.GetStationListResponseItem {
+	store.mutex.RLock()
+	defer store.mutex.RUnlock()
+
+	return append([]railinfo.GetStationListResponseItem(nil), store.stations...)
+}
+
+// Station returns a station by its code, false if it is unknown
+func (store *Store) Station(stationCode string) (railinfo.GetStationListResponseItem, bool) {
+	store.mutex.RLock()
+	defer store.mutex.RUnlock()
+
+	for _, station := range store.stations {
+		if station.StationCode == stationCode {
+			return station, true
+		}
+	}
+
+	return railinfo.GetStationListResponseItem{}, false
+}
+
+// StationsOnLine returns the stations served by a line
+func (store *Store) StationsOnLine(lineCode string) []railinfo.GetStationListResponseItem {
+	store.mutex.RLock()
+	defer store.mutex.RUnlock()
+
+	var stations []railinfo.GetStationListResponseItem
+
+	for _, station := range store.stations {
+		if lineCode != "" && (station.LineCode1 == lineCode || station.LineCode2 == lineCode ||
+			station.LineCode3 == lineCode || station.LineCode4 == lineCode) {
+			stations = append(stations, station)
+		}
+	}
+
+	return stations
+}
+
+// Entrances returns every station entrance
+func (store *Store) Entrances() []railinfo.StationEntrance {
+	store.mutex.RLock()
+	defer store.mutex.RUnlock()
+
+	return append([]railinfo.StationEntrance(nil), store.entrances...)
+}
+
+// StationEntrances returns the entrances of a station, including entrances shared with the station's other platform
+func (store *Store) StationEntrances(stationCode string) []railinfo.StationEntrance {
+	store.mutex.RLock()
+	defer store.mutex.RUnlock()
+
+	var entrances []railinfo.StationEntrance
+
+	for _, entrance := range store.entrances {
+		if stationCode != "" && (entrance.StationCode1 == stationCode || entrance.StationCode2 == stationCode) {
+			entrances = append(entrances, entrance)
+		}
+	}
+
+	return entrances
+}
+
+// TrackCircuits returns every track circuit
+func (store *Store) TrackCircuits() []trainpositions.TrackCircuit {
+	store.mutex.RLock()
+	defer store.mutex.RUnlock()
+
+	return append([]trainpositions.TrackCircuit(nil), store.trackCircuits...)
+}
+
+// TrackCircuit returns a track circuit by its ID, false if it is unknown
+func (store *Store) TrackCircuit(circuitID int) (trainpositions.TrackCircuit, bool) {
+	store.mutex.RLock()
+	defer store.mutex.RUnlock()
+
+	for _, circuit := range store.trackCircuits {
+		if circuit.CircuitID == circuitID {
+			return circuit, true
+		}
+	}
+
+	return trainpositions.TrackCircuit{}, false
+}
+
+// Routes returns every bus route
+func (store *Store) Routes() []businfo.Route {
+	store.mutex.RLock()
+	defer store.mutex.RUnlock()
+
+	return append([]businfo.Route(nil), store.routes...)
+}
+
+// Route returns a bus route by its ID, false if it is unknown
+func (store *Store) Route(routeID string) (businfo.Route, bool) {
+	store.mutex.RLock()
+	defer store.mutex.RUnlock()
+
+	for _, route := range store.routes {
+		if route.RouteID == routeID {
+			return route, true
+		}
+	}
+
+	return businfo.Route{}, false
+}
+
+// Stops returns every bus stop
+func (store *Store) Stops() []businfo.Stop {
+	store.mutex.RLock()
+	defer store.mutex.RUnlock()
+
+	return append([]businfo.Stop(nil), store.stops...)
+}
+
+// Stop returns a bus stop by its ID, false if it is unknown
+func (store *Store) Stop(stopID string) (businfo.Stop, bool) {
+	store.mutex.RLock()
+	defer store.mutex.RUnlock()
+
+	for _, stop := range store.stops {
+		if stop.StopID == stopID {
+			return stop, true
+		}
+	}
+
+	return businfo.Stop{}, false
+}
+
+// StopsOnRoute returns the bus stops served by a route
+func (store *Store) StopsOnRoute(routeID string) []businfo.Stop {
+	store.mutex.RLock()
+	defer store.mutex.RUnlock()
+
+	var stops []businfo.Stop
+
+	for _, stop := range store.stops {
+		for _, stopRouteID := range stop.Routes {
+			if stopRouteID == routeID {
+				stops = append(stops, stop)
+				break
+			}
+		}
+	}
+
+	return stops
+}
+
+// RouteDetails returns the details of a bus route, false if they are not in the store
+func (store *Store) RouteDetails(routeID string) (*businfo.GetRouteDetailsResponse, bool) {
+	store.mutex.RLock()
+	defer store.mutex.RUnlock()
+
+	routeDetails, exist := store.routeDetails[routeID]
+
+	if !exist {
+		return nil, false
+	}
+
+	copied := *routeDetails
+
+	return &copied, true
+}
+
+// Schedule returns the schedule of a bus route on a date in wmata.DateLayout, false if it is not in the store
+func (store *Store) Schedule(routeID, date string) (*businfo.GetScheduleResponse, bool) {
+	store.mutex.RLock()
+	defer store.mutex.RUnlock()
+
+	schedule, exist := store.schedules[routeID][date]
+
+	if !exist {
+		return nil, false
+	}
+
+	copied := *schedule
+
+	return &copied, true
+}
+
+// ScheduleDates returns the dates a bus route has a schedule for in the store, in order
+func (store *Store) ScheduleDates(routeID string) []string {
+	store.mutex.RLock()
+	defer store.mutex.RUnlock()
+
+	var dates []string
+
+	for date := range store.schedules[routeID] {
+		dates = append(dates, date)
+	}
+
+	sort.Strings(dates)
+
+	return dates
+}
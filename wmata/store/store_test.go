@@ -0,0 +1,546 @@
+package store
+
+import (
+	"errors"
+	"github.com/awiede/wmata-go-sdk/wmata/businfo"
+	"github.com/awiede/wmata-go-sdk/wmata/mocks"
+	"github.com/awiede/wmata-go-sdk/wmata/railinfo"
+	"github.com/awiede/wmata-go-sdk/wmata/trainpositions"
+	"github.com/awiede/wmata-go-sdk/wmata/wmatatest"
+	"github.com/kr/pretty"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+var start = time.Date(2019, time.April, 29, 12, 0, 0, 0, time.UTC)
+
+func tempDirectory(t *testing.T) string {
+	directory, tempErr := ioutil.TempDir("", "store")
+
+	if tempErr != nil {
+		t.Fatal(tempErr)
+	}
+
+	return directory
+}
+
+func services(scenario *mocks.Scenario) Services {
+	return Services{
+		BusInfo:        scenario.BusInfo,
+		RailInfo:       scenario.RailInfo,
+		TrainPositions: scenario.TrainPositions,
+	}
+}
+
+// testRefresher returns a refresher of a new store in directory whose clock is read from now
+func testRefresher(t *testing.T, directory string, scenario *mocks.Scenario, config Config, now *time.Time) *Refresher {
+	store, openErr := Open(directory)
+
+	if openErr != nil {
+		t.Fatal(openErr)
+	}
+
+	config.Location = time.UTC
+
+	refresher, refresherErr := NewRefresher(store, services(scenario), config)
+
+	if refresherErr != nil {
+		t.Fatal(refresherErr)
+	}
+
+	refresher.now = func() time.Time {
+		return *now
+	}
+
+	return refresher
+}
+
+func TestRefresh(t *testing.T) {
+	directory := tempDirectory(t)
+	defer os.RemoveAll(directory)
+
+	now := start
+	scenario := mocks.NormalService()
+	refresher := testRefresher(t, directory, scenario, Config{RouteIDs: []string{"70"}, ScheduleDays: 2}, &now)
+
+	if refreshErr := refresher.Refresh(); refreshErr != nil {
+		t.Fatalf("error refreshing store: %s", refreshErr)
+	}
+
+	expectedEntries := []Entry{
+		{Dataset: DatasetEntrances},
+		{Dataset: DatasetLines},
+		{Dataset: DatasetRouteDetails, RouteID: "70"},
+		{Dataset: DatasetRoutes},
+		{Dataset: DatasetSchedules, RouteID: "70", Date: "2019-04-29"},
+		{Dataset: DatasetSchedules, RouteID: "70", Date: "2019-04-30"},
+		{Dataset: DatasetStations},
+		{Dataset: DatasetStops},
+		{Dataset: DatasetTrackCircuits},
+	}
+
+	if entries := refresher.store.Entries(); !reflect.DeepEqual(expectedEntries, entries) {
+		t.Error(pretty.Diff(expectedEntries, entries))
+	}
+
+	for _, entry := range expectedEntries {
+		if refreshed, exist := refresher.store.Refreshed(entry); !exist || !refreshed.Equal(start) {
+			t.Errorf("expected %s refreshed at %s, got %s", entry, start, refreshed)
+		}
+	}
+
+	// the data survives reopening the store
+	reopened, openErr := Open(directory)
+
+	if openErr != nil {
+		t.Fatalf("error reopening store: %s", openErr)
+	}
+
+	for name, store := range map[string]*Store{"Refreshed": refresher.store, "Reopened": reopened} {
+		t.Run(name, func(t *testing.T) {
+			checkQueries(t, store)
+		})
+	}
+
+	if entries := reopened.Entries(); !reflect.DeepEqual(expectedEntries, entries) {
+		t.Error(pretty.Diff(expectedEntries, entries))
+	}
+
+	if refreshed, _ := reopened.Refreshed(Entry{Dataset: DatasetLines}); !refreshed.Equal(start) {
+		t.Errorf("expected lines refreshed at %s after reopening, got %s", start, refreshed)
+	}
+}
+
+func checkQueries(t *testing.T, store *Store) {
+	if lines := store.Lines(); !reflect.DeepEqual(wmatatest.Lines().Lines, lines) {
+		t.Error(pretty.Diff(wmatatest.Lines().Lines, lines))
+	}
+
+	if line, exist := store.Line("RD"); !exist || line.DisplayName != "Red" {
+		t.Errorf("expected Red line, got %+v", line)
+	}
+
+	if stations := store.Stations(); !reflect.DeepEqual(wmatatest.Stations().Stations, stations) {
+		t.Error(pretty.Diff(wmatatest.Stations().Stations, stations))
+	}
+
+	if station, exist := store.Station("A01"); !exist || station.Name != "Metro Center" {
+		t.Errorf("expected Metro Center, got %+v", station)
+	}
+
+	if _, exist := store.Station("Z99"); exist {
+		t.Error("expected unknown station")
+	}
+
+	var redLine []railinfo.GetStationListResponseItem
+
+	for _, station := range wmatatest.Stations().Stations {
+		if station.LineCode1 == "RD" || station.LineCode2 == "RD" || station.LineCode3 == "RD" || station.LineCode4 == "RD" {
+			redLine = append(redLine, station)
+		}
+	}
+
+	if stations := store.StationsOnLine("RD"); len(stations) == 0 || !reflect.DeepEqual(redLine, stations) {
+		t.Error(pretty.Diff(redLine, stations))
+	}
+
+	if entrances := store.Entrances(); !reflect.DeepEqual(wmatatest.StationEntrances().Entrances, entrances) {
+		t.Error(pretty.Diff(wmatatest.StationEntrances().Entrances, entrances))
+	}
+
+	var expectedEntrances []railinfo.StationEntrance
+
+	for _, entrance := range wmatatest.StationEntrances().Entrances {
+		if entrance.StationCode1 == "A01" || entrance.StationCode2 == "A01" {
+			expectedEntrances = append(expectedEntrances, entrance)
+		}
+	}
+
+	if entrances := store.StationEntrances("A01"); len(entrances) == 0 || !reflect.DeepEqual(expectedEntrances, entrances) {
+		t.Error(pretty.Diff(expectedEntrances, entrances))
+	}
+
+	if circuits := store.TrackCircuits(); !reflect.DeepEqual(wmatatest.TrackCircuits().TrackCircuits, circuits) {
+		t.Error(pretty.Diff(wmatatest.TrackCircuits().TrackCircuits, circuits))
+	}
+
+	if circuit, exist := store.TrackCircuit(1102); !exist || circuit.Track != 1 || len(circuit.Neighbors) != 2 {
+		t.Errorf("expected circuit 1102 on track 1 with 2 neighbors, got %+v", circuit)
+	}
+
+	if routes := store.Routes(); !reflect.DeepEqual(wmatatest.BusRoutes().Routes, routes) {
+		t.Error(pretty.Diff(wmatatest.BusRoutes().Routes, routes))
+	}
+
+	if route, exist := store.Route("S2"); !exist || route.LineDescription != "16th Street Line" {
+		t.Errorf("expected route S2, got %+v", route)
+	}
+
+	if stops := store.Stops(); !reflect.DeepEqual(wmatatest.BusStops().Stops, stops) {
+		t.Error(pretty.Diff(wmatatest.BusStops().Stops, stops))
+	}
+
+	if stop, exist := store.Stop("1001808"); !exist || stop.Name != "7TH ST NW + H ST NW" {
+		t.Errorf("expected stop 1001808, got %+v", stop)
+	}
+
+	if stops := store.StopsOnRoute("S2"); len(stops) != 1 || stops[0].StopID != "1003043" {
+		t.Errorf("expected stop 1003043 on route S2, got %+v", stops)
+	}
+
+	if routeDetails, exist := store.RouteDetails("70"); !exist || !reflect.DeepEqual(wmatatest.BusRouteDetails(), routeDetails) {
+		t.Error(pretty.Diff(wmatatest.BusRouteDetails(), routeDetails))
+	}
+
+	if _, exist := store.RouteDetails("79"); exist {
+		t.Error("expected no route details for route 79")
+	}
+
+	if schedule, exist := store.Schedule("70", "2019-04-30"); !exist || !reflect.DeepEqual(wmatatest.BusSchedule(), schedule) {
+		t.Error(pretty.Diff(wmatatest.BusSchedule(), schedule))
+	}
+
+	if dates := store.ScheduleDates("70"); !reflect.DeepEqual([]string{"2019-04-29", "2019-04-30"}, dates) {
+		t.Errorf("expected schedules for 2019-04-29 and 2019-04-30, got %v", dates)
+	}
+}
+
+func TestIncrementalRefresh(t *testing.T) {
+	directory := tempDirectory(t)
+	defer os.RemoveAll(directory)
+
+	now := start
+	scenario := mocks.NormalService()
+	refresher := testRefresher(t, directory, scenario, Config{RouteIDs: []string{"70"}}, &now)
+
+	refresh := func(t *testing.T, expectedCalls map[string]int) {
+		scenario.BusInfo.Reset()
+		scenario.RailInfo.Reset()
+		scenario.TrainPositions.Reset()
+
+		if refreshErr := refresher.Refresh(); refreshErr != nil {
+			t.Fatalf("error refreshing store: %s", refreshErr)
+		}
+
+		calls := map[string]int{}
+
+		for _, recorder := range []*mocks.Recorder{&scenario.BusInfo.Recorder, &scenario.RailInfo.Recorder, &scenario.TrainPositions.Recorder} {
+			for _, call := range recorder.Calls("") {
+				calls[call.Method]++
+			}
+		}
+
+		if !reflect.DeepEqual(expectedCalls, calls) {
+			t.Error(pretty.Diff(expectedCalls, calls))
+		}
+	}
+
+	t.Run("First Pass", func(t *testing.T) {
+		refresh(t, map[string]int{
+			"GetLines":            1,
+			"GetStationList":      1,
+			"GetStationEntrances": 1,
+			"GetTrackCircuits":    1,
+			"GetRoutes":           1,
+			"GetStops":            1,
+			"GetRouteDetails":     1,
+			"GetSchedule":         1,
+		})
+	})
+
+	t.Run("Nothing Stale", func(t *testing.T) {
+		now = start.Add(time.Hour)
+		refresh(t, map[string]int{})
+	})
+
+	t.Run("Schedules Stale", func(t *testing.T) {
+		now = start.Add(DefaultScheduleMaxAge)
+		refresh(t, map[string]int{"GetSchedule": 1})
+
+		if refreshed, _ := refresher.store.Refreshed(Entry{Dataset: DatasetLines}); !refreshed.Equal(start) {
+			t.Errorf("expected lines refreshed at %s, got %s", start, refreshed)
+		}
+	})
+
+	t.Run("Next Day", func(t *testing.T) {
+		now = start.Add(DefaultReferenceMaxAge)
+		refresh(t, map[string]int{
+			"GetLines":            1,
+			"GetStationList":      1,
+			"GetStationEntrances": 1,
+			"GetTrackCircuits":    1,
+			"GetRoutes":           1,
+			"GetStops":            1,
+			"GetRouteDetails":     1,
+			"GetSchedule":         1,
+		})
+
+		if dates := refresher.store.ScheduleDates("70"); !reflect.DeepEqual([]string{"2019-04-30"}, dates) {
+			t.Errorf("expected only the schedule for 2019-04-30, got %v", dates)
+		}
+
+		if _, statErr := os.Stat(filepath.Join(directory, "Schedules", "70", "2019-04-29.json")); !os.IsNotExist(statErr) {
+			t.Errorf("expected the schedule file for 2019-04-29 to be removed, got %v", statErr)
+		}
+
+		if calls := scenario.BusInfo.Calls("GetSchedule"); len(calls) != 1 || calls[0].Args[1] != "2019-04-30" {
+			t.Errorf("expected the schedule for 2019-04-30 to be requested, got %+v", calls)
+		}
+	})
+}
+
+func TestMaxRequests(t *testing.T) {
+	directory := tempDirectory(t)
+	defer os.RemoveAll(directory)
+
+	now := start
+	scenario := mocks.NormalService()
+
+	var failed []Entry
+
+	refresher := testRefresher(t, directory, scenario, Config{
+		MaxRequests: 2,
+		OnError: func(entry Entry, err error) {
+			failed = append(failed, entry)
+		},
+	}, &now)
+
+	// only route 70 has details and schedules, requests for routes 79 and S2 fail
+	passes := []struct {
+		requested []Entry
+		failed    []Entry
+	}{
+		{
+			requested: []Entry{
+				{Dataset: DatasetRouteDetails, RouteID: "70"},
+				{Dataset: DatasetRouteDetails, RouteID: "79"},
+			},
+			failed: []Entry{{Dataset: DatasetRouteDetails, RouteID: "79"}},
+		},
+		{
+			requested: []Entry{
+				{Dataset: DatasetRouteDetails, RouteID: "S2"},
+				{Dataset: DatasetSchedules, RouteID: "70", Date: "2019-04-29"},
+			},
+			failed: []Entry{{Dataset: DatasetRouteDetails, RouteID: "S2"}},
+		},
+		{
+			requested: []Entry{
+				{Dataset: DatasetSchedules, RouteID: "79", Date: "2019-04-29"},
+				{Dataset: DatasetSchedules, RouteID: "S2", Date: "2019-04-29"},
+			},
+			failed: []Entry{
+				{Dataset: DatasetSchedules, RouteID: "79", Date: "2019-04-29"},
+				{Dataset: DatasetSchedules, RouteID: "S2", Date: "2019-04-29"},
+			},
+		},
+		{
+			// entries that failed are retried once the others had their turn
+			requested: []Entry{
+				{Dataset: DatasetRouteDetails, RouteID: "79"},
+				{Dataset: DatasetRouteDetails, RouteID: "S2"},
+			},
+			failed: []Entry{
+				{Dataset: DatasetRouteDetails, RouteID: "79"},
+				{Dataset: DatasetRouteDetails, RouteID: "S2"},
+			},
+		},
+	}
+
+	for i, pass := range passes {
+		scenario.BusInfo.Reset()
+		failed = nil
+		now = start.Add(time.Duration(i) * time.Minute)
+
+		if refreshErr := refresher.Refresh(); refreshErr == nil {
+			t.Errorf("pass %d: expected an error", i)
+		}
+
+		var requested []Entry
+
+		for _, call := range scenario.BusInfo.Calls("") {
+			switch call.Method {
+			case "GetRouteDetails":
+				requested = append(requested, Entry{Dataset: DatasetRouteDetails, RouteID: call.Args[0].(string)})
+			case "GetSchedule":
+				requested = append(requested, Entry{Dataset: DatasetSchedules, RouteID: call.Args[0].(string), Date: call.Args[1].(string)})
+			}
+		}
+
+		if !reflect.DeepEqual(pass.requested, requested) {
+			t.Errorf("pass %d: %v", i, pretty.Diff(pass.requested, requested))
+		}
+
+		if !reflect.DeepEqual(pass.failed, failed) {
+			t.Errorf("pass %d: %v", i, pretty.Diff(pass.failed, failed))
+		}
+	}
+
+	if _, exist := refresher.store.Schedule("70", "2019-04-29"); !exist {
+		t.Error("expected the schedule of route 70")
+	}
+}
+
+func TestRefreshFailure(t *testing.T) {
+	directory := tempDirectory(t)
+	defer os.RemoveAll(directory)
+
+	now := start
+	scenario := mocks.NormalService()
+	refresher := testRefresher(t, directory, scenario, Config{MaxAges: map[Dataset]time.Duration{DatasetLines: time.Hour}}, &now)
+
+	if refreshErr := refresher.Refresh(); refreshErr != nil {
+		t.Fatalf("error refreshing store: %s", refreshErr)
+	}
+
+	unavailable := errors.New("service unavailable")
+	scenario.RailInfo.Fail("GetLines", unavailable)
+	now = start.Add(time.Hour)
+
+	if refreshErr := refresher.Refresh(); refreshErr != unavailable {
+		t.Errorf("expected %v, got %v", unavailable, refreshErr)
+	}
+
+	// the previous lines are kept and still due for a refresh
+	if lines := refresher.store.Lines(); !reflect.DeepEqual(wmatatest.Lines().Lines, lines) {
+		t.Error(pretty.Diff(wmatatest.Lines().Lines, lines))
+	}
+
+	if refreshed, _ := refresher.store.Refreshed(Entry{Dataset: DatasetLines}); !refreshed.Equal(start) {
+		t.Errorf("expected lines refreshed at %s, got %s", start, refreshed)
+	}
+
+	scenario.RailInfo.Fail("GetLines", nil)
+
+	if refreshErr := refresher.Refresh(); refreshErr != nil {
+		t.Fatalf("error refreshing store: %s", refreshErr)
+	}
+
+	if refreshed, _ := refresher.store.Refreshed(Entry{Dataset: DatasetLines}); !refreshed.Equal(now) {
+		t.Errorf("expected lines refreshed at %s, got %s", now, refreshed)
+	}
+
+	if entries := refresher.store.Entries(); !reflect.DeepEqual([]Entry{{Dataset: DatasetLines}}, entries) {
+		t.Errorf("expected only lines in the store, got %v", entries)
+	}
+}
+
+func TestNewRefresher(t *testing.T) {
+	store, openErr := Open(tempDirectory(t))
+
+	if openErr != nil {
+		t.Fatal(openErr)
+	}
+
+	defer os.RemoveAll(store.Directory())
+
+	tests := []struct {
+		name     string
+		services Services
+		config   Config
+		expected string
+	}{
+		{
+			name:     "Missing Service",
+			services: Services{RailInfo: &mocks.RailInfo{}, BusInfo: &mocks.BusInfo{}},
+			expected: "no service to refresh TrackCircuits",
+		},
+		{
+			name:     "Only Needed Services",
+			services: Services{TrainPositions: &mocks.TrainPositions{}},
+			config:   Config{MaxAges: map[Dataset]time.Duration{DatasetTrackCircuits: time.Hour}},
+		},
+		{
+			name:     "Max Age Not Positive",
+			services: Services{BusInfo: &mocks.BusInfo{}},
+			config:   Config{MaxAges: map[Dataset]time.Duration{DatasetRoutes: 0}},
+			expected: "max age must be positive for Routes",
+		},
+		{
+			name:     "Unknown Dataset",
+			services: Services{BusInfo: &mocks.BusInfo{}},
+			config:   Config{MaxAges: map[Dataset]time.Duration{"Fares": time.Hour}},
+			expected: "unknown dataset: Fares",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, refresherErr := NewRefresher(store, test.services, test.config)
+
+			if test.expected == "" && refresherErr != nil {
+				t.Errorf("unexpected error: %s", refresherErr)
+			}
+
+			if test.expected != "" && (refresherErr == nil || refresherErr.Error() != test.expected) {
+				t.Errorf("expected error %q, got %v", test.expected, refresherErr)
+			}
+		})
+	}
+}
+
+func TestOpen(t *testing.T) {
+	directory := tempDirectory(t)
+	defer os.RemoveAll(directory)
+
+	store, openErr := Open(filepath.Join(directory, "new"))
+
+	if openErr != nil {
+		t.Fatalf("error opening new store: %s", openErr)
+	}
+
+	if entries := store.Entries(); len(entries) != 0 {
+		t.Errorf("expected an empty store, got %v", entries)
+	}
+
+	routeDetails := &businfo.GetRouteDetailsResponse{RouteID: "10A/B", Name: "10A - HUNTING POINT"}
+	entry := Entry{Dataset: DatasetRouteDetails, RouteID: routeDetails.RouteID}
+
+	if putErr := store.put(entry, routeDetails, start); putErr != nil {
+		t.Fatalf("error writing route details: %s", putErr)
+	}
+
+	circuits := &[]trainpositions.TrackCircuit{{CircuitID: 1, Track: 2}}
+
+	if putErr := store.put(Entry{Dataset: DatasetTrackCircuits}, circuits, start); putErr != nil {
+		t.Fatalf("error writing track circuits: %s", putErr)
+	}
+
+	if putErr := store.put(Entry{Dataset: DatasetSchedules, RouteID: "70"}, &businfo.GetScheduleResponse{}, start); putErr == nil {
+		t.Error("expected an error writing a schedule without a date")
+	}
+
+	reopened, openErr := Open(filepath.Join(directory, "new"))
+
+	if openErr != nil {
+		t.Fatalf("error reopening store: %s", openErr)
+	}
+
+	if reopenedDetails, exist := reopened.RouteDetails("10A/B"); !exist || !reflect.DeepEqual(routeDetails, reopenedDetails) {
+		t.Error(pretty.Diff(routeDetails, reopenedDetails))
+	}
+
+	if circuit, exist := reopened.TrackCircuit(1); !exist || circuit.Track != 2 {
+		t.Errorf("expected circuit 1 on track 2, got %+v", circuit)
+	}
+
+	if removeErr := reopened.remove(entry); removeErr != nil {
+		t.Fatalf("error removing route details: %s", removeErr)
+	}
+
+	if _, exist := reopened.RouteDetails("10A/B"); exist {
+		t.Error("expected route details to be removed")
+	}
+
+	if ioutil.WriteFile(filepath.Join(directory, "new", indexFile), []byte("{"), 0644) != nil {
+		t.Fatal("error corrupting index")
+	}
+
+	if _, openErr := Open(filepath.Join(directory, "new")); openErr == nil {
+		t.Error("expected an error opening a store with a corrupt index")
+	}
+}
@@ -0,0 +1,395 @@
+package tabular
+
+import (
+	"encoding/csv"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Key columns added to every table. RowID numbers the rows of a table from 1, and ParentRowID is the RowID of the
+// row in the parent table a child row belongs to
+const (
+	RowIDColumn       = "RowID"
+	ParentRowIDColumn = "ParentRowID"
+	// ValueColumn holds the values of slices of strings and numbers, such as the routes serving a stop
+	ValueColumn = "Value"
+)
+
+// Table is one flat table of a response
+type Table struct {
+	// Name is the path of JSON field names from the response to the rows, e.g. "Direction0.StopTimes", or the name of
+	// the response without Get and Response for the response's own fields, e.g. "StationInformation"
+	Name string
+	// Parent is the name of the table ParentRowID refers to, empty for tables without a parent
+	Parent  string
+	Columns []string
+	Rows    [][]string
+}
+
+// WriteCSV writes the table as CSV with a header row
+func (table *Table) WriteCSV(w io.Writer) error {
+	writer := csv.NewWriter(w)
+
+	if writeErr := writer.Write(table.Columns); writeErr != nil {
+		return writeErr
+	}
+
+	if writeErr := writer.WriteAll(table.Rows); writeErr != nil {
+		return writeErr
+	}
+
+	return writer.Error()
+}
+
+// Writer writes the tables of a response in some format
+type Writer interface {
+	WriteTable(table *Table) error
+}
+
+// WriterFunc adapts a function to a Writer
+type WriterFunc func(table *Table) error
+
+var _ Writer = WriterFunc(nil)
+
+// WriteTable calls the function
+func (writerFunc WriterFunc) WriteTable(table *Table) error {
+	return writerFunc(table)
+}
+
+// CSVWriter writes each table to a CSV file named after the table in a directory, replacing any existing file
+type CSVWriter struct {
+	directory string
+}
+
+var _ Writer = (*CSVWriter)(nil)
+
+// NewCSVWriter returns a CSVWriter writing to a directory, which is created if needed
+func NewCSVWriter(directory string) *CSVWriter {
+	return &CSVWriter{directory: directory}
+}
+
+// WriteTable writes a table to <directory>/<table name>.csv
+func (writer *CSVWriter) WriteTable(table *Table) error {
+	if mkdirErr := os.MkdirAll(writer.directory, 0755); mkdirErr != nil {
+		return mkdirErr
+	}
+
+	file, createErr := os.Create(filepath.Join(writer.directory, table.Name+".csv"))
+
+	if createErr != nil {
+		return createErr
+	}
+
+	if writeErr := table.WriteCSV(file); writeErr != nil {
+		file.Close()
+		return writeErr
+	}
+
+	return file.Close()
+}
+
+// Export flattens a response and writes each of its tables
+func Export(writer Writer, response interface{}) error {
+	tables, flattenErr := Flatten(response)
+
+	if flattenErr != nil {
+		return flattenErr
+	}
+
+	for _, table := range tables {
+		if writeErr := writer.WriteTable(table); writeErr != nil {
+			return writeErr
+		}
+	}
+
+	return nil
+}
+
+// Flatten turns a response into flat tables. The fields of nested structs become columns named by their JSON path,
+// e.g. "Address.City", and each slice becomes a child table with a row for each element, linked to the row holding
+// the slice by ParentRowID. Tables and columns follow the order of the response's fields, and every table is returned
+// even when it has no rows, so the tables of a response type are always the same. The response's own table is left
+// out when the response has no fields but slices
+func Flatten(response interface{}) ([]*Table, error) {
+	value := reflect.ValueOf(response)
+
+	for value.Kind() == reflect.Ptr {
+		if value.IsNil() {
+			return nil, errors.New("response is nil")
+		}
+
+		value = value.Elem()
+	}
+
+	if value.Kind() != reflect.Struct {
+		return nil, errors.New("response must be a struct, got " + value.Type().String())
+	}
+
+	root := &tableSchema{
+		name: strings.TrimSuffix(strings.TrimPrefix(value.Type().Name(), "Get"), "Response"),
+		root: true,
+	}
+
+	if buildErr := root.build(value.Type(), nil, ""); buildErr != nil {
+		return nil, buildErr
+	}
+
+	// the response's own table is only useful if it holds something besides its slices
+	if len(root.columns) > 0 {
+		root.table = &Table{}
+	}
+
+	var tables []*Table
+
+	if tablesErr := root.tables("", &tables, make(map[string]bool)); tablesErr != nil {
+		return nil, tablesErr
+	}
+
+	root.fill(value, 0)
+
+	return tables, nil
+}
+
+// tableSchema describes how a struct type is flattened into a table and its child tables
+type tableSchema struct {
+	name     string
+	root     bool
+	columns  []column
+	children []child
+	// table is nil for a response's own table when it is left out, rows of its children then have no parent
+	table *Table
+}
+
+// column is a scalar field reached through nested structs, or the element itself for slices of scalars
+type column struct {
+	name  string
+	index []int
+}
+
+// child is a slice field reached through nested structs
+type child struct {
+	index  []int
+	schema *tableSchema
+}
+
+var (
+	timeType     = reflect.TypeOf(time.Time{})
+	durationType = reflect.TypeOf(time.Duration(0))
+)
+
+// build adds the columns and children of a struct type reached by index from the table's elements, naming columns
+// with prefix
+func (schema *tableSchema) build(structType reflect.Type, index []int, prefix string) error {
+	for i := 0; i < structType.NumField(); i++ {
+		field := structType.Field(i)
+
+		if field.PkgPath != "" {
+			continue
+		}
+
+		name := strings.Split(field.Tag.Get("json"), ",")[0]
+
+		if name == "-" {
+			continue
+		}
+
+		if name == "" {
+			name = field.Name
+		}
+
+		fieldIndex := append(append([]int(nil), index...), i)
+		fieldType := indirect(field.Type)
+
+		switch {
+		case fieldType.Kind() == reflect.Struct && fieldType != timeType:
+			if buildErr := schema.build(fieldType, fieldIndex, prefix+name+"."); buildErr != nil {
+				return buildErr
+			}
+		case fieldType.Kind() == reflect.Slice:
+			childSchema := &tableSchema{name: prefix + name, table: &Table{}}
+
+			if !schema.root {
+				childSchema.name = schema.name + "." + childSchema.name
+			}
+
+			elementType := indirect(fieldType.Elem())
+
+			switch {
+			case elementType.Kind() == reflect.Struct && elementType != timeType:
+				if buildErr := childSchema.build(elementType, nil, ""); buildErr != nil {
+					return buildErr
+				}
+			case scalar(elementType):
+				childSchema.columns = []column{{name: ValueColumn}}
+			default:
+				return errors.New("unsupported field type " + fieldType.String() + " for " + childSchema.name)
+			}
+
+			schema.children = append(schema.children, child{index: fieldIndex, schema: childSchema})
+		case scalar(fieldType):
+			schema.columns = append(schema.columns, column{name: prefix + name, index: fieldIndex})
+		default:
+			return errors.New("unsupported field type " + fieldType.String() + " for " + prefix + name)
+		}
+	}
+
+	return nil
+}
+
+// tables sets up the tables of the schema and its children in order and appends them, parent is the name of the
+// nearest table above
+func (schema *tableSchema) tables(parent string, tables *[]*Table, names map[string]bool) error {
+	if schema.table != nil {
+		if names[schema.name] {
+			return errors.New("duplicate table name: " + schema.name)
+		}
+
+		names[schema.name] = true
+
+		schema.table.Name = schema.name
+		schema.table.Parent = parent
+		schema.table.Columns = []string{RowIDColumn}
+
+		if parent != "" {
+			schema.table.Columns = append(schema.table.Columns, ParentRowIDColumn)
+		}
+
+		for _, column := range schema.columns {
+			schema.table.Columns = append(schema.table.Columns, column.name)
+		}
+
+		*tables = append(*tables, schema.table)
+		parent = schema.name
+	}
+
+	for _, child := range schema.children {
+		if tablesErr := child.schema.tables(parent, tables, names); tablesErr != nil {
+			return tablesErr
+		}
+	}
+
+	return nil
+}
+
+// fill adds a row for a value and the rows of its slices, parentRowID is 0 for rows without a parent
+func (schema *tableSchema) fill(value reflect.Value, parentRowID int) {
+	rowID := parentRowID
+
+	if schema.table != nil {
+		rowID = len(schema.table.Rows) + 1
+		row := []string{strconv.Itoa(rowID)}
+
+		if schema.table.Parent != "" {
+			row = append(row, strconv.Itoa(parentRowID))
+		}
+
+		for _, column := range schema.columns {
+			if field, ok := fieldByIndex(value, column.index); ok {
+				row = append(row, format(field))
+			} else {
+				row = append(row, "")
+			}
+		}
+
+		schema.table.Rows = append(schema.table.Rows, row)
+	}
+
+	for _, child := range schema.children {
+		slice, ok := fieldByIndex(value, child.index)
+
+		if !ok {
+			continue
+		}
+
+		for i := 0; i < slice.Len(); i++ {
+			if element, ok := fieldByIndex(slice.Index(i), nil); ok {
+				child.schema.fill(element, rowID)
+			}
+		}
+	}
+}
+
+// indirect returns the type pointed to by pointer types
+func indirect(valueType reflect.Type) reflect.Type {
+	for valueType.Kind() == reflect.Ptr {
+		valueType = valueType.Elem()
+	}
+
+	return valueType
+}
+
+// fieldByIndex returns the field reached by index following pointers, false when a nil pointer is in the way
+func fieldByIndex(value reflect.Value, index []int) (reflect.Value, bool) {
+	for _, i := range index {
+		for value.Kind() == reflect.Ptr {
+			if value.IsNil() {
+				return value, false
+			}
+
+			value = value.Elem()
+		}
+
+		value = value.Field(i)
+	}
+
+	for value.Kind() == reflect.Ptr {
+		if value.IsNil() {
+			return value, false
+		}
+
+		value = value.Elem()
+	}
+
+	return value, true
+}
+
+// scalar returns whether values of a type fit in a single column
+func scalar(valueType reflect.Type) bool {
+	switch valueType.Kind() {
+	case reflect.String, reflect.Bool,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	}
+
+	return valueType == timeType
+}
+
+// format formats a scalar value for a column
+func format(value reflect.Value) string {
+	if value.Type() == timeType {
+		timestamp := value.Interface().(time.Time)
+
+		if timestamp.IsZero() {
+			return ""
+		}
+
+		return timestamp.Format(time.RFC3339)
+	}
+
+	if value.Type() == durationType {
+		return value.Interface().(time.Duration).String()
+	}
+
+	switch value.Kind() {
+	case reflect.Bool:
+		return strconv.FormatBool(value.Bool())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(value.Int(), 10)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(value.Uint(), 10)
+	case reflect.Float32:
+		return strconv.FormatFloat(value.Float(), 'f', -1, 32)
+	case reflect.Float64:
+		return strconv.FormatFloat(value.Float(), 'f', -1, 64)
+	}
+
+	return value.String()
+}
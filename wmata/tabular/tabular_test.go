@@ -0,0 +1,335 @@
+package tabular
+
+import (
+	"bytes"
+	"errors"
+	"github.com/awiede/wmata-go-sdk/wmata/businfo"
+	"github.com/awiede/wmata-go-sdk/wmata/buspredictions"
+	"github.com/awiede/wmata-go-sdk/wmata/railinfo"
+	"github.com/awiede/wmata-go-sdk/wmata/stopboard"
+	"github.com/awiede/wmata-go-sdk/wmata/wmatatest"
+	"github.com/kr/pretty"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestFlattenResponses(t *testing.T) {
+	responses := map[string]interface{}{
+		"Lines":             wmatatest.Lines(),
+		"Stations":          wmatatest.Stations(),
+		"StationParking":    wmatatest.StationParking(),
+		"Path":              wmatatest.Path(),
+		"StationEntrances":  wmatatest.StationEntrances(),
+		"StationTimes":      wmatatest.StationTimes(),
+		"StationToStation":  wmatatest.StationToStation(),
+		"NextTrains":        wmatatest.NextTrains(),
+		"TrainPositions":    wmatatest.LiveTrainPositions(),
+		"StandardRoutes":    wmatatest.StandardRoutes(),
+		"TrackCircuits":     wmatatest.TrackCircuits(),
+		"BusRoutes":         wmatatest.BusRoutes(),
+		"BusStops":          wmatatest.BusStops(),
+		"BusRouteDetails":   wmatatest.BusRouteDetails(),
+		"BusSchedule":       wmatatest.BusSchedule(),
+		"BusStopSchedule":   wmatatest.BusStopSchedule(),
+		"BusPositions":      wmatatest.BusPositions(),
+		"NextBuses":         wmatatest.NextBuses(),
+		"RailIncidents":     wmatatest.RailIncidents(),
+		"BusIncidents":      wmatatest.BusIncidents(),
+		"ElevatorIncidents": wmatatest.ElevatorIncidents(),
+	}
+
+	for name, response := range responses {
+		t.Run(name, func(t *testing.T) {
+			tables, flattenErr := Flatten(response)
+
+			if flattenErr != nil {
+				t.Fatalf("error flattening response: %s", flattenErr)
+			}
+
+			if len(tables) == 0 {
+				t.Fatal("expected at least one table")
+			}
+
+			rowIDs := make(map[string]map[string]bool)
+
+			for _, table := range tables {
+				rowIDs[table.Name] = make(map[string]bool)
+
+				if table.Columns[0] != RowIDColumn {
+					t.Errorf("table %s starts with column %s", table.Name, table.Columns[0])
+				}
+
+				for i, row := range table.Rows {
+					if len(row) != len(table.Columns) {
+						t.Errorf("table %s row %d has %d values for %d columns", table.Name, i, len(row), len(table.Columns))
+					}
+
+					if row[0] != strconv.Itoa(i+1) {
+						t.Errorf("table %s row %d has RowID %s", table.Name, i, row[0])
+					}
+
+					rowIDs[table.Name][row[0]] = true
+
+					if table.Parent == "" {
+						continue
+					}
+
+					if !rowIDs[table.Parent][row[1]] {
+						t.Errorf("table %s row %d refers to missing row %s of %s", table.Name, i, row[1], table.Parent)
+					}
+				}
+			}
+
+			// flattening an empty response gives the same tables and columns
+			emptyTables, emptyErr := Flatten(reflect.New(reflect.TypeOf(response).Elem()).Interface())
+
+			if emptyErr != nil {
+				t.Fatalf("error flattening empty response: %s", emptyErr)
+			}
+
+			if len(emptyTables) != len(tables) {
+				t.Fatalf("expected %d tables for an empty response, got %d", len(tables), len(emptyTables))
+			}
+
+			for i, table := range tables {
+				if emptyTables[i].Name != table.Name || !reflect.DeepEqual(emptyTables[i].Columns, table.Columns) {
+					t.Errorf("expected table %s with columns %v, got %s with %v", table.Name, table.Columns, emptyTables[i].Name, emptyTables[i].Columns)
+				}
+
+				// only the response's own table has a row
+				if emptyTables[i].Parent != "" && len(emptyTables[i].Rows) != 0 || len(emptyTables[i].Rows) > 1 {
+					t.Errorf("expected no slice rows in %s, got %d", table.Name, len(emptyTables[i].Rows))
+				}
+			}
+		})
+	}
+}
+
+func TestFlatten(t *testing.T) {
+	tests := []struct {
+		name     string
+		response interface{}
+		expected []*Table
+	}{
+		{
+			name: "Root Table With Nested Struct",
+			response: &railinfo.GetStationInformationResponse{
+				Address:          railinfo.StationAddress{City: "Washington", State: "DC", Street: "607 13th St. NW", Zip: "20005"},
+				Latitude:         38.898303,
+				LineCode1:        "RD",
+				Longitude:        -77.028099,
+				Name:             "Metro Center",
+				StationCode:      "A01",
+				StationTogether1: "C01",
+			},
+			expected: []*Table{
+				{
+					Name:    "StationInformation",
+					Columns: []string{"RowID", "Address.City", "Address.State", "Address.Street", "Address.Zip", "Lat", "LineCode1", "LineCode2", "Lon", "Name", "Code", "StationTogether1", "StationTogether2"},
+					Rows: [][]string{
+						{"1", "Washington", "DC", "607 13th St. NW", "20005", "38.898303", "RD", "", "-77.028099", "Metro Center", "A01", "C01", ""},
+					},
+				},
+			},
+		},
+		{
+			name:     "Slices Of Scalars",
+			response: wmatatest.BusIncidents(),
+			expected: []*Table{
+				{
+					Name:    "BusIncidents",
+					Columns: []string{"RowID", "DateUpdated", "Description", "IncidentID", "IncidentType"},
+					Rows: [][]string{
+						{"1", "2019-04-29T07:48:16", "Due to traffic congestion on Georgia Ave, buses may experience delays up to 15 minutes.", "32297013-A0B3-4A4B-8C5B-2A3E8E6C9A1D", "Delay"},
+						{"2", "2019-04-29T06:12:03", "S2 buses detoured via 14th St between Colorado Ave and Decatur St due to construction.", "0C89F6B2-5C61-4C2E-9A5A-7B4A3C1D2E3F", "Detour"},
+					},
+				},
+				{
+					Name:    "BusIncidents.RoutesAffected",
+					Parent:  "BusIncidents",
+					Columns: []string{"RowID", "ParentRowID", "Value"},
+					Rows:    [][]string{{"1", "1", "70"}, {"2", "1", "79"}, {"3", "2", "S2"}},
+				},
+			},
+		},
+		{
+			name: "Nested Slices",
+			response: &buspredictions.GetNextBusResponse{
+				StopName: "7th St + Pennsylvania Ave NW",
+				NextBusPredictions: []buspredictions.NextBusPrediction{
+					{DirectionNumber: "0", DirectionText: "North to Silver Spring", Minutes: 4, RouteID: "70", TripID: "7001", VehicleID: "7201"},
+				},
+			},
+			expected: []*Table{
+				{
+					Name:    "NextBus",
+					Columns: []string{"RowID", "StopName"},
+					Rows:    [][]string{{"1", "7th St + Pennsylvania Ave NW"}},
+				},
+				{
+					Name:    "Predictions",
+					Parent:  "NextBus",
+					Columns: []string{"RowID", "ParentRowID", "DirectionNum", "DirectionText", "Minutes", "RouteID", "TripID", "VehicleID"},
+					Rows:    [][]string{{"1", "1", "0", "North to Silver Spring", "4", "70", "7001", "7201"}},
+				},
+			},
+		},
+		{
+			name: "Times And Booleans",
+			response: &stopboard.GetStopBoardResponse{
+				StopID:      "1001195",
+				GeneratedAt: time.Date(2019, 4, 29, 8, 0, 0, 0, time.UTC),
+				Arrivals: []stopboard.Arrival{
+					{RouteID: "70", Realtime: true, Minutes: 4, Lateness: 90 * time.Second, Vehicle: &businfo.BusPosition{VehicleID: "7201", Deviation: 2}},
+					{RouteID: "79", ScheduledTime: time.Date(2019, 4, 29, 8, 12, 0, 0, time.UTC), Minutes: 12},
+				},
+			},
+			expected: []*Table{
+				{
+					Name:    "StopBoard",
+					Columns: []string{"RowID", "StopID", "StopName", "GeneratedAt"},
+					Rows:    [][]string{{"1", "1001195", "", "2019-04-29T08:00:00Z"}},
+				},
+				{
+					Name:   "Arrivals",
+					Parent: "StopBoard",
+					Columns: []string{
+						"RowID", "ParentRowID", "RouteID", "DirectionText", "TripDestination", "TripID", "VehicleID", "Realtime", "ScheduledTime", "ExpectedTime", "Minutes", "Lateness",
+						"Vehicle.BlockNumber", "Vehicle.DateTime", "Vehicle.Deviation", "Vehicle.DirectionNum", "Vehicle.DirectionText", "Vehicle.Lat", "Vehicle.Lon",
+						"Vehicle.RouteID", "Vehicle.TripEndTime", "Vehicle.TripHeadsign", "Vehicle.TripID", "Vehicle.TripStartTime", "Vehicle.VehicleID",
+					},
+					Rows: [][]string{
+						{"1", "1", "70", "", "", "", "", "true", "", "", "4", "1m30s", "", "", "2", "0", "", "0", "0", "", "", "", "", "", "7201"},
+						{"2", "1", "79", "", "", "", "", "false", "2019-04-29T08:12:00Z", "", "12", "0s", "", "", "", "", "", "", "", "", "", "", "", "", ""},
+					},
+				},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			actual, flattenErr := Flatten(test.response)
+
+			if flattenErr != nil {
+				t.Fatalf("error flattening response: %s", flattenErr)
+			}
+
+			if !reflect.DeepEqual(test.expected, actual) {
+				t.Error(pretty.Diff(test.expected, actual))
+			}
+		})
+	}
+}
+
+func TestFlattenErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		response interface{}
+		expected string
+	}{
+		{
+			name:     "Nil Response",
+			response: (*railinfo.GetStationListResponse)(nil),
+			expected: "response is nil",
+		},
+		{
+			name:     "Not A Struct",
+			response: []string{"A01"},
+			expected: "response must be a struct, got []string",
+		},
+		{
+			name:     "Unsupported Field",
+			response: &buspredictions.GetNextBusesForStopsResponse{},
+			expected: "unsupported field type map[string]*buspredictions.GetNextBusResponse for Predictions",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, flattenErr := Flatten(test.response)
+
+			if flattenErr == nil || flattenErr.Error() != test.expected {
+				t.Errorf("expected error %q, got %v", test.expected, flattenErr)
+			}
+		})
+	}
+}
+
+func TestWriteCSV(t *testing.T) {
+	table := &Table{
+		Name:    "Lines",
+		Columns: []string{"RowID", "LineCode", "DisplayName"},
+		Rows:    [][]string{{"1", "RD", "Red"}, {"2", "SV", "Silver, \"New\""}},
+	}
+
+	var buffer bytes.Buffer
+
+	if writeErr := table.WriteCSV(&buffer); writeErr != nil {
+		t.Fatalf("error writing csv: %s", writeErr)
+	}
+
+	expected := "RowID,LineCode,DisplayName\n1,RD,Red\n2,SV,\"Silver, \"\"New\"\"\"\n"
+
+	if buffer.String() != expected {
+		t.Errorf("expected %q, got %q", expected, buffer.String())
+	}
+}
+
+func TestExport(t *testing.T) {
+	directory, tempErr := ioutil.TempDir("", "tabular")
+
+	if tempErr != nil {
+		t.Fatalf("error creating directory: %s", tempErr)
+	}
+
+	defer os.RemoveAll(directory)
+
+	if exportErr := Export(NewCSVWriter(filepath.Join(directory, "incidents")), wmatatest.BusIncidents()); exportErr != nil {
+		t.Fatalf("error exporting response: %s", exportErr)
+	}
+
+	contents, readErr := ioutil.ReadFile(filepath.Join(directory, "incidents", "BusIncidents.RoutesAffected.csv"))
+
+	if readErr != nil {
+		t.Fatalf("error reading csv: %s", readErr)
+	}
+
+	expected := "RowID,ParentRowID,Value\n1,1,70\n2,1,79\n3,2,S2\n"
+
+	if string(contents) != expected {
+		t.Errorf("expected %q, got %q", expected, string(contents))
+	}
+
+	if _, statErr := os.Stat(filepath.Join(directory, "incidents", "BusIncidents.csv")); statErr != nil {
+		t.Errorf("expected BusIncidents.csv: %s", statErr)
+	}
+
+	var names []string
+	writeErr := errors.New("write failed")
+
+	exportErr := Export(WriterFunc(func(table *Table) error {
+		names = append(names, table.Name)
+
+		if table.Name == "Direction0.StopTimes" {
+			return writeErr
+		}
+
+		return nil
+	}), wmatatest.BusSchedule())
+
+	if exportErr != writeErr {
+		t.Errorf("expected %v, got %v", writeErr, exportErr)
+	}
+
+	expectedNames := []string{"Schedule", "Direction0", "Direction0.StopTimes"}
+
+	if !reflect.DeepEqual(expectedNames, names) {
+		t.Error(pretty.Diff(expectedNames, names))
+	}
+}
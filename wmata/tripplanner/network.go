@@ -0,0 +1,198 @@
+package tripplanner
+
+import (
+	"errors"
+	"github.com/awiede/wmata-go-sdk/wmata/railinfo"
+	"github.com/awiede/wmata-go-sdk/wmata/stationmatrix"
+	"github.com/awiede/wmata-go-sdk/wmata/trainpositions"
+	"sort"
+)
+
+// Network is the rail network used for planning: the station catalog, the ordered stations on each line and the
+// travel time and fare between station pairs
+type Network struct {
+	stations map[string]railinfo.GetStationListResponseItem
+	lines    map[string][]string
+	together map[string][]string
+	matrix   *stationmatrix.Matrix
+}
+
+// NewNetwork builds a network from a station list, the ordered station codes of each line keyed by line code and the
+// station to station information between station pairs
+func NewNetwork(stations []railinfo.GetStationListResponseItem, lineSequences map[string][]string, stationToStation []railinfo.StationToStation) *Network {
+	network := Network{
+		stations: make(map[string]railinfo.GetStationListResponseItem),
+		lines:    make(map[string][]string),
+		together: make(map[string][]string),
+		matrix:   stationmatrix.New(stationToStation),
+	}
+
+	for _, station := range stations {
+		network.stations[station.StationCode] = station
+	}
+
+	// StationTogether is not always listed on both stations of a complex, so links are recorded in both directions
+	for _, station := range stations {
+		for _, together := range []string{station.StationTogether1, station.StationTogether2} {
+			if together == "" || together == station.StationCode {
+				continue
+			}
+
+			network.link(station.StationCode, together)
+			network.link(together, station.StationCode)
+		}
+	}
+
+	for lineCode, sequence := range lineSequences {
+		network.lines[lineCode] = append([]string(nil), sequence...)
+	}
+
+	return &network
+}
+
+// LoadNetwork retrieves the station list, the path of every line and station to station information for all stations
+func LoadNetwork(railInfo railinfo.RailInfo) (*Network, error) {
+	stations, stationsErr := railInfo.GetStationList("")
+
+	if stationsErr != nil {
+		return nil, stationsErr
+	}
+
+	lines, linesErr := railInfo.GetLines()
+
+	if linesErr != nil {
+		return nil, linesErr
+	}
+
+	lineSequences := make(map[string][]string)
+
+	for _, line := range lines.Lines {
+		path, pathErr := railInfo.GetPathBetweenStations(line.StartStationCode, line.EndStationCode)
+
+		if pathErr != nil {
+			return nil, pathErr
+		}
+
+		lineSequences[line.LineCode] = SequenceFromPath(path.Path)
+	}
+
+	stationToStation, stationToStationErr := railInfo.GetStationToStationInformation("", "")
+
+	if stationToStationErr != nil {
+		return nil, stationToStationErr
+	}
+
+	return NewNetwork(stations.Stations, lineSequences, stationToStation.StationToStationInformation), nil
+}
+
+// SequenceFromPath returns the station codes of a path in sequence order
+func SequenceFromPath(path []railinfo.PathItem) []string {
+	items := make([]railinfo.PathItem, len(path))
+	copy(items, path)
+
+	sort.SliceStable(items, func(i, j int) bool {
+		return items[i].SequenceNumber < items[j].SequenceNumber
+	})
+
+	sequence := make([]string, 0, len(items))
+
+	for _, item := range items {
+		sequence = append(sequence, item.StationCode)
+	}
+
+	return sequence
+}
+
+// SequencesFromStandardRoutes returns the station codes of each line in track 1 circuit order, which can be used in
+// place of GetPathBetweenStations when standard routes are already loaded
+func SequencesFromStandardRoutes(routes []trainpositions.Route) map[string][]string {
+	sequences := make(map[string][]string)
+
+	for _, route := range routes {
+		if route.TrackNumber != 1 {
+			continue
+		}
+
+		circuits := make([]trainpositions.StandardTrackCircuit, len(route.TrackCircuits))
+		copy(circuits, route.TrackCircuits)
+
+		sort.SliceStable(circuits, func(i, j int) bool {
+			return circuits[i].SequenceNumber < circuits[j].SequenceNumber
+		})
+
+		var sequence []string
+
+		for _, circuit := range circuits {
+			if circuit.StationCode == "" || (len(sequence) > 0 && sequence[len(sequence)-1] == circuit.StationCode) {
+				continue
+			}
+
+			sequence = append(sequence, circuit.StationCode)
+		}
+
+		sequences[route.LineCode] = sequence
+	}
+
+	return sequences
+}
+
+// Station returns the catalog entry for a station code
+func (network *Network) Station(stationCode string) (railinfo.GetStationListResponseItem, bool) {
+	station, exist := network.stations[stationCode]
+
+	return station, exist
+}
+
+// Complex returns the station code along with any station codes it shares a station complex with
+func (network *Network) Complex(stationCode string) []string {
+	return append([]string{stationCode}, network.together[stationCode]...)
+}
+
+// LinesAt returns the codes of the lines stopping at a station, in alphabetical order
+func (network *Network) LinesAt(stationCode string) []string {
+	var lineCodes []string
+
+	for lineCode, sequence := range network.lines {
+		if indexOf(sequence, stationCode) >= 0 {
+			lineCodes = append(lineCodes, lineCode)
+		}
+	}
+
+	sort.Strings(lineCodes)
+
+	return lineCodes
+}
+
+// StationToStation returns the distance, fare and travel time between two stations
+func (network *Network) StationToStation(from, to string) (railinfo.StationToStation, bool) {
+	return network.matrix.Lookup(from, to)
+}
+
+// validate reports an error when a station code is not in the network
+func (network *Network) validate(stationCode string) error {
+	if stationCode == "" {
+		return errors.New("station code is required")
+	}
+
+	if _, exist := network.stations[stationCode]; !exist {
+		return errors.New("unknown station code: " + stationCode)
+	}
+
+	return nil
+}
+
+func (network *Network) link(from, to string) {
+	if indexOf(network.together[from], to) < 0 {
+		network.together[from] = append(network.together[from], to)
+	}
+}
+
+func indexOf(values []string, value string) int {
+	for i, candidate := range values {
+		if candidate == value {
+			return i
+		}
+	}
+
+	return -1
+}
@@ -0,0 +1,361 @@
+package tripplanner
+
+import (
+	"container/heap"
+	"errors"
+	"github.com/awiede/wmata-go-sdk/wmata/railinfo"
+	"sort"
+)
+
+// Defaults used when a Config field is left at zero
+const (
+	DefaultTransferMinutes = 5
+	DefaultMaxTransfers    = 2
+	// DefaultMinutesPerStation estimates a leg's RailTime when station to station information is missing
+	DefaultMinutesPerStation = 2
+)
+
+// Config controls how itineraries are searched and ranked
+type Config struct {
+	// TransferMinutes is added for every change of line
+	TransferMinutes int
+	// MaxTransfers is the most line changes an itinerary may include
+	MaxTransfers int
+	// MinutesPerStation estimates RailTime for legs without station to station information
+	MinutesPerStation int
+}
+
+func (config Config) withDefaults() Config {
+	if config.TransferMinutes <= 0 {
+		config.TransferMinutes = DefaultTransferMinutes
+	}
+
+	if config.MaxTransfers <= 0 {
+		config.MaxTransfers = DefaultMaxTransfers
+	}
+
+	if config.MinutesPerStation <= 0 {
+		config.MinutesPerStation = DefaultMinutesPerStation
+	}
+
+	return config
+}
+
+// Itinerary is a way of traveling between two stations, made of one leg per line ridden
+type Itinerary struct {
+	Legs []Leg `json:"Legs"`
+	// Transfers is the number of line changes
+	Transfers int `json:"Transfers"`
+	// RailTime is the sum of leg RailTimes in minutes
+	RailTime int `json:"RailTime"`
+	// TotalTime is RailTime plus the time allowed for transfers, in minutes
+	TotalTime int `json:"TotalTime"`
+	// Fare is charged from the origin to the final destination, transfers between lines are free
+	Fare           railinfo.RailFare `json:"Fare"`
+	CompositeMiles float64           `json:"CompositeMiles"`
+}
+
+// Leg is a ride on a single line
+type Leg struct {
+	LineCode        string `json:"LineCode"`
+	FromStationCode string `json:"FromStationCode"`
+	FromStationName string `json:"FromStationName"`
+	ToStationCode   string `json:"ToStationCode"`
+	ToStationName   string `json:"ToStationName"`
+	// TowardStationCode is the end of the line in the direction of travel, as shown on train destination signs
+	TowardStationCode string `json:"TowardStationCode"`
+	// Stations are the station codes passed through from boarding to alighting, inclusive
+	Stations []string `json:"Stations"`
+	RailTime int      `json:"RailTime"`
+}
+
+// TransferStations returns the station codes where the rider changes line
+func (itinerary *Itinerary) TransferStations() []string {
+	var stationCodes []string
+
+	for i := 1; i < len(itinerary.Legs); i++ {
+		stationCodes = append(stationCodes, itinerary.Legs[i-1].ToStationCode)
+	}
+
+	return stationCodes
+}
+
+// Planner computes itineraries over a Network
+type Planner struct {
+	network *Network
+	config  Config
+}
+
+// NewPlanner returns a Planner for the given network
+func NewPlanner(network *Network, config Config) *Planner {
+	return &Planner{
+		network: network,
+		config:  config.withDefaults(),
+	}
+}
+
+// Plan finds the fastest itinerary for each number of transfers up to MaxTransfers, dropping any itinerary that is not
+// faster than one with fewer transfers. Itineraries are ranked by total time, then number of transfers
+func (planner *Planner) Plan(fromStation, toStation string) ([]Itinerary, error) {
+	return planner.PlanAvoiding(fromStation, toStation, nil)
+}
+
+// PlanAvoiding plans like Plan without riding any of the excluded lines, so detours slower than the usual routes are
+// found when a line is disrupted
+func (planner *Planner) PlanAvoiding(fromStation, toStation string, excludedLines []string) ([]Itinerary, error) {
+	if validateErr := planner.network.validate(fromStation); validateErr != nil {
+		return nil, validateErr
+	}
+
+	if validateErr := planner.network.validate(toStation); validateErr != nil {
+		return nil, validateErr
+	}
+
+	destinations := make(map[string]bool)
+
+	for _, stationCode := range planner.network.Complex(toStation) {
+		destinations[stationCode] = true
+	}
+
+	if destinations[fromStation] {
+		return nil, errors.New("fromStation and toStation are the same station")
+	}
+
+	excluded := make(map[string]bool)
+
+	for _, lineCode := range excludedLines {
+		excluded[lineCode] = true
+	}
+
+	best := planner.search(planner.network.Complex(fromStation), destinations, excluded)
+
+	var itineraries []Itinerary
+	fastest := -1
+
+	for transfers := 0; transfers <= planner.config.MaxTransfers; transfers++ {
+		arrival, exist := best[transfers]
+
+		if !exist || (fastest >= 0 && arrival.cost >= fastest) {
+			continue
+		}
+
+		fastest = arrival.cost
+		itineraries = append(itineraries, planner.buildItinerary(arrival, fromStation, toStation))
+	}
+
+	if len(itineraries) == 0 {
+		return nil, errors.New("no route found between " + fromStation + " and " + toStation)
+	}
+
+	sort.SliceStable(itineraries, func(i, j int) bool {
+		if itineraries[i].TotalTime != itineraries[j].TotalTime {
+			return itineraries[i].TotalTime < itineraries[j].TotalTime
+		}
+
+		return itineraries[i].Transfers < itineraries[j].Transfers
+	})
+
+	return itineraries, nil
+}
+
+// LegRailTime returns the minutes to ride a line between two stations, from station to station information when
+// available and otherwise estimated from the number of stations passed
+func (planner *Planner) LegRailTime(lineCode, fromStation, toStation string) int {
+	if info, exist := planner.network.StationToStation(fromStation, toStation); exist && info.Time > 0 {
+		return info.Time
+	}
+
+	sequence := planner.network.lines[lineCode]
+	stops := indexOf(sequence, toStation) - indexOf(sequence, fromStation)
+
+	if stops < 0 {
+		stops = -stops
+	}
+
+	return stops * planner.config.MinutesPerStation
+}
+
+// searchNode is a rider either waiting to board a line at a station or having just alighted from it
+type searchNode struct {
+	stationCode string
+	lineCode    string
+	transfers   int
+	boarding    bool
+}
+
+// searchLabel is the cheapest known way to reach a node
+type searchLabel struct {
+	node     searchNode
+	cost     int
+	previous *searchLabel
+}
+
+// search runs a shortest path search over boarding and alighting nodes, returning the cheapest arrival at any
+// destination station for each number of transfers. Excluded lines are never boarded
+func (planner *Planner) search(origins []string, destinations map[string]bool, excluded map[string]bool) map[int]*searchLabel {
+	settled := make(map[searchNode]bool)
+	costs := make(map[searchNode]int)
+	queue := &labelQueue{}
+	best := make(map[int]*searchLabel)
+
+	push := func(label *searchLabel) {
+		if label.node.boarding && excluded[label.node.lineCode] {
+			return
+		}
+
+		if cost, exist := costs[label.node]; exist && cost <= label.cost {
+			return
+		}
+
+		costs[label.node] = label.cost
+		heap.Push(queue, label)
+	}
+
+	for _, origin := range origins {
+		for _, lineCode := range planner.network.LinesAt(origin) {
+			push(&searchLabel{node: searchNode{stationCode: origin, lineCode: lineCode, boarding: true}})
+		}
+	}
+
+	for queue.Len() > 0 {
+		label := heap.Pop(queue).(*searchLabel)
+
+		if settled[label.node] {
+			continue
+		}
+
+		settled[label.node] = true
+		node := label.node
+
+		if !node.boarding {
+			if destinations[node.stationCode] {
+				if _, exist := best[node.transfers]; !exist {
+					best[node.transfers] = label
+				}
+
+				continue
+			}
+
+			if node.transfers >= planner.config.MaxTransfers {
+				continue
+			}
+
+			for _, stationCode := range planner.network.Complex(node.stationCode) {
+				for _, lineCode := range planner.network.LinesAt(stationCode) {
+					if lineCode == node.lineCode {
+						continue
+					}
+
+					push(&searchLabel{
+						node:     searchNode{stationCode: stationCode, lineCode: lineCode, transfers: node.transfers + 1, boarding: true},
+						cost:     label.cost + planner.config.TransferMinutes,
+						previous: label,
+					})
+				}
+			}
+
+			continue
+		}
+
+		for _, stationCode := range planner.network.lines[node.lineCode] {
+			if stationCode == node.stationCode {
+				continue
+			}
+
+			push(&searchLabel{
+				node:     searchNode{stationCode: stationCode, lineCode: node.lineCode, transfers: node.transfers},
+				cost:     label.cost + planner.LegRailTime(node.lineCode, node.stationCode, stationCode),
+				previous: label,
+			})
+		}
+	}
+
+	return best
+}
+
+// buildItinerary walks the search labels back from an arrival to build the legs of an itinerary
+func (planner *Planner) buildItinerary(arrival *searchLabel, fromStation, toStation string) Itinerary {
+	var legs []Leg
+
+	for label := arrival; label.previous != nil; label = label.previous.previous {
+		boarding := label.previous
+		legs = append([]Leg{planner.buildLeg(boarding.node.lineCode, boarding.node.stationCode, label.node.stationCode)}, legs...)
+
+		if boarding.previous == nil {
+			break
+		}
+	}
+
+	itinerary := Itinerary{
+		Legs:      legs,
+		Transfers: len(legs) - 1,
+	}
+
+	for _, leg := range legs {
+		itinerary.RailTime += leg.RailTime
+	}
+
+	itinerary.TotalTime = itinerary.RailTime + itinerary.Transfers*planner.config.TransferMinutes
+
+	if info, exist := planner.network.StationToStation(legs[0].FromStationCode, legs[len(legs)-1].ToStationCode); exist {
+		itinerary.Fare = info.Fare
+		itinerary.CompositeMiles = info.CompositeMiles
+	} else if info, exist := planner.network.StationToStation(fromStation, toStation); exist {
+		itinerary.Fare = info.Fare
+		itinerary.CompositeMiles = info.CompositeMiles
+	}
+
+	return itinerary
+}
+
+func (planner *Planner) buildLeg(lineCode, fromStation, toStation string) Leg {
+	sequence := planner.network.lines[lineCode]
+	fromIndex, toIndex := indexOf(sequence, fromStation), indexOf(sequence, toStation)
+
+	leg := Leg{
+		LineCode:        lineCode,
+		FromStationCode: fromStation,
+		ToStationCode:   toStation,
+		RailTime:        planner.LegRailTime(lineCode, fromStation, toStation),
+	}
+
+	if station, exist := planner.network.Station(fromStation); exist {
+		leg.FromStationName = station.Name
+	}
+
+	if station, exist := planner.network.Station(toStation); exist {
+		leg.ToStationName = station.Name
+	}
+
+	if fromIndex <= toIndex {
+		leg.TowardStationCode = sequence[len(sequence)-1]
+		leg.Stations = append(leg.Stations, sequence[fromIndex:toIndex+1]...)
+	} else {
+		leg.TowardStationCode = sequence[0]
+
+		for i := fromIndex; i >= toIndex; i-- {
+			leg.Stations = append(leg.Stations, sequence[i])
+		}
+	}
+
+	return leg
+}
+
+// labelQueue is a min heap of search labels ordered by cost
+type labelQueue []*searchLabel
+
+func (queue labelQueue) Len() int { return len(queue) }
+
+func (queue labelQueue) Less(i, j int) bool { return queue[i].cost < queue[j].cost }
+
+func (queue labelQueue) Swap(i, j int) { queue[i], queue[j] = queue[j], queue[i] }
+
+func (queue *labelQueue) Push(label interface{}) { *queue = append(*queue, label.(*searchLabel)) }
+
+func (queue *labelQueue) Pop() interface{} {
+	old := *queue
+	label := old[len(old)-1]
+	*queue = old[:len(old)-1]
+
+	return label
+}
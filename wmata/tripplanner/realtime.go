@@ -0,0 +1,402 @@
+package tripplanner
+
+import (
+	"errors"
+	"github.com/awiede/wmata-go-sdk/wmata"
+	"github.com/awiede/wmata-go-sdk/wmata/incidents"
+	"github.com/awiede/wmata-go-sdk/wmata/railinfo"
+	"github.com/awiede/wmata-go-sdk/wmata/railpredictions"
+	"github.com/awiede/wmata-go-sdk/wmata/stationtimes"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Defaults used when a LiveConfig field is left at zero
+const (
+	DefaultConnectionMinutes = 2
+	DefaultHeadwayMinutes    = 12
+)
+
+const (
+	// DepartureSourcePrediction is used for legs timed from a realtime prediction
+	DepartureSourcePrediction = "PREDICTION"
+	// DepartureSourceSchedule is used for legs timed from scheduled headways because no prediction was available
+	DepartureSourceSchedule = "SCHEDULE"
+)
+
+// suspensionPhrases mark rail incidents that stop service on their lines, matched case insensitively against the
+// incident type and description. Other incidents such as delays leave the lines usable
+var suspensionPhrases = []string{"suspend", "no service", "not operating", "closed", "closure", "shut down"}
+
+// LiveConfig controls how live itineraries are timed
+type LiveConfig struct {
+	// ConnectionMinutes is the least time needed to walk between platforms when transferring
+	ConnectionMinutes int
+	// HeadwayMinutes is the assumed wait for a train when no prediction covers the boarding time
+	HeadwayMinutes int
+	// LineHeadwayMinutes overrides HeadwayMinutes for individual line codes
+	LineHeadwayMinutes map[string]int
+}
+
+func (config LiveConfig) withDefaults() LiveConfig {
+	if config.ConnectionMinutes <= 0 {
+		config.ConnectionMinutes = DefaultConnectionMinutes
+	}
+
+	if config.HeadwayMinutes <= 0 {
+		config.HeadwayMinutes = DefaultHeadwayMinutes
+	}
+
+	return config
+}
+
+// LiveItinerary is an itinerary timed against the trains actually running
+type LiveItinerary struct {
+	Itinerary
+	LiveLegs  []LiveLeg `json:"LiveLegs"`
+	Departure time.Time `json:"Departure"`
+	ETA       time.Time `json:"ETA"`
+	// Incidents are the rail incidents on lines the itinerary rides that do not stop service, such as delays
+	Incidents []incidents.RailIncident `json:"Incidents"`
+}
+
+// LiveLeg is a leg with the train the rider is expected to catch
+type LiveLeg struct {
+	Leg
+	Departure time.Time `json:"Departure"`
+	Arrival   time.Time `json:"Arrival"`
+	// Wait is the time spent on the platform before the train departs
+	Wait time.Duration `json:"Wait"`
+	// DepartureSource is DepartureSourcePrediction or DepartureSourceSchedule
+	DepartureSource string `json:"DepartureSource"`
+	// Train is the predicted train, nil when the departure is estimated from the schedule
+	Train *railpredictions.Train `json:"Train"`
+}
+
+// LivePlanner times itineraries from a Planner using live predictions, station timings and rail incidents
+type LivePlanner struct {
+	planner         *Planner
+	railInfo        railinfo.RailInfo
+	railPredictions railpredictions.RailPredictions
+	incidents       incidents.Incidents
+	config          LiveConfig
+}
+
+// NewLivePlanner returns a LivePlanner that uses the given services for live data
+func NewLivePlanner(planner *Planner, railInfo railinfo.RailInfo, railPredictions railpredictions.RailPredictions, incidentService incidents.Incidents, config LiveConfig) *LivePlanner {
+	return &LivePlanner{
+		planner:         planner,
+		railInfo:        railInfo,
+		railPredictions: railPredictions,
+		incidents:       incidentService,
+		config:          config.withDefaults(),
+	}
+}
+
+// PlanLeaveNow times every itinerary between two stations for a rider leaving at now, picking the next train on each
+// leg from live predictions and falling back to scheduled headways when no prediction is available. Lines with a rail
+// incident suspending service are avoided, so detours are planned around them, and other incidents are attached to
+// the itineraries riding their lines. Results are ordered by ETA, then number of transfers
+func (livePlanner *LivePlanner) PlanLeaveNow(fromStation, toStation string, now time.Time) ([]LiveItinerary, error) {
+	lineIncidents, suspendedLines, incidentsErr := livePlanner.lineIncidents()
+
+	if incidentsErr != nil {
+		return nil, incidentsErr
+	}
+
+	var excludedLines []string
+
+	for lineCode := range suspendedLines {
+		excludedLines = append(excludedLines, lineCode)
+	}
+
+	unaffected, planErr := livePlanner.planner.PlanAvoiding(fromStation, toStation, excludedLines)
+
+	if planErr != nil {
+		if len(excludedLines) > 0 {
+			if _, unrestrictedErr := livePlanner.planner.Plan(fromStation, toStation); unrestrictedErr == nil {
+				return nil, errors.New("every route between " + fromStation + " and " + toStation + " is suspended by a rail incident")
+			}
+		}
+
+		return nil, planErr
+	}
+
+	predictions, predictionsErr := livePlanner.predictions(unaffected)
+
+	if predictionsErr != nil {
+		return nil, predictionsErr
+	}
+
+	timings := make(map[string]*railinfo.StationTime)
+
+	var liveItineraries []LiveItinerary
+	var lastErr error
+
+	for _, itinerary := range unaffected {
+		liveItinerary, timeErr := livePlanner.timeItinerary(itinerary, now, predictions, timings)
+
+		if timeErr != nil {
+			lastErr = timeErr
+			continue
+		}
+
+		liveItinerary.Incidents = itineraryIncidents(itinerary, lineIncidents)
+		liveItineraries = append(liveItineraries, *liveItinerary)
+	}
+
+	if len(liveItineraries) == 0 {
+		return nil, lastErr
+	}
+
+	sort.SliceStable(liveItineraries, func(i, j int) bool {
+		if !liveItineraries[i].ETA.Equal(liveItineraries[j].ETA) {
+			return liveItineraries[i].ETA.Before(liveItineraries[j].ETA)
+		}
+
+		return liveItineraries[i].Transfers < liveItineraries[j].Transfers
+	})
+
+	return liveItineraries, nil
+}
+
+// timeItinerary picks a train for each leg in turn, starting from now
+func (livePlanner *LivePlanner) timeItinerary(itinerary Itinerary, now time.Time, predictions map[string][]railpredictions.Train, timings map[string]*railinfo.StationTime) (*LiveItinerary, error) {
+	liveItinerary := LiveItinerary{
+		Itinerary: itinerary,
+	}
+
+	ready := now
+
+	for i, leg := range itinerary.Legs {
+		if i > 0 {
+			ready = ready.Add(time.Duration(livePlanner.config.ConnectionMinutes) * time.Minute)
+		}
+
+		liveLeg := LiveLeg{
+			Leg: leg,
+		}
+
+		if train, departure, found := livePlanner.nextPredictedTrain(&leg, ready, now, predictions[leg.FromStationCode]); found {
+			liveLeg.Departure = departure
+			liveLeg.DepartureSource = DepartureSourcePrediction
+			liveLeg.Train = train
+		} else {
+			departure, scheduleErr := livePlanner.scheduledDeparture(&leg, ready, timings)
+
+			if scheduleErr != nil {
+				return nil, scheduleErr
+			}
+
+			liveLeg.Departure = departure
+			liveLeg.DepartureSource = DepartureSourceSchedule
+		}
+
+		liveLeg.Wait = liveLeg.Departure.Sub(ready)
+		liveLeg.Arrival = liveLeg.Departure.Add(time.Duration(leg.RailTime) * time.Minute)
+		ready = liveLeg.Arrival
+
+		liveItinerary.LiveLegs = append(liveItinerary.LiveLegs, liveLeg)
+	}
+
+	liveItinerary.Departure = liveItinerary.LiveLegs[0].Departure
+	liveItinerary.ETA = ready
+
+	return &liveItinerary, nil
+}
+
+// nextPredictedTrain returns the first predicted train on the leg's line heading past the leg's destination that
+// departs no earlier than ready
+func (livePlanner *LivePlanner) nextPredictedTrain(leg *Leg, ready, now time.Time, trains []railpredictions.Train) (*railpredictions.Train, time.Time, bool) {
+	var best *railpredictions.Train
+	var bestDeparture time.Time
+
+	for i := range trains {
+		train := trains[i]
+
+		if train.Line != leg.LineCode || !livePlanner.servesLeg(leg, train.DestinationCode) {
+			continue
+		}
+
+		minutes, estimated := train.ParseMinutes()
+
+		if !estimated {
+			continue
+		}
+
+		departure := now.Add(time.Duration(minutes) * time.Minute)
+
+		if departure.Before(ready) {
+			continue
+		}
+
+		if best == nil || departure.Before(bestDeparture) {
+			best = &train
+			bestDeparture = departure
+		}
+	}
+
+	return best, bestDeparture, best != nil
+}
+
+// scheduledDeparture estimates the departure for a leg from the station's first and last trains and the line headway
+func (livePlanner *LivePlanner) scheduledDeparture(leg *Leg, ready time.Time, timings map[string]*railinfo.StationTime) (time.Time, error) {
+	timing, exist := timings[leg.FromStationCode]
+
+	if !exist {
+		response, timingsErr := livePlanner.railInfo.GetStationTimings(leg.FromStationCode)
+
+		if timingsErr != nil {
+			return time.Time{}, timingsErr
+		}
+
+		if len(response.StationTimes) > 0 {
+			timing = &response.StationTimes[0]
+		}
+
+		timings[leg.FromStationCode] = timing
+	}
+
+	headway := time.Duration(livePlanner.config.HeadwayMinutes) * time.Minute
+
+	if lineHeadway, exist := livePlanner.config.LineHeadwayMinutes[leg.LineCode]; exist && lineHeadway > 0 {
+		headway = time.Duration(lineHeadway) * time.Minute
+	}
+
+	if timing == nil {
+		return ready.Add(headway), nil
+	}
+
+	// station timings are in Washington time whatever the location of now
+	day, dayErr := stationtimes.ForTime(timing, ready.In(wmata.Location))
+
+	if dayErr != nil {
+		return time.Time{}, dayErr
+	}
+
+	first, last, found := day.Window(func(destinationCode string) bool {
+		return livePlanner.servesLeg(leg, destinationCode)
+	})
+
+	switch {
+	case !found:
+		return ready.Add(headway), nil
+	case ready.Before(first):
+		return first, nil
+	case ready.After(last):
+		return time.Time{}, errors.New("no more trains from " + leg.FromStationCode + " on the " + leg.LineCode + " line today")
+	}
+
+	departure := ready.Add(headway)
+
+	if departure.After(last) {
+		departure = last
+	}
+
+	return departure, nil
+}
+
+// servesLeg reports whether a train bound for destinationCode passes the leg's destination in its direction of travel
+func (livePlanner *LivePlanner) servesLeg(leg *Leg, destinationCode string) bool {
+	sequence := livePlanner.planner.network.lines[leg.LineCode]
+	fromIndex, toIndex, destinationIndex := indexOf(sequence, leg.FromStationCode), indexOf(sequence, leg.ToStationCode), indexOf(sequence, destinationCode)
+
+	if destinationIndex < 0 {
+		return false
+	}
+
+	if fromIndex <= toIndex {
+		return destinationIndex >= toIndex
+	}
+
+	return destinationIndex <= toIndex
+}
+
+// predictions retrieves predictions for every boarding station in a single request
+func (livePlanner *LivePlanner) predictions(itineraries []Itinerary) (map[string][]railpredictions.Train, error) {
+	var stationCodes []string
+	seen := make(map[string]bool)
+
+	for _, itinerary := range itineraries {
+		for _, leg := range itinerary.Legs {
+			if !seen[leg.FromStationCode] {
+				seen[leg.FromStationCode] = true
+				stationCodes = append(stationCodes, leg.FromStationCode)
+			}
+		}
+	}
+
+	response, predictionsErr := livePlanner.railPredictions.GetNextTrains(stationCodes)
+
+	if predictionsErr != nil {
+		return nil, predictionsErr
+	}
+
+	byStation := make(map[string][]railpredictions.Train)
+
+	for _, train := range response.Trains {
+		byStation[train.LocationCode] = append(byStation[train.LocationCode], train)
+	}
+
+	return byStation, nil
+}
+
+// lineIncidents returns the active rail incidents by the line codes they list, and the lines whose service is
+// suspended by one of them
+func (livePlanner *LivePlanner) lineIncidents() (map[string][]incidents.RailIncident, map[string]bool, error) {
+	response, incidentsErr := livePlanner.incidents.GetRailIncidents()
+
+	if incidentsErr != nil {
+		return nil, nil, incidentsErr
+	}
+
+	byLine := make(map[string][]incidents.RailIncident)
+	suspended := make(map[string]bool)
+
+	for _, incident := range response.RailIncidents {
+		for _, lineCode := range strings.Split(incident.LinesAffected, ";") {
+			if lineCode = strings.TrimSpace(lineCode); lineCode == "" {
+				continue
+			}
+
+			byLine[lineCode] = append(byLine[lineCode], incident)
+
+			if suspendsService(&incident) {
+				suspended[lineCode] = true
+			}
+		}
+	}
+
+	return byLine, suspended, nil
+}
+
+// suspendsService reports whether a rail incident stops service on its lines
+func suspendsService(incident *incidents.RailIncident) bool {
+	text := strings.ToLower(incident.IncidentType + " " + incident.Description)
+
+	for _, phrase := range suspensionPhrases {
+		if strings.Contains(text, phrase) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// itineraryIncidents returns the incidents on the lines an itinerary rides, each listed once
+func itineraryIncidents(itinerary Itinerary, lineIncidents map[string][]incidents.RailIncident) []incidents.RailIncident {
+	var matched []incidents.RailIncident
+	seen := make(map[string]bool)
+
+	for _, leg := range itinerary.Legs {
+		for _, incident := range lineIncidents[leg.LineCode] {
+			if !seen[incident.IncidentID] {
+				seen[incident.IncidentID] = true
+				matched = append(matched, incident)
+			}
+		}
+	}
+
+	return matched
+}
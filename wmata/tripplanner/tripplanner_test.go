@@ -0,0 +1,401 @@
+package tripplanner
+
+import (
+	"errors"
+	"github.com/awiede/wmata-go-sdk/wmata"
+	"github.com/awiede/wmata-go-sdk/wmata/incidents"
+	"github.com/awiede/wmata-go-sdk/wmata/railinfo"
+	"github.com/awiede/wmata-go-sdk/wmata/railpredictions"
+	"github.com/awiede/wmata-go-sdk/wmata/trainpositions"
+	"github.com/kr/pretty"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// testClient is a mock implementation of wmata.HTTPClient interface used for testing purposes
+type testClient struct{}
+
+// ensure testClient implements wmata.HTTPClient interface
+var _ wmata.HTTPClient = (*testClient)(nil)
+
+// Do stubs out an httpClient.Do request
+func (client *testClient) Do(req *http.Request) (*http.Response, error) {
+	testResponses, exist := testData[req.URL.Path]
+
+	if !exist {
+		return nil, errors.New("no test data found")
+	}
+
+	for _, response := range testResponses {
+		if response.rawQuery == req.URL.RawQuery {
+			rr := httptest.NewRecorder()
+			rr.WriteHeader(http.StatusOK)
+			_, writeErr := rr.Write([]byte(response.response))
+
+			return rr.Result(), writeErr
+		}
+	}
+
+	return nil, errors.New("no data found")
+}
+
+type testResponseData struct {
+	rawQuery string
+	response string
+}
+
+var testData = map[string][]testResponseData{
+	"/Rail.svc/json/jStations": {
+		{
+			rawQuery: "LineCode=",
+			response: `{"Stations":[{"Code":"A01","Name":"Metro Center","StationTogether1":"C01","LineCode1":"RD"},{"Code":"A15","Name":"Shady Grove","StationTogether1":"","LineCode1":"RD"},{"Code":"C01","Name":"Metro Center","StationTogether1":"A01","LineCode1":"BL"},{"Code":"J03","Name":"Franconia-Springfield","StationTogether1":"","LineCode1":"BL"}]}`,
+		},
+	},
+	"/Rail.svc/json/jLines": {
+		{
+			rawQuery: "",
+			response: `{"Lines":[{"LineCode":"BL","DisplayName":"Blue","StartStationCode":"J03","EndStationCode":"C01"},{"LineCode":"RD","DisplayName":"Red","StartStationCode":"A15","EndStationCode":"A01"}]}`,
+		},
+	},
+	"/Rail.svc/json/jPath": {
+		{
+			rawQuery: "FromStationCode=J03&ToStationCode=C01",
+			response: `{"Path":[{"LineCode":"BL","StationCode":"C01","StationName":"Metro Center","SeqNum":2,"DistanceToPrev":100},{"LineCode":"BL","StationCode":"J03","StationName":"Franconia-Springfield","SeqNum":1,"DistanceToPrev":0}]}`,
+		},
+		{
+			rawQuery: "FromStationCode=A15&ToStationCode=A01",
+			response: `{"Path":[{"LineCode":"RD","StationCode":"A15","StationName":"Shady Grove","SeqNum":1,"DistanceToPrev":0},{"LineCode":"RD","StationCode":"A01","StationName":"Metro Center","SeqNum":2,"DistanceToPrev":100}]}`,
+		},
+	},
+	"/Rail.svc/json/jSrcStationToDstStationInfo": {
+		{
+			rawQuery: "",
+			response: `{"StationToStationInfos":[{"SourceStation":"A15","DestinationStation":"A01","CompositeMiles":15,"RailTime":30,"RailFare":{"PeakTime":5,"OffPeakTime":3.5,"SeniorDisabled":2.5}},{"SourceStation":"C01","DestinationStation":"J03","CompositeMiles":16,"RailTime":30,"RailFare":{"PeakTime":5,"OffPeakTime":3.5,"SeniorDisabled":2.5}},{"SourceStation":"A15","DestinationStation":"J03","CompositeMiles":31,"RailTime":65,"RailFare":{"PeakTime":6,"OffPeakTime":3.85,"SeniorDisabled":3}}]}`,
+		},
+	},
+	"/StationPrediction.svc/json/GetPrediction/A15,F01,C13,C01": {
+		{
+			rawQuery: "",
+			response: `{"Trains":[` +
+				`{"Car":"8","Destination":"Shady Gr","DestinationCode":"A15","Group":"2","Line":"RD","LocationCode":"A15","Min":"1"},` +
+				`{"Car":"8","Destination":"Grsvnor","DestinationCode":"A11","Group":"1","Line":"RD","LocationCode":"A15","Min":"2"},` +
+				`{"Car":"8","Destination":"Glenmont","DestinationCode":"B11","Group":"1","Line":"RD","LocationCode":"A15","Min":"3"},` +
+				`{"Car":"8","Destination":"Glenmont","DestinationCode":"B11","Group":"1","Line":"RD","LocationCode":"A15","Min":"---"},` +
+				`{"Car":"6","Destination":"Huntingtn","DestinationCode":"C15","Group":"2","Line":"YL","LocationCode":"F01","Min":"10"},` +
+				`{"Car":"6","Destination":"Huntingtn","DestinationCode":"C15","Group":"2","Line":"YL","LocationCode":"F01","Min":"40"},` +
+				`{"Car":"6","Destination":"Frnconia","DestinationCode":"J03","Group":"2","Line":"BL","LocationCode":"C01","Min":"37"}` +
+				`]}`,
+		},
+	},
+	"/Incidents.svc/json/Incidents": {
+		{
+			rawQuery: "",
+			response: `{"Incidents":[]}`,
+		},
+	},
+	"/Rail.svc/json/jStationTimes": {
+		{
+			rawQuery: "StationCode=C13",
+			response: `{"StationTimes":[{"Code":"C13","StationName":"King St-Old Town","Monday":{"OpeningTime":"04:50","FirstTrains":[{"Time":"05:00","DestinationStation":"J03"},{"Time":"05:02","DestinationStation":"D01"}],"LastTrains":[{"Time":"00:30","DestinationStation":"J03"},{"Time":"23:40","DestinationStation":"D01"}]}}]}`,
+		},
+	},
+}
+
+// testNetwork is a simplified network with two ways from Shady Grove to Franconia-Springfield
+func testNetwork() *Network {
+	stations := []railinfo.GetStationListResponseItem{
+		{StationCode: "A15", Name: "Shady Grove"},
+		{StationCode: "A14", Name: "Rockville"},
+		{StationCode: "A01", Name: "Metro Center", StationTogether1: "C01"},
+		{StationCode: "B01", Name: "Gallery Pl-Chinatown", StationTogether1: "F01"},
+		{StationCode: "B11", Name: "Glenmont"},
+		{StationCode: "J03", Name: "Franconia-Springfield"},
+		{StationCode: "C13", Name: "King St-Old Town"},
+		{StationCode: "C01", Name: "Metro Center"},
+		{StationCode: "D01", Name: "Federal Triangle"},
+		{StationCode: "C15", Name: "Huntington"},
+		{StationCode: "F01", Name: "Gallery Pl-Chinatown"},
+	}
+
+	lineSequences := map[string][]string{
+		"RD": {"A15", "A14", "A01", "B01", "B11"},
+		"BL": {"J03", "C13", "C01", "D01"},
+		"YL": {"C15", "C13", "F01"},
+	}
+
+	stationToStation := []railinfo.StationToStation{
+		{SourceStation: "A15", DestinationStation: "A01", Time: 30},
+		{SourceStation: "A15", DestinationStation: "B01", Time: 32},
+		{SourceStation: "C01", DestinationStation: "J03", Time: 30},
+		{SourceStation: "C01", DestinationStation: "C13", Time: 22},
+		{SourceStation: "F01", DestinationStation: "C13", Time: 12},
+		{SourceStation: "C13", DestinationStation: "J03", Time: 8},
+		{SourceStation: "A15", DestinationStation: "J03", Time: 65, CompositeMiles: 31, Fare: railinfo.RailFare{PeakTime: 6, OffPeakTime: 3.85, SeniorDisabled: 3}},
+	}
+
+	return NewNetwork(stations, lineSequences, stationToStation)
+}
+
+func TestPlan(t *testing.T) {
+	planner := NewPlanner(testNetwork(), Config{})
+
+	itineraries, err := planner.Plan("A15", "J03")
+
+	if err != nil {
+		t.Fatalf("error calling Plan: %s", err)
+	}
+
+	if len(itineraries) != 2 {
+		t.Fatalf("expected 2 itineraries, got: %# v", pretty.Formatter(itineraries))
+	}
+
+	fastest := itineraries[0]
+
+	if fastest.TotalTime != 62 || fastest.RailTime != 52 || fastest.Transfers != 2 {
+		t.Errorf("unexpected fastest itinerary: %# v", pretty.Formatter(fastest))
+	}
+
+	if !reflect.DeepEqual(fastest.TransferStations(), []string{"B01", "C13"}) {
+		t.Errorf("unexpected transfer stations: %v", fastest.TransferStations())
+	}
+
+	expectedLegs := []Leg{
+		{LineCode: "RD", FromStationCode: "A15", FromStationName: "Shady Grove", ToStationCode: "B01", ToStationName: "Gallery Pl-Chinatown", TowardStationCode: "B11", Stations: []string{"A15", "A14", "A01", "B01"}, RailTime: 32},
+		{LineCode: "YL", FromStationCode: "F01", FromStationName: "Gallery Pl-Chinatown", ToStationCode: "C13", ToStationName: "King St-Old Town", TowardStationCode: "C15", Stations: []string{"F01", "C13"}, RailTime: 12},
+		{LineCode: "BL", FromStationCode: "C13", FromStationName: "King St-Old Town", ToStationCode: "J03", ToStationName: "Franconia-Springfield", TowardStationCode: "J03", Stations: []string{"C13", "J03"}, RailTime: 8},
+	}
+
+	if !reflect.DeepEqual(fastest.Legs, expectedLegs) {
+		t.Error(pretty.Diff(fastest.Legs, expectedLegs))
+	}
+
+	if fastest.Fare.PeakTime != 6 || fastest.CompositeMiles != 31 {
+		t.Errorf("unexpected fare: %+v", fastest.Fare)
+	}
+
+	fewestTransfers := itineraries[1]
+
+	if fewestTransfers.TotalTime != 65 || fewestTransfers.Transfers != 1 || !reflect.DeepEqual(fewestTransfers.TransferStations(), []string{"A01"}) {
+		t.Errorf("unexpected second itinerary: %# v", pretty.Formatter(fewestTransfers))
+	}
+
+	if fewestTransfers.Legs[1].FromStationCode != "C01" || fewestTransfers.Legs[1].TowardStationCode != "J03" {
+		t.Errorf("unexpected transfer leg: %# v", pretty.Formatter(fewestTransfers.Legs[1]))
+	}
+
+	direct, err := planner.Plan("B11", "A14")
+
+	if err != nil || len(direct) != 1 || direct[0].Transfers != 0 || direct[0].RailTime != 6 || direct[0].Legs[0].TowardStationCode != "A15" {
+		t.Errorf("unexpected direct itinerary: %# v, %v", pretty.Formatter(direct), err)
+	}
+
+	errorTests := []struct {
+		from     string
+		to       string
+		expected string
+	}{
+		{from: "", to: "J03", expected: "station code is required"},
+		{from: "A15", to: "Z99", expected: "unknown station code: Z99"},
+		{from: "A01", to: "C01", expected: "fromStation and toStation are the same station"},
+	}
+
+	for _, test := range errorTests {
+		if _, err := planner.Plan(test.from, test.to); err == nil || err.Error() != test.expected {
+			t.Errorf("expected error %q, got: %v", test.expected, err)
+		}
+	}
+
+	limited := NewPlanner(testNetwork(), Config{MaxTransfers: 1})
+
+	if itineraries, _ := limited.Plan("A15", "J03"); len(itineraries) != 1 || itineraries[0].Transfers != 1 {
+		t.Errorf("unexpected itineraries with one transfer allowed: %# v", pretty.Formatter(itineraries))
+	}
+}
+
+func TestLoadNetwork(t *testing.T) {
+	network, err := LoadNetwork(railinfo.NewService(&wmata.Client{HTTPClient: &testClient{}}, wmata.JSON))
+
+	if err != nil {
+		t.Fatalf("error calling LoadNetwork: %s", err)
+	}
+
+	if !reflect.DeepEqual(network.LinesAt("C01"), []string{"BL"}) || !reflect.DeepEqual(network.Complex("C01"), []string{"C01", "A01"}) {
+		t.Errorf("unexpected network: %# v", pretty.Formatter(network))
+	}
+
+	itineraries, err := NewPlanner(network, Config{}).Plan("A15", "J03")
+
+	if err != nil || len(itineraries) != 1 || itineraries[0].TotalTime != 65 || itineraries[0].Fare.PeakTime != 6 {
+		t.Errorf("unexpected itineraries: %# v, %v", pretty.Formatter(itineraries), err)
+	}
+}
+
+func TestSequencesFromStandardRoutes(t *testing.T) {
+	routes := []trainpositions.Route{
+		{
+			LineCode:    "RD",
+			TrackNumber: 1,
+			TrackCircuits: []trainpositions.StandardTrackCircuit{
+				{CircuitID: 3, SequenceNumber: 2, StationCode: "A15"},
+				{CircuitID: 4, SequenceNumber: 3, StationCode: "A15"},
+				{CircuitID: 5, SequenceNumber: 4},
+				{CircuitID: 1, SequenceNumber: 0},
+				{CircuitID: 6, SequenceNumber: 5, StationCode: "A14"},
+			},
+		},
+		{
+			LineCode:    "RD",
+			TrackNumber: 2,
+			TrackCircuits: []trainpositions.StandardTrackCircuit{
+				{CircuitID: 10, SequenceNumber: 1, StationCode: "A14"},
+			},
+		},
+	}
+
+	expected := map[string][]string{"RD": {"A15", "A14"}}
+
+	if actual := SequencesFromStandardRoutes(routes); !reflect.DeepEqual(actual, expected) {
+		t.Error(pretty.Diff(actual, expected))
+	}
+}
+
+// setupLivePlanner creates a live planner over testNetwork backed by services with a mock http client
+func setupLivePlanner() *LivePlanner {
+	wmataClient := wmata.Client{
+		HTTPClient: &testClient{},
+	}
+
+	return NewLivePlanner(
+		NewPlanner(testNetwork(), Config{}),
+		railinfo.NewService(&wmataClient, wmata.JSON),
+		railpredictions.NewService(&wmataClient, wmata.JSON),
+		incidents.NewService(&wmataClient, wmata.JSON),
+		LiveConfig{},
+	)
+}
+
+func TestPlanLeaveNow(t *testing.T) {
+	local := time.Date(2019, 4, 29, 8, 0, 0, 0, wmata.Location)
+
+	// station timings are in Washington time whatever the location of now
+	for _, now := range []time.Time{local, local.UTC()} {
+		testPlanLeaveNowAt(t, setupLivePlanner(), now)
+	}
+}
+
+// testPlanLeaveNowAt checks the itineraries from A15 to J03 leaving at 8:00 in Washington for now in any location
+func testPlanLeaveNowAt(t *testing.T, livePlanner *LivePlanner, now time.Time) {
+	itineraries, err := livePlanner.PlanLeaveNow("A15", "J03", now)
+
+	if err != nil {
+		t.Fatalf("error calling PlanLeaveNow at %s: %s", now, err)
+	}
+
+	if len(itineraries) != 2 {
+		t.Fatalf("expected 2 live itineraries, got: %# v", pretty.Formatter(itineraries))
+	}
+
+	type summary struct {
+		LineCode  string
+		Departure string
+		Arrival   string
+		Wait      time.Duration
+		Source    string
+	}
+
+	summarize := func(itinerary *LiveItinerary) []summary {
+		var legs []summary
+
+		for _, leg := range itinerary.LiveLegs {
+			legs = append(legs, summary{
+				LineCode:  leg.LineCode,
+				Departure: leg.Departure.In(wmata.Location).Format("15:04"),
+				Arrival:   leg.Arrival.In(wmata.Location).Format("15:04"),
+				Wait:      leg.Wait,
+				Source:    leg.DepartureSource,
+			})
+		}
+
+		return legs
+	}
+
+	expectedFastest := []summary{
+		{LineCode: "RD", Departure: "08:03", Arrival: "08:33", Wait: 3 * time.Minute, Source: DepartureSourcePrediction},
+		{LineCode: "BL", Departure: "08:37", Arrival: "09:07", Wait: 2 * time.Minute, Source: DepartureSourcePrediction},
+	}
+
+	if actual := summarize(&itineraries[0]); !reflect.DeepEqual(actual, expectedFastest) {
+		t.Error(pretty.Diff(actual, expectedFastest))
+	}
+
+	expectedSecond := []summary{
+		{LineCode: "RD", Departure: "08:03", Arrival: "08:35", Wait: 3 * time.Minute, Source: DepartureSourcePrediction},
+		{LineCode: "YL", Departure: "08:40", Arrival: "08:52", Wait: 3 * time.Minute, Source: DepartureSourcePrediction},
+		{LineCode: "BL", Departure: "09:06", Arrival: "09:14", Wait: 12 * time.Minute, Source: DepartureSourceSchedule},
+	}
+
+	if actual := summarize(&itineraries[1]); !reflect.DeepEqual(actual, expectedSecond) {
+		t.Error(pretty.Diff(actual, expectedSecond))
+	}
+
+	if itineraries[0].LiveLegs[0].Train.DestinationCode != "B11" || itineraries[1].LiveLegs[2].Train != nil {
+		t.Errorf("unexpected trains: %# v", pretty.Formatter(itineraries))
+	}
+
+	if itineraries[0].Departure.In(wmata.Location).Format("15:04") != "08:03" || itineraries[0].ETA.In(wmata.Location).Format("15:04") != "09:07" {
+		t.Errorf("unexpected departure and ETA: %s %s", itineraries[0].Departure, itineraries[0].ETA)
+	}
+}
+
+func TestPlanLeaveNowIncidents(t *testing.T) {
+	livePlanner := setupLivePlanner()
+	now := time.Date(2019, 4, 29, 8, 0, 0, 0, wmata.Location)
+
+	incidentResponses := testData["/Incidents.svc/json/Incidents"]
+	defer func() { testData["/Incidents.svc/json/Incidents"] = incidentResponses }()
+
+	testData["/Incidents.svc/json/Incidents"] = []testResponseData{{response: `{"Incidents":[{"IncidentID":"1","IncidentType":"Alert","Description":"Yellow Line: No service between Huntington and Mt Vernon Sq.","LinesAffected":"YL;"}]}`}}
+	testData["/StationPrediction.svc/json/GetPrediction/A15,C01"] = testData["/StationPrediction.svc/json/GetPrediction/A15,F01,C13,C01"]
+	defer delete(testData, "/StationPrediction.svc/json/GetPrediction/A15,C01")
+
+	itineraries, err := livePlanner.PlanLeaveNow("A15", "J03", now)
+
+	if err != nil || len(itineraries) != 1 || itineraries[0].Legs[1].LineCode != "BL" {
+		t.Errorf("unexpected itineraries avoiding the yellow line: %# v, %v", pretty.Formatter(itineraries), err)
+	}
+
+	// the only itinerary planned without incidents rides the yellow line, the blue line detour is slower
+	if planned, err := livePlanner.planner.Plan("A15", "C13"); err != nil || len(planned) != 1 || planned[0].Legs[1].LineCode != "YL" {
+		t.Fatalf("unexpected itineraries without incidents: %# v, %v", pretty.Formatter(planned), err)
+	}
+
+	itineraries, err = livePlanner.PlanLeaveNow("A15", "C13", now)
+
+	if err != nil || len(itineraries) != 1 || itineraries[0].Legs[1].LineCode != "BL" {
+		t.Errorf("unexpected detour around the yellow line: %# v, %v", pretty.Formatter(itineraries), err)
+	}
+
+	testData["/Incidents.svc/json/Incidents"] = []testResponseData{{response: `{"Incidents":[{"IncidentID":"1","IncidentType":"Alert","Description":"Blue and Red Line service suspended.","LinesAffected":"BL; RD;"}]}`}}
+
+	if _, err := livePlanner.PlanLeaveNow("A15", "J03", now); err == nil {
+		t.Error("expected error when every route is suspended by an incident")
+	}
+
+	// a delay leaves the only line out of A15 usable, the itineraries carry the incident instead
+	testData["/Incidents.svc/json/Incidents"] = []testResponseData{{response: `{"Incidents":[{"IncidentID":"2","IncidentType":"Delay","Description":"Red Line: Expect residual delays to Glenmont.","LinesAffected":"RD;"}]}`}}
+
+	itineraries, err = livePlanner.PlanLeaveNow("A15", "J03", now)
+
+	if err != nil || len(itineraries) != 2 {
+		t.Fatalf("unexpected itineraries with a red line delay: %# v, %v", pretty.Formatter(itineraries), err)
+	}
+
+	for _, itinerary := range itineraries {
+		if len(itinerary.Incidents) != 1 || itinerary.Incidents[0].IncidentID != "2" {
+			t.Errorf("expected the red line delay on every itinerary, got: %# v", pretty.Formatter(itinerary.Incidents))
+		}
+	}
+}
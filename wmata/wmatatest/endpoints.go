@@ -0,0 +1,370 @@
+package wmatatest
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"github.com/awiede/wmata-go-sdk/wmata"
+	"github.com/awiede/wmata-go-sdk/wmata/businfo"
+	"github.com/awiede/wmata-go-sdk/wmata/incidents"
+	"github.com/awiede/wmata-go-sdk/wmata/railinfo"
+	"github.com/awiede/wmata-go-sdk/wmata/railpredictions"
+	"net/url"
+	"strings"
+)
+
+// endpoint is an API operation served at a JSON and an XML path
+type endpoint struct {
+	jsonPath string
+	xmlPath  string
+	// prefix endpoints also serve paths continuing after a "/", passing the rest of the path to respond
+	prefix bool
+	// format picks the response format from the query, for endpoints serving both formats at one path
+	format func(query url.Values) wmata.ResponseType
+	// respond returns the response to a request, false when the request is invalid
+	respond func(query url.Values, tail string) (interface{}, bool)
+}
+
+// endpoints are the operations used by the SDK services
+var endpoints = []endpoint{
+	{jsonPath: "/Misc/Validate", xmlPath: "/Misc/Validate", respond: validate},
+	{jsonPath: "/Rail.svc/json/jLines", xmlPath: "/Rail.svc/Lines", respond: lines},
+	{jsonPath: "/Rail.svc/json/jStationParking", xmlPath: "/Rail.svc/StationParking", respond: stationParking},
+	{jsonPath: "/Rail.svc/json/jPath", xmlPath: "/Rail.svc/Path", respond: pathBetween},
+	{jsonPath: "/Rail.svc/json/jStationEntrances", xmlPath: "/Rail.svc/StationEntrances", respond: stationEntrances},
+	{jsonPath: "/Rail.svc/json/jStationInfo", xmlPath: "/Rail.svc/StationInfo", respond: stationInfo},
+	{jsonPath: "/Rail.svc/json/jStations", xmlPath: "/Rail.svc/Stations", respond: stations},
+	{jsonPath: "/Rail.svc/json/jStationTimes", xmlPath: "/Rail.svc/StationTimes", respond: stationTimes},
+	{jsonPath: "/Rail.svc/json/jSrcStationToDstStationInfo", xmlPath: "/Rail.svc/SrcStationToDstStationInfo", respond: stationToStation},
+	{jsonPath: "/StationPrediction.svc/json/GetPrediction", xmlPath: "/StationPrediction.svc/GetPrediction", prefix: true, respond: nextTrains},
+	{jsonPath: "/TrainPositions/TrainPositions", xmlPath: "/TrainPositions/TrainPositions", format: contentType, respond: liveTrainPositions},
+	{jsonPath: "/TrainPositions/StandardRoutes", xmlPath: "/TrainPositions/StandardRoutes", format: contentType, respond: standardRoutes},
+	{jsonPath: "/TrainPositions/TrackCircuits", xmlPath: "/TrainPositions/TrackCircuits", format: contentType, respond: trackCircuits},
+	{jsonPath: "/Bus.svc/json/jBusPositions", xmlPath: "/Bus.svc/BusPositions", respond: busPositions},
+	{jsonPath: "/Bus.svc/json/jRouteDetails", xmlPath: "/Bus.svc/RouteDetails", respond: busRouteDetails},
+	{jsonPath: "/Bus.svc/json/jRoutes", xmlPath: "/Bus.svc/Routes", respond: busRoutes},
+	{jsonPath: "/Bus.svc/json/jRouteSchedule", xmlPath: "/Bus.svc/RouteSchedule", respond: busSchedule},
+	{jsonPath: "/Bus.svc/json/jStopSchedule", xmlPath: "/Bus.svc/StopSchedule", respond: busStopSchedule},
+	{jsonPath: "/Bus.svc/json/jStops", xmlPath: "/Bus.svc/Stops", respond: busStopList},
+	{jsonPath: "/NextBusService.svc/json/jPredictions", xmlPath: "/NextBusService.svc/Predictions", respond: nextBuses},
+	{jsonPath: "/Incidents.svc/json/BusIncidents", xmlPath: "/Incidents.svc/BusIncidents", respond: busIncidents},
+	{jsonPath: "/Incidents.svc/json/ElevatorIncidents", xmlPath: "/Incidents.svc/ElevatorIncidents", respond: elevatorIncidents},
+	{jsonPath: "/Incidents.svc/json/Incidents", xmlPath: "/Incidents.svc/Incidents", respond: railIncidents},
+}
+
+// findEndpoint returns the endpoint serving path with the format of the path and, for prefix endpoints, the rest of
+// the path. The endpoint is nil when no endpoint serves path
+func findEndpoint(path string) (*endpoint, wmata.ResponseType, string) {
+	for i := range endpoints {
+		e := &endpoints[i]
+
+		for _, candidate := range []struct {
+			path   string
+			format wmata.ResponseType
+		}{{e.jsonPath, wmata.JSON}, {e.xmlPath, wmata.XML}} {
+			if path == candidate.path {
+				return e, candidate.format, ""
+			}
+
+			if e.prefix && strings.HasPrefix(path, candidate.path+"/") {
+				return e, candidate.format, strings.TrimPrefix(path, candidate.path+"/")
+			}
+		}
+	}
+
+	return nil, wmata.JSON, ""
+}
+
+// encode returns the body and content type of a response
+func encode(response interface{}, format wmata.ResponseType) ([]byte, string, error) {
+	if format == wmata.XML {
+		body, encodeErr := xml.Marshal(response)
+
+		return body, "application/xml; charset=utf-8", encodeErr
+	}
+
+	body, encodeErr := json.Marshal(response)
+
+	return body, "application/json; charset=utf-8", encodeErr
+}
+
+// contentType picks the format requested by the contentType query parameter of the TrainPositions endpoints
+func contentType(query url.Values) wmata.ResponseType {
+	if strings.EqualFold(query.Get("contentType"), "xml") {
+		return wmata.XML
+	}
+
+	return wmata.JSON
+}
+
+// validateResponse is the empty response of a successful API key validation
+type validateResponse struct {
+	XMLName xml.Name `json:"-" xml:"Validate"`
+}
+
+func validate(url.Values, string) (interface{}, bool) {
+	return &validateResponse{}, true
+}
+
+func lines(url.Values, string) (interface{}, bool) {
+	return Lines(), true
+}
+
+func stations(query url.Values, _ string) (interface{}, bool) {
+	response := Stations()
+	lineCode := query.Get("LineCode")
+
+	if lineCode == "" {
+		return response, true
+	}
+
+	var filtered []railinfo.GetStationListResponseItem
+
+	for _, station := range response.Stations {
+		for _, code := range []string{station.LineCode1, station.LineCode2, station.LineCode3, station.LineCode4} {
+			if code == lineCode {
+				filtered = append(filtered, station)
+				break
+			}
+		}
+	}
+
+	response.Stations = filtered
+
+	return response, true
+}
+
+func stationInfo(query url.Values, _ string) (interface{}, bool) {
+	stationCode := query.Get("StationCode")
+
+	for _, station := range Stations().Stations {
+		if station.StationCode == stationCode {
+			return &railinfo.GetStationInformationResponse{
+				Address:          station.Address,
+				Latitude:         station.Latitude,
+				LineCode1:        station.LineCode1,
+				LineCode2:        station.LineCode2,
+				Longitude:        station.Longitude,
+				Name:             station.Name,
+				StationCode:      station.StationCode,
+				StationTogether1: station.StationTogether1,
+				StationTogether2: station.StationTogether2,
+			}, true
+		}
+	}
+
+	return nil, false
+}
+
+func stationParking(query url.Values, _ string) (interface{}, bool) {
+	response := StationParking()
+	stationCode := query.Get("StationCode")
+
+	if stationCode == "" {
+		return response, true
+	}
+
+	var filtered []railinfo.StationParking
+
+	for _, parking := range response.ParkingInformation {
+		if parking.StationCode == stationCode {
+			filtered = append(filtered, parking)
+		}
+	}
+
+	response.ParkingInformation = filtered
+
+	return response, true
+}
+
+func pathBetween(query url.Values, _ string) (interface{}, bool) {
+	from, to := query.Get("FromStationCode"), query.Get("ToStationCode")
+
+	if from == "" || to == "" {
+		return nil, false
+	}
+
+	return PathBetween(from, to), true
+}
+
+func stationEntrances(url.Values, string) (interface{}, bool) {
+	return StationEntrances(), true
+}
+
+func stationTimes(query url.Values, _ string) (interface{}, bool) {
+	response := StationTimes()
+	stationCode := query.Get("StationCode")
+
+	if stationCode == "" {
+		return response, true
+	}
+
+	var filtered []railinfo.StationTime
+
+	for _, times := range response.StationTimes {
+		if times.StationCode == stationCode {
+			filtered = append(filtered, times)
+		}
+	}
+
+	response.StationTimes = filtered
+
+	return response, true
+}
+
+func stationToStation(query url.Values, _ string) (interface{}, bool) {
+	response := StationToStation()
+	from, to := query.Get("FromStationCode"), query.Get("ToStationCode")
+
+	var filtered []railinfo.StationToStation
+
+	for _, info := range response.StationToStationInformation {
+		if (from == "" || info.SourceStation == from) && (to == "" || info.DestinationStation == to) {
+			filtered = append(filtered, info)
+		}
+	}
+
+	response.StationToStationInformation = filtered
+
+	return response, true
+}
+
+// nextTrains answers "All" with every prediction, or the predictions for a comma separated list of station codes
+func nextTrains(_ url.Values, tail string) (interface{}, bool) {
+	if tail == "" {
+		return nil, false
+	}
+
+	response := NextTrains()
+
+	if tail == "All" {
+		return response, true
+	}
+
+	requested := make(map[string]bool)
+
+	for _, stationCode := range strings.Split(tail, ",") {
+		requested[stationCode] = true
+	}
+
+	var filtered []railpredictions.Train
+
+	for _, train := range response.Trains {
+		if requested[train.LocationCode] {
+			filtered = append(filtered, train)
+		}
+	}
+
+	response.Trains = filtered
+
+	return response, true
+}
+
+func liveTrainPositions(url.Values, string) (interface{}, bool) {
+	return LiveTrainPositions(), true
+}
+
+func standardRoutes(url.Values, string) (interface{}, bool) {
+	return StandardRoutes(), true
+}
+
+func trackCircuits(url.Values, string) (interface{}, bool) {
+	return TrackCircuits(), true
+}
+
+func busPositions(query url.Values, _ string) (interface{}, bool) {
+	response := BusPositions()
+	routeID := query.Get("RouteID")
+
+	if routeID == "" {
+		return response, true
+	}
+
+	var filtered []businfo.BusPosition
+
+	for _, position := range response.BusPositions {
+		if position.RouteID == routeID {
+			filtered = append(filtered, position)
+		}
+	}
+
+	response.BusPositions = filtered
+
+	return response, true
+}
+
+func busRouteDetails(query url.Values, _ string) (interface{}, bool) {
+	response := BusRouteDetails()
+
+	return response, query.Get("RouteID") == response.RouteID
+}
+
+func busRoutes(url.Values, string) (interface{}, bool) {
+	return BusRoutes(), true
+}
+
+func busSchedule(query url.Values, _ string) (interface{}, bool) {
+	return BusSchedule(), query.Get("RouteID") == BusRouteDetails().RouteID
+}
+
+func busStopSchedule(query url.Values, _ string) (interface{}, bool) {
+	return stopSchedule(query.Get("StopID"))
+}
+
+func busStopList(url.Values, string) (interface{}, bool) {
+	return BusStops(), true
+}
+
+func nextBuses(query url.Values, _ string) (interface{}, bool) {
+	response, exist := nextBusesByStop()[query.Get("StopID")]
+
+	return response, exist
+}
+
+func busIncidents(query url.Values, _ string) (interface{}, bool) {
+	response := BusIncidents()
+	route := query.Get("Route")
+
+	if route == "" {
+		return response, true
+	}
+
+	var filtered []incidents.BusIncident
+
+	for _, incident := range response.BusIncidents {
+		for _, affected := range incident.RoutesAffected {
+			if affected == route {
+				filtered = append(filtered, incident)
+				break
+			}
+		}
+	}
+
+	response.BusIncidents = filtered
+
+	return response, true
+}
+
+func elevatorIncidents(query url.Values, _ string) (interface{}, bool) {
+	response := ElevatorIncidents()
+	stationCode := query.Get("StationCode")
+
+	if stationCode == "" {
+		return response, true
+	}
+
+	var filtered []incidents.ElevatorIncident
+
+	for _, incident := range response.ElevatorIncidents {
+		if incident.StationCode == stationCode {
+			filtered = append(filtered, incident)
+		}
+	}
+
+	response.ElevatorIncidents = filtered
+
+	return response, true
+}
+
+func railIncidents(url.Values, string) (interface{}, bool) {
+	return RailIncidents(), true
+}
@@ -0,0 +1,629 @@
+package wmatatest
+
+import (
+	"github.com/awiede/wmata-go-sdk/wmata/businfo"
+	"github.com/awiede/wmata-go-sdk/wmata/buspredictions"
+	"github.com/awiede/wmata-go-sdk/wmata/incidents"
+	"github.com/awiede/wmata-go-sdk/wmata/railinfo"
+	"github.com/awiede/wmata-go-sdk/wmata/railpredictions"
+	"github.com/awiede/wmata-go-sdk/wmata/trainpositions"
+)
+
+// The fixtures below describe a small, consistent slice of the network: the downtown Red Line stations from Dupont
+// Circle to Gallery Place, their transfer stations, the Red and Orange Line terminals and bus route 70 along 7th St.
+// Each function returns a new copy, so callers may modify the result
+
+// Lines returns the canned response for the Lines endpoint
+func Lines() *railinfo.GetLinesResponse {
+	return &railinfo.GetLinesResponse{
+		Lines: []railinfo.LineResponse{
+			{LineCode: "BL", DisplayName: "Blue", StartStationCode: "J03", EndStationCode: "G05"},
+			{LineCode: "GR", DisplayName: "Green", StartStationCode: "F11", EndStationCode: "E10"},
+			{LineCode: "OR", DisplayName: "Orange", StartStationCode: "K08", EndStationCode: "D13"},
+			{LineCode: "RD", DisplayName: "Red", StartStationCode: "A15", EndStationCode: "B11", InternalDestination1: "A11", InternalDestination2: "B08"},
+			{LineCode: "SV", DisplayName: "Silver", StartStationCode: "N06", EndStationCode: "G05"},
+			{LineCode: "YL", DisplayName: "Yellow", StartStationCode: "C15", EndStationCode: "E06", InternalDestination1: "E01"},
+		},
+	}
+}
+
+// Stations returns the canned response for the Stations endpoint without a line code
+func Stations() *railinfo.GetStationListResponse {
+	return &railinfo.GetStationListResponse{
+		Stations: []railinfo.GetStationListResponseItem{
+			{
+				StationCode: "A01", Name: "Metro Center", StationTogether1: "C01", LineCode1: "RD",
+				Latitude: 38.898303, Longitude: -77.028099,
+				Address: railinfo.StationAddress{Street: "607 13th St. NW", City: "Washington", State: "DC", Zip: "20005"},
+			},
+			{
+				StationCode: "A02", Name: "Farragut North", LineCode1: "RD",
+				Latitude: 38.903192, Longitude: -77.039766,
+				Address: railinfo.StationAddress{Street: "1001 Connecticut Avenue NW", City: "Washington", State: "DC", Zip: "20036"},
+			},
+			{
+				StationCode: "A03", Name: "Dupont Circle", LineCode1: "RD",
+				Latitude: 38.909499, Longitude: -77.04362,
+				Address: railinfo.StationAddress{Street: "1525 20th St. NW", City: "Washington", State: "DC", Zip: "20036"},
+			},
+			{
+				StationCode: "A15", Name: "Shady Grove", LineCode1: "RD",
+				Latitude: 39.119819, Longitude: -77.164921,
+				Address: railinfo.StationAddress{Street: "15903 Somerville Drive", City: "Rockville", State: "MD", Zip: "20855"},
+			},
+			{
+				StationCode: "B01", Name: "Gallery Pl-Chinatown", StationTogether1: "F01", LineCode1: "RD",
+				Latitude: 38.89834, Longitude: -77.021851,
+				Address: railinfo.StationAddress{Street: "630 H St. NW", City: "Washington", State: "DC", Zip: "20001"},
+			},
+			{
+				StationCode: "B11", Name: "Glenmont", LineCode1: "RD",
+				Latitude: 39.061713, Longitude: -77.05341,
+				Address: railinfo.StationAddress{Street: "12501 Georgia Avenue", City: "Silver Spring", State: "MD", Zip: "20906"},
+			},
+			{
+				StationCode: "C01", Name: "Metro Center", StationTogether1: "A01", LineCode1: "BL", LineCode2: "OR", LineCode3: "SV",
+				Latitude: 38.898303, Longitude: -77.028099,
+				Address: railinfo.StationAddress{Street: "607 13th St. NW", City: "Washington", State: "DC", Zip: "20005"},
+			},
+			{
+				StationCode: "F01", Name: "Gallery Pl-Chinatown", StationTogether1: "B01", LineCode1: "GR", LineCode2: "YL",
+				Latitude: 38.89834, Longitude: -77.021851,
+				Address: railinfo.StationAddress{Street: "630 H St. NW", City: "Washington", State: "DC", Zip: "20001"},
+			},
+			{
+				StationCode: "K08", Name: "Vienna/Fairfax-GMU", LineCode1: "OR",
+				Latitude: 38.877693, Longitude: -77.271562,
+				Address: railinfo.StationAddress{Street: "9550 Saintsbury Drive", City: "Fairfax", State: "VA", Zip: "22031"},
+			},
+		},
+	}
+}
+
+// StationParking returns the canned response for the StationParking endpoint without a station code
+func StationParking() *railinfo.GetParkingInformationResponse {
+	return &railinfo.GetParkingInformationResponse{
+		ParkingInformation: []railinfo.StationParking{
+			{
+				StationCode: "A15",
+				Notes:       "Parking available 7:30 AM - 2 AM. 154 spaces metered for 12-hr. max @ $1.00 per 60 mins.",
+				AllDay:      railinfo.AllDayParking{TotalCount: 5745, RiderCost: 5.2, NonRiderCost: 5.2},
+			},
+			{
+				StationCode: "B11",
+				Notes:       "30 short term metered spaces.",
+				AllDay:      railinfo.AllDayParking{TotalCount: 1781, RiderCost: 5.2, NonRiderCost: 5.2},
+				ShortTerm:   railinfo.ShortTermParking{TotalCount: 30},
+			},
+			{
+				StationCode: "K08",
+				Notes:       "North Kiss & Ride - 45 short term metered spaces. South Kiss & Ride - 26 short term metered spaces.  101 spaces metered for 12-hr. max @ $1.00 per 60 mins. 17 spaces metered for 7-hr. max. @ $1.00 per 60 mins. Parking available from 8:30 AM to 2 AM.",
+				AllDay:      railinfo.AllDayParking{TotalCount: 5169, RiderCost: 4.95, NonRiderCost: 4.95},
+				ShortTerm:   railinfo.ShortTermParking{TotalCount: 71, Notes: "Parking available in section B between 8:30 AM - 3:30 PM and 7 PM - 2 AM, in section D between 10 AM - 2 PM."},
+			},
+		},
+	}
+}
+
+// Path returns the canned response for the Path endpoint from Dupont Circle (A03) to Gallery Pl-Chinatown (B01).
+// Requests for other pairs of these stations, in either direction, are answered by PathBetween
+func Path() *railinfo.GetPathBetweenStationsResponse {
+	return &railinfo.GetPathBetweenStationsResponse{
+		Path: []railinfo.PathItem{
+			{LineCode: "RD", SequenceNumber: 1, StationCode: "A03", StationName: "Dupont Circle", DistanceToPreviousStation: 0},
+			{LineCode: "RD", SequenceNumber: 2, StationCode: "A02", StationName: "Farragut North", DistanceToPreviousStation: 4020},
+			{LineCode: "RD", SequenceNumber: 3, StationCode: "A01", StationName: "Metro Center", DistanceToPreviousStation: 4408},
+			{LineCode: "RD", SequenceNumber: 4, StationCode: "B01", StationName: "Gallery Pl-Chinatown", DistanceToPreviousStation: 2426},
+		},
+	}
+}
+
+// PathBetween returns the part of Path between two of its stations, reversed when travelling towards Dupont Circle,
+// and an empty path when either station is not on it
+func PathBetween(fromStation, toStation string) *railinfo.GetPathBetweenStationsResponse {
+	canned := Path().Path
+	fromIndex, toIndex := -1, -1
+
+	for i, item := range canned {
+		switch item.StationCode {
+		case fromStation:
+			fromIndex = i
+		case toStation:
+			toIndex = i
+		}
+	}
+
+	response := railinfo.GetPathBetweenStationsResponse{}
+
+	if fromIndex < 0 || toIndex < 0 {
+		return &response
+	}
+
+	step := 1
+
+	if toIndex < fromIndex {
+		step = -1
+	}
+
+	for i := fromIndex; ; i += step {
+		item := canned[i]
+		item.SequenceNumber = len(response.Path) + 1
+		item.DistanceToPreviousStation = 0
+
+		if i != fromIndex {
+			// distances are measured between neighbors, so the distance back to the previous station is the same
+			// in both directions
+			if step > 0 {
+				item.DistanceToPreviousStation = canned[i].DistanceToPreviousStation
+			} else {
+				item.DistanceToPreviousStation = canned[i-step].DistanceToPreviousStation
+			}
+		}
+
+		response.Path = append(response.Path, item)
+
+		if i == toIndex {
+			break
+		}
+	}
+
+	return &response
+}
+
+// StationEntrances returns the canned response for the StationEntrances endpoint
+func StationEntrances() *railinfo.GetStationEntrancesResponse {
+	return &railinfo.GetStationEntrancesResponse{
+		Entrances: []railinfo.StationEntrance{
+			{
+				ID: "1", Name: "METRO CENTER, NE CORNER OF 12TH & G ST",
+				Description:  "Building entrance from the east side of 12th St between G St and F St.",
+				StationCode1: "A01", StationCode2: "C01",
+				Latitude: 38.898762, Longitude: -77.027481,
+			},
+			{
+				ID: "2", Name: "METRO CENTER, SE CORNER OF 11TH & G ST",
+				Description:  "Escalator entrance from the southeast corner of 11th St and G St.",
+				StationCode1: "A01", StationCode2: "C01",
+				Latitude: 38.898218, Longitude: -77.026592,
+			},
+			{
+				ID: "3", Name: "FARRAGUT NORTH, NW CORNER OF CONNECTICUT AVE & K ST",
+				Description:  "Escalator entrance from the northwest corner of Connecticut Ave and K St.",
+				StationCode1: "A02",
+				Latitude:     38.902694, Longitude: -77.039395,
+			},
+		},
+	}
+}
+
+// StationTimes returns the canned response for the StationTimes endpoint without a station code
+func StationTimes() *railinfo.GetStationTimingsResponse {
+	metroCenter := railinfo.StationTime{StationCode: "A01", StationName: "Metro Center"}
+	weekday := railinfo.StationDayItem{
+		OpeningTime: "05:01",
+		FirstTrains: []railinfo.StationTrainInformation{{Time: "05:15", DestinationStation: "A15"}, {Time: "05:18", DestinationStation: "B11"}},
+		LastTrains:  []railinfo.StationTrainInformation{{Time: "23:56", DestinationStation: "B11"}, {Time: "00:05", DestinationStation: "A15"}},
+	}
+	friday := railinfo.StationDayItem{
+		OpeningTime: weekday.OpeningTime,
+		FirstTrains: weekday.FirstTrains,
+		LastTrains:  []railinfo.StationTrainInformation{{Time: "00:56", DestinationStation: "B11"}, {Time: "01:05", DestinationStation: "A15"}},
+	}
+	saturday := railinfo.StationDayItem{
+		OpeningTime: "06:46",
+		FirstTrains: []railinfo.StationTrainInformation{{Time: "07:00", DestinationStation: "A15"}, {Time: "07:03", DestinationStation: "B11"}},
+		LastTrains:  friday.LastTrains,
+	}
+	sunday := railinfo.StationDayItem{
+		OpeningTime: saturday.OpeningTime,
+		FirstTrains: saturday.FirstTrains,
+		LastTrains:  weekday.LastTrains,
+	}
+	metroCenter.Monday, metroCenter.Tuesday, metroCenter.Wednesday, metroCenter.Thursday = weekday, weekday, weekday, weekday
+	metroCenter.Friday, metroCenter.Saturday, metroCenter.Sunday = friday, saturday, sunday
+
+	vienna := railinfo.StationTime{StationCode: "K08", StationName: "Vienna/Fairfax-GMU"}
+	viennaWeekday := railinfo.StationDayItem{
+		OpeningTime: "04:50",
+		FirstTrains: []railinfo.StationTrainInformation{{Time: "05:00", DestinationStation: "D13"}},
+		LastTrains:  []railinfo.StationTrainInformation{{Time: "23:30", DestinationStation: "D13"}},
+	}
+	viennaWeekend := railinfo.StationDayItem{
+		OpeningTime: "06:35",
+		FirstTrains: []railinfo.StationTrainInformation{{Time: "06:45", DestinationStation: "D13"}},
+		LastTrains:  []railinfo.StationTrainInformation{{Time: "00:30", DestinationStation: "D13"}},
+	}
+	vienna.Monday, vienna.Tuesday, vienna.Wednesday, vienna.Thursday = viennaWeekday, viennaWeekday, viennaWeekday, viennaWeekday
+	vienna.Friday, vienna.Saturday, vienna.Sunday = viennaWeekend, viennaWeekend, viennaWeekend
+
+	return &railinfo.GetStationTimingsResponse{StationTimes: []railinfo.StationTime{metroCenter, vienna}}
+}
+
+// StationToStation returns the canned response for the SrcStationToDstStationInfo endpoint without station codes
+func StationToStation() *railinfo.GetStationToStationInformationResponse {
+	fare := func(peak, offPeak, senior float64) railinfo.RailFare {
+		return railinfo.RailFare{PeakTime: peak, OffPeakTime: offPeak, SeniorDisabled: senior}
+	}
+
+	return &railinfo.GetStationToStationInformationResponse{
+		StationToStationInformation: []railinfo.StationToStation{
+			{SourceStation: "A01", DestinationStation: "A02", CompositeMiles: 0.85, Time: 2, Fare: fare(2.25, 2.25, 1.1)},
+			{SourceStation: "A01", DestinationStation: "A03", CompositeMiles: 1.61, Time: 4, Fare: fare(2.25, 2.25, 1.1)},
+			{SourceStation: "A01", DestinationStation: "A15", CompositeMiles: 15.4, Time: 32, Fare: fare(6, 3.85, 3)},
+			{SourceStation: "A01", DestinationStation: "B01", CompositeMiles: 0.46, Time: 2, Fare: fare(2.25, 2.25, 1.1)},
+			{SourceStation: "A02", DestinationStation: "A01", CompositeMiles: 0.85, Time: 2, Fare: fare(2.25, 2.25, 1.1)},
+			{SourceStation: "A03", DestinationStation: "A01", CompositeMiles: 1.61, Time: 4, Fare: fare(2.25, 2.25, 1.1)},
+			{SourceStation: "A03", DestinationStation: "B01", CompositeMiles: 2.07, Time: 6, Fare: fare(2.4, 2.25, 1.2)},
+			{SourceStation: "A15", DestinationStation: "A01", CompositeMiles: 15.4, Time: 32, Fare: fare(6, 3.85, 3)},
+			{SourceStation: "B01", DestinationStation: "A01", CompositeMiles: 0.46, Time: 2, Fare: fare(2.25, 2.25, 1.1)},
+			{SourceStation: "B01", DestinationStation: "A03", CompositeMiles: 2.07, Time: 6, Fare: fare(2.4, 2.25, 1.2)},
+			{SourceStation: "C01", DestinationStation: "K08", CompositeMiles: 13.76, Time: 30, Fare: fare(5.75, 3.75, 2.85)},
+			{SourceStation: "K08", DestinationStation: "C01", CompositeMiles: 13.76, Time: 30, Fare: fare(5.75, 3.75, 2.85)},
+		},
+	}
+}
+
+// NextTrains returns the canned response for the GetPrediction endpoint for all stations
+func NextTrains() *railpredictions.GetNextTrainResponse {
+	return &railpredictions.GetNextTrainResponse{
+		Trains: []railpredictions.Train{
+			{Car: "8", Destination: "Glenmont", DestinationCode: "B11", DestinationName: "Glenmont", Group: "1", Line: "RD", LocationCode: "A01", LocationName: "Metro Center", Minutes: railpredictions.MinutesArriving},
+			{Car: "6", Destination: "Shady Grv", DestinationCode: "A15", DestinationName: "Shady Grove", Group: "2", Line: "RD", LocationCode: "A01", LocationName: "Metro Center", Minutes: "4"},
+			{Car: "8", Destination: "Glenmont", DestinationCode: "B11", DestinationName: "Glenmont", Group: "1", Line: "RD", LocationCode: "A01", LocationName: "Metro Center", Minutes: "9"},
+			{Car: "8", Destination: "Glenmont", DestinationCode: "B11", DestinationName: "Glenmont", Group: "1", Line: "RD", LocationCode: "A02", LocationName: "Farragut North", Minutes: "2"},
+			{Car: "6", Destination: "Shady Grv", DestinationCode: "A15", DestinationName: "Shady Grove", Group: "2", Line: "RD", LocationCode: "A02", LocationName: "Farragut North", Minutes: "1"},
+			{Car: "8", Destination: "Glenmont", DestinationCode: "B11", DestinationName: "Glenmont", Group: "1", Line: "RD", LocationCode: "A03", LocationName: "Dupont Circle", Minutes: "5"},
+			{Car: "6", Destination: "Shady Grv", DestinationCode: "A15", DestinationName: "Shady Grove", Group: "2", Line: "RD", LocationCode: "B01", LocationName: "Gallery Pl-Chinatown", Minutes: "2"},
+			{Car: "8", Destination: "Vienna", DestinationCode: "K08", DestinationName: "Vienna/Fairfax-GMU", Group: "2", Line: "OR", LocationCode: "C01", LocationName: "Metro Center", Minutes: railpredictions.MinutesBoarding},
+			{Car: "8", Destination: "Largo", DestinationCode: "G05", DestinationName: "Downtown Largo", Group: "1", Line: "BL", LocationCode: "C01", LocationName: "Metro Center", Minutes: "6"},
+			{Car: "8", Destination: "Greenbelt", DestinationCode: "E10", DestinationName: "Greenbelt", Group: "1", Line: "GR", LocationCode: "F01", LocationName: "Gallery Pl-Chinatown", Minutes: "3"},
+			{Car: "-", Destination: "Train", DestinationCode: "", DestinationName: "Train", Group: "2", Line: "--", LocationCode: "F01", LocationName: "Gallery Pl-Chinatown", Minutes: "---"},
+			{Car: "8", Destination: "NewCrltn", DestinationCode: "D13", DestinationName: "New Carrollton", Group: "1", Line: "OR", LocationCode: "K08", LocationName: "Vienna/Fairfax-GMU", Minutes: "11"},
+		},
+	}
+}
+
+// LiveTrainPositions returns the canned response for the TrainPositions endpoint. Trains are placed on the circuits
+// returned by StandardRoutes
+func LiveTrainPositions() *trainpositions.GetLiveTrainPositionsResponse {
+	return &trainpositions.GetLiveTrainPositionsResponse{
+		Positions: []trainpositions.TrainPosition{
+			{TrainID: "101", TrainNumber: "102", CarCount: 8, CircuitID: 1104, DestinationStationCode: "B11", DirectionNumber: 1, LineCode: "RD", SecondsAtLocation: 12, ServiceType: "Normal"},
+			{TrainID: "102", TrainNumber: "201", CarCount: 6, CircuitID: 2103, DestinationStationCode: "A15", DirectionNumber: 2, LineCode: "RD", SecondsAtLocation: 41, ServiceType: "Normal"},
+			{TrainID: "103", TrainNumber: "104", CarCount: 8, CircuitID: 1106, DestinationStationCode: "B11", DirectionNumber: 1, LineCode: "RD", SecondsAtLocation: 3, ServiceType: "Normal"},
+			{TrainID: "104", TrainNumber: "X11", CarCount: 6, CircuitID: 2101, DirectionNumber: 2, SecondsAtLocation: 320, ServiceType: "NoPassengers"},
+		},
+	}
+}
+
+// StandardRoutes returns the canned response for the StandardRoutes endpoint: both Red Line tracks between Dupont
+// Circle and Gallery Pl-Chinatown, with a circuit between each pair of stations
+func StandardRoutes() *trainpositions.GetStandardRoutesResponse {
+	stations := []string{"A03", "", "A02", "", "A01", "", "B01"}
+
+	track1 := trainpositions.Route{LineCode: "RD", TrackNumber: 1}
+	track2 := trainpositions.Route{LineCode: "RD", TrackNumber: 2}
+
+	for sequence, stationCode := range stations {
+		track1.TrackCircuits = append(track1.TrackCircuits, trainpositions.StandardTrackCircuit{
+			CircuitID:      1101 + sequence,
+			SequenceNumber: sequence + 1,
+			StationCode:    stationCode,
+		})
+
+		track2.TrackCircuits = append(track2.TrackCircuits, trainpositions.StandardTrackCircuit{
+			CircuitID:      2101 + sequence,
+			SequenceNumber: sequence + 1,
+			StationCode:    stations[len(stations)-1-sequence],
+		})
+	}
+
+	return &trainpositions.GetStandardRoutesResponse{Routes: []trainpositions.Route{track1, track2}}
+}
+
+// TrackCircuits returns the canned response for the TrackCircuits endpoint, linking each circuit of StandardRoutes to
+// the circuits before and after it
+func TrackCircuits() *trainpositions.GetTrackCircuitsResponse {
+	var circuits []trainpositions.TrackCircuit
+
+	for _, route := range StandardRoutes().Routes {
+		for i, standard := range route.TrackCircuits {
+			circuit := trainpositions.TrackCircuit{CircuitID: standard.CircuitID, Track: route.TrackNumber}
+
+			if i > 0 {
+				circuit.Neighbors = append(circuit.Neighbors, trainpositions.Neighbor{
+					NeighborType: "Left",
+					CircuitIDs:   []int{route.TrackCircuits[i-1].CircuitID},
+				})
+			}
+
+			if i < len(route.TrackCircuits)-1 {
+				circuit.Neighbors = append(circuit.Neighbors, trainpositions.Neighbor{
+					NeighborType: "Right",
+					CircuitIDs:   []int{route.TrackCircuits[i+1].CircuitID},
+				})
+			}
+
+			circuits = append(circuits, circuit)
+		}
+	}
+
+	return &trainpositions.GetTrackCircuitsResponse{TrackCircuits: circuits}
+}
+
+// BusRoutes returns the canned response for the Routes endpoint
+func BusRoutes() *businfo.GetRoutesResponse {
+	return &businfo.GetRoutesResponse{
+		Routes: []businfo.Route{
+			{RouteID: "70", Name: "70 - ARCHIVES - SILVER SPRING", LineDescription: "Georgia Ave-7th St Line"},
+			{RouteID: "79", Name: "79 - ARCHIVES - SILVER SPRING STA", LineDescription: "Georgia Ave MetroExtra Line"},
+			{RouteID: "S2", Name: "S2 - FED TRIANGLE - SILVER SPRING", LineDescription: "16th Street Line"},
+		},
+	}
+}
+
+// busStops are the route 70 stops from Archives to Silver Spring
+var busStops = []businfo.Stop{
+	{StopID: "1001195", Name: "7TH ST NW + PENNSYLVANIA AVE NW", Latitude: 38.893564, Longitude: -77.021875, Routes: []string{"70", "79"}},
+	{StopID: "1001808", Name: "7TH ST NW + H ST NW", Latitude: 38.899721, Longitude: -77.021911, Routes: []string{"70", "79"}},
+	{StopID: "1003043", Name: "GEORGIA AVE NW + COLESVILLE RD", Latitude: 38.996591, Longitude: -77.030016, Routes: []string{"70", "79", "S2"}},
+}
+
+// BusStops returns the canned response for the Stops endpoint
+func BusStops() *businfo.GetStopsResponse {
+	stops := make([]businfo.Stop, len(busStops))
+
+	for i, stop := range busStops {
+		stops[i] = copyStop(stop)
+	}
+
+	return &businfo.GetStopsResponse{Stops: stops}
+}
+
+// copyStop returns a stop that does not share its routes with stop
+func copyStop(stop businfo.Stop) businfo.Stop {
+	stop.Routes = append([]string(nil), stop.Routes...)
+
+	return stop
+}
+
+// BusRouteDetails returns the canned response for the RouteDetails endpoint for route 70
+func BusRouteDetails() *businfo.GetRouteDetailsResponse {
+	northbound := make([]businfo.Stop, len(busStops))
+	southbound := make([]businfo.Stop, len(busStops))
+
+	for i, stop := range busStops {
+		northbound[i] = copyStop(stop)
+		southbound[len(busStops)-1-i] = copyStop(stop)
+	}
+
+	shape := []businfo.ShapePoint{
+		{Latitude: 38.893564, Longitude: -77.021875, SequenceNumber: 1},
+		{Latitude: 38.899721, Longitude: -77.021911, SequenceNumber: 2},
+		{Latitude: 38.916418, Longitude: -77.021956, SequenceNumber: 3},
+		{Latitude: 38.950851, Longitude: -77.027589, SequenceNumber: 4},
+		{Latitude: 38.996591, Longitude: -77.030016, SequenceNumber: 5},
+	}
+	reversed := make([]businfo.ShapePoint, len(shape))
+
+	for i, point := range shape {
+		point.SequenceNumber = len(shape) - i
+		reversed[len(shape)-1-i] = point
+	}
+
+	return &businfo.GetRouteDetailsResponse{
+		RouteID: "70",
+		Name:    "70 - ARCHIVES - SILVER SPRING",
+		Direction0: businfo.Direction{
+			DirectionNumber: "0",
+			DirectionText:   "NORTH",
+			TripDestination: "SILVER SPRING STATION",
+			Shapes:          shape,
+			Stops:           northbound,
+		},
+		Direction1: businfo.Direction{
+			DirectionNumber: "1",
+			DirectionText:   "SOUTH",
+			TripDestination: "ARCHIVES",
+			Shapes:          reversed,
+			Stops:           southbound,
+		},
+	}
+}
+
+// BusSchedule returns the canned response for the RouteSchedule endpoint for route 70
+func BusSchedule() *businfo.GetScheduleResponse {
+	trip := func(tripID, direction, directionText, destination string, start string, times []string) businfo.Trip {
+		stops := busStops
+
+		if direction == "1" {
+			stops = []businfo.Stop{busStops[2], busStops[1], busStops[0]}
+		}
+
+		t := businfo.Trip{
+			TripID:          tripID,
+			RouteID:         "70",
+			DirectionNumber: direction,
+			TripDirection:   directionText,
+			TripDestination: destination,
+			StartTime:       start,
+			EndTime:         times[len(times)-1],
+		}
+
+		for i, stopTime := range times {
+			t.StopTimes = append(t.StopTimes, businfo.StopTime{StopID: stops[i].StopID, StopName: stops[i].Name, StopSequence: i + 1, Time: stopTime})
+		}
+
+		return t
+	}
+
+	return &businfo.GetScheduleResponse{
+		Name: "70 - ARCHIVES - SILVER SPRING",
+		Direction0: []businfo.Trip{
+			trip("7001", "0", "NORTH", "SILVER SPRING STATION", "2019-04-29T08:00:00", []string{"2019-04-29T08:00:00", "2019-04-29T08:04:00", "2019-04-29T08:38:00"}),
+			trip("7003", "0", "NORTH", "SILVER SPRING STATION", "2019-04-29T08:12:00", []string{"2019-04-29T08:12:00", "2019-04-29T08:16:00", "2019-04-29T08:50:00"}),
+		},
+		Direction1: []businfo.Trip{
+			trip("7002", "1", "SOUTH", "ARCHIVES", "2019-04-29T08:05:00", []string{"2019-04-29T08:05:00", "2019-04-29T08:41:00", "2019-04-29T08:46:00"}),
+			trip("7004", "1", "SOUTH", "ARCHIVES", "2019-04-29T08:17:00", []string{"2019-04-29T08:17:00", "2019-04-29T08:53:00", "2019-04-29T08:58:00"}),
+		},
+	}
+}
+
+// BusStopSchedule returns the canned response for the StopSchedule endpoint for stop 1001195, built from BusSchedule
+func BusStopSchedule() *businfo.GetScheduleAtStopResponse {
+	response, _ := stopSchedule(busStops[0].StopID)
+
+	return response
+}
+
+// stopSchedule returns the arrivals of the BusSchedule trips at a stop, false when the stop is unknown
+func stopSchedule(stopID string) (*businfo.GetScheduleAtStopResponse, bool) {
+	var response businfo.GetScheduleAtStopResponse
+	found := false
+
+	for _, stop := range busStops {
+		if stop.StopID == stopID {
+			response.StopInfo, found = copyStop(stop), true
+		}
+	}
+
+	if !found {
+		return nil, false
+	}
+
+	schedule := BusSchedule()
+
+	for _, trips := range [][]businfo.Trip{schedule.Direction0, schedule.Direction1} {
+		for _, trip := range trips {
+			for _, stopTime := range trip.StopTimes {
+				if stopTime.StopID != stopID {
+					continue
+				}
+
+				response.ScheduleArrivals = append(response.ScheduleArrivals, businfo.ScheduleArrival{
+					TripID:          trip.TripID,
+					RouteID:         trip.RouteID,
+					DirectionNumber: trip.DirectionNumber,
+					TripDirection:   trip.TripDirection,
+					TripDestination: trip.TripDestination,
+					StartTime:       trip.StartTime,
+					EndTime:         trip.EndTime,
+					ScheduleTime:    stopTime.Time,
+				})
+			}
+		}
+	}
+
+	return &response, true
+}
+
+// BusPositions returns the canned response for the BusPositions endpoint without a route
+func BusPositions() *businfo.GetPositionsResponse {
+	return &businfo.GetPositionsResponse{
+		BusPositions: []businfo.BusPosition{
+			{
+				VehicleID: "5418", RouteID: "70", TripID: "7001", BlockNumber: "70-01",
+				DirectionNumber: 0, DirectionText: "NORTH", TripDestination: "SILVER SPRING STATION",
+				TripStartTime: "2019-04-29T08:00:00", TripEndTime: "2019-04-29T08:38:00",
+				DateTime: "2019-04-29T08:03:12", Deviation: 1,
+				Latitude: 38.898102, Longitude: -77.021899,
+			},
+			{
+				VehicleID: "5421", RouteID: "70", TripID: "7002", BlockNumber: "70-02",
+				DirectionNumber: 1, DirectionText: "SOUTH", TripDestination: "ARCHIVES",
+				TripStartTime: "2019-04-29T08:05:00", TripEndTime: "2019-04-29T08:46:00",
+				DateTime: "2019-04-29T08:02:48", Deviation: -2,
+				Latitude: 38.996591, Longitude: -77.030016,
+			},
+			{
+				VehicleID: "6017", RouteID: "S2", TripID: "2201", BlockNumber: "S2-04",
+				DirectionNumber: 0, DirectionText: "NORTH", TripDestination: "SILVER SPRING STATION",
+				TripStartTime: "2019-04-29T07:50:00", TripEndTime: "2019-04-29T08:35:00",
+				DateTime: "2019-04-29T08:03:01", Deviation: 4,
+				Latitude: 38.926443, Longitude: -77.036541,
+			},
+		},
+	}
+}
+
+// NextBuses returns the canned response for the Predictions endpoint at stop 1001195
+func NextBuses() *buspredictions.GetNextBusResponse {
+	return &buspredictions.GetNextBusResponse{
+		StopName: "7th St NW + Pennsylvania Ave NW",
+		NextBusPredictions: []buspredictions.NextBusPrediction{
+			{RouteID: "70", DirectionNumber: "0", DirectionText: "North to Silver Spring Station", Minutes: 3, TripID: "7003", VehicleID: "5433"},
+			{RouteID: "79", DirectionNumber: "0", DirectionText: "North to Silver Spring Station", Minutes: 7, TripID: "7905", VehicleID: "8031"},
+			{RouteID: "70", DirectionNumber: "0", DirectionText: "North to Silver Spring Station", Minutes: 15, TripID: "7005", VehicleID: "5440"},
+		},
+	}
+}
+
+// nextBusesByStop are the canned predictions for every stop with predictions
+func nextBusesByStop() map[string]*buspredictions.GetNextBusResponse {
+	return map[string]*buspredictions.GetNextBusResponse{
+		"1001195": NextBuses(),
+		"1001808": {
+			StopName: "7th St NW + H St NW",
+			NextBusPredictions: []buspredictions.NextBusPrediction{
+				{RouteID: "70", DirectionNumber: "1", DirectionText: "South to Archives", Minutes: 1, TripID: "7002", VehicleID: "5421"},
+				{RouteID: "70", DirectionNumber: "0", DirectionText: "North to Silver Spring Station", Minutes: 6, TripID: "7003", VehicleID: "5433"},
+			},
+		},
+	}
+}
+
+// RailIncidents returns the canned response for the Incidents endpoint
+func RailIncidents() *incidents.GetRailIncidentsResponse {
+	return &incidents.GetRailIncidentsResponse{
+		RailIncidents: []incidents.RailIncident{
+			{
+				IncidentID:    "3754F8B2-A0A6-494E-A4B5-82C9E72DFA74",
+				IncidentType:  "Delay",
+				Description:   "Red Line: Expect residual delays to Glenmont due to an earlier equipment problem outside Farragut North.",
+				LinesAffected: "RD;",
+				DateUpdated:   "2019-04-29T08:01:47",
+			},
+		},
+	}
+}
+
+// BusIncidents returns the canned response for the BusIncidents endpoint without a route
+func BusIncidents() *incidents.GetBusIncidentsResponse {
+	return &incidents.GetBusIncidentsResponse{
+		BusIncidents: []incidents.BusIncident{
+			{
+				IncidentID:     "32297013-A0B3-4A4B-8C5B-2A3E8E6C9A1D",
+				IncidentType:   "Delay",
+				Description:    "Due to traffic congestion on Georgia Ave, buses may experience delays up to 15 minutes.",
+				RoutesAffected: []string{"70", "79"},
+				DateUpdated:    "2019-04-29T07:48:16",
+			},
+			{
+				IncidentID:     "0C89F6B2-5C61-4C2E-9A5A-7B4A3C1D2E3F",
+				IncidentType:   "Detour",
+				Description:    "S2 buses detoured via 14th St between Colorado Ave and Decatur St due to construction.",
+				RoutesAffected: []string{"S2"},
+				DateUpdated:    "2019-04-29T06:12:03",
+			},
+		},
+	}
+}
+
+// ElevatorIncidents returns the canned response for the ElevatorIncidents endpoint without a station code
+func ElevatorIncidents() *incidents.GetElevatorEscalatorOutagesResponse {
+	return &incidents.GetElevatorEscalatorOutagesResponse{
+		ElevatorIncidents: []incidents.ElevatorIncident{
+			{
+				UnitName: "A01N04", UnitType: "ESCALATOR", StationCode: "A01", StationName: "Metro Center, G and 13th Streets entrance",
+				LocationDescription: "Escalator between street and mezzanine", SymptomDescription: "Modernization",
+				DateOutOfService: "2019-03-04T08:30:00", DateUpdated: "2019-04-28T21:15:02", EstimatedReturnToService: "2019-06-30T23:59:59",
+			},
+			{
+				UnitName: "K08X01", UnitType: "ELEVATOR", StationCode: "K08", StationName: "Vienna/Fairfax-GMU, North entrance",
+				LocationDescription: "Elevator between street and mezzanine", SymptomDescription: "Service Call",
+				DateOutOfService: "2019-04-29T05:40:00", DateUpdated: "2019-04-29T06:02:39", EstimatedReturnToService: "2019-04-30T23:59:59",
+			},
+		},
+	}
+}
@@ -0,0 +1,351 @@
+package wmatatest
+
+import (
+	"github.com/awiede/wmata-go-sdk/wmata"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultAPIKey is the API key accepted by a server created without one
+	DefaultAPIKey = "wmatatest-api-key"
+	// DefaultClientTimeout is the timeout of clients returned by Server.Client, kept short so timeout faults fail fast
+	DefaultClientTimeout = 5 * time.Second
+)
+
+// Responses returned in place of an endpoint's response, matching the bodies sent by api.wmata.com
+const (
+	unauthorizedBody = `{ "statusCode": 401, "message": "Access denied due to invalid subscription key. Make sure to provide a valid key for an active subscription." }`
+	rateLimitedBody  = `{ "statusCode": 429, "message": "Rate limit is exceeded. Try again in 1 seconds." }`
+	serverErrorBody  = `{ "statusCode": 500, "message": "Internal server error" }`
+	notFoundBody     = `{ "statusCode": 404, "message": "Resource not found" }`
+	badRequestBody   = `{"Message":"The request is invalid."}`
+)
+
+// FaultType is the failure a fault replaces a response with
+type FaultType int
+
+const (
+	// FaultUnauthorized responds 401 as if the API key was rejected
+	FaultUnauthorized FaultType = iota + 1
+	// FaultRateLimited responds 429 as if the API key's quota was exceeded
+	FaultRateLimited
+	// FaultServerError responds 500
+	FaultServerError
+	// FaultTimeout never responds, the request is held until the client gives up or the server is closed
+	FaultTimeout
+	// FaultMalformed responds 200 with the endpoint's response cut off half way through
+	FaultMalformed
+)
+
+// Fault replaces the responses to matching requests with a failure
+type Fault struct {
+	Type FaultType
+	// Path limits the fault to requests whose path starts with Path, every request matches when empty
+	Path string
+	// Times is the number of requests the fault applies to, every matching request when zero
+	Times int
+}
+
+// ResponseFunc computes the response to each request to an endpoint, see Server.SetResponse
+type ResponseFunc func(request *http.Request) interface{}
+
+// Config holds the settings of a Server
+type Config struct {
+	// APIKey is the key requests must send in the api_key header or query parameter, DefaultAPIKey when empty
+	APIKey string
+	// ClientTimeout is the timeout of clients returned by Server.Client, DefaultClientTimeout when zero
+	ClientTimeout time.Duration
+}
+
+func (config Config) withDefaults() Config {
+	if config.APIKey == "" {
+		config.APIKey = DefaultAPIKey
+	}
+
+	if config.ClientTimeout <= 0 {
+		config.ClientTimeout = DefaultClientTimeout
+	}
+
+	return config
+}
+
+// Request is a request received by a Server
+type Request struct {
+	Method string
+	Path   string
+	Query  url.Values
+	APIKey string
+	// StatusCode is the status of the response, zero when a timeout fault held the request
+	StatusCode int
+	// Fault is the fault applied to the request, zero when none was
+	Fault FaultType
+	Time  time.Time
+}
+
+// Server is a fake api.wmata.com serving canned JSON and XML responses for every endpoint used by the SDK services.
+// It checks the API key of each request, records the requests it receives and can be scripted to fail with faults
+type Server struct {
+	// URL is the base URL of the server, e.g. "http://127.0.0.1:51234"
+	URL string
+
+	config    Config
+	server    *httptest.Server
+	closed    chan struct{}
+	closeOnce sync.Once
+
+	mutex     sync.Mutex
+	requests  []Request
+	faults    []*Fault
+	overrides map[*endpoint]interface{}
+}
+
+// NewServer starts a Server, which must be closed when no longer needed
+func NewServer(config Config) *Server {
+	server := Server{
+		config:    config.withDefaults(),
+		closed:    make(chan struct{}),
+		overrides: make(map[*endpoint]interface{}),
+	}
+
+	server.server = httptest.NewServer(http.HandlerFunc(server.serveHTTP))
+	server.URL = server.server.URL
+
+	return &server
+}
+
+// Close releases requests held by timeout faults and shuts down the server
+func (server *Server) Close() {
+	server.closeOnce.Do(func() {
+		close(server.closed)
+		server.server.Close()
+	})
+}
+
+// APIKey returns the API key the server accepts
+func (server *Server) APIKey() string {
+	return server.config.APIKey
+}
+
+// HTTPClient returns an http client that sends every request to the server, whichever host it is addressed to, so
+// services built on it send their api.wmata.com requests to the server
+func (server *Server) HTTPClient() *http.Client {
+	target, _ := url.Parse(server.URL)
+
+	return &http.Client{
+		Transport: &redirectTransport{target: target, base: server.server.Client().Transport},
+		Timeout:   server.config.ClientTimeout,
+	}
+}
+
+// Client returns a wmata.Client with the server's API key that sends every request to the server. The client has no
+// rate limiter
+func (server *Server) Client() *wmata.Client {
+	return &wmata.Client{
+		APIKey:     server.config.APIKey,
+		HTTPClient: server.HTTPClient(),
+	}
+}
+
+// InjectFault adds a fault. Faults are applied in the order they were added, the first fault matching a request that
+// has not been used up is applied to it
+func (server *Server) InjectFault(fault Fault) {
+	server.mutex.Lock()
+	defer server.mutex.Unlock()
+
+	server.faults = append(server.faults, &fault)
+}
+
+// SetResponse replaces the response of the endpoint serving path, in both JSON and XML, with response. The path may
+// be either of the endpoint's JSON or XML paths, e.g. "/Rail.svc/json/jLines" or "/Rail.svc/Lines", and a nil
+// response restores the canned one. A ResponseFunc response is called for every request to the endpoint. It returns
+// false when no endpoint serves path
+func (server *Server) SetResponse(path string, response interface{}) bool {
+	e, _, _ := findEndpoint(path)
+
+	if e == nil {
+		return false
+	}
+
+	server.mutex.Lock()
+	defer server.mutex.Unlock()
+
+	if response == nil {
+		delete(server.overrides, e)
+	} else {
+		server.overrides[e] = response
+	}
+
+	return true
+}
+
+// Requests returns the requests received since the server started or was last reset, oldest first
+func (server *Server) Requests() []Request {
+	server.mutex.Lock()
+	defer server.mutex.Unlock()
+
+	requests := make([]Request, len(server.requests))
+	copy(requests, server.requests)
+
+	return requests
+}
+
+// Reset forgets the received requests, faults and replaced responses
+func (server *Server) Reset() {
+	server.mutex.Lock()
+	defer server.mutex.Unlock()
+
+	server.requests = nil
+	server.faults = nil
+	server.overrides = make(map[*endpoint]interface{})
+}
+
+func (server *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	received := Request{
+		Method: r.Method,
+		Path:   r.URL.Path,
+		Query:  r.URL.Query(),
+		APIKey: r.Header.Get(wmata.APIKeyHeader),
+		Time:   time.Now(),
+	}
+
+	if received.APIKey == "" {
+		received.APIKey = received.Query.Get(wmata.APIKeyHeader)
+	}
+
+	if received.APIKey != server.config.APIKey {
+		received.StatusCode = writeJSON(w, http.StatusUnauthorized, []byte(unauthorizedBody))
+		server.record(received)
+		return
+	}
+
+	received.Fault = server.nextFault(r.URL.Path)
+
+	switch received.Fault {
+	case FaultUnauthorized:
+		received.StatusCode = writeJSON(w, http.StatusUnauthorized, []byte(unauthorizedBody))
+	case FaultRateLimited:
+		received.StatusCode = writeJSON(w, http.StatusTooManyRequests, []byte(rateLimitedBody))
+	case FaultServerError:
+		received.StatusCode = writeJSON(w, http.StatusInternalServerError, []byte(serverErrorBody))
+	case FaultTimeout:
+		server.record(received)
+
+		select {
+		case <-r.Context().Done():
+		case <-server.closed:
+		}
+
+		return
+	default:
+		received.StatusCode = server.respond(w, r, received.Fault == FaultMalformed)
+	}
+
+	server.record(received)
+}
+
+// respond writes the endpoint's response to a request and returns the status code
+func (server *Server) respond(w http.ResponseWriter, r *http.Request, malformed bool) int {
+	e, format, tail := findEndpoint(r.URL.Path)
+
+	if e == nil {
+		return writeJSON(w, http.StatusNotFound, []byte(notFoundBody))
+	}
+
+	if e.format != nil {
+		format = e.format(r.URL.Query())
+	}
+
+	server.mutex.Lock()
+	response, overridden := server.overrides[e]
+	server.mutex.Unlock()
+
+	if !overridden {
+		var valid bool
+		response, valid = e.respond(r.URL.Query(), tail)
+
+		if !valid {
+			return writeJSON(w, http.StatusBadRequest, []byte(badRequestBody))
+		}
+	}
+
+	if respond, dynamic := response.(ResponseFunc); dynamic {
+		response = respond(r)
+	}
+
+	body, contentType, encodeErr := encode(response, format)
+
+	if encodeErr != nil {
+		return writeJSON(w, http.StatusInternalServerError, []byte(serverErrorBody))
+	}
+
+	if malformed {
+		body = body[:len(body)/2]
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.WriteHeader(http.StatusOK)
+	w.Write(body)
+
+	return http.StatusOK
+}
+
+// nextFault returns the type of the first fault matching path and uses it up, zero when no fault matches
+func (server *Server) nextFault(path string) FaultType {
+	server.mutex.Lock()
+	defer server.mutex.Unlock()
+
+	for i, fault := range server.faults {
+		if !strings.HasPrefix(path, fault.Path) {
+			continue
+		}
+
+		if fault.Times > 0 {
+			fault.Times--
+
+			if fault.Times == 0 {
+				server.faults = append(server.faults[:i], server.faults[i+1:]...)
+			}
+		}
+
+		return fault.Type
+	}
+
+	return 0
+}
+
+func (server *Server) record(request Request) {
+	server.mutex.Lock()
+	defer server.mutex.Unlock()
+
+	server.requests = append(server.requests, request)
+}
+
+func writeJSON(w http.ResponseWriter, statusCode int, body []byte) int {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(statusCode)
+	w.Write(body)
+
+	return statusCode
+}
+
+// redirectTransport sends requests to target in place of the host they are addressed to
+type redirectTransport struct {
+	target *url.URL
+	base   http.RoundTripper
+}
+
+func (transport *redirectTransport) RoundTrip(request *http.Request) (*http.Response, error) {
+	redirected := request.WithContext(request.Context())
+	requestURL := *request.URL
+	requestURL.Scheme = transport.target.Scheme
+	requestURL.Host = transport.target.Host
+	redirected.URL = &requestURL
+	redirected.Host = ""
+
+	return transport.base.RoundTrip(redirected)
+}
@@ -0,0 +1,386 @@
+package wmatatest
+
+import (
+	"encoding/json"
+	"github.com/awiede/wmata-go-sdk/wmata"
+	"github.com/awiede/wmata-go-sdk/wmata/businfo"
+	"github.com/awiede/wmata-go-sdk/wmata/buspredictions"
+	"github.com/awiede/wmata-go-sdk/wmata/incidents"
+	"github.com/awiede/wmata-go-sdk/wmata/railinfo"
+	"github.com/awiede/wmata-go-sdk/wmata/railpredictions"
+	"github.com/awiede/wmata-go-sdk/wmata/trainpositions"
+	"github.com/kr/pretty"
+	"io/ioutil"
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type serviceTest struct {
+	name     string
+	call     func() (interface{}, error)
+	expected interface{}
+}
+
+func serviceTests(client *wmata.Client, responseType wmata.ResponseType) []serviceTest {
+	busInfo := businfo.NewService(client, responseType)
+	busPredictions := buspredictions.NewService(client, responseType)
+	incidentService := incidents.NewService(client, responseType)
+	railInfo := railinfo.NewService(client, responseType)
+	railPredictions := railpredictions.NewService(client, responseType)
+	trainPositions := trainpositions.NewService(client, responseType)
+
+	return []serviceTest{
+		{"GetLines", func() (interface{}, error) { return railInfo.GetLines() }, Lines()},
+		{"GetStationList", func() (interface{}, error) { return railInfo.GetStationList("") }, Stations()},
+		{"GetStationInformation", func() (interface{}, error) { return railInfo.GetStationInformation("A01") }, &railinfo.GetStationInformationResponse{
+			StationCode: "A01", Name: "Metro Center", StationTogether1: "C01", LineCode1: "RD", Latitude: 38.898303, Longitude: -77.028099,
+			Address: railinfo.StationAddress{Street: "607 13th St. NW", City: "Washington", State: "DC", Zip: "20005"},
+		}},
+		{"GetParkingInformation", func() (interface{}, error) { return railInfo.GetParkingInformation("") }, StationParking()},
+		{"GetPathBetweenStations", func() (interface{}, error) { return railInfo.GetPathBetweenStations("A03", "B01") }, Path()},
+		{"GetStationEntrances", func() (interface{}, error) {
+			return railInfo.GetStationEntrances(&railinfo.GetStationEntrancesRequest{})
+		}, StationEntrances()},
+		{"GetStationTimings", func() (interface{}, error) { return railInfo.GetStationTimings("") }, StationTimes()},
+		{"GetStationToStationInformation", func() (interface{}, error) { return railInfo.GetStationToStationInformation("", "") }, StationToStation()},
+		{"GetNextTrains", func() (interface{}, error) { return railPredictions.GetNextTrains(nil) }, NextTrains()},
+		{"GetLiveTrainPositions", func() (interface{}, error) { return trainPositions.GetLiveTrainPositions() }, LiveTrainPositions()},
+		{"GetStandardRoutes", func() (interface{}, error) { return trainPositions.GetStandardRoutes() }, StandardRoutes()},
+		{"GetTrackCircuits", func() (interface{}, error) { return trainPositions.GetTrackCircuits() }, TrackCircuits()},
+		{"GetPositions", func() (interface{}, error) { return busInfo.GetPositions(nil) }, BusPositions()},
+		{"GetRouteDetails", func() (interface{}, error) { return busInfo.GetRouteDetails("70", "") }, BusRouteDetails()},
+		{"GetRoutes", func() (interface{}, error) { return busInfo.GetRoutes() }, BusRoutes()},
+		{"GetSchedule", func() (interface{}, error) { return busInfo.GetSchedule("70", "", false) }, BusSchedule()},
+		{"GetScheduleAtStop", func() (interface{}, error) { return busInfo.GetScheduleAtStop("1001195", "") }, BusStopSchedule()},
+		{"GetStops", func() (interface{}, error) { return busInfo.GetStops(nil) }, BusStops()},
+		{"GetNextBuses", func() (interface{}, error) { return busPredictions.GetNextBuses("1001195") }, NextBuses()},
+		{"GetBusIncidents", func() (interface{}, error) { return incidentService.GetBusIncidents("") }, BusIncidents()},
+		{"GetOutages", func() (interface{}, error) { return incidentService.GetOutages("") }, ElevatorIncidents()},
+		{"GetRailIncidents", func() (interface{}, error) { return incidentService.GetRailIncidents() }, RailIncidents()},
+	}
+}
+
+// sameResponse compares responses by their JSON encoding, which leaves out the XMLName set when decoding XML
+func sameResponse(t *testing.T, name string, expected, actual interface{}) {
+	expectedJSON, _ := json.Marshal(expected)
+	actualJSON, _ := json.Marshal(actual)
+
+	if string(expectedJSON) != string(actualJSON) {
+		t.Errorf("%s: unexpected response:\n%v", name, pretty.Diff(expected, actual))
+	}
+}
+
+func TestServices(t *testing.T) {
+	server := NewServer(Config{})
+	defer server.Close()
+
+	for _, responseType := range []wmata.ResponseType{wmata.JSON, wmata.XML} {
+		for _, test := range serviceTests(server.Client(), responseType) {
+			response, responseErr := test.call()
+
+			if responseErr != nil {
+				t.Errorf("%s (%d): unexpected error: %s", test.name, responseType, responseErr)
+				continue
+			}
+
+			sameResponse(t, test.name, test.expected, response)
+		}
+	}
+
+	for _, request := range server.Requests() {
+		if request.StatusCode != http.StatusOK {
+			t.Errorf("unexpected status for %s: %d", request.Path, request.StatusCode)
+		}
+	}
+
+	statusCode, validateErr := server.Client().ValidateAPIKey()
+
+	if statusCode != http.StatusOK || validateErr != nil {
+		t.Errorf("unexpected validation result: %d %v", statusCode, validateErr)
+	}
+}
+
+func TestQueries(t *testing.T) {
+	server := NewServer(Config{})
+	defer server.Close()
+
+	client := server.Client()
+	railInfo := railinfo.NewService(client, wmata.XML)
+	railPredictions := railpredictions.NewService(client, wmata.JSON)
+	busPredictions := buspredictions.NewService(client, wmata.JSON)
+
+	orangeLine, orangeLineErr := railInfo.GetStationList(wmata.LineCodeOrange)
+
+	if orangeLineErr != nil || len(orangeLine.Stations) != 2 || orangeLine.Stations[0].StationCode != "C01" || orangeLine.Stations[1].StationCode != "K08" {
+		t.Errorf("unexpected orange line stations: %# v %v", pretty.Formatter(orangeLine), orangeLineErr)
+	}
+
+	path, pathErr := railInfo.GetPathBetweenStations("A01", "A03")
+
+	expectedPath := []railinfo.PathItem{
+		{LineCode: "RD", SequenceNumber: 1, StationCode: "A01", StationName: "Metro Center", DistanceToPreviousStation: 0},
+		{LineCode: "RD", SequenceNumber: 2, StationCode: "A02", StationName: "Farragut North", DistanceToPreviousStation: 4408},
+		{LineCode: "RD", SequenceNumber: 3, StationCode: "A03", StationName: "Dupont Circle", DistanceToPreviousStation: 4020},
+	}
+
+	if pathErr != nil || !reflect.DeepEqual(path.Path, expectedPath) {
+		t.Errorf("unexpected path: %v %v", pretty.Diff(path.Path, expectedPath), pathErr)
+	}
+
+	trains, trainsErr := railPredictions.GetNextTrains([]string{"A02", "B01"})
+
+	if trainsErr != nil || len(trains.Trains) != 3 {
+		t.Fatalf("unexpected predictions: %# v %v", pretty.Formatter(trains), trainsErr)
+	}
+
+	for _, train := range trains.Trains {
+		if train.LocationCode != "A02" && train.LocationCode != "B01" {
+			t.Errorf("unexpected prediction at %s", train.LocationCode)
+		}
+	}
+
+	buses, busesErr := busPredictions.GetNextBuses("1001808")
+
+	if busesErr != nil || buses.StopName != "7th St NW + H St NW" || len(buses.NextBusPredictions) != 2 {
+		t.Errorf("unexpected bus predictions: %# v %v", pretty.Formatter(buses), busesErr)
+	}
+
+	fares, faresErr := railInfo.GetStationToStationInformation("A01", "A03")
+
+	if faresErr != nil || len(fares.StationToStationInformation) != 1 || fares.StationToStationInformation[0].CompositeMiles != 1.61 {
+		t.Errorf("unexpected station to station information: %# v %v", pretty.Formatter(fares), faresErr)
+	}
+
+	// unknown stations are rejected as invalid requests
+	unknown, unknownErr := server.HTTPClient().Get(server.URL + "/Rail.svc/json/jStationInfo?StationCode=Z99&api_key=" + server.APIKey())
+
+	if unknownErr != nil {
+		t.Fatal(unknownErr)
+	}
+
+	wmata.CloseResponseBody(unknown)
+
+	if unknown.StatusCode != http.StatusBadRequest {
+		t.Errorf("expected 400 for an unknown station, got %d", unknown.StatusCode)
+	}
+}
+
+func TestAPIKey(t *testing.T) {
+	server := NewServer(Config{APIKey: "secret"})
+	defer server.Close()
+
+	client := server.Client()
+
+	if client.APIKey != "secret" {
+		t.Errorf("unexpected client API key: %s", client.APIKey)
+	}
+
+	client.APIKey = "wrong"
+	statusCode, validateErr := client.ValidateAPIKey()
+
+	if statusCode != http.StatusUnauthorized || validateErr == nil {
+		t.Errorf("expected an unauthorized validation, got %d %v", statusCode, validateErr)
+	}
+
+	// the key is also accepted as a query parameter
+	response, responseErr := server.HTTPClient().Get("https://api.wmata.com/Rail.svc/json/jLines?api_key=secret")
+
+	if responseErr != nil {
+		t.Fatal(responseErr)
+	}
+
+	wmata.CloseResponseBody(response)
+
+	if response.StatusCode != http.StatusOK {
+		t.Errorf("expected the query parameter key to be accepted, got %d", response.StatusCode)
+	}
+
+	requests := server.Requests()
+
+	if len(requests) != 2 {
+		t.Fatalf("expected 2 requests, got %d", len(requests))
+	}
+
+	if requests[0].APIKey != "wrong" || requests[0].StatusCode != http.StatusUnauthorized || requests[0].Path != "/Misc/Validate" {
+		t.Errorf("unexpected first request: %# v", pretty.Formatter(requests[0]))
+	}
+
+	if requests[1].APIKey != "secret" || requests[1].StatusCode != http.StatusOK || requests[1].Method != http.MethodGet {
+		t.Errorf("unexpected second request: %# v", pretty.Formatter(requests[1]))
+	}
+}
+
+func TestFaults(t *testing.T) {
+	server := NewServer(Config{ClientTimeout: 100 * time.Millisecond})
+	defer server.Close()
+
+	get := func(path string) (int, string) {
+		request, _ := http.NewRequest(http.MethodGet, server.URL+path, nil)
+		request.Header.Set(wmata.APIKeyHeader, server.APIKey())
+		response, responseErr := server.HTTPClient().Do(request)
+
+		if responseErr != nil {
+			t.Fatal(responseErr)
+		}
+
+		defer wmata.CloseResponseBody(response)
+
+		body, _ := ioutil.ReadAll(response.Body)
+
+		return response.StatusCode, string(body)
+	}
+
+	statusTests := []struct {
+		fault  FaultType
+		status int
+	}{
+		{FaultUnauthorized, http.StatusUnauthorized},
+		{FaultRateLimited, http.StatusTooManyRequests},
+		{FaultServerError, http.StatusInternalServerError},
+	}
+
+	for _, test := range statusTests {
+		server.InjectFault(Fault{Type: test.fault, Path: "/Rail.svc/", Times: 1})
+
+		if status, _ := get("/Incidents.svc/json/Incidents"); status != http.StatusOK {
+			t.Errorf("fault %d applied to another path: %d", test.fault, status)
+		}
+
+		if status, _ := get("/Rail.svc/json/jLines"); status != test.status {
+			t.Errorf("fault %d: expected %d, got %d", test.fault, test.status, status)
+		}
+
+		if status, _ := get("/Rail.svc/json/jLines"); status != http.StatusOK {
+			t.Errorf("fault %d was not used up, got %d", test.fault, status)
+		}
+	}
+
+	// faults are applied in order
+	server.InjectFault(Fault{Type: FaultRateLimited, Times: 2})
+	server.InjectFault(Fault{Type: FaultServerError, Times: 1})
+
+	for _, expected := range []int{http.StatusTooManyRequests, http.StatusTooManyRequests, http.StatusInternalServerError, http.StatusOK} {
+		if status, _ := get("/Rail.svc/json/jLines"); status != expected {
+			t.Errorf("expected %d, got %d", expected, status)
+		}
+	}
+
+	railInfo := railinfo.NewService(server.Client(), wmata.JSON)
+
+	server.InjectFault(Fault{Type: FaultMalformed, Times: 1})
+
+	if _, malformedErr := railInfo.GetLines(); malformedErr == nil {
+		t.Error("expected an error decoding a malformed response")
+	}
+
+	server.InjectFault(Fault{Type: FaultTimeout, Times: 1})
+
+	if _, timeoutErr := railInfo.GetLines(); timeoutErr == nil {
+		t.Error("expected a timeout error")
+	}
+
+	if _, linesErr := railInfo.GetLines(); linesErr != nil {
+		t.Errorf("unexpected error after faults: %s", linesErr)
+	}
+
+	// a fault without a limit applies until the server is reset
+	server.InjectFault(Fault{Type: FaultServerError})
+
+	for i := 0; i < 3; i++ {
+		if status, body := get("/Bus.svc/json/jRoutes"); status != http.StatusInternalServerError || body != serverErrorBody {
+			t.Errorf("unexpected response to an unlimited fault: %d %s", status, body)
+		}
+	}
+
+	requests := server.Requests()
+	last := requests[len(requests)-1]
+
+	if last.Fault != FaultServerError || last.StatusCode != http.StatusInternalServerError {
+		t.Errorf("unexpected last request: %# v", pretty.Formatter(last))
+	}
+
+	for _, request := range requests {
+		if request.Fault == FaultTimeout && request.StatusCode != 0 {
+			t.Errorf("expected no status for a timed out request, got %d", request.StatusCode)
+		}
+	}
+
+	server.Reset()
+
+	if status, _ := get("/Bus.svc/json/jRoutes"); status != http.StatusOK {
+		t.Errorf("expected reset to clear faults, got %d", status)
+	}
+
+	if requests := server.Requests(); len(requests) != 1 {
+		t.Errorf("expected reset to clear requests, got %d", len(requests))
+	}
+}
+
+func TestSetResponse(t *testing.T) {
+	server := NewServer(Config{})
+	defer server.Close()
+
+	if server.SetResponse("/Rail.svc/json/jUnknown", Lines()) {
+		t.Error("expected an unknown path to be rejected")
+	}
+
+	replaced := &railinfo.GetLinesResponse{Lines: []railinfo.LineResponse{{LineCode: "PK", DisplayName: "Purple"}}}
+
+	if !server.SetResponse("/Rail.svc/Lines", replaced) {
+		t.Fatal("expected the lines path to be accepted")
+	}
+
+	for _, responseType := range []wmata.ResponseType{wmata.JSON, wmata.XML} {
+		response, responseErr := railinfo.NewService(server.Client(), responseType).GetLines()
+
+		if responseErr != nil {
+			t.Fatal(responseErr)
+		}
+
+		sameResponse(t, "GetLines", replaced, response)
+	}
+
+	server.SetResponse("/Rail.svc/json/jLines", nil)
+	response, responseErr := railinfo.NewService(server.Client(), wmata.JSON).GetLines()
+
+	if responseErr != nil {
+		t.Fatal(responseErr)
+	}
+
+	sameResponse(t, "GetLines", Lines(), response)
+
+	server.SetResponse("/Rail.svc/json/jLines", ResponseFunc(func(request *http.Request) interface{} {
+		return &railinfo.GetLinesResponse{Lines: []railinfo.LineResponse{{LineCode: request.URL.Query().Get("LineCode")}}}
+	}))
+
+	computed := &railinfo.GetLinesResponse{}
+
+	if computeErr := server.Client().BuildAndSendGetRequest(wmata.JSON, "https://api.wmata.com/Rail.svc/json/jLines", map[string]string{"LineCode": "SV"}, computed); computeErr != nil {
+		t.Fatal(computeErr)
+	}
+
+	if len(computed.Lines) != 1 || computed.Lines[0].LineCode != "SV" {
+		t.Errorf("expected the response to be computed from the request: %# v", pretty.Formatter(computed))
+	}
+}
+
+func TestNotFound(t *testing.T) {
+	server := NewServer(Config{})
+	defer server.Close()
+
+	request, _ := http.NewRequest(http.MethodGet, server.URL+"/Rail.svc/json/jUnknown", nil)
+	request.Header.Set(wmata.APIKeyHeader, server.APIKey())
+	response, responseErr := http.DefaultClient.Do(request)
+
+	if responseErr != nil {
+		t.Fatal(responseErr)
+	}
+
+	wmata.CloseResponseBody(response)
+
+	if response.StatusCode != http.StatusNotFound {
+		t.Errorf("expected 404, got %d", response.StatusCode)
+	}
+}